@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"backend/database"
+	"backend/pkg/audit"
+	"backend/pkg/common"
+	"backend/pkg/generics"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// AuditLog lists audit entries filtered by the resource, entity_id and actor
+// query parameters, newest first
+func AuditLog() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		pageable, err := common.PageableFromQuery(c)
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid pagination parameters")
+		}
+
+		query := audit.Query{
+			Resource: c.Query("resource"),
+			Actor:    c.Query("actor"),
+		}
+		if entityID := c.Query("entity_id"); entityID != "" {
+			if query.EntityID, err = uuid.Parse(entityID); err != nil {
+				return generics.BadRequest(c, err, "Invalid entity id")
+			}
+		}
+
+		result, err := audit.Find(database.DB.WithContext(c.UserContext()), query, pageable)
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error reading the audit log")
+		}
+
+		dtos := make([]common.DTO, len(result.Items))
+		for i, entry := range result.Items {
+			dtos[i] = entry.ToDTO()
+		}
+
+		return generics.Found(c,
+			common.NewPage[common.DTO](
+				dtos,
+				result.Page,
+				result.Size,
+				result.Total,
+				result.Filtered),
+			"Found audit entries")
+	}
+}
@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/pkg/audit"
+	"backend/pkg/calendar"
+	"backend/pkg/common"
+	"backend/pkg/generics"
+	"backend/pkg/tenancy"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// ReservationCalendar renders one reservation as an iCalendar document
+func ReservationCalendar() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid reservation id")
+		}
+
+		reservation, err := generics.NewGenericRepositoryGORM[*models.Reservation, *models.ReservationDTO]().
+			WithContext(c.UserContext()).
+			FindOne(id, []string{"Business", "Service"})
+		if err != nil {
+			return generics.NotFound(c, err, "reservation not found")
+		}
+
+		return sendCalendar(c, "reservation-"+id.String(), calendar.Calendar{
+			Events: []calendar.Event{reservationEvent(*reservation)},
+		})
+	}
+}
+
+// BusinessCalendar renders the reservations of a business as a feed calendar
+// apps can subscribe to
+func BusinessCalendar() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid business id")
+		}
+
+		business, err := generics.NewGenericRepositoryGORM[*models.Business, *models.BusinessDTO]().
+			WithContext(c.UserContext()).
+			FindOne(id, common.NoFields)
+		if err != nil {
+			return generics.NotFound(c, err, "business not found")
+		}
+
+		events, err := feedEvents(c.UserContext(), "business_id = ?", id)
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error reading the reservations")
+		}
+
+		return sendCalendar(c, "business-"+id.String(), calendar.Calendar{
+			Name:   business.Name,
+			Events: events,
+		})
+	}
+}
+
+// UserCalendar renders the reservations of the user owning the token in the
+// path. It is public, the token resolves the tenant
+func UserCalendar() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var token models.CalendarToken
+		err := database.DB.
+			WithContext(common.AsSystem(c.UserContext())).
+			Preload("User").
+			First(&token, "token_hash = ?", models.HashCalendarToken(c.Params("token"))).Error
+		if err != nil {
+			return generics.NotFound(c, err, "calendar not found")
+		}
+
+		ctx := common.WithTenant(c.UserContext(), token.TenantID)
+		events, err := feedEvents(ctx, "user_id = ?", token.UserID)
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error reading the reservations")
+		}
+
+		return sendCalendar(c, "user-"+token.UserID.String(), calendar.Calendar{
+			Name:   token.User.Name,
+			Events: events,
+		})
+	}
+}
+
+// IssueCalendarToken replaces the calendar token of a user with a new one.
+// The token is only sent in this response, along with the URL of the feed
+// served under feedPath
+func IssueCalendarToken(feedPath string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid user id")
+		}
+
+		exists, err := generics.NewGenericRepositoryGORM[*models.User, *models.UserDTO]().
+			WithContext(c.UserContext()).
+			Exists(id)
+		if err != nil || !exists {
+			return generics.NotFound(c, err, "user not found")
+		}
+
+		token, secret, err := models.NewCalendarToken(id)
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error issuing the calendar token")
+		}
+
+		err = database.DB.WithContext(c.UserContext()).Transaction(func(tx *gorm.DB) error {
+			if _, err := revokeCalendarTokens(tx, id); err != nil {
+				return err
+			}
+			if err := tenancy.Assign(tx, token); err != nil {
+				return err
+			}
+			if err := tx.Create(token).Error; err != nil {
+				return err
+			}
+			return audit.Record(tx, audit.Created, "calendar_tokens", token, nil, token.ToDTO())
+		})
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error issuing the calendar token")
+		}
+
+		dto := token.ToDTO().(*models.CalendarTokenDTO)
+		dto.Token = secret
+		dto.URL = fmt.Sprintf("%s%s/%s.ics", c.BaseURL(), feedPath, secret)
+		return generics.Created(c, dto, "calendar token issued")
+	}
+}
+
+// RevokeCalendarToken deletes the calendar token of a user, its feed stops
+// working at once
+func RevokeCalendarToken() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid user id")
+		}
+
+		var revoked int64
+		err = database.DB.WithContext(c.UserContext()).Transaction(func(tx *gorm.DB) error {
+			revoked, err = revokeCalendarTokens(tx, id)
+			return err
+		})
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error revoking the calendar token")
+		}
+		if revoked == 0 {
+			return generics.NotFound(c, errors.New("the user has no calendar token"), "calendar token not found")
+		}
+
+		return generics.Deleted(c, "calendar token revoked")
+	}
+}
+
+func revokeCalendarTokens(tx *gorm.DB, userID uuid.UUID) (int64, error) {
+	var tokens []models.CalendarToken
+	if err := tx.Scopes(tenancy.Scope).Find(&tokens, "user_id = ?", userID).Error; err != nil {
+		return 0, err
+	}
+	for _, token := range tokens {
+		if err := tx.Unscoped().Delete(&token).Error; err != nil {
+			return 0, err
+		}
+		if err := audit.Record(tx, audit.HardDeleted, "calendar_tokens", &token, token.ToDTO(), nil); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(tokens)), nil
+}
+
+// feedEvents returns the events of the reservations matching query, from
+// services.calendar.past_days ago on. Cancelled reservations are kept so
+// subscribed clients remove them
+func feedEvents(ctx context.Context, query string, args ...interface{}) ([]calendar.Event, error) {
+	since := time.Now().AddDate(0, 0, -viper.GetInt("services.calendar.past_days"))
+
+	var reservations []models.Reservation
+	err := database.DB.
+		WithContext(ctx).
+		Scopes(tenancy.Scope, generics.Preload([]string{"Business", "Service"})).
+		Where(query, args...).
+		Where("date >= ?", since).
+		Order("date").
+		Find(&reservations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]calendar.Event, len(reservations))
+	for i, reservation := range reservations {
+		events[i] = reservationEvent(reservation)
+	}
+	return events, nil
+}
+
+// reservationEvent describes a reservation, until it ends
+func reservationEvent(reservation models.Reservation) calendar.Event {
+	status := calendar.Confirmed
+	summary := fmt.Sprintf("Reservation for %d at %s", reservation.NumberOfPeople, reservation.Business.Name)
+	if reservation.Service != nil {
+		summary = fmt.Sprintf("%s for %d at %s", reservation.Service.Name, reservation.NumberOfPeople, reservation.Business.Name)
+	}
+	switch reservation.Status {
+	case models.ReservationPending:
+		status = calendar.Tentative
+	case models.ReservationCancelled:
+		status = calendar.Cancelled
+		summary = "Cancelled: " + summary
+	}
+
+	return calendar.Event{
+		UID:         reservation.ID.String(),
+		Start:       reservation.Date,
+		End:         reservation.EndsAt,
+		Summary:     summary,
+		Description: fmt.Sprintf("Party of %d\nStatus: %s", reservation.NumberOfPeople, reservation.Status),
+		Location:    reservation.Business.Location,
+		Status:      status,
+		// Seconds between the creation and the last update only grow
+		Sequence:     int(reservation.UpdatedAt.Sub(reservation.CreatedAt).Seconds()),
+		Created:      reservation.CreatedAt,
+		LastModified: reservation.UpdatedAt,
+	}
+}
+
+func sendCalendar(c *fiber.Ctx, name string, document calendar.Calendar) error {
+	document.ProdID = fmt.Sprintf("-//%s//Reservations//EN", viper.GetString("general.app.name"))
+	c.Set(fiber.HeaderContentType, calendar.ContentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("inline; filename=%q", name+".ics"))
+	return calendar.Write(c, document)
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/pkg/common"
+	"backend/pkg/generics"
+	"backend/pkg/helpers"
+	"backend/pkg/tenancy"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CancelReservation cancels a reservation for the reason of the payload. The
+// response is the cancelled reservation with the fee its business charged
+func CancelReservation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid reservation id")
+		}
+		var payload models.CancellationDTO
+		if err := c.BodyParser(&payload); err != nil {
+			return generics.BadRequest(c, err, "Invalid cancellation payload")
+		}
+		if errs := helpers.ValidateStruct(payload); len(errs) > 0 {
+			return generics.PayloadValidationFailed(c, errs, "Invalid cancellation payload")
+		}
+
+		db := database.DB.WithContext(c.UserContext())
+		var reservation models.Reservation
+		if err := db.Scopes(tenancy.Scope).First(&reservation, "id = ?", id).Error; err != nil {
+			return generics.NotFound(c, err, "reservation not found")
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return models.CancelReservation(tx, &reservation, payload.Reason)
+		})
+		if errors.Is(err, models.ErrReservationCancelled) {
+			return generics.Conflict(c, err, "The reservation cannot be cancelled")
+		}
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error cancelling the reservation")
+		}
+		return generics.Updated(c, reservation.ToDTO(), "reservation cancelled")
+	}
+}
+
+// ResetNoShows forgives the no-shows of a user, who can book again the
+// businesses that blocked them. The response is the user with the counter reset
+func ResetNoShows() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid user id")
+		}
+
+		db := database.DB.WithContext(c.UserContext())
+		var user models.User
+		if err := db.Scopes(tenancy.Scope).First(&user, "id = ?", id).Error; err != nil {
+			return generics.NotFound(c, err, "user not found")
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return models.ResetNoShows(tx, &user)
+		})
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error resetting the no-shows of the user")
+		}
+		return generics.Updated(c, common.Redact(user.ToDTO()), "no-shows reset")
+	}
+}
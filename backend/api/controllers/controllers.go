@@ -1,9 +1,100 @@
 package controllers
 
-import "backend/pkg/generics"
+import (
+	"backend/models"
+	"backend/pkg/calendar"
+	"backend/pkg/common"
+	"backend/pkg/generics"
+)
 
 func init() {
-	// TODO Needs to be implemented
+	RegisterController(generics.NewController[*models.User, *models.UserDTO](
+		generics.ResourceNames{Singular: "user", Plural: "users"}).
+		WithNaturalKey("email"),
+		generics.RouteDefinition{Verb: "POST", Path: "/:id/calendar-token", Handler: IssueCalendarToken("/api/v1/calendar"),
+			Name: "Issue calendar token of one user", Response: &models.CalendarTokenDTO{}},
+		generics.RouteDefinition{Verb: "DELETE", Path: "/:id/calendar-token", Handler: RevokeCalendarToken(),
+			Name: "Revoke calendar token of one user", Response: ""},
+		generics.RouteDefinition{Verb: "POST", Path: "/:id/no-shows/reset", Handler: ResetNoShows(),
+			Name: "Reset no-shows of one user", Response: &models.UserDTO{}})
+	RegisterController(generics.NewController[*models.Business, *models.BusinessDTO](
+		generics.ResourceNames{Singular: "business", Plural: "businesses"}).
+		WithNaturalKey("owner_id", "name"),
+		generics.RouteDefinition{Verb: "GET", Path: "/:id/calendar.ics", Handler: BusinessCalendar(),
+			Name: "Get calendar of one business", ContentType: calendar.ContentType},
+		generics.RouteDefinition{Verb: "POST", Path: "/:id/waitlist", Handler: JoinWaitlist(),
+			Name: "Join waitlist of one business", Request: &models.WaitlistEntryDTO{}, Response: &models.WaitlistEntryDTO{}},
+		generics.RouteDefinition{Verb: "GET", Path: "/:id/waitlist", Handler: ListWaitlist(),
+			Name: "Get waitlist of one business", Response: []models.WaitlistEntryDTO{}},
+		generics.RouteDefinition{Verb: "GET", Path: "/:id/waitlist/:entry", Handler: GetWaitlistEntry(),
+			Name: "Get waitlist entry of one business", Response: &models.WaitlistEntryDTO{}},
+		generics.RouteDefinition{Verb: "POST", Path: "/:id/waitlist/:entry/confirm", Handler: ConfirmWaitlistEntry(),
+			Name: "Confirm waitlist entry of one business", Response: &models.WaitlistEntryDTO{}},
+		generics.RouteDefinition{Verb: "DELETE", Path: "/:id/waitlist/:entry", Handler: LeaveWaitlist(),
+			Name: "Leave waitlist of one business", Response: &models.WaitlistEntryDTO{}},
+		generics.RouteDefinition{Verb: "POST", Path: "/:id/holds", Handler: PlaceHold(),
+			Name: "Place hold on one business", Request: &models.HoldDTO{}, Response: &models.HoldDTO{}},
+		generics.RouteDefinition{Verb: "GET", Path: "/:id/holds/:hold", Handler: GetHold(),
+			Name: "Get hold of one business", Response: &models.HoldDTO{}},
+		generics.RouteDefinition{Verb: "POST", Path: "/:id/holds/:hold/confirm", Handler: ConfirmHold(),
+			Name: "Confirm hold of one business", Request: &models.HoldConfirmationDTO{}, Response: &models.ReservationDTO{}},
+		generics.RouteDefinition{Verb: "DELETE", Path: "/:id/holds/:hold", Handler: ReleaseHold(),
+			Name: "Release hold of one business", Response: &models.HoldDTO{}},
+		generics.RouteDefinition{Verb: "GET", Path: "/:id/ledger", Handler: BusinessLedger(),
+			Name: "Get ledger of one business", Response: []models.LedgerEntryDTO{}},
+		generics.RouteDefinition{Verb: "POST", Path: "/:id/holidays", Handler: ImportHolidays(),
+			Name: "Import holidays of one business", Accepts: calendar.MediaType, Response: common.ImportResult{},
+			Query: []generics.QueryParameter{generics.DryRunQuery}})
+	RegisterController(generics.NewController[*models.Schedule, *models.ScheduleDTO](
+		generics.ResourceNames{Singular: "schedule", Plural: "schedules"}).
+		WithNaturalKey("business_id", "staff_id", "day_of_week", "start_time"))
+	RegisterController(generics.NewController[*models.ScheduleException, *models.ScheduleExceptionDTO](
+		generics.ResourceNames{Singular: "schedule exception", Plural: "schedule-exceptions"}).
+		WithNaturalKey("business_id", "date", "start_time"))
+	RegisterController(generics.NewController[*models.Resource, *models.ResourceDTO](
+		generics.ResourceNames{Singular: "resource", Plural: "resources"}).
+		WithNaturalKey("business_id", "name"))
+	RegisterController(generics.NewController[*models.Service, *models.ServiceDTO](
+		generics.ResourceNames{Singular: "service", Plural: "services"}).
+		WithNaturalKey("business_id", "name"))
+	RegisterController(generics.NewController[*models.Staff, *models.StaffDTO](
+		generics.ResourceNames{Singular: "staff member", Plural: "staff"}).
+		WithNaturalKey("business_id", "name"))
+	RegisterController(generics.NewController[*models.TimeOff, *models.TimeOffDTO](
+		generics.ResourceNames{Singular: "time off", Plural: "time-off"}))
+	RegisterController(generics.NewController[*models.CancellationPolicy, *models.CancellationPolicyDTO](
+		generics.ResourceNames{Singular: "cancellation policy", Plural: "cancellation-policies"}).
+		WithNaturalKey("business_id"))
+	RegisterController(generics.NewController[*models.DepositRule, *models.DepositRuleDTO](
+		generics.ResourceNames{Singular: "deposit rule", Plural: "deposit-rules"}).
+		WithNaturalKey("business_id", "service_id"))
+	RegisterController(generics.NewController[*models.WebhookSubscription, *models.WebhookSubscriptionDTO](
+		generics.ResourceNames{Singular: "webhook subscription", Plural: "webhook-subscriptions"}),
+		generics.RouteDefinition{Verb: "GET", Path: "/:id/deliveries", Handler: ListWebhookDeliveries(),
+			Name: "Get deliveries of one webhook subscription", Response: []models.WebhookDeliveryDTO{}, Query: DeliveriesQuery},
+		generics.RouteDefinition{Verb: "POST", Path: "/:id/deliveries/:delivery/replay", Handler: ReplayWebhookDelivery(),
+			Name: "Replay one webhook delivery", Response: &models.WebhookDeliveryDTO{}},
+		generics.RouteDefinition{Verb: "POST", Path: "/:id/test", Handler: TestWebhookSubscription(),
+			Name: "Test one webhook subscription", Response: &models.WebhookDeliveryDTO{}})
+	RegisterController(generics.NewController[*models.RecurringReservation, *models.RecurringReservationDTO](
+		generics.ResourceNames{Singular: "recurring reservation", Plural: "recurring-reservations"}),
+		generics.RouteDefinition{Verb: "GET", Path: "/:id/occurrences", Handler: RecurringOccurrences(),
+			Name: "Get occurrences of one recurring reservation", Response: []models.Occurrence{}, Query: OccurrencesQuery},
+		generics.RouteDefinition{Verb: "PUT", Path: "/:id/occurrences/:occurrence", Handler: UpdateOccurrences(),
+			Name: "Update occurrences of one recurring reservation", Request: &models.ReservationDTO{},
+			Response: &models.RecurringReservationDTO{}, Query: []generics.QueryParameter{ScopeQuery}})
+	RegisterController(generics.NewController[*models.Reservation, *models.ReservationDTO](
+		generics.ResourceNames{Singular: "reservation", Plural: "reservations"}),
+		generics.RouteDefinition{Verb: "GET", Path: "/:id.ics", Handler: ReservationCalendar(),
+			Name: "Get calendar of one reservation", ContentType: calendar.ContentType},
+		generics.RouteDefinition{Verb: "POST", Path: "/:id/cancel", Handler: CancelReservation(),
+			Name: "Cancel one reservation", Request: &models.CancellationDTO{}, Response: &models.ReservationDTO{}},
+		generics.RouteDefinition{Verb: "POST", Path: "/:id/deposit", Handler: RequestDeposit(),
+			Name: "Request deposit of one reservation", Response: &models.PaymentDTO{}},
+		generics.RouteDefinition{Verb: "GET", Path: "/:id/payments", Handler: ListPayments(),
+			Name: "Get payments of one reservation", Response: []models.PaymentDTO{}},
+		generics.RouteDefinition{Verb: "GET", Path: "/:id/notifications", Handler: ListNotifications(),
+			Name: "Get notifications of one reservation", Response: []models.NotificationDTO{}})
 }
 
 var controllers = map[string]generics.GenericController{}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/pkg/audit"
+	"backend/pkg/calendar"
+	"backend/pkg/common"
+	"backend/pkg/generics"
+	"backend/pkg/tenancy"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errHolidaysFailed = errors.New("import failed")
+	errHolidaysDryRun = errors.New("dry run")
+)
+
+// ImportHolidays closes a business on the days of the events of an
+// iCalendar document, like a public holiday calendar, sent as the body or as
+// the file field of a multipart form. Every day an event covers gets a closed
+// exception named after it, the closed exception already on that day is
+// renamed instead, so importing a calendar again updates it. Cancelled events
+// are skipped and recurring ones refused. As for CSV imports, nothing is
+// committed when an event fails or on a dry run
+func ImportHolidays() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid business id")
+		}
+		var document io.Reader = bytes.NewReader(c.Body())
+		if strings.HasPrefix(string(c.Request().Header.ContentType()), fiber.MIMEMultipartForm) {
+			header, err := c.FormFile("file")
+			if err != nil {
+				return generics.BadRequest(c, err, "Invalid holidays document")
+			}
+			file, err := header.Open()
+			if err != nil {
+				return generics.BadRequest(c, err, "Invalid holidays document")
+			}
+			defer file.Close()
+			document = file
+		}
+		events, err := calendar.Read(document)
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid holidays document")
+		}
+		if limit := viper.GetInt("services.import.max_rows"); limit > 0 && len(events) > limit {
+			return generics.BadRequest(c, fmt.Errorf("at most %d events are allowed", limit), "Invalid holidays document")
+		}
+
+		db := database.DB.WithContext(c.UserContext())
+		var business models.Business
+		if err := db.Scopes(tenancy.Scope).First(&business, "id = ?", id).Error; err != nil {
+			return generics.NotFound(c, err, "business not found")
+		}
+
+		dryRun := c.QueryBool(generics.DryRunQuery.Name)
+		result := common.ImportResult{DryRun: dryRun, Rows: []common.ImportRowResult{}}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for _, event := range events {
+				if event.Status == calendar.Cancelled {
+					continue
+				}
+				item := common.ImportRowResult{Line: event.Line, Status: common.Success}
+				err := tx.Transaction(func(eventTx *gorm.DB) error {
+					return importHoliday(eventTx, business, event, &item)
+				})
+				if err != nil {
+					item.Status = common.Error
+					item.Error = err.Error()
+					item.ID = uuid.Nil
+					item.Action = ""
+				}
+				result.Add(item)
+			}
+
+			switch {
+			case len(result.Rows) == 0:
+				return errors.New("at least one event is required")
+			case result.Failed > 0:
+				return errHolidaysFailed
+			case dryRun:
+				return errHolidaysDryRun
+			}
+			return nil
+		})
+		result.Committed = err == nil
+		if err != nil && !errors.Is(err, errHolidaysFailed) && !errors.Is(err, errHolidaysDryRun) {
+			return generics.BadRequest(c, err, "Invalid holidays document")
+		}
+
+		message := fmt.Sprintf("%d holidays created and %d updated", result.Created, result.Updated)
+		if result.DryRun {
+			message = fmt.Sprintf("%d holidays would be created and %d updated", result.Created, result.Updated)
+		}
+		return generics.Imported(c, result, message)
+	}
+}
+
+// importHoliday closes business on every day event covers. The row reports
+// the exception of the first day, as created when any day was
+func importHoliday(tx *gorm.DB, business models.Business, event calendar.Event, item *common.ImportRowResult) error {
+	if event.Rule != "" {
+		return errors.New("recurring events are not supported, list every occurrence instead")
+	}
+	name := event.Summary
+	if name == "" {
+		name = "Closed"
+	}
+
+	// The end of an event is exclusive, an all-day event ends at midnight of
+	// the day after its last one. Timed events close the days they cover in
+	// the time zone of the business
+	start, end := event.Start, event.End
+	if !event.AllDay {
+		start, end = start.In(business.Zone()), end.In(business.Zone())
+	}
+	last := end.Add(-time.Nanosecond)
+	if last.Before(start) {
+		last = start
+	}
+
+	item.Action = common.ImportUpdated
+	for date := models.Day(start); !date.After(models.Day(last)); date = date.AddDate(0, 0, 1) {
+		exception := models.ScheduleException{BusinessID: business.ID, Date: date, Name: name, Closed: true}
+		var existing models.ScheduleException
+		err := tx.
+			Scopes(tenancy.Scope).
+			Where("business_id = ? AND date = ? AND closed = ?", business.ID, date, true).
+			First(&existing).Error
+		switch {
+		case err == nil:
+			before := existing.ToDTO()
+			existing.Name = name
+			if err := tx.Omit(clause.Associations).Save(&existing).Error; err != nil {
+				return err
+			}
+			if err := audit.Record(tx, audit.Updated, "schedule_exceptions", &existing, before, existing.ToDTO()); err != nil {
+				return err
+			}
+			exception = existing
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tenancy.Assign(tx, &exception); err != nil {
+				return err
+			}
+			if err := tx.Omit(clause.Associations).Create(&exception).Error; err != nil {
+				return err
+			}
+			if err := audit.Record(tx, audit.Created, "schedule_exceptions", &exception, nil, exception.ToDTO()); err != nil {
+				return err
+			}
+			item.Action = common.ImportCreated
+		default:
+			return err
+		}
+		if item.ID == uuid.Nil {
+			item.ID = exception.ID
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"backend/pkg/generics"
+	"backend/pkg/graph"
+	"errors"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/graphql-go/graphql"
+)
+
+var (
+	graphSchema     graphql.Schema
+	graphSchemaErr  error
+	graphSchemaOnce sync.Once
+)
+
+// GraphSchema returns the GraphQL schema of the registered controllers,
+// built the first time it is needed
+func GraphSchema() (graphql.Schema, error) {
+	graphSchemaOnce.Do(func() {
+		services := make([]generics.Service, 0, len(controllers))
+		for _, controller := range GetControllers() {
+			services = append(services, controller.Service())
+		}
+		graphSchema, graphSchemaErr = graph.NewSchema(services)
+	})
+	return graphSchema, graphSchemaErr
+}
+
+type graphRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// GraphQL executes the query of a POST request. The result follows the
+// GraphQL specification instead of the envelope of the REST API
+func GraphQL() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		schema, err := GraphSchema()
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error building the GraphQL schema")
+		}
+
+		var request graphRequest
+		if err := c.BodyParser(&request); err != nil {
+			return generics.BadRequest(c, err, "Invalid GraphQL request")
+		}
+		if request.Query == "" {
+			return generics.BadRequest(c, errors.New("query is required"), "Invalid GraphQL request")
+		}
+
+		result := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  request.Query,
+			VariableValues: request.Variables,
+			OperationName:  request.OperationName,
+			Context:        graph.WithLoaders(c.UserContext()),
+		})
+		return c.JSON(result)
+	}
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/pkg/generics"
+	"backend/pkg/helpers"
+	"backend/pkg/tenancy"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PlaceHold holds seats of a business for the party of the payload while
+// the guest checks out. The response carries the hold id and its TTL
+func PlaceHold() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid business id")
+		}
+		var payload models.HoldDTO
+		if err := c.BodyParser(&payload); err != nil {
+			return generics.BadRequest(c, err, "Invalid hold payload")
+		}
+		if errs := helpers.ValidateStruct(payload); len(errs) > 0 {
+			return generics.PayloadValidationFailed(c, errs, "Invalid hold payload")
+		}
+		if !payload.Date.After(time.Now()) {
+			return generics.BadRequest(c, errors.New("the date has passed"), "Invalid hold payload")
+		}
+
+		db := database.DB.WithContext(c.UserContext())
+		var business models.Business
+		if err := db.Scopes(tenancy.Scope).First(&business, "id = ?", id).Error; err != nil {
+			return generics.NotFound(c, err, "business not found")
+		}
+
+		var hold *models.Hold
+		err = db.Transaction(func(tx *gorm.DB) error {
+			service, err := models.FindService(tx, business, payload.ServiceID)
+			if err != nil {
+				return err
+			}
+			staff, err := models.FindStaff(tx, business, payload.StaffID)
+			if err != nil {
+				return err
+			}
+			booking := models.Booking{Business: business, Service: service, Staff: staff, Date: payload.Date.UTC(), People: payload.NumberOfPeople}
+			hold, err = models.PlaceHold(tx, booking, payload.ResourceIDs)
+			return err
+		})
+		if errors.Is(err, models.ErrBusinessFull) {
+			return generics.Conflict(c, err, "The business has no room left at that time")
+		}
+		if errors.Is(err, models.ErrStaffUnavailable) {
+			return generics.Conflict(c, err, "The staff member is not available at that time")
+		}
+		if errors.Is(err, models.ErrBusinessClosed) || errors.Is(err, models.ErrResourceUnfit) ||
+			errors.Is(err, models.ErrServiceNotFound) || errors.Is(err, models.ErrStaffNotFound) || errors.Is(err, models.ErrStaffUnqualified) {
+			return generics.BadRequest(c, err, "Invalid hold payload")
+		}
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error placing the hold")
+		}
+
+		return generics.Created(c, hold.ToDTO(), "hold created")
+	}
+}
+
+// GetHold returns a hold with the time it has left
+func GetHold() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		hold, err := businessHold(c, database.DB.WithContext(c.UserContext()))
+		if hold == nil {
+			return err
+		}
+		return generics.Found(c, hold.ToDTO(), "Found hold")
+	}
+}
+
+// ConfirmHold books the seats of an active hold as a reservation of the
+// guest of the payload, and returns the reservation
+func ConfirmHold() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		db := database.DB.WithContext(c.UserContext())
+		hold, err := businessHold(c, db)
+		if hold == nil {
+			return err
+		}
+		var payload models.HoldConfirmationDTO
+		if err := c.BodyParser(&payload); err != nil {
+			return generics.BadRequest(c, err, "Invalid hold confirmation payload")
+		}
+		if errs := helpers.ValidateStruct(payload); len(errs) > 0 {
+			return generics.PayloadValidationFailed(c, errs, "Invalid hold confirmation payload")
+		}
+
+		var reservation *models.Reservation
+		err = db.Transaction(func(tx *gorm.DB) error {
+			reservation, err = hold.Confirm(tx, payload.UserID)
+			return err
+		})
+		if errors.Is(err, models.ErrHoldInactive) {
+			return generics.Conflict(c, err, "The hold cannot be confirmed")
+		}
+		// The reservation is checked again as it is saved, the business may
+		// have closed or the staff member left since the hold was placed
+		if errors.Is(err, models.ErrBusinessFull) {
+			return generics.Conflict(c, err, "The business has no room left at that time")
+		}
+		if errors.Is(err, models.ErrStaffUnavailable) {
+			return generics.Conflict(c, err, "The staff member is not available at that time")
+		}
+		if errors.Is(err, models.ErrBusinessClosed) || errors.Is(err, models.ErrResourceUnfit) ||
+			errors.Is(err, models.ErrServiceNotFound) || errors.Is(err, models.ErrStaffNotFound) || errors.Is(err, models.ErrStaffUnqualified) ||
+			errors.Is(err, models.ErrGuestBlocked) {
+			return generics.BadRequest(c, err, "Invalid hold confirmation payload")
+		}
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error confirming the hold")
+		}
+		return generics.Created(c, reservation.ToDTO(), "reservation created")
+	}
+}
+
+// ReleaseHold gives the seats of an active hold back before it expires
+func ReleaseHold() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		db := database.DB.WithContext(c.UserContext())
+		hold, err := businessHold(c, db)
+		if hold == nil {
+			return err
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return hold.Release(tx, models.HoldReleased)
+		})
+		if errors.Is(err, models.ErrHoldInactive) {
+			return generics.Conflict(c, err, "The hold cannot be released")
+		}
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error releasing the hold")
+		}
+		return generics.Updated(c, hold.ToDTO(), "hold released")
+	}
+}
+
+// businessHold loads the hold of the request. Without a hold the error
+// response has been sent, and the error sending it returned
+func businessHold(c *fiber.Ctx, db *gorm.DB) (*models.Hold, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, generics.BadRequest(c, err, "Invalid business id")
+	}
+	holdID, err := uuid.Parse(c.Params("hold"))
+	if err != nil {
+		return nil, generics.BadRequest(c, err, "Invalid hold id")
+	}
+	var hold models.Hold
+	err = db.
+		Scopes(tenancy.Scope, generics.Preload([]string{"Allocations"})).
+		First(&hold, "id = ? AND business_id = ?", holdID, id).Error
+	if err != nil {
+		return nil, generics.NotFound(c, err, "hold not found")
+	}
+	return &hold, nil
+}
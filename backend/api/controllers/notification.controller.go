@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/pkg/common"
+	"backend/pkg/generics"
+	"backend/pkg/tenancy"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// ListNotifications lists the emails sent or queued to the guest of a
+// reservation, oldest first
+func ListNotifications() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid reservation id")
+		}
+
+		var found []models.Notification
+		err = database.DB.
+			WithContext(c.UserContext()).
+			Scopes(tenancy.Scope).
+			Where("reservation_id = ?", id).
+			Order("created_at").
+			Find(&found).Error
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error listing the notifications")
+		}
+		dtos := make([]common.DTO, len(found))
+		for i, notification := range found {
+			dtos[i] = notification.ToDTO()
+		}
+		return generics.Found(c, dtos, "Found notifications")
+	}
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"backend/pkg/openapi"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/filesystem"
+	"github.com/spf13/viper"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// OpenAPIDocument describes every registered controller, as served under
+// basePath
+func OpenAPIDocument(basePath string) *openapi.Document {
+	return openapi.Generate(
+		openapi.Options{
+			Info: openapi.Info{
+				Title:   viper.GetString("general.app.name"),
+				Version: viper.GetString("general.app.version"),
+			},
+			BasePath: basePath,
+			Headers: []openapi.Parameter{{
+				Name:        viper.GetString("tenancy.header"),
+				In:          "header",
+				Description: "Id or slug of the organization, unless resolved from the subdomain or the token",
+				Schema:      &openapi.Schema{Type: "string"},
+			}},
+			PostHeaders: []openapi.Parameter{{
+				Name:        "Idempotency-Key",
+				In:          "header",
+				Description: "Unique key of the request, sending it again with the same key replays the first response",
+				Schema:      &openapi.Schema{Type: "string"},
+			}},
+		},
+		GetControllers(),
+		GetExtraRoutes())
+}
+
+func OpenAPI(basePath string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(OpenAPIDocument(basePath))
+	}
+}
+
+// swaggerInitializer replaces the one bundled with Swagger UI, which points
+// to the petstore example
+const swaggerInitializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "../openapi.json",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    plugins: [SwaggerUIBundle.plugins.DownloadUrl],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+// OpenAPIDocs serves the bundled Swagger UI reading ../openapi.json, it must
+// be mounted with app.Use next to the document
+func OpenAPIDocs() fiber.Handler {
+	static := filesystem.New(filesystem.Config{
+		Root:  http.FS(swaggerFiles.FS),
+		Index: "index.html",
+	})
+
+	return func(c *fiber.Ctx) error {
+		// The UI loads its assets relative to the index
+		if c.Path() == c.Route().Path {
+			return c.Redirect(c.Path() + "/")
+		}
+		if strings.HasSuffix(c.Path(), "/swagger-initializer.js") {
+			c.Type("js")
+			return c.SendString(swaggerInitializer)
+		}
+		return static(c)
+	}
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/pkg/common"
+	"backend/pkg/generics"
+	"backend/pkg/payments"
+	"backend/pkg/tenancy"
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RequestDeposit asks the configured provider for the deposit of a
+// reservation. The response is the pending payment, with the client secret
+// the guest pays it with
+func RequestDeposit() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid reservation id")
+		}
+		provider, err := payments.Open()
+		if err != nil {
+			return generics.InternalServerError(c, err, "Payments are not configured")
+		}
+
+		db := database.DB.WithContext(c.UserContext())
+		var reservation models.Reservation
+		if err := db.Scopes(tenancy.Scope).First(&reservation, "id = ?", id).Error; err != nil {
+			return generics.NotFound(c, err, "reservation not found")
+		}
+
+		var payment *models.Payment
+		err = db.Transaction(func(tx *gorm.DB) error {
+			payment, err = models.RequestDeposit(tx, provider, reservation)
+			return err
+		})
+		if errors.Is(err, models.ErrNoDeposit) {
+			return generics.BadRequest(c, err, "The reservation takes no deposit")
+		}
+		if errors.Is(err, models.ErrDepositRequested) {
+			return generics.Conflict(c, err, "The deposit was already requested")
+		}
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error requesting the deposit")
+		}
+		return generics.Created(c, payment.ToDTO(), "payment created")
+	}
+}
+
+// ListPayments lists the payments of a reservation, oldest first
+func ListPayments() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid reservation id")
+		}
+
+		var found []models.Payment
+		err = database.DB.
+			WithContext(c.UserContext()).
+			Scopes(tenancy.Scope).
+			Where("reservation_id = ?", id).
+			Order("created_at").
+			Find(&found).Error
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error listing the payments")
+		}
+		dtos := make([]common.DTO, len(found))
+		for i, payment := range found {
+			dtos[i] = payment.ToDTO()
+		}
+		return generics.Found(c, dtos, "Found payments")
+	}
+}
+
+// BusinessLedger lists the ledger entries of a business, oldest first
+func BusinessLedger() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid business id")
+		}
+
+		var entries []models.LedgerEntry
+		err = database.DB.
+			WithContext(c.UserContext()).
+			Scopes(tenancy.Scope).
+			Where("business_id = ?", id).
+			Order("created_at").
+			Find(&entries).Error
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error listing the ledger")
+		}
+		dtos := make([]common.DTO, len(entries))
+		for i, entry := range entries {
+			dtos[i] = entry.ToDTO()
+		}
+		return generics.Found(c, dtos, "Found ledger entries")
+	}
+}
+
+// PaymentWebhook applies the status changes providers report. It is public,
+// the provider signs its calls and the payment resolves the tenant
+func PaymentWebhook() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		provider, err := payments.OpenNamed(c.Params("provider"))
+		if err != nil {
+			return generics.NotFound(c, err, "payment provider not found")
+		}
+		event, err := provider.Parse(http.Header(c.GetReqHeaders()), c.Body())
+		if errors.Is(err, payments.ErrInvalidSignature) {
+			return generics.Unauthorized(c, err, "Invalid webhook signature")
+		}
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid webhook payload")
+		}
+
+		var payment models.Payment
+		err = database.DB.
+			WithContext(common.AsSystem(c.UserContext())).
+			First(&payment, "provider = ? AND reference = ?", provider.Name(), event.Reference).Error
+		if err != nil {
+			return generics.NotFound(c, err, "payment not found")
+		}
+
+		ctx := common.WithTenant(c.UserContext(), payment.TenantID)
+		var applied *models.Payment
+		err = database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			applied, err = models.ApplyPaymentEvent(tx, provider.Name(), event)
+			return err
+		})
+		if errors.Is(err, models.ErrPaymentNotFound) {
+			return generics.NotFound(c, err, "payment not found")
+		}
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error applying the payment event")
+		}
+		return generics.Ok(c, applied.ToDTO(), "payment event applied")
+	}
+}
@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/pkg/audit"
+	"backend/pkg/generics"
+	"backend/pkg/recurrence"
+	"backend/pkg/tenancy"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Scopes of an edit made to an occurrence of a recurring reservation
+const (
+	ScopeThis      = "this"
+	ScopeFollowing = "following"
+	ScopeAll       = "all"
+)
+
+var (
+	ScopeQuery = generics.QueryParameter{
+		Name:        "scope",
+		Description: fmt.Sprintf("Occurrences the edit applies to: %s (default), %s or %s", ScopeThis, ScopeFollowing, ScopeAll),
+	}
+	OccurrencesQuery = []generics.QueryParameter{
+		{Name: "from", Description: "RFC 3339 date-time of the first occurrence, now by default"},
+		{Name: "to", Description: "RFC 3339 date-time the occurrences end before, the horizon by default"},
+	}
+)
+
+// RecurringOccurrences lists the occurrences of a series in a window, with
+// their reservation or the reason they have none
+func RecurringOccurrences() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid recurring reservation id")
+		}
+
+		from := time.Now().UTC()
+		to := from.AddDate(0, 0, viper.GetInt("services.recurrence.horizon_days"))
+		if query := c.Query("from"); query != "" {
+			if from, err = time.Parse(time.RFC3339, query); err != nil {
+				return generics.BadRequest(c, err, "Invalid occurrence window")
+			}
+			from = from.UTC()
+		}
+		if query := c.Query("to"); query != "" {
+			if to, err = time.Parse(time.RFC3339, query); err != nil {
+				return generics.BadRequest(c, err, "Invalid occurrence window")
+			}
+			to = to.UTC()
+		}
+		if !to.After(from) || to.Sub(from) > 366*24*time.Hour {
+			return generics.BadRequest(c, errors.New("the window must end after it starts and last at most a year"), "Invalid occurrence window")
+		}
+
+		db := database.DB.WithContext(c.UserContext())
+		var series models.RecurringReservation
+		if err := db.Scopes(tenancy.Scope).First(&series, "id = ?", id).Error; err != nil {
+			return generics.NotFound(c, err, "recurring reservation not found")
+		}
+
+		occurrences, err := series.Occurrences(db, from, to)
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error listing the occurrences")
+		}
+		return generics.Found(c, occurrences, "Found occurrences")
+	}
+}
+
+// UpdateOccurrences edits an occurrence of a series, the ones after it or all
+// of them. The date, party and status of the payload are applied when set; a
+// new date moves the occurrences by as much as it moves this one, and the
+// cancelled status cancels them. The response is the series now holding the
+// edited occurrences, with the ones that could not be booked as conflicts
+func UpdateOccurrences() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid recurring reservation id")
+		}
+		occurrenceID, err := uuid.Parse(c.Params("occurrence"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid reservation id")
+		}
+		scope := c.Query(ScopeQuery.Name, ScopeThis)
+		if scope != ScopeThis && scope != ScopeFollowing && scope != ScopeAll {
+			return generics.BadRequest(c, fmt.Errorf("unknown scope %q", scope), "Invalid occurrence scope")
+		}
+		var payload models.ReservationDTO
+		if err := c.BodyParser(&payload); err != nil {
+			return generics.BadRequest(c, err, "Invalid reservation payload")
+		}
+
+		db := database.DB.WithContext(c.UserContext())
+		var series models.RecurringReservation
+		if err := db.Scopes(tenancy.Scope).First(&series, "id = ?", id).Error; err != nil {
+			return generics.NotFound(c, err, "recurring reservation not found")
+		}
+		var reservation models.Reservation
+		if err := db.Scopes(tenancy.Scope).First(&reservation, "id = ? AND series_id = ?", occurrenceID, id).Error; err != nil {
+			return generics.NotFound(c, err, "occurrence not found")
+		}
+		var business models.Business
+		if err := db.Scopes(tenancy.Scope).First(&business, "id = ?", series.BusinessID).Error; err != nil {
+			return generics.NotFound(c, err, "business not found")
+		}
+
+		var result *models.RecurringReservation
+		err = db.Transaction(func(tx *gorm.DB) error {
+			switch scope {
+			case ScopeThis:
+				result = &series
+				return updateOccurrence(tx, &series, &reservation, payload)
+			case ScopeFollowing:
+				result, err = updateFollowingOccurrences(tx, &series, business.Zone(), reservation, payload)
+				return err
+			default:
+				result = &series
+				return updateAllOccurrences(tx, &series, business.Zone(), reservation, payload)
+			}
+		})
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid reservation payload")
+		}
+
+		return generics.Updated(c, result.ToDTO(), "recurring reservation updated")
+	}
+}
+
+// updateOccurrence edits one occurrence, which keeps its place in the series.
+// When the business has no room for it the occurrence is left as it was and
+// the conflict is reported on the series
+func updateOccurrence(tx *gorm.DB, series *models.RecurringReservation, reservation *models.Reservation, payload models.ReservationDTO) error {
+	before := reservation.ToDTO()
+	if !payload.Date.IsZero() {
+		reservation.Date = payload.Date
+	}
+	if payload.NumberOfPeople > 0 {
+		reservation.NumberOfPeople = payload.NumberOfPeople
+	}
+	if payload.Status != "" {
+		reservation.Status = payload.Status
+	}
+
+	series.Conflicts = []models.Conflict{}
+	if reservation.Status != models.ReservationCancelled {
+		var business models.Business
+		if err := tx.Scopes(tenancy.Scope).First(&business, "id = ?", reservation.BusinessID).Error; err != nil {
+			return err
+		}
+		service, err := models.FindService(tx, business, reservation.ServiceID)
+		if err != nil {
+			return err
+		}
+		staff, err := models.FindStaff(tx, business, reservation.StaffID)
+		if err != nil {
+			return err
+		}
+		err = models.CheckAvailability(tx, models.Booking{
+			Business: business,
+			Service:  service,
+			Staff:    staff,
+			Date:     reservation.Date,
+			People:   reservation.NumberOfPeople,
+			Ignore:   reservation.ID,
+		})
+		if errors.Is(err, models.ErrBusinessClosed) || errors.Is(err, models.ErrBusinessFull) || errors.Is(err, models.ErrStaffUnavailable) {
+			series.Conflicts = append(series.Conflicts, models.Conflict{Date: reservation.Date, Reason: err.Error()})
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Omit(clause.Associations).Save(reservation).Error; err != nil {
+		return err
+	}
+	return audit.Record(tx, audit.Updated, "reservations", reservation, before, reservation.ToDTO())
+}
+
+// updateFollowingOccurrences ends the series before the occurrence and starts
+// a new one from it with the changes of the payload, which is returned. From
+// the first occurrence on, that is the whole series
+func updateFollowingOccurrences(tx *gorm.DB, series *models.RecurringReservation, zone *time.Location, reservation models.Reservation, payload models.ReservationDTO) (*models.RecurringReservation, error) {
+	rule, err := recurrence.Parse(series.Rule)
+	if err != nil {
+		return nil, err
+	}
+	if rule.Before(series.Start.In(zone), *reservation.OccurrenceAt) == 0 {
+		return series, updateAllOccurrences(tx, series, zone, reservation, payload)
+	}
+
+	before := series.ToDTO()
+	next, err := series.Split(*reservation.OccurrenceAt, zone)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Omit(clause.Associations).Save(series).Error; err != nil {
+		return nil, err
+	}
+	if err := audit.Record(tx, audit.Updated, "recurring_reservations", series, before, series.ToDTO()); err != nil {
+		return nil, err
+	}
+	if payload.Status == models.ReservationCancelled {
+		return series, nil
+	}
+
+	if err := moveSeries(next, zone, rule, reservation, payload); err != nil {
+		return nil, err
+	}
+	next.TenantID = series.TenantID
+	if err := tenancy.Assign(tx, next); err != nil {
+		return nil, err
+	}
+	if err := tx.Omit(clause.Associations).Create(next).Error; err != nil {
+		return nil, err
+	}
+	return next, audit.Record(tx, audit.Created, "recurring_reservations", next, nil, next.ToDTO())
+}
+
+// updateAllOccurrences applies the payload to the series, which books its
+// occurrences again. Cancelling an occurrence cancels the series
+func updateAllOccurrences(tx *gorm.DB, series *models.RecurringReservation, zone *time.Location, reservation models.Reservation, payload models.ReservationDTO) error {
+	before := series.ToDTO()
+	if payload.Status == models.ReservationCancelled {
+		if err := tx.Scopes(tenancy.Scope).Delete(series).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.Deleted, "recurring_reservations", series, before, nil)
+	}
+
+	rule, err := recurrence.Parse(series.Rule)
+	if err != nil {
+		return err
+	}
+	if err := moveSeries(series, zone, rule, reservation, payload); err != nil {
+		return err
+	}
+	if err := tx.Omit(clause.Associations).Save(series).Error; err != nil {
+		return err
+	}
+	return audit.Record(tx, audit.Updated, "recurring_reservations", series, before, series.ToDTO())
+}
+
+// moveSeries shifts the start of series by as much as the payload moves the
+// occurrence, and takes its party. Rules listing days cannot be moved to other
+// days of the time zone of the business, their days must be changed instead
+func moveSeries(series *models.RecurringReservation, zone *time.Location, rule recurrence.Rule, reservation models.Reservation, payload models.ReservationDTO) error {
+	if payload.NumberOfPeople > 0 {
+		series.NumberOfPeople = payload.NumberOfPeople
+	}
+	if payload.Date.IsZero() {
+		return nil
+	}
+
+	shift := payload.Date.Sub(*reservation.OccurrenceAt)
+	start := series.Start.Add(shift)
+	if (len(rule.Weekdays) > 0 || len(rule.MonthDays) > 0) && start.In(zone).YearDay() != series.Start.In(zone).YearDay() {
+		return errors.New("occurrences of a rule with BYDAY or BYMONTHDAY can only be moved within their day, change the rule instead")
+	}
+	series.Start = start
+	return nil
+}
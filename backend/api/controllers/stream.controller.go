@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/events"
+	"backend/pkg/feed"
+	"backend/pkg/generics"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// LastEventIDHeader is sent by EventSource clients reconnecting to a stream.
+// WebSocket clients pass the last_event_id query parameter instead
+const LastEventIDHeader = "Last-Event-ID"
+
+// heartbeat is how often idle streams are written to, so proxies keep them
+// open and gone clients are noticed
+const heartbeat = 15 * time.Second
+
+var errNoStreamTenant = errors.New("streams are of the changes of a tenant")
+
+// stream is a subscription to the feed and the changes its client missed
+type stream struct {
+	subscription *feed.Subscription
+	missed       []events.Event
+}
+
+// Stream streams the changes of the resources of the tenant listed in the
+// resources query parameter, of the business business_id when it is set,
+// as Server-Sent Events or over a WebSocket when the request upgrades to
+// one. Only the resources in services.stream.resources can be streamed.
+// Clients resuming from the last event they got first get the ones they
+// missed
+func Stream() fiber.Handler {
+	socket := websocket.New(streamSocket)
+	return func(c *fiber.Ctx) error {
+		tenant, ok := common.TenantFromContext(c.UserContext())
+		if !ok {
+			return generics.BadRequest(c, errNoStreamTenant, "Tenant could not be resolved")
+		}
+		filter := feed.Filter{TenantID: tenant}
+		for _, resource := range strings.Split(c.Query("resources"), ",") {
+			if resource = strings.TrimSpace(resource); resource == "" {
+				continue
+			}
+			if !feed.Streamable(resource) {
+				return generics.Forbidden(c, fmt.Errorf("the changes of %s cannot be streamed", resource), "Invalid stream")
+			}
+			filter.Resources = append(filter.Resources, resource)
+		}
+		if len(filter.Resources) == 0 {
+			return generics.BadRequest(c, errors.New("list the resources to stream, like resources=reservations"), "Invalid stream")
+		}
+		if query := c.Query("business_id"); query != "" {
+			id, err := uuid.Parse(query)
+			if err != nil {
+				return generics.BadRequest(c, err, "Invalid business id")
+			}
+			filter.BusinessID = &id
+		}
+
+		// Subscribing before reading the missed changes leaves no gap between
+		// them, the ones read twice are skipped
+		opened := &stream{subscription: feed.Subscribe(filter)}
+		if last := c.Get(LastEventIDHeader, c.Query("last_event_id")); last != "" {
+			id, err := uuid.Parse(last)
+			if err == nil {
+				opened.missed, err = feed.Since(database.DB.WithContext(c.UserContext()), filter, id)
+			}
+			if err != nil {
+				opened.subscription.Cancel()
+				return generics.BadRequest(c, err, "Invalid last event id")
+			}
+		}
+
+		if websocket.IsWebSocketUpgrade(c) {
+			c.Locals("stream", opened)
+			err := socket(c)
+			if c.Response().StatusCode() != fiber.StatusSwitchingProtocols {
+				opened.subscription.Cancel()
+			}
+			return err
+		}
+		c.Set(fiber.HeaderContentType, "text/event-stream")
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		c.Set(fiber.HeaderConnection, "keep-alive")
+		c.Set("X-Accel-Buffering", "no")
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			// Flushing right away sends the headers, the client is streaming
+			if retry := viper.GetDuration("services.stream.retry"); retry > 0 {
+				fmt.Fprintf(w, "retry: %d\n\n", retry.Milliseconds())
+			} else {
+				w.WriteString(": connected\n\n")
+			}
+			if err := w.Flush(); err != nil {
+				opened.subscription.Cancel()
+				return
+			}
+			opened.relay(func(event events.Event) error {
+				data, err := json.Marshal(event)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+				return w.Flush()
+			}, func() error {
+				w.WriteString(": heartbeat\n\n")
+				return w.Flush()
+			})
+		})
+		return nil
+	}
+}
+
+// streamSocket sends the changes as JSON text messages. Messages of the
+// client are ignored, reading them only notices when it leaves
+func streamSocket(conn *websocket.Conn) {
+	opened := conn.Locals("stream").(*stream)
+	go func() {
+		defer opened.subscription.Cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	opened.relay(func(event events.Event) error {
+		return conn.WriteJSON(event)
+	}, func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(heartbeat))
+	})
+}
+
+// relay sends the missed changes and then the new ones until the client
+// leaves or the subscription is cancelled, pinging the client while there
+// are none
+func (s *stream) relay(send func(event events.Event) error, ping func() error) {
+	defer s.subscription.Cancel()
+	sent := map[uuid.UUID]bool{}
+	for _, event := range s.missed {
+		if err := send(event); err != nil {
+			return
+		}
+		sent[event.ID] = true
+	}
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-s.subscription.Events:
+			if !ok {
+				return
+			}
+			if sent[event.ID] {
+				continue
+			}
+			if err := send(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := ping(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/pkg/audit"
+	"backend/pkg/generics"
+	"backend/pkg/helpers"
+	"backend/pkg/tenancy"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JoinWaitlist puts a party on the waitlist of a business for a time it is
+// full. A time with room left must be booked as a reservation instead, and
+// one the business is closed at cannot be waited for
+func JoinWaitlist() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid business id")
+		}
+		var payload models.WaitlistEntryDTO
+		if err := c.BodyParser(&payload); err != nil {
+			return generics.BadRequest(c, err, "Invalid waitlist entry payload")
+		}
+		if errs := helpers.ValidateStruct(payload); len(errs) > 0 {
+			return generics.PayloadValidationFailed(c, errs, "Invalid waitlist entry payload")
+		}
+		if !payload.Date.After(time.Now()) {
+			return generics.BadRequest(c, errors.New("the date has passed"), "Invalid waitlist entry payload")
+		}
+
+		db := database.DB.WithContext(c.UserContext())
+		var business models.Business
+		if err := db.Scopes(tenancy.Scope).First(&business, "id = ?", id).Error; err != nil {
+			return generics.NotFound(c, err, "business not found")
+		}
+
+		entry := &models.WaitlistEntry{
+			BusinessID:     business.ID,
+			UserID:         payload.UserID,
+			Date:           payload.Date.UTC(),
+			NumberOfPeople: payload.NumberOfPeople,
+			Status:         models.WaitlistWaiting,
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			err := models.CheckAvailability(tx, models.Booking{Business: business, Date: entry.Date, People: entry.NumberOfPeople})
+			if err == nil {
+				return errSlotAvailable
+			}
+			if !errors.Is(err, models.ErrBusinessFull) {
+				return err
+			}
+			if err := tenancy.Assign(tx, entry); err != nil {
+				return err
+			}
+			if err := tx.Omit(clause.Associations).Create(entry).Error; err != nil {
+				return err
+			}
+			if err := audit.Record(tx, audit.Created, "waitlist_entries", entry, nil, entry.ToDTO()); err != nil {
+				return err
+			}
+			return entry.Locate(tx, business)
+		})
+		if errors.Is(err, errSlotAvailable) {
+			return generics.Conflict(c, err, "The business has room at that time, book a reservation instead")
+		}
+		if errors.Is(err, models.ErrBusinessClosed) {
+			return generics.BadRequest(c, err, "Invalid waitlist entry payload")
+		}
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error joining the waitlist")
+		}
+
+		return generics.Created(c, entry.ToDTO(), "waitlist entry created")
+	}
+}
+
+var errSlotAvailable = errors.New("the business has room at that time")
+
+// ListWaitlist lists the entries of a business still waiting or offered a
+// reservation, in the order they joined, with the position of the waiting ones
+func ListWaitlist() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid business id")
+		}
+
+		db := database.DB.WithContext(c.UserContext())
+		var business models.Business
+		if err := db.Scopes(tenancy.Scope).First(&business, "id = ?", id).Error; err != nil {
+			return generics.NotFound(c, err, "business not found")
+		}
+
+		var entries []models.WaitlistEntry
+		err = db.
+			Scopes(tenancy.Scope).
+			Where("business_id = ? AND status IN ?", business.ID, []string{models.WaitlistWaiting, models.WaitlistOffered}).
+			Order("created_at").
+			Find(&entries).Error
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error listing the waitlist")
+		}
+
+		dtos := make([]models.WaitlistEntryDTO, len(entries))
+		for i := range entries {
+			if err := entries[i].Locate(db, business); err != nil {
+				return generics.InternalServerError(c, err, "Error listing the waitlist")
+			}
+			dtos[i] = *entries[i].ToDTO().(*models.WaitlistEntryDTO)
+		}
+		return generics.Found(c, dtos, "Found waitlist entries")
+	}
+}
+
+// GetWaitlistEntry returns an entry with its position and ETA while waiting,
+// or the reservation it was offered
+func GetWaitlistEntry() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		db := database.DB.WithContext(c.UserContext())
+		business, entry, err := waitlistEntry(c, db)
+		if entry == nil {
+			return err
+		}
+		if err := entry.Locate(db, business); err != nil {
+			return generics.InternalServerError(c, err, "Error locating the waitlist entry")
+		}
+		return generics.Found(c, entry.ToDTO(), "Found waitlist entry")
+	}
+}
+
+// ConfirmWaitlistEntry confirms the reservation offered to an entry before
+// the offer expires
+func ConfirmWaitlistEntry() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		db := database.DB.WithContext(c.UserContext())
+		_, entry, err := waitlistEntry(c, db)
+		if entry == nil {
+			return err
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return entry.Confirm(tx)
+		})
+		if errors.Is(err, models.ErrNotOffered) || errors.Is(err, models.ErrOfferExpired) {
+			return generics.Conflict(c, err, "The waitlist entry cannot be confirmed")
+		}
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error confirming the waitlist entry")
+		}
+		return generics.Updated(c, entry.ToDTO(), "waitlist entry confirmed")
+	}
+}
+
+// LeaveWaitlist takes an entry off the waitlist, declining the reservation
+// offered to it if any
+func LeaveWaitlist() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		db := database.DB.WithContext(c.UserContext())
+		_, entry, err := waitlistEntry(c, db)
+		if entry == nil {
+			return err
+		}
+		if entry.Status != models.WaitlistWaiting && entry.Status != models.WaitlistOffered {
+			return generics.Conflict(c, errors.New("the entry is no longer on the waitlist"), "The waitlist entry cannot be cancelled")
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return entry.Withdraw(tx, models.WaitlistCancelled)
+		})
+		if errors.Is(err, models.ErrEntryChanged) {
+			return generics.Conflict(c, err, "The waitlist entry cannot be cancelled")
+		}
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error cancelling the waitlist entry")
+		}
+		return generics.Updated(c, entry.ToDTO(), "waitlist entry cancelled")
+	}
+}
+
+// waitlistEntry loads the business and the entry of the request. Without an
+// entry the error response has been sent, and the error sending it returned
+func waitlistEntry(c *fiber.Ctx, db *gorm.DB) (models.Business, *models.WaitlistEntry, error) {
+	var business models.Business
+	var entry models.WaitlistEntry
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return business, nil, generics.BadRequest(c, err, "Invalid business id")
+	}
+	entryID, err := uuid.Parse(c.Params("entry"))
+	if err != nil {
+		return business, nil, generics.BadRequest(c, err, "Invalid waitlist entry id")
+	}
+	if err := db.Scopes(tenancy.Scope).First(&business, "id = ?", id).Error; err != nil {
+		return business, nil, generics.NotFound(c, err, "business not found")
+	}
+	if err := db.Scopes(tenancy.Scope).First(&entry, "id = ? AND business_id = ?", entryID, id).Error; err != nil {
+		return business, nil, generics.NotFound(c, err, "waitlist entry not found")
+	}
+	return business, &entry, nil
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/pkg/common"
+	"backend/pkg/generics"
+	"backend/pkg/tenancy"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var DeliveriesQuery = []generics.QueryParameter{
+	{Name: "status", Description: "Status of the deliveries: pending, succeeded or failed"},
+	{Name: "limit", Description: "Most deliveries listed, 100 by default and 1000 at most"},
+}
+
+// ListWebhookDeliveries lists the deliveries of a webhook subscription, newest
+// first
+func ListWebhookDeliveries() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid webhook subscription id")
+		}
+		limit := c.QueryInt("limit", 100)
+		if limit <= 0 || limit > 1000 {
+			limit = 100
+		}
+
+		query := database.DB.
+			WithContext(c.UserContext()).
+			Scopes(tenancy.Scope).
+			Where("subscription_id = ?", id)
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		var found []models.WebhookDelivery
+		if err := query.Order("created_at DESC").Limit(limit).Find(&found).Error; err != nil {
+			return generics.InternalServerError(c, err, "Error listing the webhook deliveries")
+		}
+		dtos := make([]common.DTO, len(found))
+		for i, delivery := range found {
+			dtos[i] = delivery.ToDTO()
+		}
+		return generics.Found(c, dtos, "Found webhook deliveries")
+	}
+}
+
+// ReplayWebhookDelivery queues the event of a delivery to its subscription
+// again. The response is the new delivery
+func ReplayWebhookDelivery() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid webhook subscription id")
+		}
+		deliveryID, err := uuid.Parse(c.Params("delivery"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid webhook delivery id")
+		}
+
+		db := database.DB.WithContext(c.UserContext())
+		var original models.WebhookDelivery
+		if err := db.Scopes(tenancy.Scope).First(&original, "id = ? AND subscription_id = ?", deliveryID, id).Error; err != nil {
+			return generics.NotFound(c, err, "webhook delivery not found")
+		}
+
+		var replay *models.WebhookDelivery
+		err = db.Transaction(func(tx *gorm.DB) error {
+			replay, err = models.ReplayDelivery(tx, original)
+			return err
+		})
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error replaying the webhook delivery")
+		}
+		return generics.Created(c, replay.ToDTO(), "webhook delivery queued")
+	}
+}
+
+// TestWebhookSubscription posts a test event to a webhook subscription and
+// responds with the delivery, holding the answer of the receiver
+func TestWebhookSubscription() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return generics.BadRequest(c, err, "Invalid webhook subscription id")
+		}
+
+		db := database.DB.WithContext(c.UserContext())
+		var subscription models.WebhookSubscription
+		if err := db.Scopes(tenancy.Scope).First(&subscription, "id = ?", id).Error; err != nil {
+			return generics.NotFound(c, err, "webhook subscription not found")
+		}
+
+		delivery, err := models.TestWebhook(c.UserContext(), db, subscription)
+		if err != nil {
+			return generics.InternalServerError(c, err, "Error testing the webhook subscription")
+		}
+		return generics.Created(c, delivery.ToDTO(), "webhook test delivered")
+	}
+}
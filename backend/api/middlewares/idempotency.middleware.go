@@ -0,0 +1,100 @@
+package middlewares
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/pkg/common"
+	"backend/pkg/generics"
+
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spf13/viper"
+)
+
+// IdempotencyHeader carries the key that makes sending a POST request again
+// replay the response to the first one instead of handling it twice
+const IdempotencyHeader = "Idempotency-Key"
+
+// ReplayedHeader is set on the responses replayed for an idempotency key
+const ReplayedHeader = "Idempotent-Replayed"
+
+// idempotencyPoll is how often a request waits on the one in progress with
+// the same key
+const idempotencyPoll = 100 * time.Millisecond
+
+var errLongIdempotencyKey = errors.New("idempotency keys are at most 255 characters")
+
+// Idempotency handles the POST requests sent with an idempotency key once
+// per user and key. The response is stored, unless the request failed on
+// the server, and replayed to the same request sent again until the key
+// expires. A duplicate of a request still being handled waits for it up to
+// services.idempotency.wait, then gets a conflict
+func Idempotency() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyHeader)
+		if c.Method() != fiber.MethodPost || key == "" {
+			return c.Next()
+		}
+		if len(key) > 255 {
+			return generics.BadRequest(c, errLongIdempotencyKey, "Invalid idempotency key")
+		}
+
+		ctx := c.UserContext()
+		db := database.DB.WithContext(ctx)
+		actor := common.ActorFromContext(ctx)
+		fingerprint := requestFingerprint(c)
+		deadline := time.Now().Add(viper.GetDuration("services.idempotency.wait"))
+		entry, reserved, err := models.ReserveIdempotencyKey(db, actor, key, fingerprint)
+		for errors.Is(err, models.ErrIdempotencyKeyInFlight) && time.Now().Before(deadline) {
+			select {
+			case <-ctx.Done():
+				return generics.Conflict(c, err, "Request already in progress")
+			case <-time.After(idempotencyPoll):
+			}
+			entry, reserved, err = models.ReserveIdempotencyKey(db, actor, key, fingerprint)
+		}
+		switch {
+		case errors.Is(err, models.ErrIdempotencyKeyInFlight):
+			return generics.Conflict(c, err, "Request already in progress")
+		case errors.Is(err, models.ErrIdempotencyKeyReused):
+			return generics.UnprocessableEntity(c, err, "Idempotency key already used")
+		case err != nil:
+			return generics.InternalServerError(c, err, "Idempotency key could not be checked")
+		case !reserved:
+			c.Set(ReplayedHeader, "true")
+			c.Set(fiber.HeaderContentType, entry.ContentType)
+			return c.Status(entry.Status).SendString(entry.Body)
+		}
+
+		// The request is handled even if the client goes away, the key is
+		// settled without its context
+		settle := database.DB.WithContext(context.WithoutCancel(ctx))
+		err = c.Next()
+		status := c.Response().StatusCode()
+		if err != nil || status >= fiber.StatusInternalServerError {
+			if released := entry.Release(settle); released != nil {
+				log.Printf("Error releasing idempotency key %s: %s", key, released)
+			}
+			return err
+		}
+		contentType := string(c.Response().Header.ContentType())
+		if stored := entry.Complete(settle, status, contentType, string(c.Response().Body())); stored != nil {
+			log.Printf("Error storing the response of idempotency key %s: %s", key, stored)
+		}
+		return nil
+	}
+}
+
+// requestFingerprint tells requests apart by their method, URL and body
+func requestFingerprint(c *fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Method() + " " + c.OriginalURL() + "\n"))
+	hash.Write(c.Body())
+	return hex.EncodeToString(hash.Sum(nil))
+}
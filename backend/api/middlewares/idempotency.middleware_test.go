@@ -0,0 +1,208 @@
+package middlewares_test
+
+import (
+	"backend/api/middlewares"
+	"backend/database/databasetest"
+	"backend/models"
+	"backend/pkg/common"
+
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// server is an app behind the idempotency middleware counting the requests
+// its routes handle. /slow waits for release
+type server struct {
+	app     *fiber.App
+	handled atomic.Int32
+	release chan struct{}
+}
+
+func newServer(t *testing.T) *server {
+	t.Helper()
+	databasetest.Open(t, &models.IdempotencyKey{})
+	viper.Set("services.idempotency.ttl", time.Hour)
+	viper.Set("services.idempotency.timeout", time.Minute)
+	viper.Set("services.idempotency.wait", 200*time.Millisecond)
+	t.Cleanup(func() {
+		viper.Set("services.idempotency.ttl", nil)
+		viper.Set("services.idempotency.timeout", nil)
+		viper.Set("services.idempotency.wait", nil)
+	})
+
+	s := &server{app: fiber.New(), release: make(chan struct{})}
+	tenant := uuid.New()
+	s.app.Use(func(c *fiber.Ctx) error {
+		c.SetUserContext(common.WithTenant(c.UserContext(), tenant))
+		return c.Next()
+	})
+	s.app.Use(middlewares.Idempotency())
+	s.app.Post("/reservations", func(c *fiber.Ctx) error {
+		handled := s.handled.Add(1)
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"handled": handled})
+	})
+	s.app.Post("/fail", func(c *fiber.Ctx) error {
+		s.handled.Add(1)
+		return c.Status(fiber.StatusServiceUnavailable).SendString("try again")
+	})
+	s.app.Post("/slow", func(c *fiber.Ctx) error {
+		s.handled.Add(1)
+		<-s.release
+		return c.SendStatus(fiber.StatusCreated)
+	})
+	return s
+}
+
+// post sends body to path with key, and returns the response and its body
+func (s *server) post(t *testing.T, path string, key string, body string) (*http.Response, string) {
+	t.Helper()
+	response, err := s.app.Test(request(path, key, body), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(response.Body)
+	return response, string(raw)
+}
+
+// background posts an empty body to path with key from another goroutine,
+// and sends the status of the response to the channel returned
+func (s *server) background(path string, key string) chan int {
+	done := make(chan int, 1)
+	go func() {
+		response, err := s.app.Test(request(path, key, `{}`), -1)
+		if err != nil {
+			done <- 0
+			return
+		}
+		done <- response.StatusCode
+	}()
+	return done
+}
+
+func request(path string, key string, body string) *http.Request {
+	request := httptest.NewRequest(fiber.MethodPost, path, strings.NewReader(body))
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	request.Header.Set(middlewares.IdempotencyHeader, key)
+	return request
+}
+
+func TestIdempotencyReplays(t *testing.T) {
+	s := newServer(t)
+	first, body := s.post(t, "/reservations", "key", `{"people":2}`)
+	if first.StatusCode != fiber.StatusCreated || first.Header.Get(middlewares.ReplayedHeader) != "" {
+		t.Fatalf("got %d replayed %q, want the request handled", first.StatusCode, first.Header.Get(middlewares.ReplayedHeader))
+	}
+	again, replayed := s.post(t, "/reservations", "key", `{"people":2}`)
+	if again.StatusCode != fiber.StatusCreated || replayed != body || again.Header.Get(middlewares.ReplayedHeader) != "true" {
+		t.Fatalf("got %d %s replayed %q, want %d %s replayed", again.StatusCode, replayed, again.Header.Get(middlewares.ReplayedHeader), first.StatusCode, body)
+	}
+	if got := again.Header.Get(fiber.HeaderContentType); got != first.Header.Get(fiber.HeaderContentType) {
+		t.Errorf("got content type %q, want %q", got, first.Header.Get(fiber.HeaderContentType))
+	}
+	if other, _ := s.post(t, "/reservations", "other", `{"people":2}`); other.StatusCode != fiber.StatusCreated {
+		t.Fatalf("got %d with another key, want the request handled", other.StatusCode)
+	}
+	if handled := s.handled.Load(); handled != 2 {
+		t.Fatalf("handled %d requests, want 2", handled)
+	}
+}
+
+func TestIdempotencyKeyReused(t *testing.T) {
+	s := newServer(t)
+	s.post(t, "/reservations", "key", `{"people":2}`)
+	for _, tt := range []struct{ path, body string }{{"/reservations", `{"people":3}`}, {"/fail", `{"people":2}`}} {
+		if response, body := s.post(t, tt.path, "key", tt.body); response.StatusCode != fiber.StatusUnprocessableEntity {
+			t.Fatalf("got %d %s for another request to %s, want 422", response.StatusCode, body, tt.path)
+		}
+	}
+	if handled := s.handled.Load(); handled != 1 {
+		t.Fatalf("handled %d requests, want 1", handled)
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	s := newServer(t)
+	done := s.background("/slow", "key")
+	for s.handled.Load() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	start := time.Now()
+	response, _ := s.post(t, "/slow", "key", `{}`)
+	if waited := time.Since(start); response.StatusCode != fiber.StatusConflict || waited < 200*time.Millisecond {
+		t.Fatalf("got %d after %s, want 409 after waiting for the first request", response.StatusCode, waited)
+	}
+	close(s.release)
+	if status := <-done; status != fiber.StatusCreated {
+		t.Fatalf("got %d for the first request, want 201", status)
+	}
+	if handled := s.handled.Load(); handled != 1 {
+		t.Fatalf("handled %d requests, want 1", handled)
+	}
+}
+
+func TestIdempotencyWaitsForTheFirstResponse(t *testing.T) {
+	s := newServer(t)
+	viper.Set("services.idempotency.wait", 5*time.Second)
+	go func() {
+		for s.handled.Load() == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		time.Sleep(100 * time.Millisecond)
+		close(s.release)
+	}()
+	done := s.background("/slow", "key")
+	for s.handled.Load() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	response, _ := s.post(t, "/slow", "key", `{}`)
+	if response.StatusCode != fiber.StatusCreated || response.Header.Get(middlewares.ReplayedHeader) != "true" {
+		t.Fatalf("got %d replayed %q, want the first response replayed", response.StatusCode, response.Header.Get(middlewares.ReplayedHeader))
+	}
+	if status := <-done; status != fiber.StatusCreated {
+		t.Fatalf("got %d for the first request, want 201", status)
+	}
+}
+
+func TestIdempotencyReleasesFailures(t *testing.T) {
+	s := newServer(t)
+	for i := 1; i <= 2; i++ {
+		response, body := s.post(t, "/fail", "key", `{}`)
+		if response.StatusCode != fiber.StatusServiceUnavailable || response.Header.Get(middlewares.ReplayedHeader) != "" {
+			t.Fatalf("attempt %d: got %d %s, want the request handled again", i, response.StatusCode, body)
+		}
+	}
+	if handled := s.handled.Load(); handled != 2 {
+		t.Fatalf("handled %d requests, want 2", handled)
+	}
+}
+
+func TestIdempotencyOnlyForPOST(t *testing.T) {
+	s := newServer(t)
+	s.app.Put("/reservations", func(c *fiber.Ctx) error {
+		s.handled.Add(1)
+		return c.SendStatus(fiber.StatusOK)
+	})
+	for i := 0; i < 2; i++ {
+		request := httptest.NewRequest(fiber.MethodPut, "/reservations", nil)
+		request.Header.Set(middlewares.IdempotencyHeader, "key")
+		if _, err := s.app.Test(request, -1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if response, body := s.post(t, "/reservations", strings.Repeat("k", 256), `{}`); response.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("got %d %s for a long key, want 400", response.StatusCode, body)
+	}
+	if handled := s.handled.Load(); handled != 2 {
+		t.Fatalf("handled %d requests, want the 2 PUT", handled)
+	}
+}
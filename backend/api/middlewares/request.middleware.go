@@ -0,0 +1,34 @@
+package middlewares
+
+import (
+	"backend/pkg/common"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+)
+
+// ActorHeader identifies the user making the request until authentication
+// is implemented
+const ActorHeader = "X-User-ID"
+
+const anonymousActor = "anonymous"
+
+// RequestContext stores the request ID and the actor in the user context so
+// repositories can read them through gorm's Statement.Context
+func RequestContext() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID, _ := c.Locals(requestid.ConfigDefault.ContextKey).(string)
+		if requestID == "" {
+			requestID = c.Get(fiber.HeaderXRequestID)
+		}
+
+		actor := c.Get(ActorHeader, anonymousActor)
+
+		ctx := c.UserContext()
+		ctx = common.WithRequestID(ctx, requestID)
+		ctx = common.WithActor(ctx, actor)
+		c.SetUserContext(ctx)
+
+		return c.Next()
+	}
+}
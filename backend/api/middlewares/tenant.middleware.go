@@ -0,0 +1,126 @@
+package middlewares
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/pkg/common"
+	"backend/pkg/generics"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+var errNoTenant = errors.New("no tenant in the token, header or subdomain")
+
+var errAmbiguousTenant = errors.New("the token, header and subdomain name different tenants")
+
+// Tenant resolves the organization a request belongs to from the tenant claim
+// of the bearer token, the tenant header and the subdomain. Every source that
+// is present must name the same organization, and at least one is required
+func Tenant() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		references, err := tenantReferences(c)
+		if err != nil {
+			return generics.Unauthorized(c, err, "Invalid tenant token")
+		}
+		if len(references) == 0 {
+			return generics.BadRequest(c, errNoTenant, "Tenant could not be resolved")
+		}
+
+		var tenant *models.Organization
+		for _, reference := range references {
+			organization, err := findOrganization(reference)
+			if err != nil {
+				return generics.NotFound(c, err, fmt.Sprintf("Tenant %s not found", reference))
+			}
+			if tenant != nil && tenant.ID != organization.ID {
+				return generics.BadRequest(c, errAmbiguousTenant, "Tenant could not be resolved")
+			}
+			tenant = organization
+		}
+
+		c.SetUserContext(common.WithTenant(c.UserContext(), tenant.ID))
+		return c.Next()
+	}
+}
+
+// tenantReferences collects the id or slug of the organization named by each
+// source present in the request
+func tenantReferences(c *fiber.Ctx) ([]string, error) {
+	references := []string{}
+
+	claim, err := tenantFromToken(c)
+	if err != nil {
+		return nil, err
+	}
+	if claim != "" {
+		references = append(references, claim)
+	}
+
+	if header := c.Get(viper.GetString("tenancy.header")); header != "" {
+		references = append(references, header)
+	}
+
+	if subdomain := tenantFromSubdomain(c.Hostname()); subdomain != "" {
+		references = append(references, subdomain)
+	}
+
+	return references, nil
+}
+
+// tenantFromToken reads the tenant claim of an HS256 bearer token. Tokens are
+// ignored unless tenancy.token.secret is configured
+func tenantFromToken(c *fiber.Ctx) (string, error) {
+	secret := viper.GetString("tenancy.token.secret")
+	authorization := c.Get(fiber.HeaderAuthorization)
+	if secret == "" || !strings.HasPrefix(authorization, "Bearer ") {
+		return "", nil
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(
+		strings.TrimPrefix(authorization, "Bearer "),
+		claims,
+		func(token *jwt.Token) (interface{}, error) {
+			return []byte(secret), nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	claim, _ := claims[viper.GetString("tenancy.token.claim")].(string)
+	return claim, nil
+}
+
+// tenantFromSubdomain returns the first label of hostname when it is a direct
+// subdomain of tenancy.base_domain
+func tenantFromSubdomain(hostname string) string {
+	baseDomain := viper.GetString("tenancy.base_domain")
+	if baseDomain == "" || !strings.HasSuffix(hostname, "."+baseDomain) {
+		return ""
+	}
+	subdomain := strings.TrimSuffix(hostname, "."+baseDomain)
+	if strings.Contains(subdomain, ".") {
+		return ""
+	}
+	return subdomain
+}
+
+func findOrganization(reference string) (*models.Organization, error) {
+	var organization models.Organization
+	query := database.DB.Where("slug = ?", reference)
+	if id, err := uuid.Parse(reference); err == nil {
+		query = database.DB.Where("id = ?", id)
+	}
+	if err := query.First(&organization).Error; err != nil {
+		return nil, err
+	}
+	return &organization, nil
+}
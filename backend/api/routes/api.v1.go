@@ -2,6 +2,7 @@ package routes
 
 import (
 	"backend/api/controllers"
+	"backend/api/middlewares"
 	"backend/pkg/generics"
 
 	"github.com/gofiber/fiber/v2"
@@ -21,7 +22,21 @@ func ApiRouterV1() *fiber.App {
 		})
 	}).Name("Get Routes Info")
 
-	// TODO: Implement middleware
+	app.Get("/openapi.json", controllers.OpenAPI("/api/v1")).Name("Get OpenAPI document")
+	app.Use("/docs", controllers.OpenAPIDocs())
+
+	// Calendar apps cannot send headers, the token resolves the tenant
+	app.Get("/calendar/:token.ics", controllers.UserCalendar()).Name("Get calendar of a user")
+
+	// Payment providers sign their calls, the payment resolves the tenant
+	app.Post("/payments/webhooks/:provider", controllers.PaymentWebhook()).Name("Receive payment webhook")
+
+	app.Use(middlewares.RequestContext())
+	app.Use(middlewares.Tenant())
+	app.Use(middlewares.Idempotency())
+
+	app.Get("/audit", controllers.AuditLog()).Name("Get audit log")
+	app.Get("/stream", controllers.Stream()).Name("Stream changes")
 
 	// Private routes
 	for key, controller := range controllers.GetControllers() {
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"backend/api/controllers"
+	"backend/api/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func GraphQLRouter() *fiber.App {
+	app := fiber.New()
+
+	app.Use(middlewares.RequestContext())
+	app.Use(middlewares.Tenant())
+	app.Use(middlewares.Idempotency())
+
+	app.Post("/", controllers.GraphQL()).Name("GraphQL")
+
+	return app
+}
@@ -3,8 +3,14 @@ package api
 import (
 	"backend/api/routes"
 	"backend/database"
+	"backend/models"
+	"backend/pkg/jobs"
+	"backend/pkg/notify"
+	"backend/pkg/outbox"
+	"backend/pkg/payments"
 	"backend/public"
 
+	"context"
 	"fmt"
 	"net/http"
 
@@ -14,6 +20,7 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/helmet"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/spf13/viper"
 )
 
@@ -24,6 +31,36 @@ func Serve() {
 		panic(err)
 	}
 
+	// Keep booking the occurrences of recurring reservations ahead
+	jobs.Cron(models.JobExtendRecurring, jobs.Every(viper.GetDuration("services.recurrence.extend_interval")))
+	// Expire the waitlist offers nobody confirmed and promote the next entries
+	jobs.Cron(models.JobSweepWaitlist, jobs.Every(viper.GetDuration("services.waitlist.sweep_interval")))
+	// Mark the holds past their TTL as expired and hand their seats on
+	jobs.Cron(models.JobSweepHolds, jobs.Every(viper.GetDuration("services.holds.sweep_interval")))
+	// Mark the reservations nobody showed up to as no-shows
+	jobs.Cron(models.JobMarkNoShows, jobs.Every(viper.GetDuration("services.no_shows.sweep_interval")))
+	// Remind the guests of their reservations a day ahead
+	jobs.Cron(models.JobSendReminders, jobs.Every(viper.GetDuration("services.notifications.reminder_interval")))
+	if _, err := notify.Open(); err != nil {
+		panic(err)
+	}
+	// Refuse to take payments with a provider that is not set up
+	if _, err := payments.Open(); err != nil {
+		panic(err)
+	}
+	// Delete the rows soft deleted long ago
+	purge, err := jobs.Parse(viper.GetString("services.jobs.purge_schedule"))
+	if err != nil {
+		panic(err)
+	}
+	jobs.Cron(models.JobPurge, purge)
+	go jobs.Start(context.Background())
+	// Publish the events written to the outbox along with each change
+	if _, err := outbox.Open(); err != nil {
+		panic(err)
+	}
+	go outbox.Start(context.Background())
+
 	environment := viper.GetString("general.app.enviroment")
 
 	appName := fmt.Sprintf("%s (%s)", viper.GetString("general.app.name"), environment)
@@ -33,6 +70,8 @@ func Serve() {
 	})
 
 	// Middleware
+	// Setup the request id middleware
+	api.Use(requestid.New())
 	// Setup Logger middleware
 	if environment == "development" {
 		api.Use(logger.New(
@@ -62,6 +101,7 @@ func Serve() {
 
 	// Mount the routes
 	api.Mount("/api/v1", routes.ApiRouterV1())
+	api.Mount("/api/graphql", routes.GraphQLRouter())
 
 	api.Use("/", staticFiles)
 
@@ -0,0 +1,16 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func init() {
+	rootCmd.AddCommand(codegenCmd)
+}
+
+var codegenCmd = &cobra.Command{
+	Use:   "codegen",
+	Short: "Code generation commands",
+	Long:  `Code generation commands.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
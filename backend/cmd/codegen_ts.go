@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"backend/api/controllers"
+	"backend/pkg/codegen"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var codegenTsOutput string
+
+func init() {
+	codegenCmd.AddCommand(codegenTsCmd)
+	codegenTsCmd.Flags().StringVarP(&codegenTsOutput, "output", "o", "../frontend/src/types/generated", "directory to write the client to")
+}
+
+var codegenTsCmd = &cobra.Command{
+	Use:   "ts",
+	Short: "Generates the TypeScript API client",
+	Long:  `Generates the TypeScript types and a typed fetch client for every registered resource. Files generated by a previous run are replaced.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := os.MkdirAll(codegenTsOutput, 0755); err != nil {
+			panic(err)
+		}
+
+		// Remove clients of resources that are no longer registered
+		previous, err := filepath.Glob(filepath.Join(codegenTsOutput, "*.ts"))
+		if err != nil {
+			panic(err)
+		}
+		for _, path := range previous {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				panic(err)
+			}
+			if bytes.HasPrefix(content, []byte("// Code generated by `backend codegen ts`")) {
+				if err := os.Remove(path); err != nil {
+					panic(err)
+				}
+			}
+		}
+
+		for _, file := range codegen.TypeScript(controllers.GetControllers(), controllers.GetExtraRoutes()) {
+			path := filepath.Join(codegenTsOutput, file.Name)
+			if err := os.WriteFile(path, []byte(file.Content), 0644); err != nil {
+				panic(err)
+			}
+			fmt.Println("Generated", path)
+		}
+	},
+}
@@ -0,0 +1,16 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func init() {
+	rootCmd.AddCommand(holdCmd)
+}
+
+var holdCmd = &cobra.Command{
+	Use:   "holds",
+	Short: "Hold commands",
+	Long:  `Manages the holds placed on slots during checkout.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
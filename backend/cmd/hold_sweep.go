@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"backend/database"
+	"backend/models"
+	"context"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	holdCmd.AddCommand(holdSweepCmd)
+}
+
+var holdSweepCmd = &cobra.Command{
+	Use:   "sweep",
+	Short: "Expires the holds past their TTL",
+	Long:  `Marks the active holds older than services.holds.ttl as expired and offers their seats to the waitlist, which the server also does every services.holds.sweep_interval.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := database.Connect(); err != nil {
+			panic(err)
+		}
+
+		if err := models.SweepHolds(context.Background()); err != nil {
+			panic(err)
+		}
+	},
+}
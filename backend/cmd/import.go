@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"backend/api/controllers"
+	"backend/database"
+	"backend/models"
+	"backend/pkg/common"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	importOrganization string
+	importDryRun       bool
+)
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVar(&importOrganization, "organization", "", "slug of the organization the rows belong to")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "check and report every row without saving anything")
+	importCmd.MarkFlagRequired("organization")
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <resource> <file.csv>",
+	Short: "Imports a CSV document",
+	Long: `Creates or updates an entity of the resource per row of a CSV document, like POST /<resource>/import.
+The header names the fields of the resource and rows are matched to existing entities by its natural key.
+Nothing is saved unless every row is valid. Resources: ` + strings.Join(importableResources(), ", ") + `.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		controller, ok := controllers.GetControllers()[args[0]]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Unknown resource %s\n", args[0])
+			os.Exit(1)
+		}
+
+		file, err := os.Open(args[1])
+		if err != nil {
+			panic(err)
+		}
+		defer file.Close()
+
+		if _, err := database.Connect(); err != nil {
+			panic(err)
+		}
+
+		var organization models.Organization
+		err = database.DB.
+			WithContext(common.AsSystem(context.Background())).
+			First(&organization, "slug = ?", importOrganization).Error
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unknown organization %s\n", importOrganization)
+			os.Exit(1)
+		}
+
+		ctx := common.WithTenant(context.Background(), organization.ID)
+		result, err := controller.Service().Import(ctx, file, importDryRun)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid document: %s\n", err)
+			os.Exit(1)
+		}
+
+		for _, row := range result.Rows {
+			if row.Status == common.Error {
+				fmt.Printf("line %d\terror\t%s\n", row.Line, row.Error)
+				for _, invalid := range row.Errors {
+					fmt.Printf("line %d\terror\t%s fails %s %s\n", row.Line, invalid.Field, invalid.Rule, invalid.Value)
+				}
+				continue
+			}
+			fmt.Printf("line %d\t%s\t%s\n", row.Line, row.Action, row.ID)
+		}
+
+		switch {
+		case result.Failed > 0:
+			fmt.Printf("%d of %d rows failed, nothing was saved\n", result.Failed, len(result.Rows))
+			os.Exit(1)
+		case result.DryRun:
+			fmt.Printf("Dry run: %d %s would be created and %d updated\n", result.Created, args[0], result.Updated)
+		default:
+			fmt.Printf("%d %s created and %d updated\n", result.Created, args[0], result.Updated)
+		}
+	},
+}
+
+// importableResources lists the resources with a natural key to match rows on
+func importableResources() []string {
+	resources := []string{}
+	for plural, controller := range controllers.GetControllers() {
+		for _, route := range controller.Routes() {
+			if route.Accepts != "" {
+				resources = append(resources, plural)
+				break
+			}
+		}
+	}
+	sort.Strings(resources)
+	return resources
+}
@@ -0,0 +1,16 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func init() {
+	rootCmd.AddCommand(jobsCmd)
+}
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Job commands",
+	Long:  `Manages the queue of background jobs the server works.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
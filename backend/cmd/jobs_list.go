@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/jobs"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	jobsListStatus string
+	jobsListLimit  int
+)
+
+func init() {
+	jobsListCmd.Flags().StringVar(&jobsListStatus, "status", "", "only list the jobs with this status: queued, running, succeeded or failed")
+	jobsListCmd.Flags().IntVar(&jobsListLimit, "limit", 50, "most jobs listed")
+	jobsCmd.AddCommand(jobsListCmd)
+}
+
+var jobsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the jobs",
+	Long:  `Lists the jobs of the queue, most recently due first, along with the jobs that can be run.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := database.Connect(); err != nil {
+			panic(err)
+		}
+
+		found, err := jobs.List(common.AsSystem(context.Background()), jobsListStatus, jobsListLimit)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Jobs: %s\n", strings.Join(jobs.Names(), ", "))
+		for _, job := range found {
+			fmt.Printf("%s\t%s\t%s\t%d/%d\t%s\t%s\n",
+				job.ID, job.Name, job.Status, job.Attempts, job.MaxAttempts, job.RunAt.Format(time.RFC3339), job.LastError)
+		}
+	},
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/jobs"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	jobsCmd.AddCommand(jobsRetryCmd)
+}
+
+var jobsRetryCmd = &cobra.Command{
+	Use:   "retry <id>",
+	Short: "Queues a failed job again",
+	Long:  `Queues a job that ran out of attempts again, due now with its attempts reset.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := uuid.Parse(args[0])
+		if err != nil {
+			panic(err)
+		}
+		if _, err := database.Connect(); err != nil {
+			panic(err)
+		}
+
+		job, err := jobs.Retry(database.DB.WithContext(common.AsSystem(context.Background())), id)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Job %s queued again\n", job.ID)
+	},
+}
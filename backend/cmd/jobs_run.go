@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/jobs"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var jobsRunPayload string
+
+func init() {
+	jobsRunCmd.Flags().StringVar(&jobsRunPayload, "payload", "", "JSON payload of the job")
+	jobsCmd.AddCommand(jobsRunCmd)
+}
+
+var jobsRunCmd = &cobra.Command{
+	Use:   "run <name>",
+	Short: "Runs a job now",
+	Long:  `Runs a job right away in this process, whatever is queued, and records it in the queue like the jobs the server works.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := database.Connect(); err != nil {
+			panic(err)
+		}
+
+		var payload interface{}
+		if jobsRunPayload != "" {
+			payload = json.RawMessage(jobsRunPayload)
+		}
+		job, err := jobs.Run(common.AsSystem(context.Background()), args[0], payload)
+		if job == nil {
+			panic(err)
+		}
+		fmt.Printf("Job %s %s\n", job.ID, job.Status)
+		if err != nil {
+			panic(err)
+		}
+	},
+}
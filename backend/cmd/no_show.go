@@ -0,0 +1,16 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func init() {
+	rootCmd.AddCommand(noShowCmd)
+}
+
+var noShowCmd = &cobra.Command{
+	Use:   "no-shows",
+	Short: "No-show commands",
+	Long:  `Manages the reservations guests did not show up to.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"backend/database"
+	"backend/models"
+	"context"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	noShowCmd.AddCommand(noShowMarkCmd)
+}
+
+var noShowMarkCmd = &cobra.Command{
+	Use:   "mark",
+	Short: "Marks the reservations nobody showed up to",
+	Long:  `Marks as no-shows the reservations still confirmed once the cancellation policy of their business gives up on the guest, and counts them against the guest, which the server also does every services.no_shows.sweep_interval.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := database.Connect(); err != nil {
+			panic(err)
+		}
+
+		if err := models.MarkNoShows(context.Background()); err != nil {
+			panic(err)
+		}
+	},
+}
@@ -0,0 +1,16 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func init() {
+	rootCmd.AddCommand(openapiCmd)
+}
+
+var openapiCmd = &cobra.Command{
+	Use:   "openapi",
+	Short: "OpenAPI commands",
+	Long:  `OpenAPI commands.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"backend/api/controllers"
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var openapiOutput string
+
+func init() {
+	openapiCmd.AddCommand(openapiExportCmd)
+	openapiExportCmd.Flags().StringVarP(&openapiOutput, "output", "o", "", "file to write the document to (default is stdout)")
+}
+
+var openapiExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports the OpenAPI document",
+	Long:  `Writes the OpenAPI 3.1 document served at /api/v1/openapi.json, without starting the server.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		document, err := json.MarshalIndent(controllers.OpenAPIDocument("/api/v1"), "", "  ")
+		if err != nil {
+			panic(err)
+		}
+
+		if openapiOutput == "" {
+			os.Stdout.Write(append(document, '\n'))
+			return
+		}
+		if err := os.WriteFile(openapiOutput, append(document, '\n'), 0644); err != nil {
+			panic(err)
+		}
+	},
+}
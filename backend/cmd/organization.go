@@ -0,0 +1,16 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func init() {
+	rootCmd.AddCommand(organizationCmd)
+}
+
+var organizationCmd = &cobra.Command{
+	Use:   "organization",
+	Short: "Organization commands",
+	Long:  `Manages the organizations (tenants) sharing the deployment.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
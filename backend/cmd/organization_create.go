@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/pkg/common"
+	"backend/pkg/generics"
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	organizationCmd.AddCommand(organizationCreateCmd)
+}
+
+var organizationCreateCmd = &cobra.Command{
+	Use:   "create <name> <slug>",
+	Short: "Creates an organization",
+	Long:  `Creates an organization. The slug is the subdomain its requests are served from.`,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := database.Connect(); err != nil {
+			panic(err)
+		}
+
+		repository := generics.NewGenericRepositoryGORM[*models.Organization, *models.OrganizationDTO]().
+			WithContext(common.AsSystem(context.Background()))
+
+		organization, err := repository.Create(&models.Organization{Name: args[0], Slug: args[1]})
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Created organization %s (%s)\n", organization.Slug, organization.ID)
+	},
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/pkg/common"
+	"backend/pkg/generics"
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	organizationCmd.AddCommand(organizationListCmd)
+}
+
+var organizationListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the organizations",
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := database.Connect(); err != nil {
+			panic(err)
+		}
+
+		repository := generics.NewGenericRepositoryGORM[*models.Organization, *models.OrganizationDTO]().
+			WithContext(common.AsSystem(context.Background()))
+
+		page, err := repository.FindAll(
+			common.Pageable{Page: 1, Size: math.MaxInt32},
+			common.NoConditions,
+			common.NoFields,
+			common.OrderBys{{Field: "slug", Direction: common.Asc}})
+		if err != nil {
+			panic(err)
+		}
+		for _, organization := range page.Items {
+			fmt.Printf("%s\t%s\t%s\n", organization.ID, organization.Slug, organization.Name)
+		}
+	},
+}
@@ -0,0 +1,16 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func init() {
+	rootCmd.AddCommand(outboxCmd)
+}
+
+var outboxCmd = &cobra.Command{
+	Use:   "outbox",
+	Short: "Outbox commands",
+	Long:  `Manages the outbox of the events waiting to be published to the sinks.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/outbox"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	outboxListStatus string
+	outboxListLimit  int
+)
+
+func init() {
+	outboxListCmd.Flags().StringVar(&outboxListStatus, "status", "", "only list the messages with this status: pending or published")
+	outboxListCmd.Flags().IntVar(&outboxListLimit, "limit", 50, "most messages listed")
+	outboxCmd.AddCommand(outboxListCmd)
+}
+
+var outboxListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the outbox messages",
+	Long:  `Lists the messages of the outbox, most recent first, along with the sinks that can be relayed to.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := database.Connect(); err != nil {
+			panic(err)
+		}
+
+		found, err := outbox.List(common.AsSystem(context.Background()), outboxListStatus, outboxListLimit)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Sinks: %s\n", strings.Join(outbox.Names(), ", "))
+		for _, message := range found {
+			fmt.Printf("%s\t%s\t%s\t%d\t%s\t%s\n",
+				message.Key, message.Topic, message.Status, message.Attempts, message.AvailableAt.Format(time.RFC3339), message.LastError)
+		}
+	},
+}
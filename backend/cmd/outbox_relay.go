@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"backend/database"
+	"backend/pkg/outbox"
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	outboxCmd.AddCommand(outboxRelayCmd)
+}
+
+var outboxRelayCmd = &cobra.Command{
+	Use:   "relay",
+	Short: "Relays the outbox once",
+	Long:  `Publishes the due messages of the outbox to the configured sinks, then exits.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := database.Connect(); err != nil {
+			panic(err)
+		}
+
+		sinks, err := outbox.Open()
+		if err != nil {
+			panic(err)
+		}
+		published, err := outbox.Relay(context.Background(), sinks)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Published %d messages\n", published)
+	},
+}
@@ -0,0 +1,16 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func init() {
+	rootCmd.AddCommand(recurringCmd)
+}
+
+var recurringCmd = &cobra.Command{
+	Use:   "recurring",
+	Short: "Recurring reservation commands",
+	Long:  `Manages the series of recurring reservations.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"backend/database"
+	"backend/models"
+	"context"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	recurringCmd.AddCommand(recurringExtendCmd)
+}
+
+var recurringExtendCmd = &cobra.Command{
+	Use:   "extend",
+	Short: "Books the occurrences of every series up to the horizon",
+	Long:  `Books the occurrences of every recurring reservation up to services.recurrence.horizon_days ahead, which the server also does every services.recurrence.extend_interval.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := database.Connect(); err != nil {
+			panic(err)
+		}
+
+		if err := models.ExtendRecurringReservations(context.Background()); err != nil {
+			panic(err)
+		}
+	},
+}
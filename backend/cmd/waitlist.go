@@ -0,0 +1,16 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func init() {
+	rootCmd.AddCommand(waitlistCmd)
+}
+
+var waitlistCmd = &cobra.Command{
+	Use:   "waitlist",
+	Short: "Waitlist commands",
+	Long:  `Manages the waitlists of the businesses.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
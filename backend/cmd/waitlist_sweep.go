@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"backend/database"
+	"backend/models"
+	"context"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	waitlistCmd.AddCommand(waitlistSweepCmd)
+}
+
+var waitlistSweepCmd = &cobra.Command{
+	Use:   "sweep",
+	Short: "Expires unconfirmed offers and promotes the next entries",
+	Long:  `Expires the waitlist offers not confirmed within services.waitlist.offer_minutes and the entries whose date has passed, then offers a reservation to the entries that fit, which the server also does every services.waitlist.sweep_interval.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := database.Connect(); err != nil {
+			panic(err)
+		}
+
+		if err := models.SweepWaitlist(context.Background()); err != nil {
+			panic(err)
+		}
+	},
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"backend/database/drivers"
+	"time"
 
 	"github.com/spf13/viper"
 	"gorm.io/gorm"
@@ -13,7 +14,9 @@ func Connect() (*gorm.DB, error) {
 	var db *gorm.DB
 	var err error
 
-	config := &gorm.Config{}
+	config := &gorm.Config{
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	}
 
 	// Engine
 	switch viper.GetString("database.driver") {
@@ -22,7 +25,13 @@ func Connect() (*gorm.DB, error) {
 	default:
 		panic("Invalid database driver")
 	}
+	if err != nil {
+		return db, err
+	}
+	if err := db.Use(UTC{}); err != nil {
+		return db, err
+	}
 	DB = db
 
-	return db, err
+	return db, nil
 }
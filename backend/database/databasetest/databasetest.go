@@ -0,0 +1,47 @@
+// Databasetest package opens in-memory databases for the tests of the packages reading database.DB.
+
+package databasetest
+
+import (
+	"backend/database"
+
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open makes database.DB an in-memory SQLite database holding the tables of
+// models, until the test ends
+func Open(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger:  logger.Default.LogMode(logger.Silent),
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(database.UTC{}); err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: is a database of its own
+	pool, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		pool.Close()
+	})
+	return db
+}
@@ -2,12 +2,20 @@ package database
 
 import (
 	"backend/pkg/common"
+
+	"gorm.io/gorm"
 )
 
 type MigrationTask struct {
 	Model           common.Entity
 	DropOnFlush     bool
 	TruncateOnFlush bool
+	// Prepare runs before the tables are migrated, to adapt existing data
+	// to changes automatic migrations cannot make, like column types
+	Prepare func(db *gorm.DB) error
+	// Backfill runs after the tables are migrated, to fill in the columns
+	// added to existing rows
+	Backfill func(db *gorm.DB) error
 }
 
 type JoinTableMigrationTask struct {
@@ -32,6 +40,11 @@ func Migrate() error {
 
 	models := []interface{}{}
 	for _, task := range migrationTasks {
+		if task.Prepare != nil {
+			if err := task.Prepare(DB); err != nil {
+				return err
+			}
+		}
 		models = append(models, task.Model)
 	}
 
@@ -48,5 +61,13 @@ func Migrate() error {
 		DB.SetupJoinTable(task.Model, task.Property, task.JoinTableStruct)
 	}
 
+	for _, task := range migrationTasks {
+		if task.Backfill != nil {
+			if err := task.Backfill(DB); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,26 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Purge deletes for good the rows of every model soft deleted before before,
+// and returns how many there were. Hooks do not run, the rows are long gone
+// for the API
+func Purge(db *gorm.DB, before time.Time) (int64, error) {
+	var purged int64
+	for _, task := range migrationTasks {
+		result := db.
+			Session(&gorm.Session{NewDB: true, SkipHooks: true}).
+			Unscoped().
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Delete(task.Model)
+		if result.Error != nil {
+			return purged, result.Error
+		}
+		purged += result.RowsAffected
+	}
+	return purged, nil
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UTC is a plugin storing every time written through GORM in UTC, and
+// comparing columns with times in UTC. Timestamp columns have no time zone,
+// the driver writes the wall clock of a time, so one in another zone would be
+// read back shifted by its offset, or match the rows of another instant
+type UTC struct{}
+
+func (UTC) Name() string {
+	return "utc"
+}
+
+func (UTC) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("utc:create", toUTC); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("utc:update", toUTC); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().Before("gorm:query").Register("utc:query", varsToUTC); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("utc:delete", varsToUTC); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("utc:row", varsToUTC); err != nil {
+		return err
+	}
+	return db.Callback().Raw().Before("gorm:raw").Register("utc:raw", varsToUTC)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// toUTC converts the times of the entities of the statement, the ones of
+// column updates and the ones it is conditioned on
+func toUTC(db *gorm.DB) {
+	varsToUTC(db)
+	if columns, ok := db.Statement.Dest.(map[string]interface{}); ok {
+		for column, value := range columns {
+			if instant, ok := value.(time.Time); ok {
+				columns[column] = instant.UTC()
+			}
+		}
+	}
+	if db.Statement.Schema == nil {
+		return
+	}
+
+	convert := func(value reflect.Value) {
+		for _, field := range db.Statement.Schema.Fields {
+			if field.IndirectFieldType != timeType {
+				continue
+			}
+			current, zero := field.ValueOf(db.Statement.Context, value)
+			if zero {
+				continue
+			}
+			switch instant := current.(type) {
+			case time.Time:
+				db.AddError(field.Set(db.Statement.Context, value, instant.UTC()))
+			case *time.Time:
+				utc := instant.UTC()
+				db.AddError(field.Set(db.Statement.Context, value, &utc))
+			}
+		}
+	}
+
+	value := reflect.Indirect(db.Statement.ReflectValue)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			convert(reflect.Indirect(value.Index(i)))
+		}
+	case reflect.Struct:
+		convert(value)
+	}
+}
+
+// varsToUTC converts the times the statement is conditioned on, and the
+// arguments of raw SQL
+func varsToUTC(db *gorm.DB) {
+	for name, c := range db.Statement.Clauses {
+		if c.Expression != nil {
+			c.Expression = expressionToUTC(c.Expression)
+			db.Statement.Clauses[name] = c
+		}
+	}
+	valuesToUTC(db.Statement.Vars)
+}
+
+func expressionToUTC(expression clause.Expression) clause.Expression {
+	switch e := expression.(type) {
+	case clause.Where:
+		expressionsToUTC(e.Exprs)
+	case clause.AndConditions:
+		expressionsToUTC(e.Exprs)
+	case clause.OrConditions:
+		expressionsToUTC(e.Exprs)
+	case clause.NotConditions:
+		expressionsToUTC(e.Exprs)
+	case clause.Expr:
+		valuesToUTC(e.Vars)
+	case clause.NamedExpr:
+		valuesToUTC(e.Vars)
+	case clause.IN:
+		valuesToUTC(e.Values)
+	case clause.Eq:
+		e.Value = valueToUTC(e.Value)
+		return e
+	case clause.Neq:
+		e.Value = valueToUTC(e.Value)
+		return e
+	case clause.Gt:
+		e.Value = valueToUTC(e.Value)
+		return e
+	case clause.Gte:
+		e.Value = valueToUTC(e.Value)
+		return e
+	case clause.Lt:
+		e.Value = valueToUTC(e.Value)
+		return e
+	case clause.Lte:
+		e.Value = valueToUTC(e.Value)
+		return e
+	}
+	return expression
+}
+
+func expressionsToUTC(expressions []clause.Expression) {
+	for i, expression := range expressions {
+		expressions[i] = expressionToUTC(expression)
+	}
+}
+
+func valuesToUTC(values []interface{}) {
+	for i, value := range values {
+		values[i] = valueToUTC(value)
+	}
+}
+
+func valueToUTC(value interface{}) interface{} {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC()
+	case *time.Time:
+		if v != nil {
+			utc := v.UTC()
+			return &utc
+		}
+	case []interface{}:
+		valuesToUTC(v)
+	case clause.Expression:
+		return expressionToUTC(v)
+	}
+	return value
+}
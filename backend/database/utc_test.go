@@ -0,0 +1,75 @@
+package database_test
+
+import (
+	"backend/database/databasetest"
+
+	"testing"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+type stamp struct {
+	ID uint
+	At time.Time `gorm:"type:timestamp"`
+}
+
+func TestUTCConditions(t *testing.T) {
+	db := databasetest.Open(t, &stamp{})
+	zone, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone is not available: %s", err)
+	}
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, zone)
+	if err := db.Create(&stamp{At: at}).Error; err != nil {
+		t.Fatal(err)
+	}
+	before, after := at.Add(-time.Second), at.Add(time.Second)
+
+	tests := []struct {
+		name  string
+		query func() (int64, error)
+	}{
+		{"expression", func() (count int64, err error) {
+			err = db.Model(&stamp{}).Where("at > ? AND at < ?", before, &after).Count(&count).Error
+			return
+		}},
+		{"or and not", func() (count int64, err error) {
+			err = db.Model(&stamp{}).Where("at < ?", before).Or("at = ?", at).Not("at > ?", after).Count(&count).Error
+			return
+		}},
+		{"clauses", func() (count int64, err error) {
+			err = db.Model(&stamp{}).Where(clause.Gte{Column: "at", Value: at}, clause.IN{Column: "at", Values: []interface{}{at}}).Count(&count).Error
+			return
+		}},
+		{"inline", func() (count int64, err error) {
+			var found []stamp
+			err = db.Find(&found, "at = ?", at).Error
+			return int64(len(found)), err
+		}},
+		{"raw", func() (count int64, err error) {
+			err = db.Raw("SELECT count(*) FROM stamps WHERE at BETWEEN ? AND ?", before, after).Scan(&count).Error
+			return
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := tt.query()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != 1 {
+				t.Fatalf("got %d rows, want the one stored at %s", count, at.UTC())
+			}
+		})
+	}
+
+	// Updates and deletes are conditioned in UTC too
+	if err := db.Model(&stamp{}).Where("at = ?", at).UpdateColumn("at", after).Error; err != nil {
+		t.Fatal(err)
+	}
+	deleted := db.Where("at = ?", after).Delete(&stamp{})
+	if deleted.Error != nil || deleted.RowsAffected != 1 {
+		t.Fatalf("got %d rows deleted and %v, want the one updated", deleted.RowsAffected, deleted.Error)
+	}
+}
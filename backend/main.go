@@ -1,6 +1,11 @@
 package main
 
-import "backend/cmd"
+import (
+	"backend/cmd"
+
+	// Embeds the time zone database, businesses can be in any IANA zone
+	_ "time/tzdata"
+)
 
 func main() {
 	cmd.Execute()
@@ -3,6 +3,14 @@ package models
 import (
 	"backend/database"
 	"backend/pkg/common"
+	"backend/pkg/notify"
+
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
 )
 
 func init() {
@@ -20,19 +28,81 @@ type Business struct {
 	Location            string `gorm:"type:varchar(255);not null"`
 	OwnerID             string `gorm:"type:varchar(255);not null"`
 	Capacity            int    `gorm:"type:int;not null"`
+	SlotMinutes         int    `gorm:"type:int;not null;default:60"`
+	// TimeZone is the IANA name of the zone the schedules of the business
+	// are read in, like Atlantic/Canary
+	TimeZone string `gorm:"type:varchar(64);not null;default:UTC"`
+	// LogoURL, BrandColor and EmailFooter brand the emails sent to the
+	// guests of the business. BrandColor is a hex color like #1d4ed8
+	LogoURL     string `gorm:"type:varchar(1024);not null;default:''"`
+	BrandColor  string `gorm:"type:varchar(9);not null;default:''"`
+	EmailFooter string `gorm:"type:text;not null;default:''"`
 
 	// Relationships
 	Owner        User          `gorm:"foreignKey:OwnerID"`
 	Reservations []Reservation `gorm:"foreignKey:BusinessID"`
+	Schedules    []Schedule    `gorm:"foreignKey:BusinessID"`
 }
 
 type BusinessDTO struct {
 	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
-	Name             string `json:"name" tstype:"string,required"`
+	Name             string `json:"name" tstype:"string,required" validate:"required"`
 	Type             string `json:"type" tstype:"string,required"`
 	Location         string `json:"location" tstype:"string,required"`
-	OwnerID          string `json:"owner_id" tstype:"string,required"`
-	Capacity         int    `json:"capacity" tstype:"number,required"`
+	OwnerID          string `json:"owner_id" tstype:"string,required" validate:"required"`
+	Capacity         int    `json:"capacity" tstype:"number,required" validate:"gte=0"`
+	SlotMinutes      int    `json:"slot_minutes" tstype:"number,required" validate:"gte=0"`
+	TimeZone         string `json:"time_zone" tstype:"string,required"`
+	LogoURL          string `json:"logo_url" tstype:"string" validate:"omitempty,url"`
+	BrandColor       string `json:"brand_color" tstype:"string" validate:"omitempty,hexcolor"`
+	EmailFooter      string `json:"email_footer" tstype:"string"`
+}
+
+// hexColor matches the colors the emails of a business can be branded with
+var hexColor = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+// BeforeSave defaults the time zone to UTC and refuses unknown ones, and
+// brand colors that are not hex colors
+func (b *Business) BeforeSave(tx *gorm.DB) error {
+	if b.TimeZone == "" {
+		b.TimeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(b.TimeZone); err != nil {
+		return fmt.Errorf("unknown time zone %q", b.TimeZone)
+	}
+	if b.BrandColor != "" && !hexColor.MatchString(b.BrandColor) {
+		return fmt.Errorf("brand color %q is not a hex color like #1d4ed8", b.BrandColor)
+	}
+	return nil
+}
+
+// Zone is the location of the time zone of the business, UTC when it has
+// none
+func (b Business) Zone() *time.Location {
+	location, err := time.LoadLocation(b.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// Brand is how the business appears in the emails sent to its guests
+func (b Business) Brand() notify.Brand {
+	color := b.BrandColor
+	if color == "" {
+		color = notify.DefaultColor
+	}
+	return notify.Brand{Name: b.Name, LogoURL: b.LogoURL, Color: color, Footer: b.EmailFooter}
+}
+
+// Slot is how long a reservation of the business lasts, the configured
+// default when the business has none
+func (b Business) Slot() time.Duration {
+	minutes := b.SlotMinutes
+	if minutes <= 0 {
+		minutes = viper.GetInt("services.calendar.default_slot_minutes")
+	}
+	return time.Duration(minutes) * time.Minute
 }
 
 func (b Business) ToDTO() common.DTO {
@@ -42,11 +112,16 @@ func (b Business) ToDTO() common.DTO {
 			CreatedAt: b.CreatedAt,
 			UpdatedAt: b.UpdatedAt,
 		},
-		Name:     b.Name,
-		Type:     b.Type,
-		Location: b.Location,
-		OwnerID:  b.OwnerID,
-		Capacity: b.Capacity,
+		Name:        b.Name,
+		Type:        b.Type,
+		Location:    b.Location,
+		OwnerID:     b.OwnerID,
+		Capacity:    b.Capacity,
+		SlotMinutes: b.SlotMinutes,
+		TimeZone:    b.TimeZone,
+		LogoURL:     b.LogoURL,
+		BrandColor:  b.BrandColor,
+		EmailFooter: b.EmailFooter,
 	}
 	return dto
 }
@@ -58,11 +133,16 @@ func (b BusinessDTO) ToEntity() common.Entity {
 			CreatedAt: b.CreatedAt,
 			UpdatedAt: b.UpdatedAt,
 		},
-		Name:     b.Name,
-		Type:     b.Type,
-		Location: b.Location,
-		OwnerID:  b.OwnerID,
-		Capacity: b.Capacity,
+		Name:        b.Name,
+		Type:        b.Type,
+		Location:    b.Location,
+		OwnerID:     b.OwnerID,
+		Capacity:    b.Capacity,
+		SlotMinutes: b.SlotMinutes,
+		TimeZone:    b.TimeZone,
+		LogoURL:     b.LogoURL,
+		BrandColor:  b.BrandColor,
+		EmailFooter: b.EmailFooter,
 	}
 	return entity
 }
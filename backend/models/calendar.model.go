@@ -0,0 +1,88 @@
+package models
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/google/uuid"
+)
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &CalendarToken{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+}
+
+// CalendarToken grants access to the calendar feed of a user without any other
+// credential, so calendar apps can subscribe to it. Only the hash of the
+// token is stored, deleting the row revokes it
+type CalendarToken struct {
+	common.CommonEntity `gorm:"embedded"`
+	UserID              uuid.UUID `gorm:"type:uuid;not null;index"`
+	TokenHash           string    `gorm:"type:varchar(64);not null;unique"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID"`
+}
+
+type CalendarTokenDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	UserID           uuid.UUID `json:"user_id" tstype:"string,required"`
+	// Token and URL are only known when the token is issued
+	Token string `json:"token,omitempty" tstype:"string"`
+	URL   string `json:"url,omitempty" tstype:"string"`
+}
+
+// NewCalendarToken returns a token for userID and the secret it was hashed
+// from, which must be handed to the user as it cannot be recovered
+func NewCalendarToken(userID uuid.UUID) (*CalendarToken, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+	return &CalendarToken{UserID: userID, TokenHash: HashCalendarToken(token)}, token, nil
+}
+
+func HashCalendarToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func (t CalendarToken) ToDTO() common.DTO {
+	dto := &CalendarTokenDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        t.ID,
+			CreatedAt: t.CreatedAt,
+			UpdatedAt: t.UpdatedAt,
+		},
+		UserID: t.UserID,
+	}
+	return dto
+}
+
+func (t CalendarTokenDTO) ToEntity() common.Entity {
+	entity := &CalendarToken{
+		CommonEntity: common.CommonEntity{
+			ID:        t.ID,
+			CreatedAt: t.CreatedAt,
+			UpdatedAt: t.UpdatedAt,
+		},
+		UserID: t.UserID,
+	}
+	return entity
+}
+
+// Redacted leaves the token and the URL embedding it out
+func (t CalendarTokenDTO) Redacted() common.DTO {
+	t.Token = ""
+	t.URL = ""
+	return &t
+}
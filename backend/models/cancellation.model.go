@@ -0,0 +1,295 @@
+package models
+
+import (
+	"backend/database"
+	"backend/pkg/audit"
+	"backend/pkg/common"
+	"backend/pkg/tenancy"
+
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &CancellationPolicy{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+}
+
+var (
+	ErrReservationCancelled = errors.New("the reservation is already cancelled")
+	ErrGuestBlocked         = errors.New("the guest missed too many reservations to book the business")
+)
+
+// CancellationPolicy is what a business charges for cancellations and how it
+// deals with guests who do not show up. Reservations cancelled at least
+// FreeHours before they start are cancelled for free, later ones cost Fee, in
+// the minor units of Currency, which is kept from their deposit. Reservations
+// still confirmed NoShowMinutes after they start are marked as no-shows, and
+// guests with MaxNoShows of them in the last NoShowDays cannot book the
+// business anymore. Zero minutes never marks them, zero no-shows never blocks
+// a guest, and zero days counts all of their no-shows
+type CancellationPolicy struct {
+	common.CommonEntity `gorm:"embedded"`
+	BusinessID          uuid.UUID `gorm:"type:uuid;not null;index"`
+	FreeHours           int       `gorm:"type:int;not null;default:0"`
+	Fee                 int64     `gorm:"type:bigint;not null;default:0"`
+	Currency            string    `gorm:"type:varchar(3);not null;default:''"`
+	NoShowMinutes       int       `gorm:"type:int;not null;default:0"`
+	MaxNoShows          int       `gorm:"type:int;not null;default:0"`
+	NoShowDays          int       `gorm:"type:int;not null;default:0"`
+
+	// Relationships
+	Business Business `gorm:"foreignKey:BusinessID"`
+}
+
+type CancellationPolicyDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	BusinessID       uuid.UUID `json:"business_id" tstype:"string,required" validate:"required"`
+	FreeHours        int       `json:"free_hours" tstype:"number" validate:"gte=0"`
+	Fee              int64     `json:"fee" tstype:"number" validate:"gte=0"`
+	Currency         string    `json:"currency" tstype:"string" validate:"omitempty,len=3"`
+	NoShowMinutes    int       `json:"no_show_minutes" tstype:"number" validate:"gte=0"`
+	MaxNoShows       int       `json:"max_no_shows" tstype:"number" validate:"gte=0"`
+	NoShowDays       int       `json:"no_show_days" tstype:"number" validate:"gte=0"`
+}
+
+// CancellationDTO gives the reason a reservation is cancelled
+type CancellationDTO struct {
+	Reason string `json:"reason" tstype:"string,required" validate:"required"`
+}
+
+// BeforeSave checks the policy holds no negative numbers, and keeps the
+// currency in upper case
+func (p *CancellationPolicy) BeforeSave(tx *gorm.DB) error {
+	if p.FreeHours < 0 || p.Fee < 0 || p.NoShowMinutes < 0 || p.MaxNoShows < 0 || p.NoShowDays < 0 {
+		return errors.New("the hours, fee, minutes, no-shows and days of a cancellation policy cannot be negative")
+	}
+	p.Currency = strings.ToUpper(p.Currency)
+	return nil
+}
+
+// FindCancellationPolicy loads the cancellation policy of the business
+// business. Without one, cancellations are free and no-shows are not tracked
+func FindCancellationPolicy(tx *gorm.DB, business uuid.UUID) (CancellationPolicy, error) {
+	var policy CancellationPolicy
+	err := tx.
+		Session(&gorm.Session{NewDB: true}).
+		Scopes(tenancy.Scope).
+		Where("business_id = ?", business).
+		Order("created_at").
+		Limit(1).
+		Find(&policy).Error
+	return policy, err
+}
+
+// FeeAt is what cancelling a reservation starting at date costs at now
+func (p CancellationPolicy) FeeAt(date time.Time, now time.Time) int64 {
+	if now.After(date.Add(-time.Duration(p.FreeHours) * time.Hour)) {
+		return p.Fee
+	}
+	return 0
+}
+
+// admit returns ErrGuestBlocked when user has missed as many reservations as
+// the policy tolerates. With a window, only the no-shows of its days and
+// since the last reset of the guest count
+func (p CancellationPolicy) admit(db *gorm.DB, user uuid.UUID, now time.Time) error {
+	if p.MaxNoShows == 0 {
+		return nil
+	}
+	var guest User
+	if err := db.Scopes(tenancy.Scope).Limit(1).Find(&guest, "id = ?", user).Error; err != nil {
+		return err
+	}
+	missed := int64(guest.NoShows)
+	if p.NoShowDays > 0 {
+		since := now.AddDate(0, 0, -p.NoShowDays)
+		if guest.NoShowsResetAt != nil && guest.NoShowsResetAt.After(since) {
+			since = *guest.NoShowsResetAt
+		}
+		err := db.
+			Model(&Reservation{}).
+			Scopes(tenancy.Scope).
+			Where("user_id = ? AND status = ? AND date >= ?", user, ReservationNoShow, since).
+			Count(&missed).Error
+		if err != nil {
+			return err
+		}
+	}
+	if missed >= int64(p.MaxNoShows) {
+		return fmt.Errorf("%w: %d no-shows", ErrGuestBlocked, missed)
+	}
+	return nil
+}
+
+// ResetNoShows forgives the no-shows of user, who can book the businesses
+// that blocked them again
+func ResetNoShows(tx *gorm.DB, user *User) error {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	before := user.ToDTO()
+	now := time.Now()
+	// Through the table, as the fields of User only take writes on create
+	err := db.
+		Table("users").
+		Scopes(tenancy.Scope).
+		Where("id = ?", user.ID).
+		UpdateColumns(map[string]interface{}{"no_shows": 0, "no_shows_reset_at": now}).Error
+	if err != nil {
+		return err
+	}
+	user.NoShows = 0
+	user.NoShowsResetAt = &now
+	return audit.Record(db, audit.Updated, "users", user, before, user.ToDTO())
+}
+
+// cancel stamps a reservation cancelled now with its fee under policy.
+// Reservations cancelled before keep the time and fee stored, and the others
+// have none
+func (r *Reservation) cancel(stored Reservation, policy CancellationPolicy) {
+	switch {
+	case r.Status != ReservationCancelled:
+		r.CancelledAt = nil
+		r.CancellationReason = ""
+		r.CancellationFee = 0
+	case stored.Status == ReservationCancelled && stored.CancelledAt != nil:
+		r.CancelledAt = stored.CancelledAt
+		r.CancellationFee = stored.CancellationFee
+		if r.CancellationReason == "" {
+			r.CancellationReason = stored.CancellationReason
+		}
+	default:
+		now := time.Now()
+		r.CancelledAt = &now
+		r.CancellationFee = policy.FeeAt(r.Date, now)
+	}
+}
+
+// CancelReservation cancels reservation for reason. The fee of the policy of
+// its business is kept from its deposits and the rest refunded
+func CancelReservation(tx *gorm.DB, reservation *Reservation, reason string) error {
+	if reservation.Status == ReservationCancelled {
+		return ErrReservationCancelled
+	}
+	db := tx.Session(&gorm.Session{NewDB: true})
+	before := reservation.ToDTO()
+	reservation.Status = ReservationCancelled
+	reservation.CancellationReason = reason
+	if err := db.Omit(clause.Associations).Save(reservation).Error; err != nil {
+		return err
+	}
+	return audit.Record(db, audit.Updated, "reservations", reservation, before, reservation.ToDTO())
+}
+
+// MarkNoShows marks as no-shows the reservations still confirmed once the
+// policy of their business gives up on the guest, and counts them against
+// the guest. Reservations starting before the policy was written are left
+// alone. Each business is handled in a transaction bound to its tenant
+func MarkNoShows(ctx context.Context) error {
+	var policies []CancellationPolicy
+	err := database.DB.
+		WithContext(common.AsSystem(ctx)).
+		Where("no_show_minutes > 0").
+		Find(&policies).Error
+	if err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		err := database.DB.
+			WithContext(common.WithTenant(ctx, policy.TenantID)).
+			Transaction(func(tx *gorm.DB) error {
+				return policy.markNoShows(tx, time.Now())
+			})
+		if err != nil {
+			return fmt.Errorf("no-shows of business %s: %w", policy.BusinessID, err)
+		}
+	}
+	return nil
+}
+
+func (p CancellationPolicy) markNoShows(tx *gorm.DB, now time.Time) error {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	var missed []Reservation
+	err := db.
+		Scopes(tenancy.Scope).
+		Where("business_id = ? AND status = ?", p.BusinessID, ReservationConfirmed).
+		Where("date >= ? AND date <= ?", p.CreatedAt, now.Add(-time.Duration(p.NoShowMinutes)*time.Minute)).
+		Find(&missed).Error
+	if err != nil {
+		return err
+	}
+	for i := range missed {
+		reservation := &missed[i]
+		before := reservation.ToDTO()
+		claim := db.
+			Model(&Reservation{}).
+			Scopes(tenancy.Scope).
+			Where("id = ? AND status = ?", reservation.ID, ReservationConfirmed).
+			UpdateColumn("status", ReservationNoShow)
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+		reservation.Status = ReservationNoShow
+		if err := audit.Record(db, audit.Updated, "reservations", reservation, before, reservation.ToDTO()); err != nil {
+			return err
+		}
+		// Through the table, as the field of User only takes writes on create
+		err := db.
+			Table("users").
+			Scopes(tenancy.Scope).
+			Where("id = ?", reservation.UserID).
+			UpdateColumn("no_shows", gorm.Expr("no_shows + 1")).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p CancellationPolicy) ToDTO() common.DTO {
+	dto := &CancellationPolicyDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        p.ID,
+			CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt,
+		},
+		BusinessID:    p.BusinessID,
+		FreeHours:     p.FreeHours,
+		Fee:           p.Fee,
+		Currency:      p.Currency,
+		NoShowMinutes: p.NoShowMinutes,
+		MaxNoShows:    p.MaxNoShows,
+		NoShowDays:    p.NoShowDays,
+	}
+	return dto
+}
+
+func (p CancellationPolicyDTO) ToEntity() common.Entity {
+	entity := &CancellationPolicy{
+		CommonEntity: common.CommonEntity{
+			ID:        p.ID,
+			CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt,
+		},
+		BusinessID:    p.BusinessID,
+		FreeHours:     p.FreeHours,
+		Fee:           p.Fee,
+		Currency:      p.Currency,
+		NoShowMinutes: p.NoShowMinutes,
+		MaxNoShows:    p.MaxNoShows,
+		NoShowDays:    p.NoShowDays,
+	}
+	return entity
+}
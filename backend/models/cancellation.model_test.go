@@ -0,0 +1,155 @@
+package models
+
+import (
+	"backend/database"
+	"backend/database/databasetest"
+	"backend/pkg/common"
+
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// missed stores a reservation user did not show up to, days ago
+func missed(t *testing.T, db *gorm.DB, user uuid.UUID, days int) {
+	t.Helper()
+	reservation := Reservation{BusinessID: uuid.New(), UserID: user, NumberOfPeople: 1, Status: ReservationNoShow}
+	reservation.ID = uuid.New()
+	reservation.Date = time.Now().AddDate(0, 0, -days)
+	reservation.EndsAt = reservation.Date.Add(time.Hour)
+	reservation.BusyFrom, reservation.BusyUntil = reservation.Date, reservation.EndsAt
+	// Without its hooks, which would check the availability of the business
+	if err := db.Session(&gorm.Session{SkipHooks: true}).Omit(clause.Associations).Create(&reservation).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAdmit(t *testing.T) {
+	databasetest.Open(t)
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	db := database.DB.WithContext(common.AsSystem(context.Background()))
+	guest := User{Name: "Guest", Email: "guest@example.com", Password: "secret", Role: "user", NoShows: 3}
+	if err := db.Create(&guest).Error; err != nil {
+		t.Fatal(err)
+	}
+	missed(t, db, guest.ID, 40)
+	missed(t, db, guest.ID, 20)
+	missed(t, db, guest.ID, 2)
+
+	tests := []struct {
+		name   string
+		policy CancellationPolicy
+		want   error
+	}{
+		{"no limit", CancellationPolicy{}, nil},
+		{"every no-show counts", CancellationPolicy{MaxNoShows: 3}, ErrGuestBlocked},
+		{"too few no-shows", CancellationPolicy{MaxNoShows: 4}, nil},
+		{"no-shows of the window", CancellationPolicy{MaxNoShows: 2, NoShowDays: 30}, ErrGuestBlocked},
+		{"no-shows before the window", CancellationPolicy{MaxNoShows: 2, NoShowDays: 10}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.admit(db, guest.ID, time.Now()); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResetNoShows(t *testing.T) {
+	databasetest.Open(t)
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	db := database.DB.WithContext(common.AsSystem(context.Background()))
+	guest := User{Name: "Guest", Email: "guest@example.com", Password: "secret", Role: "user", NoShows: 2}
+	if err := db.Create(&guest).Error; err != nil {
+		t.Fatal(err)
+	}
+	missed(t, db, guest.ID, 2)
+	missed(t, db, guest.ID, 1)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return ResetNoShows(tx, &guest)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored User
+	if err := db.First(&stored, "id = ?", guest.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.NoShows != 0 || stored.NoShowsResetAt == nil {
+		t.Fatalf("got %d no-shows reset at %v, want none and a reset", stored.NoShows, stored.NoShowsResetAt)
+	}
+
+	for _, policy := range []CancellationPolicy{{MaxNoShows: 1}, {MaxNoShows: 1, NoShowDays: 30}} {
+		if err := policy.admit(db, guest.ID, time.Now()); err != nil {
+			t.Errorf("%d days: got %v after the reset, want nil", policy.NoShowDays, err)
+		}
+	}
+	missed(t, db, guest.ID, 0)
+	policy := CancellationPolicy{MaxNoShows: 1, NoShowDays: 30}
+	if err := policy.admit(db, guest.ID, time.Now().Add(time.Hour)); !errors.Is(err, ErrGuestBlocked) {
+		t.Fatalf("got %v for a no-show after the reset, want %v", err, ErrGuestBlocked)
+	}
+}
+
+func TestSavingACancelledReservationAgain(t *testing.T) {
+	databasetest.Open(t)
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	db := database.DB.WithContext(common.AsSystem(context.Background()))
+	b := tenantBusiness(t, db)
+	date := sunday()
+	reservation := Reservation{BusinessID: b.ID, UserID: uuid.New(), NumberOfPeople: 2, Status: ReservationConfirmed}
+	reservation.ID = uuid.New()
+	reservation.TenantID = b.TenantID
+	reservation.Date = date
+	reservation.EndsAt = date.Add(time.Hour)
+	reservation.BusyFrom, reservation.BusyUntil = reservation.Date, reservation.EndsAt
+	if err := db.Session(&gorm.Session{SkipHooks: true}).Omit(clause.Associations).Create(&reservation).Error; err != nil {
+		t.Fatal(err)
+	}
+	waiting := func() *WaitlistEntry {
+		entry := &WaitlistEntry{BusinessID: b.ID, UserID: uuid.New(), Date: date, NumberOfPeople: 2, Status: WaitlistWaiting}
+		entry.TenantID = b.TenantID
+		if err := db.Omit(clause.Associations).Create(entry).Error; err != nil {
+			t.Fatal(err)
+		}
+		return entry
+	}
+	first := waiting()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return CancelReservation(tx, &reservation, "change of plans")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.First(first, "id = ?", first.ID).Error; err != nil || first.Status != WaitlistOffered {
+		t.Fatalf("got the first entry %s and %v once cancelled, want it offered the seats", first.Status, err)
+	}
+
+	// The offer is dropped behind the back of the hooks, and another party
+	// waits for the seats it frees
+	if err := db.Model(&Reservation{}).Where("id = ?", first.ReservationID).UpdateColumn("status", ReservationCancelled).Error; err != nil {
+		t.Fatal(err)
+	}
+	second := waiting()
+	reservation.CancellationReason = "the kitchen is closed"
+	if err := db.Omit(clause.Associations).Save(&reservation).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.First(second, "id = ?", second.ID).Error; err != nil || second.Status != WaitlistWaiting {
+		t.Fatalf("got the second entry %s and %v once saved again, want it still waiting", second.Status, err)
+	}
+}
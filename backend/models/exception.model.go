@@ -0,0 +1,123 @@
+package models
+
+import (
+	"backend/database"
+	"backend/pkg/clock"
+	"backend/pkg/common"
+
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &ScheduleException{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+		Prepare:         clockColumns("schedule_exceptions", "start_time", "end_time"),
+	})
+}
+
+// ScheduleException overrides the weekly schedules of a business on one
+// date of its time zone, and its hours are read on the wall clock of that
+// zone. A closed exception closes the whole day. Exceptions with hours
+// replace the schedules of the day, several of them give several openings.
+// A capacity lowers the capacity of the business during the hours of the
+// exception, or all day when it has none
+type ScheduleException struct {
+	common.CommonEntity `gorm:"embedded"`
+	BusinessID          uuid.UUID    `gorm:"type:uuid;not null;index"`
+	Date                time.Time    `gorm:"type:timestamp;not null;index"`
+	Name                string       `gorm:"type:varchar(255);not null"`
+	Closed              bool         `gorm:"not null;default:false"`
+	StartTime           *clock.Clock `gorm:"type:varchar(8)"`
+	EndTime             *clock.Clock `gorm:"type:varchar(8)"`
+	Capacity            *int         `gorm:"type:int"`
+
+	// Relationships
+	Business Business `gorm:"foreignKey:BusinessID"`
+}
+
+type ScheduleExceptionDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	BusinessID       uuid.UUID    `json:"business_id" tstype:"string,required" validate:"required"`
+	Date             time.Time    `json:"date" tstype:"string,required" validate:"required"`
+	Name             string       `json:"name" tstype:"string,required"`
+	Closed           bool         `json:"closed" tstype:"boolean,required"`
+	StartTime        *clock.Clock `json:"start_time,omitempty" tstype:"string"`
+	EndTime          *clock.Clock `json:"end_time,omitempty" tstype:"string"`
+	Capacity         *int         `json:"capacity,omitempty" tstype:"number" validate:"omitempty,gte=0"`
+}
+
+// BeforeSave keeps the date alone, and checks the hours come in pairs
+func (e *ScheduleException) BeforeSave(tx *gorm.DB) error {
+	e.Date = Day(e.Date)
+	if (e.StartTime == nil) != (e.EndTime == nil) {
+		return errors.New("an exception needs both a start and an end time, or neither")
+	}
+	if e.StartTime != nil && e.EndTime.Offset() <= e.StartTime.Offset() {
+		return errors.New("an exception must end after it starts")
+	}
+	return nil
+}
+
+// Hours tells whether the exception replaces the opening hours of its day
+func (e ScheduleException) Hours() bool {
+	return e.StartTime != nil && e.EndTime != nil
+}
+
+// covers tells whether length from local on falls inside the hours of the
+// exception, which is true all day for exceptions without hours. local must
+// be in the time zone of the business
+func (e ScheduleException) covers(local time.Time, length time.Duration) bool {
+	if !e.Hours() {
+		return true
+	}
+	return within(local, length, *e.StartTime, *e.EndTime)
+}
+
+// Day is the midnight UTC starting the date of instant, exceptions are
+// stored at the Day of their date
+func Day(instant time.Time) time.Time {
+	year, month, date := instant.Date()
+	return time.Date(year, month, date, 0, 0, 0, 0, time.UTC)
+}
+
+func (e ScheduleException) ToDTO() common.DTO {
+	dto := &ScheduleExceptionDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        e.ID,
+			CreatedAt: e.CreatedAt,
+			UpdatedAt: e.UpdatedAt,
+		},
+		BusinessID: e.BusinessID,
+		Date:       e.Date,
+		Name:       e.Name,
+		Closed:     e.Closed,
+		StartTime:  e.StartTime,
+		EndTime:    e.EndTime,
+		Capacity:   e.Capacity,
+	}
+	return dto
+}
+
+func (e ScheduleExceptionDTO) ToEntity() common.Entity {
+	entity := &ScheduleException{
+		CommonEntity: common.CommonEntity{
+			ID:        e.ID,
+			CreatedAt: e.CreatedAt,
+			UpdatedAt: e.UpdatedAt,
+		},
+		BusinessID: e.BusinessID,
+		Date:       e.Date,
+		Name:       e.Name,
+		Closed:     e.Closed,
+		StartTime:  e.StartTime,
+		EndTime:    e.EndTime,
+		Capacity:   e.Capacity,
+	}
+	return entity
+}
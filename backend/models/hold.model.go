@@ -0,0 +1,286 @@
+package models
+
+import (
+	"backend/database"
+	"backend/pkg/audit"
+	"backend/pkg/common"
+	"backend/pkg/tenancy"
+
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &Hold{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+		Backfill:        backfillBusy("holds"),
+	})
+}
+
+// Statuses of a hold
+const (
+	HoldActive    = "active"
+	HoldConfirmed = "confirmed"
+	HoldReleased  = "released"
+	HoldExpired   = "expired"
+)
+
+var ErrHoldInactive = errors.New("the hold has expired or was already confirmed or released")
+
+// Hold keeps the seats of a slot for a guest while they check out. Active
+// holds count against the capacity of the business until they expire,
+// services.holds.ttl after they are placed, or are confirmed into a
+// reservation
+type Hold struct {
+	common.CommonEntity `gorm:"embedded"`
+	BusinessID          uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Date                time.Time  `gorm:"type:timestamp;not null"`
+	NumberOfPeople      int        `gorm:"type:int;not null"`
+	Status              string     `gorm:"type:varchar(255);not null"`
+	ExpiresAt           time.Time  `gorm:"type:timestamp;not null;index"`
+	ReservationID       *uuid.UUID `gorm:"type:uuid"`
+	// ServiceID, StaffID, EndsAt, BusyFrom and BusyUntil are the ones of
+	// the reservation the hold becomes
+	ServiceID *uuid.UUID `gorm:"type:uuid"`
+	StaffID   *uuid.UUID `gorm:"type:uuid;index"`
+	EndsAt    time.Time  `gorm:"type:timestamp"`
+	BusyFrom  time.Time  `gorm:"type:timestamp;index"`
+	BusyUntil time.Time  `gorm:"type:timestamp;index"`
+
+	// Relationships
+	Business    Business     `gorm:"foreignKey:BusinessID"`
+	Reservation *Reservation `gorm:"foreignKey:ReservationID"`
+	Service     *Service     `gorm:"foreignKey:ServiceID"`
+	Staff       *Staff       `gorm:"foreignKey:StaffID"`
+	Allocations []Allocation `gorm:"foreignKey:HoldID"`
+}
+
+type HoldDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	BusinessID       uuid.UUID  `json:"business_id" tstype:"string,required"`
+	Date             time.Time  `json:"date" tstype:"string,required" validate:"required"`
+	NumberOfPeople   int        `json:"number_of_people" tstype:"number,required" validate:"gte=1"`
+	Status           string     `json:"status" tstype:"string,required"`
+	ServiceID        *uuid.UUID `json:"service_id,omitempty" tstype:"string"`
+	StaffID          *uuid.UUID `json:"staff_id,omitempty" tstype:"string"`
+	EndsAt           time.Time  `json:"ends_at" tstype:"string"`
+	ExpiresAt        time.Time  `json:"expires_at" tstype:"string,required"`
+	// TTL is the number of seconds left before an active hold expires
+	TTL           int        `json:"ttl" tstype:"number,required"`
+	ReservationID *uuid.UUID `json:"reservation_id,omitempty" tstype:"string"`
+	// ResourceIDs are the resources the hold keeps, the ones asked for when
+	// it is placed or otherwise the best fit. The reservation confirmed from
+	// the hold gets them
+	ResourceIDs []uuid.UUID `json:"resource_ids,omitempty" tstype:"string[]"`
+}
+
+// HoldConfirmationDTO names the guest a hold is booked for
+type HoldConfirmationDTO struct {
+	UserID uuid.UUID `json:"user_id" tstype:"string,required" validate:"required"`
+}
+
+// PlaceHold holds seats for the party of booking when the business has room
+// for them, along with the requested resources or the best fit
+func PlaceHold(tx *gorm.DB, booking Booking, requested []uuid.UUID) (*Hold, error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	if err := CheckAvailability(db, booking); err != nil {
+		return nil, err
+	}
+	resources, err := Allocate(db, booking, requested)
+	if err != nil {
+		return nil, err
+	}
+
+	hold := &Hold{
+		BusinessID:     booking.Business.ID,
+		Date:           booking.Date,
+		NumberOfPeople: booking.People,
+		Status:         HoldActive,
+		ExpiresAt:      time.Now().Add(viper.GetDuration("services.holds.ttl")),
+		EndsAt:         booking.End(),
+	}
+	if booking.Service != nil {
+		hold.ServiceID = &booking.Service.ID
+	}
+	if booking.Staff != nil {
+		hold.StaffID = &booking.Staff.ID
+	}
+	hold.BusyFrom, hold.BusyUntil = booking.Busy()
+	if err := tenancy.Assign(db, hold); err != nil {
+		return nil, err
+	}
+	if err := db.Omit(clause.Associations).Create(hold).Error; err != nil {
+		return nil, err
+	}
+	template := Allocation{HoldID: &hold.ID}
+	template.TenantID = hold.TenantID
+	if hold.Allocations, err = allocate(db, template, resources); err != nil {
+		return nil, err
+	}
+	return hold, audit.Record(db, audit.Created, "holds", hold, nil, hold.ToDTO())
+}
+
+// Confirm books the seats of an active hold as a confirmed reservation of
+// user. The hold is claimed before the reservation is created, so a hold is
+// confirmed at most once even when requests race
+func (h *Hold) Confirm(tx *gorm.DB, user uuid.UUID) (*Reservation, error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	before := h.ToDTO()
+
+	claim := db.
+		Model(&Hold{}).
+		Scopes(tenancy.Scope).
+		Where("id = ? AND status = ? AND expires_at > ?", h.ID, HoldActive, time.Now()).
+		UpdateColumn("status", HoldConfirmed)
+	if claim.Error != nil {
+		return nil, claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return nil, ErrHoldInactive
+	}
+
+	reservation := &Reservation{
+		UserID:         user,
+		BusinessID:     h.BusinessID,
+		Date:           h.Date,
+		NumberOfPeople: h.NumberOfPeople,
+		Status:         ReservationConfirmed,
+		ServiceID:      h.ServiceID,
+		StaffID:        h.StaffID,
+	}
+	reservation.TenantID = h.TenantID
+	err := db.
+		Model(&Allocation{}).
+		Scopes(tenancy.Scope).
+		Where("hold_id = ?", h.ID).
+		Pluck("resource_id", &reservation.requested).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := tenancy.Assign(db, reservation); err != nil {
+		return nil, err
+	}
+	if err := db.Omit(clause.Associations).Create(reservation).Error; err != nil {
+		return nil, err
+	}
+	if err := audit.Record(db, audit.Created, "reservations", reservation, nil, reservation.ToDTO()); err != nil {
+		return nil, err
+	}
+
+	h.Status = HoldConfirmed
+	h.ReservationID = &reservation.ID
+	if err := db.Omit(clause.Associations).Save(h).Error; err != nil {
+		return nil, err
+	}
+	return reservation, audit.Record(db, audit.Updated, "holds", h, before, h.ToDTO())
+}
+
+// Release ends an active hold with status and offers its seats to the
+// waitlist of the business
+func (h *Hold) Release(tx *gorm.DB, status string) error {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	before := h.ToDTO()
+
+	release := db.
+		Model(&Hold{}).
+		Scopes(tenancy.Scope).
+		Where("id = ? AND status = ?", h.ID, HoldActive).
+		UpdateColumn("status", status)
+	if release.Error != nil {
+		return release.Error
+	}
+	if release.RowsAffected == 0 {
+		return ErrHoldInactive
+	}
+	h.Status = status
+	if err := audit.Record(db, audit.Updated, "holds", h, before, h.ToDTO()); err != nil {
+		return err
+	}
+
+	var business Business
+	if err := db.Scopes(tenancy.Scope).First(&business, "id = ?", h.BusinessID).Error; err != nil {
+		return err
+	}
+	return PromoteWaitlist(db, business, h.BusyFrom, h.BusyUntil)
+}
+
+// SweepHolds expires the active holds past their expiry. They already stopped
+// counting against capacity then, this marks them and hands their seats to
+// the waitlist. Each hold is handled in a transaction bound to its tenant
+func SweepHolds(ctx context.Context) error {
+	var holds []Hold
+	err := database.DB.
+		WithContext(common.AsSystem(ctx)).
+		Where("status = ? AND expires_at <= ?", HoldActive, time.Now()).
+		Find(&holds).Error
+	if err != nil {
+		return err
+	}
+	for i := range holds {
+		err := database.DB.
+			WithContext(common.WithTenant(ctx, holds[i].TenantID)).
+			Transaction(func(tx *gorm.DB) error {
+				return holds[i].Release(tx, HoldExpired)
+			})
+		if errors.Is(err, ErrHoldInactive) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("hold %s: %w", holds[i].ID, err)
+		}
+	}
+	return nil
+}
+
+func (h Hold) ToDTO() common.DTO {
+	dto := &HoldDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        h.ID,
+			CreatedAt: h.CreatedAt,
+			UpdatedAt: h.UpdatedAt,
+		},
+		BusinessID:     h.BusinessID,
+		Date:           h.Date,
+		NumberOfPeople: h.NumberOfPeople,
+		Status:         h.Status,
+		ServiceID:      h.ServiceID,
+		StaffID:        h.StaffID,
+		EndsAt:         h.EndsAt,
+		ExpiresAt:      h.ExpiresAt,
+		ReservationID:  h.ReservationID,
+		ResourceIDs:    resourceIDs(h.Allocations),
+	}
+	if left := time.Until(h.ExpiresAt); h.Status == HoldActive && left > 0 {
+		dto.TTL = int(left.Seconds())
+	}
+	return dto
+}
+
+func (h HoldDTO) ToEntity() common.Entity {
+	entity := &Hold{
+		CommonEntity: common.CommonEntity{
+			ID:        h.ID,
+			CreatedAt: h.CreatedAt,
+			UpdatedAt: h.UpdatedAt,
+		},
+		BusinessID:     h.BusinessID,
+		Date:           h.Date,
+		NumberOfPeople: h.NumberOfPeople,
+		Status:         h.Status,
+		ServiceID:      h.ServiceID,
+		StaffID:        h.StaffID,
+		EndsAt:         h.EndsAt,
+		ExpiresAt:      h.ExpiresAt,
+		ReservationID:  h.ReservationID,
+	}
+	return entity
+}
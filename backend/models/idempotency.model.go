@@ -0,0 +1,200 @@
+package models
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/tenancy"
+
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &IdempotencyKey{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+}
+
+var (
+	// ErrIdempotencyKeyReused is returned for a key sent again with another
+	// request than the one it was first sent with
+	ErrIdempotencyKeyReused = errors.New("the idempotency key was used with another request")
+	// ErrIdempotencyKeyInFlight is returned for a key whose first request is
+	// still being handled
+	ErrIdempotencyKeyInFlight = errors.New("a request with the idempotency key is in progress")
+)
+
+// IdempotencyKey is the key a user sent a request with, and the response to
+// that request once handled. Owner is the tenant, the user and the key hashed
+// together, keys are unique to it. Fingerprint tells the request apart from
+// another one sent with the same key
+type IdempotencyKey struct {
+	common.CommonEntity `gorm:"embedded"`
+	Owner               string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Actor               string     `gorm:"type:varchar(255);not null"`
+	Key                 string     `gorm:"type:varchar(255);not null"`
+	Fingerprint         string     `gorm:"type:varchar(64);not null"`
+	Status              int        `gorm:"type:int;not null;default:0"`
+	ContentType         string     `gorm:"type:varchar(255);not null;default:''"`
+	Body                string     `gorm:"type:text;not null;default:''"`
+	CompletedAt         *time.Time `gorm:"type:timestamp"`
+	ExpiresAt           time.Time  `gorm:"type:timestamp;not null;index"`
+}
+
+type IdempotencyKeyDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	Actor            string     `json:"actor" tstype:"string,required"`
+	Key              string     `json:"key" tstype:"string,required"`
+	Status           int        `json:"status" tstype:"number,required"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty" tstype:"string"`
+	ExpiresAt        time.Time  `json:"expires_at" tstype:"string,required"`
+}
+
+// Completed tells whether the response to the request of the key is stored
+func (k IdempotencyKey) Completed() bool {
+	return k.CompletedAt != nil
+}
+
+// ReserveIdempotencyKey takes key for the request of actor with fingerprint
+// and returns it, reserved is then true and the request is to be handled.
+// Otherwise the key was taken by an earlier request, whose stored response
+// is returned. Keys past services.idempotency.ttl, and the ones whose request
+// did not complete within services.idempotency.timeout, are taken anew
+func ReserveIdempotencyKey(tx *gorm.DB, actor string, key string, fingerprint string) (*IdempotencyKey, bool, error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	now := time.Now()
+	entry := &IdempotencyKey{
+		Actor:       actor,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   now.Add(viper.GetDuration("services.idempotency.ttl")),
+	}
+	if err := tenancy.Assign(db, entry); err != nil {
+		return nil, false, err
+	}
+	owner := sha256.Sum256([]byte(entry.TenantID.String() + "\x00" + actor + "\x00" + key))
+	entry.Owner = hex.EncodeToString(owner[:])
+
+	// The stale key is deleted once, by whoever sees it first, then taken by
+	// whoever creates it first
+	for range 2 {
+		created := db.Clauses(clause.OnConflict{DoNothing: true}).Create(entry)
+		if created.Error != nil {
+			return nil, false, created.Error
+		}
+		if created.RowsAffected == 1 {
+			return entry, true, nil
+		}
+
+		var stored IdempotencyKey
+		if err := db.Scopes(tenancy.Scope).Where("owner = ?", entry.Owner).Limit(1).Find(&stored).Error; err != nil {
+			return nil, false, err
+		}
+		if stored.ID == uuid.Nil {
+			continue
+		}
+		abandoned := !stored.Completed() && stored.CreatedAt.Before(now.Add(-viper.GetDuration("services.idempotency.timeout")))
+		if stored.ExpiresAt.Before(now) || abandoned {
+			err := db.
+				Unscoped().
+				Where("id = ? AND updated_at = ?", stored.ID, stored.UpdatedAt).
+				Delete(&IdempotencyKey{}).Error
+			if err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+		if stored.Fingerprint != fingerprint {
+			return nil, false, ErrIdempotencyKeyReused
+		}
+		if !stored.Completed() {
+			return nil, false, ErrIdempotencyKeyInFlight
+		}
+		return &stored, false, nil
+	}
+	return nil, false, ErrIdempotencyKeyInFlight
+}
+
+// Complete stores the response to the request of the key, for it to be
+// replayed
+func (k *IdempotencyKey) Complete(tx *gorm.DB, status int, contentType string, body string) error {
+	now := time.Now()
+	k.Status = status
+	k.ContentType = contentType
+	k.Body = body
+	k.CompletedAt = &now
+	return tx.
+		Session(&gorm.Session{NewDB: true}).
+		Model(&IdempotencyKey{}).
+		Scopes(tenancy.Scope).
+		Where("id = ?", k.ID).
+		UpdateColumns(map[string]interface{}{
+			"status":       status,
+			"content_type": contentType,
+			"body":         body,
+			"completed_at": now,
+		}).Error
+}
+
+// Release gives the key up without a response, for the request to be sent
+// again with it
+func (k *IdempotencyKey) Release(tx *gorm.DB) error {
+	return tx.
+		Session(&gorm.Session{NewDB: true}).
+		Unscoped().
+		Scopes(tenancy.Scope).
+		Where("id = ?", k.ID).
+		Delete(&IdempotencyKey{}).Error
+}
+
+// PruneIdempotencyKeys deletes the keys that expired before before
+func PruneIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	result := database.DB.
+		WithContext(common.AsSystem(ctx)).
+		Unscoped().
+		Where("expires_at < ?", before).
+		Delete(&IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
+
+func (k IdempotencyKey) ToDTO() common.DTO {
+	dto := &IdempotencyKeyDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        k.ID,
+			CreatedAt: k.CreatedAt,
+			UpdatedAt: k.UpdatedAt,
+		},
+		Actor:       k.Actor,
+		Key:         k.Key,
+		Status:      k.Status,
+		CompletedAt: k.CompletedAt,
+		ExpiresAt:   k.ExpiresAt,
+	}
+	return dto
+}
+
+func (k IdempotencyKeyDTO) ToEntity() common.Entity {
+	entity := &IdempotencyKey{
+		CommonEntity: common.CommonEntity{
+			ID:        k.ID,
+			CreatedAt: k.CreatedAt,
+			UpdatedAt: k.UpdatedAt,
+		},
+		Actor:       k.Actor,
+		Key:         k.Key,
+		Status:      k.Status,
+		CompletedAt: k.CompletedAt,
+		ExpiresAt:   k.ExpiresAt,
+	}
+	return entity
+}
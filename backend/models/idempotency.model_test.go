@@ -0,0 +1,97 @@
+package models
+
+import (
+	"backend/database/databasetest"
+	"backend/pkg/common"
+
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// idempotency sets the ttl and timeout of the idempotency keys for the test
+func idempotency(t *testing.T, ttl time.Duration, timeout time.Duration) {
+	t.Helper()
+	viper.Set("services.idempotency.ttl", ttl)
+	viper.Set("services.idempotency.timeout", timeout)
+	t.Cleanup(func() {
+		viper.Set("services.idempotency.ttl", nil)
+		viper.Set("services.idempotency.timeout", nil)
+	})
+}
+
+func TestReserveIdempotencyKey(t *testing.T) {
+	db := databasetest.Open(t, &IdempotencyKey{}).WithContext(common.WithTenant(context.Background(), uuid.New()))
+	idempotency(t, time.Hour, time.Minute)
+
+	first, reserved, err := ReserveIdempotencyKey(db, "ana", "key", "request")
+	if err != nil || !reserved {
+		t.Fatalf("got %v reserved %t, want the key taken", err, reserved)
+	}
+	if _, _, err := ReserveIdempotencyKey(db, "ana", "key", "request"); !errors.Is(err, ErrIdempotencyKeyInFlight) {
+		t.Fatalf("got %v while the first request is handled, want %v", err, ErrIdempotencyKeyInFlight)
+	}
+	if _, _, err := ReserveIdempotencyKey(db, "ana", "key", "another request"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Fatalf("got %v for another request, want %v", err, ErrIdempotencyKeyReused)
+	}
+	if _, reserved, err := ReserveIdempotencyKey(db, "luis", "key", "request"); err != nil || !reserved {
+		t.Fatalf("got %v reserved %t for another user, want the key taken", err, reserved)
+	}
+
+	if err := first.Complete(db, 201, "application/json", `{"id":1}`); err != nil {
+		t.Fatal(err)
+	}
+	stored, reserved, err := ReserveIdempotencyKey(db, "ana", "key", "request")
+	if err != nil || reserved {
+		t.Fatalf("got %v reserved %t once completed, want the response", err, reserved)
+	}
+	if stored.Status != 201 || stored.ContentType != "application/json" || stored.Body != `{"id":1}` {
+		t.Fatalf("got the response %d %s %s, want the stored one", stored.Status, stored.ContentType, stored.Body)
+	}
+	if _, _, err := ReserveIdempotencyKey(db, "ana", "key", "another request"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Fatalf("got %v for another request once completed, want %v", err, ErrIdempotencyKeyReused)
+	}
+}
+
+func TestReserveIdempotencyKeyTakenAgain(t *testing.T) {
+	db := databasetest.Open(t, &IdempotencyKey{}).WithContext(common.WithTenant(context.Background(), uuid.New()))
+	idempotency(t, time.Hour, time.Minute)
+
+	tests := []struct {
+		name  string
+		leave func(db *gorm.DB, key *IdempotencyKey) error
+	}{
+		{"released", func(db *gorm.DB, key *IdempotencyKey) error {
+			return key.Release(db)
+		}},
+		{"expired", func(db *gorm.DB, key *IdempotencyKey) error {
+			if err := key.Complete(db, 201, "application/json", `{}`); err != nil {
+				return err
+			}
+			return db.Model(key).UpdateColumn("expires_at", time.Now().Add(-time.Second)).Error
+		}},
+		{"abandoned", func(db *gorm.DB, key *IdempotencyKey) error {
+			return db.Model(key).UpdateColumn("created_at", time.Now().Add(-2*time.Minute)).Error
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, _, err := ReserveIdempotencyKey(db, "ana", tt.name, "request")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.leave(db, key); err != nil {
+				t.Fatal(err)
+			}
+			again, reserved, err := ReserveIdempotencyKey(db, "ana", tt.name, "another request")
+			if err != nil || !reserved || again.ID == key.ID {
+				t.Fatalf("got %v reserved %t, want the key taken anew", err, reserved)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/jobs"
+	"backend/pkg/outbox"
+
+	"context"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Jobs of the models, the server runs them on schedules but for the sending
+// of notifications and webhooks and the refunds, queued as they are written
+const (
+	JobExtendRecurring  = "recurring.extend"
+	JobSweepWaitlist    = "waitlist.sweep"
+	JobSweepHolds       = "holds.sweep"
+	JobMarkNoShows      = "no_shows.mark"
+	JobPurge            = "database.purge"
+	JobSendNotification = "notifications.send"
+	JobSendReminders    = "notifications.reminders"
+	JobDeliverWebhook   = "webhooks.deliver"
+	JobRefundPayment    = "payments.refund"
+)
+
+func init() {
+	jobs.Register(JobExtendRecurring, jobs.Task(ExtendRecurringReservations))
+	jobs.Register(JobSweepWaitlist, jobs.Task(SweepWaitlist))
+	jobs.Register(JobSweepHolds, jobs.Task(SweepHolds))
+	jobs.Register(JobMarkNoShows, jobs.Task(MarkNoShows))
+	jobs.Register(JobPurge, jobs.Task(Purge))
+	jobs.Register(JobSendNotification, SendNotification)
+	jobs.Register(JobSendReminders, jobs.Task(SendReminders))
+	jobs.Register(JobDeliverWebhook, DeliverWebhook)
+	jobs.Register(JobRefundPayment, RefundPayment)
+}
+
+// Purge deletes the expired idempotency keys, and for good the rows soft
+// deleted, the jobs succeeded and the outbox messages published more than
+// services.jobs.purge_after_days ago. Zero days keeps them
+func Purge(ctx context.Context) error {
+	if _, err := PruneIdempotencyKeys(ctx, time.Now()); err != nil {
+		return err
+	}
+	days := viper.GetInt("services.jobs.purge_after_days")
+	if days <= 0 {
+		return nil
+	}
+	before := time.Now().AddDate(0, 0, -days)
+	if _, err := database.Purge(database.DB.WithContext(common.AsSystem(ctx)), before); err != nil {
+		return err
+	}
+	if _, err := jobs.Prune(ctx, before); err != nil {
+		return err
+	}
+	_, err := outbox.Prune(ctx, before)
+	return err
+}
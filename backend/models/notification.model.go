@@ -0,0 +1,300 @@
+package models
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/helpers"
+	"backend/pkg/jobs"
+	"backend/pkg/notify"
+	"backend/pkg/tenancy"
+
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &Notification{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+}
+
+// Statuses of a notification. Skipped ones had nobody to be sent to
+const (
+	NotificationQueued  = "queued"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+	NotificationSkipped = "skipped"
+)
+
+// Notification is an email about a reservation sent to its guest, or queued
+// to be. Event is the template it is rendered from, and Error the reason the
+// last attempt to send it failed
+type Notification struct {
+	common.CommonEntity `gorm:"embedded"`
+	BusinessID          uuid.UUID  `gorm:"type:uuid;not null;index"`
+	ReservationID       uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Event               string     `gorm:"type:varchar(64);not null;index"`
+	Recipient           string     `gorm:"type:varchar(255);not null;default:''"`
+	Status              string     `gorm:"type:varchar(16);not null;index"`
+	SentAt              *time.Time `gorm:"type:timestamp"`
+	Error               string     `gorm:"type:text;not null;default:''"`
+
+	// Relationships
+	Business    Business    `gorm:"foreignKey:BusinessID"`
+	Reservation Reservation `gorm:"foreignKey:ReservationID"`
+}
+
+type NotificationDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	BusinessID       uuid.UUID  `json:"business_id" tstype:"string,required"`
+	ReservationID    uuid.UUID  `json:"reservation_id" tstype:"string,required"`
+	Event            string     `json:"event" tstype:"string,required"`
+	Recipient        string     `json:"recipient" tstype:"string,required"`
+	Status           string     `json:"status" tstype:"string,required"`
+	SentAt           *time.Time `json:"sent_at,omitempty" tstype:"string"`
+	Error            string     `json:"error,omitempty" tstype:"string"`
+}
+
+// notification is the payload of the job sending a notification
+type notification struct {
+	ID uuid.UUID `json:"notification_id"`
+}
+
+// reservationEmail is what the templates of the notifications of a
+// reservation are rendered with
+type reservationEmail struct {
+	Brand    notify.Brand
+	Guest    string
+	Status   string
+	Date     string
+	People   int
+	Service  string
+	Staff    string
+	Location string
+	Reason   string
+	Fee      string
+}
+
+// transition returns the event the guest of the reservation is notified of
+// when it is saved over stored, if any. Occurrences of a series are not
+// notified as they are booked, their reminder is enough, and nor are past
+// reservations
+func (r *Reservation) transition(stored Reservation) string {
+	if r.Date.Before(time.Now()) {
+		return ""
+	}
+	if stored.ID == uuid.Nil {
+		if r.SeriesID != nil || (r.Status != ReservationPending && r.Status != ReservationConfirmed) {
+			return ""
+		}
+		return notify.ReservationCreated
+	}
+	if r.Status == stored.Status {
+		return ""
+	}
+	switch r.Status {
+	case ReservationConfirmed:
+		return notify.ReservationConfirmed
+	case ReservationCancelled:
+		return notify.ReservationCancelled
+	}
+	return ""
+}
+
+// Notify queues the email of event to the guest of reservation, which the job
+// queue sends once the transaction commits. Guests without an email address
+// are skipped
+func Notify(tx *gorm.DB, reservation Reservation, event string) (*Notification, error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	var guest User
+	if err := db.Scopes(tenancy.Scope).Limit(1).Find(&guest, "id = ?", reservation.UserID).Error; err != nil {
+		return nil, err
+	}
+	entry := &Notification{
+		BusinessID:    reservation.BusinessID,
+		ReservationID: reservation.ID,
+		Event:         event,
+		Recipient:     guest.Email,
+		Status:        NotificationQueued,
+	}
+	if guest.Email == "" {
+		entry.Status = NotificationSkipped
+	}
+	entry.TenantID = reservation.TenantID
+	if err := tenancy.Assign(db, entry); err != nil {
+		return nil, err
+	}
+	if err := db.Omit(clause.Associations).Create(entry).Error; err != nil {
+		return nil, err
+	}
+	if entry.Status == NotificationSkipped {
+		return entry, nil
+	}
+	_, err := jobs.Enqueue(db, JobSendNotification, notification{ID: entry.ID})
+	return entry, err
+}
+
+// SendNotification renders and sends the notification of the payload with
+// the configured transport. Failures are kept on the notification and
+// returned, for the queue to try again
+func SendNotification(ctx context.Context, payload helpers.JSON) error {
+	var job notification
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+	db := database.DB.WithContext(ctx)
+	var entry Notification
+	if err := db.Scopes(tenancy.Scope).First(&entry, "id = ?", job.ID).Error; err != nil {
+		return err
+	}
+	if entry.Status == NotificationSent || entry.Status == NotificationSkipped {
+		return nil
+	}
+
+	err := entry.send(ctx, db)
+	if err != nil {
+		update := db.
+			Model(&entry).
+			Scopes(tenancy.Scope).
+			UpdateColumns(map[string]interface{}{"status": NotificationFailed, "error": err.Error()})
+		if update.Error != nil {
+			return update.Error
+		}
+		return err
+	}
+	return db.
+		Model(&entry).
+		Scopes(tenancy.Scope).
+		UpdateColumns(map[string]interface{}{"status": NotificationSent, "sent_at": time.Now(), "error": ""}).Error
+}
+
+func (n Notification) send(ctx context.Context, db *gorm.DB) error {
+	transport, err := notify.Open()
+	if err != nil {
+		return err
+	}
+	var reservation Reservation
+	err = db.
+		Scopes(tenancy.Scope).
+		Preload("User").
+		Preload("Business").
+		Preload("Service").
+		Preload("Staff").
+		First(&reservation, "id = ?", n.ReservationID).Error
+	if err != nil {
+		return err
+	}
+
+	business := reservation.Business
+	data := reservationEmail{
+		Brand:    business.Brand(),
+		Guest:    reservation.User.Name,
+		Status:   reservation.Status,
+		Date:     reservation.Date.In(business.Zone()).Format("Monday 2 January 2006, 15:04 MST"),
+		People:   reservation.NumberOfPeople,
+		Location: business.Location,
+		Reason:   reservation.CancellationReason,
+	}
+	if reservation.Service != nil {
+		data.Service = reservation.Service.Name
+	}
+	if reservation.Staff != nil {
+		data.Staff = reservation.Staff.Name
+	}
+	if reservation.CancellationFee > 0 {
+		policy, err := FindCancellationPolicy(db, business.ID)
+		if err != nil {
+			return err
+		}
+		data.Fee = fmt.Sprintf("%d.%02d %s", reservation.CancellationFee/100, reservation.CancellationFee%100, policy.Currency)
+	}
+
+	message, err := notify.Render(n.Event, data)
+	if err != nil {
+		return err
+	}
+	message.From = (&mail.Address{Name: business.Name, Address: viper.GetString("services.notifications.from")}).String()
+	message.To = (&mail.Address{Name: reservation.User.Name, Address: n.Recipient}).String()
+	return transport.Send(ctx, message)
+}
+
+// SendReminders notifies the guests of the confirmed reservations starting
+// within services.notifications.reminder_hours that were not reminded yet.
+// Reservations booked within that window already got their confirmation
+func SendReminders(ctx context.Context) error {
+	hours := viper.GetInt("services.notifications.reminder_hours")
+	if hours <= 0 {
+		return nil
+	}
+	window := time.Duration(hours) * time.Hour
+	now := time.Now()
+	reminded := database.DB.
+		Model(&Notification{}).
+		Select("reservation_id").
+		Where("event = ?", notify.ReservationReminder)
+	var upcoming []Reservation
+	err := database.DB.
+		WithContext(common.AsSystem(ctx)).
+		Where("status = ? AND date > ? AND date <= ?", ReservationConfirmed, now, now.Add(window)).
+		Where("id NOT IN (?)", reminded).
+		Find(&upcoming).Error
+	if err != nil {
+		return err
+	}
+	for _, reservation := range upcoming {
+		if reservation.CreatedAt.After(reservation.Date.Add(-window)) {
+			continue
+		}
+		_, err := Notify(database.DB.WithContext(common.WithTenant(ctx, reservation.TenantID)), reservation, notify.ReservationReminder)
+		if err != nil {
+			return fmt.Errorf("reminder of reservation %s: %w", reservation.ID, err)
+		}
+	}
+	return nil
+}
+
+func (n Notification) ToDTO() common.DTO {
+	dto := &NotificationDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        n.ID,
+			CreatedAt: n.CreatedAt,
+			UpdatedAt: n.UpdatedAt,
+		},
+		BusinessID:    n.BusinessID,
+		ReservationID: n.ReservationID,
+		Event:         n.Event,
+		Recipient:     n.Recipient,
+		Status:        n.Status,
+		SentAt:        n.SentAt,
+		Error:         n.Error,
+	}
+	return dto
+}
+
+func (n NotificationDTO) ToEntity() common.Entity {
+	entity := &Notification{
+		CommonEntity: common.CommonEntity{
+			ID:        n.ID,
+			CreatedAt: n.CreatedAt,
+			UpdatedAt: n.UpdatedAt,
+		},
+		BusinessID:    n.BusinessID,
+		ReservationID: n.ReservationID,
+		Event:         n.Event,
+		Recipient:     n.Recipient,
+		Status:        n.Status,
+		SentAt:        n.SentAt,
+		Error:         n.Error,
+	}
+	return entity
+}
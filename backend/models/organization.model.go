@@ -0,0 +1,64 @@
+package models
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &Organization{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+}
+
+// Organization is the tenant every other entity belongs to. It is its own
+// tenant, so a request only ever sees the organization it was resolved to
+type Organization struct {
+	common.CommonEntity `gorm:"embedded"`
+	Name                string `gorm:"type:varchar(255);not null"`
+	Slug                string `gorm:"type:varchar(63);not null;unique"`
+}
+
+type OrganizationDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	Name             string `json:"name" tstype:"string,required"`
+	Slug             string `json:"slug" tstype:"string,required"`
+}
+
+func (o *Organization) BeforeCreate(tx *gorm.DB) error {
+	if err := o.CommonEntity.BeforeCreate(tx); err != nil {
+		return err
+	}
+	o.TenantID = o.ID
+	return nil
+}
+
+func (o Organization) ToDTO() common.DTO {
+	dto := &OrganizationDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        o.ID,
+			CreatedAt: o.CreatedAt,
+			UpdatedAt: o.UpdatedAt,
+		},
+		Name: o.Name,
+		Slug: o.Slug,
+	}
+	return dto
+}
+
+func (o OrganizationDTO) ToEntity() common.Entity {
+	entity := &Organization{
+		CommonEntity: common.CommonEntity{
+			ID:        o.ID,
+			CreatedAt: o.CreatedAt,
+			UpdatedAt: o.UpdatedAt,
+		},
+		Name: o.Name,
+		Slug: o.Slug,
+	}
+	return entity
+}
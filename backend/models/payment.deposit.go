@@ -0,0 +1,339 @@
+package models
+
+import (
+	"backend/database"
+	"backend/pkg/audit"
+	"backend/pkg/helpers"
+	"backend/pkg/jobs"
+	"backend/pkg/notify"
+	"backend/pkg/payments"
+	"backend/pkg/tenancy"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNoDeposit        = errors.New("the reservation takes no deposit")
+	ErrDepositRequested = errors.New("the deposit of the reservation is already paid or being paid")
+	ErrPaymentNotFound  = errors.New("no payment has that reference")
+)
+
+// Deposit returns the rule the deposit of reservation follows and how much
+// it is, in the minor units of the currency returned. Reservations no rule
+// covers get ErrNoDeposit
+func Deposit(tx *gorm.DB, reservation Reservation) (*DepositRule, int64, string, error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	var business Business
+	if err := db.Scopes(tenancy.Scope).First(&business, "id = ?", reservation.BusinessID).Error; err != nil {
+		return nil, 0, "", err
+	}
+	service, err := FindService(db, business, reservation.ServiceID)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	query := db.Scopes(tenancy.Scope).Where("business_id = ?", business.ID)
+	if service != nil {
+		query = query.Where("service_id = ? OR service_id IS NULL", service.ID)
+	} else {
+		query = query.Where("service_id IS NULL")
+	}
+	var rules []DepositRule
+	if err := query.Find(&rules).Error; err != nil {
+		return nil, 0, "", err
+	}
+	var rule *DepositRule
+	for i := range rules {
+		if rule == nil || rules[i].ServiceID != nil {
+			rule = &rules[i]
+		}
+	}
+	if rule == nil {
+		return nil, 0, "", ErrNoDeposit
+	}
+
+	amount := rule.Amount
+	if rule.PerPerson {
+		amount *= int64(reservation.NumberOfPeople)
+	}
+	currency := rule.Currency
+	if service != nil {
+		amount += service.Price * int64(rule.Percent) / 100
+		if currency == "" {
+			currency = service.Currency
+		}
+	}
+	if amount <= 0 {
+		return nil, 0, "", ErrNoDeposit
+	}
+	return rule, amount, currency, nil
+}
+
+// RequestDeposit asks provider to collect the deposit of reservation, and
+// returns the pending payment carrying the client secret the guest pays
+// with. A reservation has one deposit at a time, a failed one can be asked
+// for again
+func RequestDeposit(tx *gorm.DB, provider payments.Provider, reservation Reservation) (*Payment, error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	if reservation.Status == ReservationCancelled {
+		return nil, fmt.Errorf("%w: it is cancelled", ErrNoDeposit)
+	}
+	var requested int64
+	err := db.
+		Model(&Payment{}).
+		Scopes(tenancy.Scope).
+		Where("reservation_id = ? AND status IN ?", reservation.ID, []string{payments.StatusPending, payments.StatusSucceeded}).
+		Count(&requested).Error
+	if err != nil {
+		return nil, err
+	}
+	if requested > 0 {
+		return nil, ErrDepositRequested
+	}
+	rule, amount, currency, err := Deposit(db, reservation)
+	if err != nil {
+		return nil, err
+	}
+
+	payment := &Payment{
+		BusinessID:    reservation.BusinessID,
+		ReservationID: reservation.ID,
+		Provider:      provider.Name(),
+		Amount:        amount,
+		Currency:      currency,
+	}
+	payment.ID = uuid.New()
+	payment.TenantID = reservation.TenantID
+	if rule.RefundHours >= 0 {
+		until := reservation.Date.Add(-time.Duration(rule.RefundHours) * time.Hour)
+		payment.RefundableUntil = &until
+	}
+	intent, err := provider.Create(db.Statement.Context, payments.Charge{
+		Key:         payment.ID.String(),
+		Amount:      amount,
+		Currency:    currency,
+		Description: fmt.Sprintf("Deposit of reservation %s", reservation.ID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	payment.Reference = intent.Reference
+	payment.Status = intent.Status
+	payment.clientSecret = intent.ClientSecret
+	if err := tenancy.Assign(db, payment); err != nil {
+		return nil, err
+	}
+	if err := db.Omit(clause.Associations).Create(payment).Error; err != nil {
+		return nil, err
+	}
+	return payment, audit.Record(db, audit.Created, "payments", payment, nil, payment.ToDTO())
+}
+
+// ApplyPaymentEvent moves the payment of provider event refers to to the
+// status of event. A succeeded payment is added to the ledger and confirms
+// its pending reservation, and a payment refunded at the provider gives the
+// rest of it back in the ledger. Events repeating the status are ignored, as
+// providers deliver them more than once. The status is claimed before the
+// ledger is written, so racing deliveries of one event record it once
+func ApplyPaymentEvent(tx *gorm.DB, provider string, event payments.Event) (*Payment, error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	var payment Payment
+	err := db.
+		Scopes(tenancy.Scope).
+		First(&payment, "provider = ? AND reference = ?", provider, event.Reference).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrPaymentNotFound, event.Reference)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status == event.Status || payment.Status == payments.StatusRefunded {
+		return &payment, nil
+	}
+
+	before := payment.ToDTO()
+	previous := payment.Status
+	claim := db.
+		Model(&Payment{}).
+		Scopes(tenancy.Scope).
+		Where("id = ? AND status = ?", payment.ID, previous).
+		UpdateColumn("status", event.Status)
+	if claim.Error != nil {
+		return nil, claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return &payment, nil
+	}
+	payment.Status = event.Status
+	switch {
+	case event.Status == payments.StatusSucceeded:
+		if err := payment.record(db, LedgerCharge, payment.Amount); err != nil {
+			return nil, err
+		}
+		if err := confirmPaid(db, payment.ReservationID); err != nil {
+			return nil, err
+		}
+	case event.Status == payments.StatusRefunded && previous == payments.StatusSucceeded:
+		if err := payment.record(db, LedgerRefund, payment.Amount-payment.RefundedAmount); err != nil {
+			return nil, err
+		}
+		payment.RefundedAmount = payment.Amount
+	}
+	if err := db.Omit(clause.Associations).Save(&payment).Error; err != nil {
+		return nil, err
+	}
+	return &payment, audit.Record(db, audit.Updated, "payments", &payment, before, payment.ToDTO())
+}
+
+// refund is the payload of the job giving amount of a payment back
+type refund struct {
+	ID     uuid.UUID `json:"payment_id"`
+	Amount int64     `json:"amount"`
+}
+
+// RefundDeposits refunds the paid deposits of a cancelled reservation that
+// are still refundable, but for its cancellation fee, which is kept from the
+// deposits in the currency of the policy. Each payment is claimed and the
+// refund written to the ledger in the transaction, which queues the job
+// asking the provider for it, so the provider is only called once the
+// cancellation commits
+func RefundDeposits(tx *gorm.DB, reservation Reservation) error {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	policy, err := FindCancellationPolicy(db, reservation.BusinessID)
+	if err != nil {
+		return err
+	}
+	fee := reservation.CancellationFee
+	var paid []Payment
+	err = db.
+		Scopes(tenancy.Scope).
+		Where("reservation_id = ? AND status = ? AND refundable_until >= ?", reservation.ID, payments.StatusSucceeded, time.Now()).
+		Find(&paid).Error
+	if err != nil {
+		return err
+	}
+	for i := range paid {
+		payment := &paid[i]
+		amount := payment.Amount - payment.RefundedAmount
+		if policy.Currency == "" || policy.Currency == payment.Currency {
+			kept := min(fee, amount)
+			fee -= kept
+			amount -= kept
+		}
+		if amount <= 0 {
+			continue
+		}
+		claim := db.
+			Model(&Payment{}).
+			Scopes(tenancy.Scope).
+			Where("id = ? AND status = ?", payment.ID, payments.StatusSucceeded).
+			UpdateColumn("status", payments.StatusRefunded)
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+		before := payment.ToDTO()
+		if err := payment.record(db, LedgerRefund, amount); err != nil {
+			return err
+		}
+		payment.Status = payments.StatusRefunded
+		payment.RefundedAmount += amount
+		if err := db.Omit(clause.Associations).Save(payment).Error; err != nil {
+			return err
+		}
+		if err := audit.Record(db, audit.Updated, "payments", payment, before, payment.ToDTO()); err != nil {
+			return err
+		}
+		if _, err := jobs.Enqueue(db, JobRefundPayment, refund{ID: payment.ID, Amount: amount}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RefundPayment asks the provider of the payment of the payload to give the
+// amount of the payload back. The refund is keyed on the payment, so a job
+// tried again after the provider took the refund does not refund twice
+func RefundPayment(ctx context.Context, payload helpers.JSON) error {
+	var job refund
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+	var payment Payment
+	err := database.DB.
+		WithContext(ctx).
+		Scopes(tenancy.Scope).
+		First(&payment, "id = ?", job.ID).Error
+	if err != nil {
+		return err
+	}
+	provider, err := payments.OpenNamed(payment.Provider)
+	if err != nil {
+		return err
+	}
+	err = provider.Refund(ctx, payments.Refund{
+		Key:       fmt.Sprintf("%s/refund", payment.ID),
+		Reference: payment.Reference,
+		Amount:    job.Amount,
+	})
+	if err != nil {
+		return fmt.Errorf("refunding payment %s: %w", payment.Reference, err)
+	}
+	return nil
+}
+
+// record adds the movement of amount of the payment to the ledger, negated
+// for refunds
+func (p Payment) record(db *gorm.DB, kind string, amount int64) error {
+	if kind == LedgerRefund {
+		amount = -amount
+	}
+	entry := LedgerEntry{
+		BusinessID:    p.BusinessID,
+		ReservationID: p.ReservationID,
+		PaymentID:     p.ID,
+		Kind:          kind,
+		Amount:        amount,
+		Currency:      p.Currency,
+		Reference:     p.Reference,
+	}
+	entry.TenantID = p.TenantID
+	if err := tenancy.Assign(db, &entry); err != nil {
+		return err
+	}
+	return db.Omit(clause.Associations).Create(&entry).Error
+}
+
+// confirmPaid confirms the reservation id when it is pending, and notifies
+// its guest. Its seats are already booked, so it is not checked again
+func confirmPaid(db *gorm.DB, id uuid.UUID) error {
+	var reservation Reservation
+	err := db.Scopes(tenancy.Scope).First(&reservation, "id = ?", id).Error
+	if err != nil || reservation.Status != ReservationPending {
+		return err
+	}
+	before := reservation.ToDTO()
+	err = db.
+		Model(&reservation).
+		Scopes(tenancy.Scope).
+		UpdateColumn("status", ReservationConfirmed).Error
+	if err != nil {
+		return err
+	}
+	reservation.Status = ReservationConfirmed
+	if err := audit.Record(db, audit.Updated, "reservations", &reservation, before, reservation.ToDTO()); err != nil {
+		return err
+	}
+	_, err = Notify(db, reservation, notify.ReservationConfirmed)
+	return err
+}
@@ -0,0 +1,234 @@
+package models
+
+import (
+	"backend/database"
+	"backend/database/databasetest"
+	"backend/pkg/common"
+	"backend/pkg/jobs"
+	"backend/pkg/payments"
+
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recording is a provider keeping the refunds it is asked for, once per key
+// as the real ones do
+type recording struct {
+	refunds []payments.Refund
+}
+
+func (r *recording) Name() string {
+	return "recording"
+}
+
+func (r *recording) Create(ctx context.Context, charge payments.Charge) (payments.Intent, error) {
+	return payments.Intent{Reference: charge.Key, Status: payments.StatusPending}, nil
+}
+
+func (r *recording) Refund(ctx context.Context, refund payments.Refund) error {
+	for _, taken := range r.refunds {
+		if taken.Key == refund.Key {
+			return nil
+		}
+	}
+	r.refunds = append(r.refunds, refund)
+	return nil
+}
+
+func (r *recording) Parse(header http.Header, body []byte) (payments.Event, error) {
+	return payments.Event{}, errors.New("the recording provider has no webhook")
+}
+
+// record registers a recording provider for the test
+func record(t *testing.T) *recording {
+	t.Helper()
+	provider := &recording{}
+	payments.Register(provider.Name(), func() (payments.Provider, error) { return provider, nil })
+	return provider
+}
+
+// paid stores a confirmed reservation of business on the next Sunday with a
+// paid deposit of amount, refundable until refundable
+func paid(t *testing.T, db *gorm.DB, business Business, amount int64, refundable time.Time) (*Reservation, Payment) {
+	t.Helper()
+	reservation := &Reservation{BusinessID: business.ID, UserID: uuid.New(), NumberOfPeople: 2, Status: ReservationConfirmed}
+	reservation.ID = uuid.New()
+	reservation.TenantID = business.TenantID
+	reservation.Date = sunday()
+	reservation.EndsAt = reservation.Date.Add(time.Hour)
+	reservation.BusyFrom, reservation.BusyUntil = reservation.Date, reservation.EndsAt
+	if err := db.Session(&gorm.Session{SkipHooks: true}).Omit(clause.Associations).Create(reservation).Error; err != nil {
+		t.Fatal(err)
+	}
+	payment := Payment{
+		BusinessID:      business.ID,
+		ReservationID:   reservation.ID,
+		Provider:        "recording",
+		Reference:       "pay_" + reservation.ID.String(),
+		Amount:          amount,
+		Currency:        "EUR",
+		Status:          payments.StatusSucceeded,
+		RefundableUntil: &refundable,
+	}
+	payment.TenantID = business.TenantID
+	if err := db.Omit(clause.Associations).Create(&payment).Error; err != nil {
+		t.Fatal(err)
+	}
+	return reservation, payment
+}
+
+// ledger returns the sum of the refunds of payment in the ledger
+func ledger(t *testing.T, db *gorm.DB, payment Payment) int64 {
+	t.Helper()
+	var entries []LedgerEntry
+	if err := db.Find(&entries, "payment_id = ? AND kind = ?", payment.ID, LedgerRefund).Error; err != nil {
+		t.Fatal(err)
+	}
+	var sum int64
+	for _, entry := range entries {
+		sum += entry.Amount
+	}
+	return sum
+}
+
+func TestRefundDeposits(t *testing.T) {
+	databasetest.Open(t)
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	provider := record(t)
+	ctx := common.AsSystem(context.Background())
+	db := database.DB.WithContext(ctx)
+	b := tenantBusiness(t, db)
+	// Cancelling within a day of the reservation costs 15.00 EUR
+	policy := CancellationPolicy{BusinessID: b.ID, FreeHours: 24 * 365, Fee: 1500, Currency: "EUR"}
+	policy.TenantID = b.TenantID
+	if err := db.Omit(clause.Associations).Create(&policy).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		amount     int64
+		refundable time.Time
+		fee        int64
+		want       int64
+	}{
+		{"the fee kept", 5000, time.Now().Add(time.Hour), 1500, 3500},
+		{"a deposit below the fee", 1000, time.Now().Add(time.Hour), 1000, 0},
+		{"past the refund window", 5000, time.Now().Add(-time.Hour), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider.refunds = nil
+			reservation, payment := paid(t, db, b, tt.amount, tt.refundable)
+			err := db.Transaction(func(tx *gorm.DB) error {
+				return CancelReservation(tx, reservation, "change of plans")
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(provider.refunds) != 0 {
+				t.Fatalf("got %d refunds asked for within the transaction, want none", len(provider.refunds))
+			}
+			if err := jobs.Work(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			var stored Payment
+			if err := db.First(&stored, "id = ?", payment.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == 0 {
+				if stored.Status != payments.StatusSucceeded || len(provider.refunds) != 0 || ledger(t, db, payment) != 0 {
+					t.Fatalf("got the payment %s with %d refunds, want it kept", stored.Status, len(provider.refunds))
+				}
+				return
+			}
+			if stored.Status != payments.StatusRefunded || stored.RefundedAmount != tt.want {
+				t.Errorf("got the payment %s with %d refunded, want %d refunded", stored.Status, stored.RefundedAmount, tt.want)
+			}
+			if got := ledger(t, db, payment); got != -tt.want {
+				t.Errorf("got %d refunded in the ledger, want %d", got, -tt.want)
+			}
+			if len(provider.refunds) != 1 || provider.refunds[0].Amount != tt.want || provider.refunds[0].Reference != payment.Reference {
+				t.Fatalf("got the refunds %+v, want one of %d", provider.refunds, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefundDepositsWithinPolicy(t *testing.T) {
+	databasetest.Open(t)
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	provider := record(t)
+	ctx := common.AsSystem(context.Background())
+	db := database.DB.WithContext(ctx)
+	b := tenantBusiness(t, db)
+	reservation, payment := paid(t, db, b, 5000, time.Now().Add(time.Hour))
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return CancelReservation(tx, reservation, "change of plans")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := jobs.Work(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.refunds) != 1 || provider.refunds[0].Amount != 5000 {
+		t.Fatalf("got the refunds %+v, want the whole deposit", provider.refunds)
+	}
+
+	// The job tried again, as when its worker went away after the provider
+	// took the refund, asks it with the same key
+	first := provider.refunds[0]
+	if _, err := jobs.Run(common.WithTenant(context.Background(), b.TenantID), JobRefundPayment, refund{ID: payment.ID, Amount: 5000}); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.refunds) != 1 || provider.refunds[0].Key != first.Key {
+		t.Fatalf("got the refunds %+v, want the one keyed on the payment", provider.refunds)
+	}
+}
+
+func TestRefundDepositsRolledBack(t *testing.T) {
+	databasetest.Open(t)
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	provider := record(t)
+	ctx := common.AsSystem(context.Background())
+	db := database.DB.WithContext(ctx)
+	b := tenantBusiness(t, db)
+	reservation, payment := paid(t, db, b, 5000, time.Now().Add(time.Hour))
+
+	failed := errors.New("the request failed after the cancellation")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := CancelReservation(tx, reservation, "change of plans"); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("got %v, want %v", err, failed)
+	}
+	if err := jobs.Work(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var stored Payment
+	if err := db.First(&stored, "id = ?", payment.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != payments.StatusSucceeded || len(provider.refunds) != 0 {
+		t.Fatalf("got the payment %s with the refunds %+v, want it paid and nothing refunded", stored.Status, provider.refunds)
+	}
+}
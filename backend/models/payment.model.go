@@ -0,0 +1,278 @@
+package models
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/payments"
+
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &DepositRule{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &Payment{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &LedgerEntry{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+}
+
+// Kinds of ledger entry
+const (
+	LedgerCharge = "charge"
+	LedgerRefund = "refund"
+)
+
+// DepositRule asks the reservations of a business for a deposit of Amount,
+// for each person when PerPerson, plus Percent of the price of their
+// service. Rules of a service apply to its reservations, the rule without
+// one to the rest. Deposits of reservations cancelled at least RefundHours
+// before they start are refunded, a negative RefundHours never refunds them.
+// Amounts are in the minor units of Currency, the one of the service when
+// empty
+type DepositRule struct {
+	common.CommonEntity `gorm:"embedded"`
+	BusinessID          uuid.UUID  `gorm:"type:uuid;not null;index"`
+	ServiceID           *uuid.UUID `gorm:"type:uuid;index"`
+	Amount              int64      `gorm:"type:bigint;not null;default:0"`
+	PerPerson           bool       `gorm:"not null;default:false"`
+	Percent             int        `gorm:"type:int;not null;default:0"`
+	Currency            string     `gorm:"type:varchar(3);not null;default:''"`
+	RefundHours         int        `gorm:"type:int;not null;default:0"`
+
+	// Relationships
+	Business Business `gorm:"foreignKey:BusinessID"`
+	Service  *Service `gorm:"foreignKey:ServiceID"`
+}
+
+type DepositRuleDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	BusinessID       uuid.UUID  `json:"business_id" tstype:"string,required" validate:"required"`
+	ServiceID        *uuid.UUID `json:"service_id,omitempty" tstype:"string"`
+	Amount           int64      `json:"amount" tstype:"number" validate:"gte=0"`
+	PerPerson        bool       `json:"per_person" tstype:"boolean"`
+	Percent          int        `json:"percent" tstype:"number" validate:"gte=0,lte=100"`
+	Currency         string     `json:"currency" tstype:"string" validate:"omitempty,len=3"`
+	RefundHours      int        `json:"refund_hours" tstype:"number"`
+}
+
+// Payment is a deposit collected for a reservation through Provider, which
+// knows it as Reference. Cancelling the reservation until RefundableUntil
+// refunds it, nil never does
+type Payment struct {
+	common.CommonEntity `gorm:"embedded"`
+	BusinessID          uuid.UUID  `gorm:"type:uuid;not null;index"`
+	ReservationID       uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Provider            string     `gorm:"type:varchar(255);not null"`
+	Reference           string     `gorm:"type:varchar(255);not null;index"`
+	Amount              int64      `gorm:"type:bigint;not null"`
+	Currency            string     `gorm:"type:varchar(3);not null;default:''"`
+	Status              string     `gorm:"type:varchar(255);not null"`
+	RefundedAmount      int64      `gorm:"type:bigint;not null;default:0"`
+	RefundableUntil     *time.Time `gorm:"type:timestamp"`
+
+	// clientSecret lets the guest pay, it is only known when the payment is
+	// created and never stored
+	clientSecret string
+
+	// Relationships
+	Business    Business    `gorm:"foreignKey:BusinessID"`
+	Reservation Reservation `gorm:"foreignKey:ReservationID"`
+}
+
+type PaymentDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	BusinessID       uuid.UUID  `json:"business_id" tstype:"string,required"`
+	ReservationID    uuid.UUID  `json:"reservation_id" tstype:"string,required"`
+	Provider         string     `json:"provider" tstype:"string,required"`
+	Reference        string     `json:"reference" tstype:"string,required"`
+	Amount           int64      `json:"amount" tstype:"number,required"`
+	Currency         string     `json:"currency" tstype:"string,required"`
+	Status           string     `json:"status" tstype:"string,required"`
+	RefundedAmount   int64      `json:"refunded_amount" tstype:"number,required"`
+	RefundableUntil  *time.Time `json:"refundable_until,omitempty" tstype:"string"`
+	// ClientSecret is handed to the provider by the guest to pay, only the
+	// response creating the payment carries it
+	ClientSecret string `json:"client_secret,omitempty" tstype:"string"`
+}
+
+// LedgerEntry records money moved for a business: charges are positive and
+// refunds negative, in the minor units of Currency. Entries are only ever
+// added
+type LedgerEntry struct {
+	common.CommonEntity `gorm:"embedded"`
+	BusinessID          uuid.UUID `gorm:"type:uuid;not null;index"`
+	ReservationID       uuid.UUID `gorm:"type:uuid;not null;index"`
+	PaymentID           uuid.UUID `gorm:"type:uuid;not null;index"`
+	Kind                string    `gorm:"type:varchar(255);not null"`
+	Amount              int64     `gorm:"type:bigint;not null"`
+	Currency            string    `gorm:"type:varchar(3);not null;default:''"`
+	Reference           string    `gorm:"type:varchar(255);not null;default:''"`
+}
+
+type LedgerEntryDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	BusinessID       uuid.UUID `json:"business_id" tstype:"string,required"`
+	ReservationID    uuid.UUID `json:"reservation_id" tstype:"string,required"`
+	PaymentID        uuid.UUID `json:"payment_id" tstype:"string,required"`
+	Kind             string    `json:"kind" tstype:"string,required"`
+	Amount           int64     `json:"amount" tstype:"number,required"`
+	Currency         string    `json:"currency" tstype:"string,required"`
+	Reference        string    `json:"reference" tstype:"string"`
+}
+
+// BeforeSave checks the rule asks for something, and keeps the currency in
+// upper case
+func (d *DepositRule) BeforeSave(tx *gorm.DB) error {
+	if d.Amount < 0 || d.Percent < 0 || d.Percent > 100 {
+		return errors.New("a deposit takes a positive amount and a percent up to 100")
+	}
+	if d.Amount == 0 && d.Percent == 0 {
+		return errors.New("a deposit rule needs an amount or a percent")
+	}
+	d.Currency = strings.ToUpper(d.Currency)
+	return nil
+}
+
+// BeforeSave checks the payment has a status providers report
+func (p *Payment) BeforeSave(tx *gorm.DB) error {
+	switch p.Status {
+	case payments.StatusPending, payments.StatusSucceeded, payments.StatusFailed, payments.StatusRefunded:
+		return nil
+	default:
+		return errors.New("unknown payment status " + p.Status)
+	}
+}
+
+func (d DepositRule) ToDTO() common.DTO {
+	dto := &DepositRuleDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        d.ID,
+			CreatedAt: d.CreatedAt,
+			UpdatedAt: d.UpdatedAt,
+		},
+		BusinessID:  d.BusinessID,
+		ServiceID:   d.ServiceID,
+		Amount:      d.Amount,
+		PerPerson:   d.PerPerson,
+		Percent:     d.Percent,
+		Currency:    d.Currency,
+		RefundHours: d.RefundHours,
+	}
+	return dto
+}
+
+func (d DepositRuleDTO) ToEntity() common.Entity {
+	entity := &DepositRule{
+		CommonEntity: common.CommonEntity{
+			ID:        d.ID,
+			CreatedAt: d.CreatedAt,
+			UpdatedAt: d.UpdatedAt,
+		},
+		BusinessID:  d.BusinessID,
+		ServiceID:   d.ServiceID,
+		Amount:      d.Amount,
+		PerPerson:   d.PerPerson,
+		Percent:     d.Percent,
+		Currency:    d.Currency,
+		RefundHours: d.RefundHours,
+	}
+	return entity
+}
+
+func (p Payment) ToDTO() common.DTO {
+	dto := &PaymentDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        p.ID,
+			CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt,
+		},
+		BusinessID:      p.BusinessID,
+		ReservationID:   p.ReservationID,
+		Provider:        p.Provider,
+		Reference:       p.Reference,
+		Amount:          p.Amount,
+		Currency:        p.Currency,
+		Status:          p.Status,
+		RefundedAmount:  p.RefundedAmount,
+		RefundableUntil: p.RefundableUntil,
+		ClientSecret:    p.clientSecret,
+	}
+	return dto
+}
+
+// Redacted leaves the client secret out
+func (p PaymentDTO) Redacted() common.DTO {
+	p.ClientSecret = ""
+	return &p
+}
+
+func (p PaymentDTO) ToEntity() common.Entity {
+	entity := &Payment{
+		CommonEntity: common.CommonEntity{
+			ID:        p.ID,
+			CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt,
+		},
+		BusinessID:      p.BusinessID,
+		ReservationID:   p.ReservationID,
+		Provider:        p.Provider,
+		Reference:       p.Reference,
+		Amount:          p.Amount,
+		Currency:        p.Currency,
+		Status:          p.Status,
+		RefundedAmount:  p.RefundedAmount,
+		RefundableUntil: p.RefundableUntil,
+	}
+	return entity
+}
+
+func (l LedgerEntry) ToDTO() common.DTO {
+	dto := &LedgerEntryDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        l.ID,
+			CreatedAt: l.CreatedAt,
+			UpdatedAt: l.UpdatedAt,
+		},
+		BusinessID:    l.BusinessID,
+		ReservationID: l.ReservationID,
+		PaymentID:     l.PaymentID,
+		Kind:          l.Kind,
+		Amount:        l.Amount,
+		Currency:      l.Currency,
+		Reference:     l.Reference,
+	}
+	return dto
+}
+
+func (l LedgerEntryDTO) ToEntity() common.Entity {
+	entity := &LedgerEntry{
+		CommonEntity: common.CommonEntity{
+			ID:        l.ID,
+			CreatedAt: l.CreatedAt,
+			UpdatedAt: l.UpdatedAt,
+		},
+		BusinessID:    l.BusinessID,
+		ReservationID: l.ReservationID,
+		PaymentID:     l.PaymentID,
+		Kind:          l.Kind,
+		Amount:        l.Amount,
+		Currency:      l.Currency,
+		Reference:     l.Reference,
+	}
+	return entity
+}
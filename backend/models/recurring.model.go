@@ -0,0 +1,401 @@
+package models
+
+import (
+	"backend/database"
+	"backend/pkg/audit"
+	"backend/pkg/common"
+	"backend/pkg/recurrence"
+	"backend/pkg/tenancy"
+
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &RecurringReservation{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+}
+
+// Statuses of an occurrence that has no reservation
+const (
+	OccurrenceUnbooked = "unbooked"
+	OccurrenceUpcoming = "upcoming"
+)
+
+// RecurringReservation is a series of reservations repeating with an RRULE.
+// Its occurrences are booked as Reservation rows up to a rolling horizon,
+// services.recurrence.horizon_days ahead, whenever the series is written and
+// then periodically. Occurrences the business has no room for are skipped and
+// reported as conflicts. The rule is expanded in the time zone of the
+// business, so occurrences keep their wall-clock time across DST transitions
+type RecurringReservation struct {
+	common.CommonEntity `gorm:"embedded"`
+	UserID              uuid.UUID `gorm:"type:uuid;not null"`
+	BusinessID          uuid.UUID `gorm:"type:uuid;not null"`
+	Start               time.Time `gorm:"type:timestamp;not null"`
+	Rule                string    `gorm:"type:varchar(255);not null"`
+	NumberOfPeople      int       `gorm:"type:int;not null"`
+	MaterializedUntil   time.Time `gorm:"type:timestamp"`
+
+	// Conflicts found by the last sync, they are not stored
+	Conflicts []Conflict `gorm:"-"`
+
+	// Relationships
+	User         User          `gorm:"foreignKey:UserID"`
+	Business     Business      `gorm:"foreignKey:BusinessID"`
+	Reservations []Reservation `gorm:"foreignKey:SeriesID"`
+}
+
+type RecurringReservationDTO struct {
+	common.CommonDTO  `json:",inline,omitempty" tstype:",extends,required"`
+	UserID            uuid.UUID  `json:"user_id" tstype:"string,required" validate:"required"`
+	BusinessID        uuid.UUID  `json:"business_id" tstype:"string,required" validate:"required"`
+	Start             time.Time  `json:"start" tstype:"string,required" validate:"required"`
+	Rule              string     `json:"rule" tstype:"string,required" validate:"required"`
+	NumberOfPeople    int        `json:"number_of_people" tstype:"number,required" validate:"gte=1"`
+	MaterializedUntil time.Time  `json:"materialized_until" tstype:"string"`
+	Conflicts         []Conflict `json:"conflicts,omitempty" tstype:"Conflict[]"`
+}
+
+// Conflict is an occurrence of a series that could not be booked
+type Conflict struct {
+	Date   time.Time `json:"date"`
+	Reason string    `json:"reason"`
+}
+
+// Occurrence is a start given by the rule of a series, along with the
+// reservation booked for it when there is one
+type Occurrence struct {
+	Date          time.Time  `json:"date"`
+	ReservationID *uuid.UUID `json:"reservation_id,omitempty"`
+	// Status is the one of the reservation, unbooked or upcoming when the
+	// horizon has not reached the occurrence yet
+	Status   string `json:"status"`
+	Conflict string `json:"conflict,omitempty"`
+}
+
+func (s *RecurringReservation) BeforeSave(tx *gorm.DB) error {
+	rule, err := recurrence.Parse(s.Rule)
+	if err != nil {
+		return fmt.Errorf("invalid rule: %w", err)
+	}
+	s.Rule = rule.String()
+	if s.Start.IsZero() {
+		return errors.New("the start of the series is required")
+	}
+	return nil
+}
+
+func (s *RecurringReservation) AfterCreate(tx *gorm.DB) error {
+	return s.Sync(tx)
+}
+
+func (s *RecurringReservation) AfterUpdate(tx *gorm.DB) error {
+	return s.Sync(tx)
+}
+
+// AfterDelete cancels the occurrences still to come. Once the series is hard
+// deleted its reservations are kept on their own
+func (s *RecurringReservation) AfterDelete(tx *gorm.DB) error {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	if tx.Statement.Unscoped {
+		return db.Unscoped().
+			Model(&Reservation{}).
+			Where("series_id = ?", s.ID).
+			UpdateColumn("series_id", nil).Error
+	}
+
+	var reservations []Reservation
+	err := db.
+		Scopes(tenancy.Scope).
+		Where("series_id = ? AND occurrence_at >= ? AND status <> ?", s.ID, time.Now(), ReservationCancelled).
+		Find(&reservations).Error
+	if err != nil {
+		return err
+	}
+	for i := range reservations {
+		if err := cancelOccurrence(db, &reservations[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sync books the occurrences of the series from now to the horizon. Booked
+// occurrences follow the party, user and business of the series, the ones the
+// rule no longer gives are cancelled, and cancelled ones are left alone
+func (s *RecurringReservation) Sync(tx *gorm.DB) error {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	rule, err := recurrence.Parse(s.Rule)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	horizon := now.AddDate(0, 0, viper.GetInt("services.recurrence.horizon_days"))
+	s.Conflicts = []Conflict{}
+
+	var business Business
+	if err := db.Scopes(tenancy.Scope).First(&business, "id = ?", s.BusinessID).Error; err != nil {
+		return fmt.Errorf("business %s not found", s.BusinessID)
+	}
+
+	var reservations []Reservation
+	err = db.
+		Scopes(tenancy.Scope).
+		Where("series_id = ? AND occurrence_at >= ?", s.ID, now).
+		Find(&reservations).Error
+	if err != nil {
+		return err
+	}
+	booked := map[int64]*Reservation{}
+	for i, reservation := range reservations {
+		booked[reservation.OccurrenceAt.Unix()] = &reservations[i]
+	}
+
+	for _, occurrence := range rule.Between(s.Start.In(business.Zone()), now, horizon) {
+		reservation, ok := booked[occurrence.Unix()]
+		delete(booked, occurrence.Unix())
+		switch {
+		case ok && reservation.Status == ReservationCancelled:
+			continue
+		case ok && reservation.UserID == s.UserID && reservation.BusinessID == s.BusinessID && reservation.NumberOfPeople == s.NumberOfPeople:
+			continue
+		case ok:
+			service, err := FindService(db, business, reservation.ServiceID)
+			if err != nil {
+				return err
+			}
+			booking := reservation.booking(business, service)
+			booking.People = s.NumberOfPeople
+			if err := CheckAvailability(db, booking); err != nil {
+				if s.conflict(reservation.Date, err) {
+					continue
+				}
+				return err
+			}
+			before := reservation.ToDTO()
+			reservation.UserID = s.UserID
+			reservation.BusinessID = s.BusinessID
+			reservation.NumberOfPeople = s.NumberOfPeople
+			if err := db.Omit(clause.Associations).Save(reservation).Error; err != nil {
+				return err
+			}
+			if err := audit.Record(db, audit.Updated, "reservations", reservation, before, reservation.ToDTO()); err != nil {
+				return err
+			}
+		default:
+			if err := CheckAvailability(db, Booking{Business: business, Date: occurrence, People: s.NumberOfPeople}); err != nil {
+				if s.conflict(occurrence, err) {
+					continue
+				}
+				return err
+			}
+			if err := s.book(db, occurrence); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, reservation := range booked {
+		if reservation.Status == ReservationCancelled {
+			continue
+		}
+		if err := cancelOccurrence(db, reservation); err != nil {
+			return err
+		}
+	}
+
+	s.MaterializedUntil = horizon
+	return db.Model(s).UpdateColumn("materialized_until", horizon).Error
+}
+
+// conflict records an occurrence that could not be booked, it returns false
+// when err is not about availability
+func (s *RecurringReservation) conflict(date time.Time, err error) bool {
+	if !errors.Is(err, ErrBusinessClosed) && !errors.Is(err, ErrBusinessFull) {
+		return false
+	}
+	s.Conflicts = append(s.Conflicts, Conflict{Date: date.UTC(), Reason: err.Error()})
+	return true
+}
+
+func (s *RecurringReservation) book(db *gorm.DB, occurrence time.Time) error {
+	reservation := &Reservation{
+		UserID:         s.UserID,
+		BusinessID:     s.BusinessID,
+		Date:           occurrence,
+		NumberOfPeople: s.NumberOfPeople,
+		Status:         ReservationConfirmed,
+		SeriesID:       &s.ID,
+		OccurrenceAt:   &occurrence,
+	}
+	// System contexts keep the tenant of the entity, which is the series one
+	reservation.TenantID = s.TenantID
+	if err := tenancy.Assign(db, reservation); err != nil {
+		return err
+	}
+	if err := db.Omit(clause.Associations).Create(reservation).Error; err != nil {
+		return err
+	}
+	return audit.Record(db, audit.Created, "reservations", reservation, nil, reservation.ToDTO())
+}
+
+func cancelOccurrence(db *gorm.DB, reservation *Reservation) error {
+	before := reservation.ToDTO()
+	reservation.Status = ReservationCancelled
+	if err := db.Omit(clause.Associations).Save(reservation).Error; err != nil {
+		return err
+	}
+	return audit.Record(db, audit.Updated, "reservations", reservation, before, reservation.ToDTO())
+}
+
+// Occurrences lists what became of the occurrences of the series in [from,
+// to). Those without a reservation get the reason they cannot be booked now,
+// if any
+func (s RecurringReservation) Occurrences(tx *gorm.DB, from time.Time, to time.Time) ([]Occurrence, error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	rule, err := recurrence.Parse(s.Rule)
+	if err != nil {
+		return nil, err
+	}
+
+	var business Business
+	if err := db.Scopes(tenancy.Scope).First(&business, "id = ?", s.BusinessID).Error; err != nil {
+		return nil, fmt.Errorf("business %s not found", s.BusinessID)
+	}
+
+	var reservations []Reservation
+	err = db.
+		Scopes(tenancy.Scope).
+		Where("series_id = ? AND occurrence_at >= ? AND occurrence_at < ?", s.ID, from, to).
+		Find(&reservations).Error
+	if err != nil {
+		return nil, err
+	}
+	booked := map[int64]Reservation{}
+	for _, reservation := range reservations {
+		booked[reservation.OccurrenceAt.Unix()] = reservation
+	}
+
+	occurrences := []Occurrence{}
+	for _, date := range rule.Between(s.Start.In(business.Zone()), from, to) {
+		occurrence := Occurrence{Date: date.UTC(), Status: OccurrenceUnbooked}
+		if reservation, ok := booked[date.Unix()]; ok {
+			occurrence.ReservationID = &reservation.ID
+			occurrence.Status = reservation.Status
+		} else if !date.Before(s.MaterializedUntil) {
+			occurrence.Status = OccurrenceUpcoming
+		} else if err := CheckAvailability(db, Booking{Business: business, Date: date, People: s.NumberOfPeople}); err != nil {
+			occurrence.Conflict = err.Error()
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences, nil
+}
+
+// Split ends the series right before the occurrence at and returns a new
+// series taking the occurrences from at on over, which is not saved. zone is
+// the time zone of the business the series expands in
+func (s *RecurringReservation) Split(at time.Time, zone *time.Location) (*RecurringReservation, error) {
+	rule, err := recurrence.Parse(s.Rule)
+	if err != nil {
+		return nil, err
+	}
+	before := rule.Before(s.Start.In(zone), at)
+	if before == 0 {
+		return nil, errors.New("the series cannot be split at its first occurrence")
+	}
+
+	next := rule
+	if rule.Count > 0 {
+		next.Count = rule.Count - before
+		rule.Count = before
+	} else {
+		rule.Until = at.Add(-time.Second)
+	}
+	s.Rule = rule.String()
+
+	return &RecurringReservation{
+		UserID:         s.UserID,
+		BusinessID:     s.BusinessID,
+		Start:          at,
+		Rule:           next.String(),
+		NumberOfPeople: s.NumberOfPeople,
+	}, nil
+}
+
+// ExtendRecurringReservations moves every series forward to the horizon,
+// each one in its own transaction. Conflicts are logged, as nobody is
+// waiting for them
+func ExtendRecurringReservations(ctx context.Context) error {
+	horizon := time.Now().UTC().AddDate(0, 0, viper.GetInt("services.recurrence.horizon_days"))
+
+	var series []RecurringReservation
+	err := database.DB.
+		WithContext(common.AsSystem(ctx)).
+		Find(&series, "materialized_until IS NULL OR materialized_until < ?", horizon.Add(-time.Hour)).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range series {
+		s := &series[i]
+		err := database.DB.
+			WithContext(common.WithTenant(ctx, s.TenantID)).
+			Transaction(func(tx *gorm.DB) error {
+				return s.Sync(tx)
+			})
+		if err != nil {
+			return fmt.Errorf("recurring reservation %s: %w", s.ID, err)
+		}
+		for _, conflict := range s.Conflicts {
+			log.Printf("Recurring reservation %s skipped %s: %s", s.ID, conflict.Date.Format(time.RFC3339), conflict.Reason)
+		}
+	}
+	return nil
+}
+
+func (s RecurringReservation) ToDTO() common.DTO {
+	dto := &RecurringReservationDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        s.ID,
+			CreatedAt: s.CreatedAt,
+			UpdatedAt: s.UpdatedAt,
+		},
+		UserID:            s.UserID,
+		BusinessID:        s.BusinessID,
+		Start:             s.Start,
+		Rule:              s.Rule,
+		NumberOfPeople:    s.NumberOfPeople,
+		MaterializedUntil: s.MaterializedUntil,
+		Conflicts:         s.Conflicts,
+	}
+	return dto
+}
+
+func (s RecurringReservationDTO) ToEntity() common.Entity {
+	entity := &RecurringReservation{
+		CommonEntity: common.CommonEntity{
+			ID:        s.ID,
+			CreatedAt: s.CreatedAt,
+			UpdatedAt: s.UpdatedAt,
+		},
+		UserID:         s.UserID,
+		BusinessID:     s.BusinessID,
+		Start:          s.Start,
+		Rule:           s.Rule,
+		NumberOfPeople: s.NumberOfPeople,
+	}
+	return entity
+}
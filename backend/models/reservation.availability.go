@@ -0,0 +1,232 @@
+package models
+
+import (
+	"backend/pkg/clock"
+	"backend/pkg/tenancy"
+
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBusinessClosed = errors.New("the business is closed at that time")
+	ErrBusinessFull   = errors.New("the business has no room left at that time")
+)
+
+// Booking is a party of People at Business from Date on, for Service or,
+// without one, for a slot of the business, and with Staff when it has one.
+// Ignore is the reservation or hold being booked again, which is not counted
+// against itself when it is updated
+type Booking struct {
+	Business Business
+	Service  *Service
+	Staff    *Staff
+	Date     time.Time
+	People   int
+	Ignore   uuid.UUID
+}
+
+// Length is how long the booking lasts, the duration of its service or the
+// slot of its business
+func (b Booking) Length() time.Duration {
+	if b.Service != nil {
+		return b.Service.Duration()
+	}
+	return b.Business.Slot()
+}
+
+// End is when the booking ends
+func (b Booking) End() time.Time {
+	return b.Date.Add(b.Length())
+}
+
+// Busy returns the interval the booking keeps its seats, which includes the
+// buffers of its service
+func (b Booking) Busy() (time.Time, time.Time) {
+	if b.Service == nil {
+		return b.Date, b.End()
+	}
+	return b.Date.Add(-b.Service.BufferBefore()), b.End().Add(b.Service.BufferAfter())
+}
+
+// CheckAvailability returns nil when the party of booking can be seated. The
+// booking must fit in the opening hours of its day in the time zone of the
+// business, which are the ones of its exceptions when it has any with hours,
+// and otherwise the weekly schedules, when the business has any. A closed
+// exception closes the day. The parties of the reservations and active holds
+// busy at the same time must leave room for this one when the business has a
+// capacity, lowered by the exceptions covering the booking, and so must the
+// ones of its service when the service has a capacity. Some of the free
+// resources of the business must seat the party when it has resources, and
+// its staff member must be available. The bookings of the business are
+// checked one at a time until the transaction of tx ends, so the seats left
+// cannot be counted by two of them before either is written
+func CheckAvailability(tx *gorm.DB, booking Booking) error {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	booking.Date = booking.Date.UTC()
+	business := booking.Business
+	if err := lockBookings(db, business.ID); err != nil {
+		return err
+	}
+	local := booking.Date.In(business.Zone())
+
+	var exceptions []ScheduleException
+	err := db.
+		Scopes(tenancy.Scope).
+		Find(&exceptions, "business_id = ? AND date = ?", business.ID, Day(local)).Error
+	if err != nil {
+		return err
+	}
+	capacity, err := opening(db, business, exceptions, local, booking.Length())
+	if err != nil {
+		return err
+	}
+
+	from, until := booking.Busy()
+	if capacity > 0 {
+		seated, err := occupied(db, booking, from, until, nil)
+		if err != nil {
+			return err
+		}
+		if seated+booking.People > capacity {
+			return ErrBusinessFull
+		}
+	}
+	if booking.Service != nil && booking.Service.Capacity > 0 {
+		// Buffers are the time of the business, not of the service
+		seated, err := occupied(db, booking, booking.Date, booking.End(), &booking.Service.ID)
+		if err != nil {
+			return err
+		}
+		if seated+booking.People > booking.Service.Capacity {
+			return fmt.Errorf("%w: %s is fully booked", ErrBusinessFull, booking.Service.Name)
+		}
+	}
+	if booking.Staff != nil {
+		if err := booking.Staff.available(db, booking, local); err != nil {
+			return err
+		}
+	}
+	_, err = Allocate(db, booking, nil)
+	return err
+}
+
+// bookingLock is the class of the Postgres advisory locks taken on the
+// bookings of a business, "book" in ASCII
+const bookingLock = 0x626f6f6b
+
+// lockBookings holds the lock on the bookings of a business until the
+// transaction of db ends. Databases without advisory locks write one
+// transaction at a time anyway
+func lockBookings(db *gorm.DB, business uuid.UUID) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	return db.Exec("SELECT pg_advisory_xact_lock(?, hashtext(?))", bookingLock, business.String()).Error
+}
+
+// occupied returns the people of the reservations and active holds of the
+// business of booking busy between from and until, other than the booking
+// itself. With a service, only the ones for that service are counted, over
+// their time without buffers
+func occupied(db *gorm.DB, booking Booking, from time.Time, until time.Time, service *uuid.UUID) (int, error) {
+	overlap := func(db *gorm.DB) *gorm.DB {
+		if service != nil {
+			return db.Where("service_id = ? AND date < ? AND ends_at > ?", *service, until, from)
+		}
+		return db.Where("busy_from < ? AND busy_until > ?", until, from)
+	}
+
+	var seated int64
+	err := db.
+		Model(&Reservation{}).
+		Scopes(tenancy.Scope, overlap).
+		Where("business_id = ? AND status NOT IN ? AND id <> ?", booking.Business.ID, released, booking.Ignore).
+		Select("COALESCE(SUM(number_of_people), 0)").
+		Scan(&seated).Error
+	if err != nil {
+		return 0, err
+	}
+	var held int64
+	err = db.
+		Model(&Hold{}).
+		Scopes(tenancy.Scope, overlap).
+		Where("business_id = ? AND status = ? AND expires_at > ? AND id <> ?", booking.Business.ID, HoldActive, time.Now(), booking.Ignore).
+		Select("COALESCE(SUM(number_of_people), 0)").
+		Scan(&held).Error
+	if err != nil {
+		return 0, err
+	}
+	return int(seated + held), nil
+}
+
+// opening returns the capacity of business for length from local on, zero
+// meaning unlimited, or ErrBusinessClosed when that time is outside of the
+// opening hours of the day. local must be in the time zone of the business
+func opening(db *gorm.DB, business Business, exceptions []ScheduleException, local time.Time, length time.Duration) (int, error) {
+	hours := false
+	open := false
+	for _, exception := range exceptions {
+		if exception.Closed {
+			return 0, ErrBusinessClosed
+		}
+		if exception.Hours() {
+			hours = true
+			open = open || exception.covers(local, length)
+		}
+	}
+	if hours && !open {
+		return 0, ErrBusinessClosed
+	}
+
+	if !hours {
+		var schedules []Schedule
+		if err := db.Scopes(tenancy.Scope).Find(&schedules, "business_id = ? AND staff_id IS NULL", business.ID).Error; err != nil {
+			return 0, err
+		}
+		if len(schedules) > 0 && !scheduled(schedules, local, length) {
+			return 0, ErrBusinessClosed
+		}
+	}
+
+	capacity := business.Capacity
+	limited := capacity > 0
+	for _, exception := range exceptions {
+		if exception.Capacity == nil || !exception.covers(local, length) {
+			continue
+		}
+		if !limited || *exception.Capacity < capacity {
+			capacity = *exception.Capacity
+			limited = true
+		}
+	}
+	if limited && capacity <= 0 {
+		return 0, ErrBusinessFull
+	}
+	return capacity, nil
+}
+
+// scheduled tells whether length from local on falls inside one of schedules
+// on the same day of the week. local must be in the time zone of the
+// business
+func scheduled(schedules []Schedule, local time.Time, length time.Duration) bool {
+	for _, schedule := range schedules {
+		if schedule.DayOfWeek == int(local.Weekday()) && within(local, length, schedule.StartTime, schedule.EndTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// within tells whether length from local on fits between start and end on
+// the day of local. The hours are placed on that day in its location, so they
+// keep their wall-clock times across DST transitions. The hours open as the
+// clock first shows start, and close as it last shows end, an end skipped
+// by DST being moved forward by the gap as On does
+func within(local time.Time, length time.Duration, start clock.Clock, end clock.Clock) bool {
+	return !local.Before(start.First(local)) && !local.Add(length).After(end.On(local))
+}
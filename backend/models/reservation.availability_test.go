@@ -0,0 +1,149 @@
+package models
+
+import (
+	"backend/database"
+	"backend/database/databasetest"
+	"backend/pkg/clock"
+	"backend/pkg/common"
+
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// The last Sundays of March and October 2026, when DST starts and ends in
+// Europe/Madrid at 02:00 and 03:00, and in Atlantic/Canary at 01:00 and 02:00
+const (
+	springForward = "2026-03-29"
+	fallBack      = "2026-10-25"
+)
+
+func instant(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func location(t *testing.T, name string) *time.Location {
+	t.Helper()
+	zone, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("no time zone data for %s: %s", name, err)
+	}
+	return zone
+}
+
+func TestWithin(t *testing.T) {
+	tests := []struct {
+		name   string
+		zone   string
+		at     string
+		length time.Duration
+		start  clock.Clock
+		end    clock.Clock
+		want   bool
+	}{
+		{"madrid before a start in the skipped hour", "Europe/Madrid", "2026-03-29T00:30:00Z", time.Hour, "02:30:00", "05:00:00", false},
+		{"madrid as the clock jumps past the start", "Europe/Madrid", "2026-03-29T01:00:00Z", time.Hour, "02:30:00", "05:00:00", true},
+		{"madrid ending at the end after the jump", "Europe/Madrid", "2026-03-29T02:00:00Z", time.Hour, "02:30:00", "05:00:00", true},
+		{"madrid ending after the end after the jump", "Europe/Madrid", "2026-03-29T02:30:00Z", time.Hour, "02:30:00", "05:00:00", false},
+		{"madrid across the skipped hour", "Europe/Madrid", "2026-03-29T00:30:00Z", time.Hour, "01:00:00", "03:30:00", true},
+		{"madrid first occurrence of a repeated start", "Europe/Madrid", "2026-10-25T00:30:00Z", time.Hour, "02:30:00", "04:00:00", true},
+		{"madrid second occurrence of a repeated start", "Europe/Madrid", "2026-10-25T01:30:00Z", time.Hour, "02:30:00", "04:00:00", true},
+		{"madrid before a repeated start", "Europe/Madrid", "2026-10-25T00:00:00Z", time.Hour, "02:30:00", "04:00:00", false},
+		{"madrid ending in the repeated hour", "Europe/Madrid", "2026-10-24T23:00:00Z", 2 * time.Hour, "01:00:00", "02:30:00", true},
+		{"madrid ending after the last occurrence of the end", "Europe/Madrid", "2026-10-25T01:00:00Z", time.Hour, "01:00:00", "02:30:00", false},
+		{"canary before a start in the skipped hour", "Atlantic/Canary", "2026-03-29T00:30:00Z", time.Hour, "01:30:00", "04:00:00", false},
+		{"canary as the clock jumps past the start", "Atlantic/Canary", "2026-03-29T01:00:00Z", time.Hour, "01:30:00", "04:00:00", true},
+		{"canary first occurrence of a repeated start", "Atlantic/Canary", "2026-10-25T00:30:00Z", time.Hour, "01:30:00", "03:00:00", true},
+		{"canary before a repeated start", "Atlantic/Canary", "2026-10-25T00:00:00Z", time.Hour, "01:30:00", "03:00:00", false},
+		{"canary ending at the end after fall back", "Atlantic/Canary", "2026-10-25T02:00:00Z", time.Hour, "01:30:00", "03:00:00", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := instant(t, tt.at).In(location(t, tt.zone))
+			if got := within(local, tt.length, tt.start, tt.end); got != tt.want {
+				t.Fatalf("got %v, want %v for %s", got, tt.want, local)
+			}
+		})
+	}
+}
+
+// business stores a business of zone seating two parties an hour, open on
+// Sundays between start and end
+func business(t *testing.T, db *gorm.DB, zone string, start clock.Clock, end clock.Clock) Business {
+	t.Helper()
+	b := Business{Name: "Bistro", Type: "restaurant", Location: "Main St 1", OwnerID: "owner", Capacity: 2, SlotMinutes: 60, TimeZone: zone}
+	if err := db.Omit(clause.Associations).Create(&b).Error; err != nil {
+		t.Fatal(err)
+	}
+	schedule := Schedule{BusinessID: b.ID, DayOfWeek: int(time.Sunday), StartTime: start, EndTime: end}
+	if err := db.Omit(clause.Associations).Create(&schedule).Error; err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCheckAvailabilityAcrossDST(t *testing.T) {
+	databasetest.Open(t)
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	db := database.DB.WithContext(common.AsSystem(context.Background()))
+	madridSpring := business(t, db, "Europe/Madrid", "02:30:00", "05:00:00")
+	madridFall := business(t, db, "Europe/Madrid", "02:30:00", "04:00:00")
+	canarySpring := business(t, db, "Atlantic/Canary", "01:30:00", "04:00:00")
+	canaryFall := business(t, db, "Atlantic/Canary", "01:30:00", "03:00:00")
+	location(t, "Europe/Madrid")
+	location(t, "Atlantic/Canary")
+
+	// Two people from the first 02:30 of the repeated hour in Madrid
+	taken := Reservation{BusinessID: madridFall.ID, UserID: uuid.New(), NumberOfPeople: 2, Status: ReservationConfirmed}
+	taken.ID = uuid.New()
+	taken.Date = instant(t, fallBack+"T00:30:00Z")
+	taken.EndsAt = taken.Date.Add(time.Hour)
+	taken.BusyFrom, taken.BusyUntil = taken.Date, taken.EndsAt
+	// Without its hooks, which would check the availability being tested
+	if err := db.Session(&gorm.Session{SkipHooks: true}).Omit(clause.Associations).Create(&taken).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		business Business
+		at       string
+		want     error
+	}{
+		{"madrid before opening in the skipped hour", madridSpring, springForward + "T00:30:00Z", ErrBusinessClosed},
+		{"madrid as the clock jumps past opening", madridSpring, springForward + "T01:00:00Z", nil},
+		{"madrid last slot after the jump", madridSpring, springForward + "T02:00:00Z", nil},
+		{"madrid past closing after the jump", madridSpring, springForward + "T02:30:00Z", ErrBusinessClosed},
+		{"madrid slot taken in the repeated hour", madridFall, fallBack + "T00:30:00Z", ErrBusinessFull},
+		{"madrid overlapping the taken slot", madridFall, fallBack + "T01:00:00Z", ErrBusinessFull},
+		{"madrid second 02:30 of the repeated hour", madridFall, fallBack + "T01:30:00Z", nil},
+		{"madrid before opening in the repeated hour", madridFall, fallBack + "T00:00:00Z", ErrBusinessClosed},
+		{"madrid last slot after fall back", madridFall, fallBack + "T02:00:00Z", nil},
+		{"canary before opening in the skipped hour", canarySpring, springForward + "T00:30:00Z", ErrBusinessClosed},
+		{"canary as the clock jumps past opening", canarySpring, springForward + "T01:00:00Z", nil},
+		{"canary first 01:30 of the repeated hour", canaryFall, fallBack + "T00:30:00Z", nil},
+		{"canary second 01:30 of the repeated hour", canaryFall, fallBack + "T01:30:00Z", nil},
+		{"canary last slot after fall back", canaryFall, fallBack + "T02:00:00Z", nil},
+		{"canary past closing after fall back", canaryFall, fallBack + "T02:30:00Z", ErrBusinessClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckAvailability(db, Booking{Business: tt.business, Date: instant(t, tt.at), People: 2})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -3,10 +3,13 @@ package models
 import (
 	"backend/database"
 	"backend/pkg/common"
+	"backend/pkg/tenancy"
 
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 func init() {
@@ -14,9 +17,25 @@ func init() {
 		Model:           &Reservation{},
 		DropOnFlush:     true,
 		TruncateOnFlush: true,
+		Backfill:        backfillBusy("reservations"),
 	})
 }
 
+// Statuses of a reservation. Completed reservations are the ones the guest
+// showed up to, the ones still confirmed once their business gives up on the
+// guest become no-shows
+const (
+	ReservationPending   = "pending"
+	ReservationConfirmed = "confirmed"
+	ReservationCancelled = "cancelled"
+	ReservationCompleted = "completed"
+	ReservationNoShow    = "no_show"
+)
+
+// released lists the statuses of the reservations that no longer keep their
+// seats
+var released = []string{ReservationCancelled, ReservationNoShow}
+
 type Reservation struct {
 	common.CommonEntity `gorm:"embedded"`
 	UserID              uuid.UUID `gorm:"type:uuid;not null"`
@@ -24,19 +43,217 @@ type Reservation struct {
 	Date                time.Time `gorm:"type:timestamp;not null"`
 	NumberOfPeople      int       `gorm:"type:int;not null"`
 	Status              string    `gorm:"type:varchar(255);not null"`
+	// ServiceID is what the reservation is for, it lasts a slot of the
+	// business without one. EndsAt is when it ends, and BusyFrom and
+	// BusyUntil bound the time it keeps its seats, buffers included
+	ServiceID *uuid.UUID `gorm:"type:uuid;index"`
+	// StaffID is the staff member the reservation is booked with
+	StaffID   *uuid.UUID `gorm:"type:uuid;index"`
+	EndsAt    time.Time  `gorm:"type:timestamp"`
+	BusyFrom  time.Time  `gorm:"type:timestamp;index"`
+	BusyUntil time.Time  `gorm:"type:timestamp;index"`
+	// SeriesID and OccurrenceAt are set on the occurrences of a recurring
+	// reservation. OccurrenceAt is the start the rule gave it, which still
+	// identifies the occurrence once its date is changed
+	SeriesID     *uuid.UUID `gorm:"type:uuid;index"`
+	OccurrenceAt *time.Time `gorm:"type:timestamp"`
+	// CancelledAt is when the reservation was cancelled, and
+	// CancellationFee what the cancellation policy of the business charged
+	// for it then, in the currency of the policy
+	CancelledAt        *time.Time `gorm:"type:timestamp"`
+	CancellationReason string     `gorm:"type:varchar(255);not null;default:''"`
+	CancellationFee    int64      `gorm:"type:bigint;not null;default:0"`
+
+	// requested are the resources the payload asked for, and resources the
+	// ones picked before the reservation is saved
+	requested []uuid.UUID
+	resources []Resource
+	allocated bool
+	// event is what the guest is notified of once the reservation is saved
+	event string
+	// cancelled is set when the save cancels the reservation, which was not
+	// cancelled before
+	cancelled bool
 
 	// Relationships
-	User     User     `gorm:"foreignKey:UserID"`
-	Business Business `gorm:"foreignKey:BusinessID"`
+	User        User                  `gorm:"foreignKey:UserID"`
+	Business    Business              `gorm:"foreignKey:BusinessID"`
+	Series      *RecurringReservation `gorm:"foreignKey:SeriesID"`
+	Service     *Service              `gorm:"foreignKey:ServiceID"`
+	Staff       *Staff                `gorm:"foreignKey:StaffID"`
+	Allocations []Allocation          `gorm:"foreignKey:ReservationID"`
 }
 
 type ReservationDTO struct {
 	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
-	UserID           uuid.UUID `json:"user_id" tstype:"string,required"`
-	BusinessID       uuid.UUID `json:"business_id" tstype:"string,required"`
-	Date             time.Time `json:"date" tstype:"string,required"`
-	NumberOfPeople   int       `json:"number_of_people" tstype:"number,required"`
-	Status           string    `json:"status" tstype:"string,required"`
+	UserID           uuid.UUID  `json:"user_id" tstype:"string,required"`
+	BusinessID       uuid.UUID  `json:"business_id" tstype:"string,required"`
+	Date             time.Time  `json:"date" tstype:"string,required"`
+	NumberOfPeople   int        `json:"number_of_people" tstype:"number,required"`
+	Status           string     `json:"status" tstype:"string,required"`
+	ServiceID        *uuid.UUID `json:"service_id,omitempty" tstype:"string"`
+	StaffID          *uuid.UUID `json:"staff_id,omitempty" tstype:"string"`
+	EndsAt           time.Time  `json:"ends_at" tstype:"string"`
+	SeriesID         *uuid.UUID `json:"series_id,omitempty" tstype:"string"`
+	OccurrenceAt     *time.Time `json:"occurrence_at,omitempty" tstype:"string"`
+	// CancelledAt and CancellationFee are set when the reservation is
+	// cancelled, along with the reason it was given
+	CancelledAt        *time.Time `json:"cancelled_at,omitempty" tstype:"string"`
+	CancellationReason string     `json:"cancellation_reason,omitempty" tstype:"string"`
+	CancellationFee    int64      `json:"cancellation_fee" tstype:"number"`
+	// ResourceIDs are the resources the reservation is allocated. When a
+	// reservation is written they can be asked for, otherwise the best fit is
+	// picked. Reads list them when the Allocations relation is loaded
+	ResourceIDs []uuid.UUID `json:"resource_ids,omitempty" tstype:"string[]"`
+}
+
+// BeforeSave works out when the reservation ends from its service and what
+// cancelling it costs, refuses upcoming reservations the business is closed
+// at or has no room for, and new ones of guests it blocks, and picks the
+// resources they are allocated. Reservations that no longer keep their seats
+// and past ones are not checked, and keep their resources
+func (r *Reservation) BeforeSave(tx *gorm.DB) error {
+	if r.BusinessID == uuid.Nil {
+		return nil
+	}
+	db := tx.Session(&gorm.Session{NewDB: true})
+	var business Business
+	err := db.Scopes(tenancy.Scope).First(&business, "id = ?", r.BusinessID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	service, err := FindService(db, business, r.ServiceID)
+	if err != nil {
+		return err
+	}
+	booking := r.booking(business, service)
+	r.EndsAt = booking.End()
+	r.BusyFrom, r.BusyUntil = booking.Busy()
+
+	var stored Reservation
+	err = db.Scopes(tenancy.Scope).Limit(1).Find(&stored, "id = ?", r.ID).Error
+	if err != nil {
+		return err
+	}
+	policy, err := FindCancellationPolicy(db, business.ID)
+	if err != nil {
+		return err
+	}
+	r.cancel(stored, policy)
+	r.event = r.transition(stored)
+	r.cancelled = stored.ID != uuid.Nil && stored.Status != ReservationCancelled && r.Status == ReservationCancelled
+
+	if r.Status == ReservationCancelled || r.Status == ReservationNoShow || r.Date.Before(time.Now()) {
+		return nil
+	}
+	if stored.ID == uuid.Nil {
+		if err := policy.admit(db, r.UserID, time.Now()); err != nil {
+			return err
+		}
+	}
+	if booking.Staff, err = FindStaff(db, business, r.StaffID); err != nil {
+		return err
+	}
+	if err := CheckAvailability(db, booking); err != nil {
+		return err
+	}
+	r.resources, err = r.pick(db, booking)
+	r.allocated = err == nil
+	return err
+}
+
+// booking is the reservation as booked at business for service
+func (r *Reservation) booking(business Business, service *Service) Booking {
+	return Booking{
+		Business: business,
+		Service:  service,
+		Date:     r.Date,
+		People:   r.NumberOfPeople,
+		Ignore:   r.ID,
+	}
+}
+
+// pick returns the resources asked for, otherwise the ones the reservation
+// already has while they still seat it, otherwise the best fit
+func (r *Reservation) pick(db *gorm.DB, booking Booking) ([]Resource, error) {
+	if len(r.requested) == 0 && r.ID != uuid.Nil {
+		var current []uuid.UUID
+		err := db.
+			Model(&Allocation{}).
+			Scopes(tenancy.Scope).
+			Where("reservation_id = ?", r.ID).
+			Pluck("resource_id", &current).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(current) > 0 {
+			if resources, err := Allocate(db, booking, current); err == nil {
+				return resources, nil
+			}
+		}
+	}
+	return Allocate(db, booking, r.requested)
+}
+
+// AfterSave notifies the guest of the new status of the reservation, and
+// allocates it the resources picked before it was saved
+func (r *Reservation) AfterSave(tx *gorm.DB) error {
+	if event := r.event; event != "" {
+		r.event = ""
+		if _, err := Notify(tx, *r, event); err != nil {
+			return err
+		}
+	}
+	if !r.allocated {
+		return nil
+	}
+	r.allocated = false
+	template := Allocation{ReservationID: &r.ID}
+	template.TenantID = r.TenantID
+	allocations, err := allocate(tx, template, r.resources)
+	if err != nil {
+		return err
+	}
+	r.Allocations = allocations
+	return nil
+}
+
+// AfterUpdate refunds the deposits of a reservation the update cancelled
+// still within their refund window, and offers its seats to the waitlist.
+// Saving an already cancelled reservation again does neither
+func (r *Reservation) AfterUpdate(tx *gorm.DB) error {
+	if !r.cancelled {
+		return nil
+	}
+	r.cancelled = false
+	if err := RefundDeposits(tx, *r); err != nil {
+		return err
+	}
+	return r.free(tx)
+}
+
+// AfterDelete offers the seats of a deleted reservation to the waitlist
+func (r *Reservation) AfterDelete(tx *gorm.DB) error {
+	return r.free(tx)
+}
+
+func (r *Reservation) free(tx *gorm.DB) error {
+	if r.BusinessID == uuid.Nil {
+		return nil
+	}
+	db := tx.Session(&gorm.Session{NewDB: true})
+	var business Business
+	err := db.Scopes(tenancy.Scope).First(&business, "id = ?", r.BusinessID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return PromoteWaitlist(db, business, r.BusyFrom, r.BusyUntil)
 }
 
 func (r Reservation) ToDTO() common.DTO {
@@ -46,11 +263,20 @@ func (r Reservation) ToDTO() common.DTO {
 			CreatedAt: r.CreatedAt,
 			UpdatedAt: r.UpdatedAt,
 		},
-		UserID:         r.UserID,
-		BusinessID:     r.BusinessID,
-		Date:           r.Date,
-		NumberOfPeople: r.NumberOfPeople,
-		Status:         r.Status,
+		UserID:             r.UserID,
+		BusinessID:         r.BusinessID,
+		Date:               r.Date,
+		NumberOfPeople:     r.NumberOfPeople,
+		Status:             r.Status,
+		ServiceID:          r.ServiceID,
+		StaffID:            r.StaffID,
+		EndsAt:             r.EndsAt,
+		SeriesID:           r.SeriesID,
+		OccurrenceAt:       r.OccurrenceAt,
+		ResourceIDs:        resourceIDs(r.Allocations),
+		CancelledAt:        r.CancelledAt,
+		CancellationReason: r.CancellationReason,
+		CancellationFee:    r.CancellationFee,
 	}
 	return dto
 }
@@ -62,11 +288,17 @@ func (r ReservationDTO) ToEntity() common.Entity {
 			CreatedAt: r.CreatedAt,
 			UpdatedAt: r.UpdatedAt,
 		},
-		UserID:         r.UserID,
-		BusinessID:     r.BusinessID,
-		Date:           r.Date,
-		NumberOfPeople: r.NumberOfPeople,
-		Status:         r.Status,
+		UserID:             r.UserID,
+		BusinessID:         r.BusinessID,
+		Date:               r.Date,
+		NumberOfPeople:     r.NumberOfPeople,
+		Status:             r.Status,
+		ServiceID:          r.ServiceID,
+		StaffID:            r.StaffID,
+		SeriesID:           r.SeriesID,
+		OccurrenceAt:       r.OccurrenceAt,
+		requested:          r.ResourceIDs,
+		CancellationReason: r.CancellationReason,
 	}
 	return entity
 }
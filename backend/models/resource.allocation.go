@@ -0,0 +1,228 @@
+package models
+
+import (
+	"backend/pkg/tenancy"
+
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrResourceUnfit = errors.New("the resources asked for cannot seat the party together")
+
+// Allocate picks the resources of the business of booking seating its
+// party, among the ones no reservation or active hold busy at the same time
+// is allocated. The reservation or hold booking ignores does not count, as
+// for CheckAvailability. When requested is not empty, those resources are
+// checked instead of picked. Businesses without resources get none and no
+// error. When nothing fits, the error wraps ErrBusinessFull
+func Allocate(tx *gorm.DB, booking Booking, requested []uuid.UUID) ([]Resource, error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	var resources []Resource
+	err := db.
+		Scopes(tenancy.Scope).
+		Where("business_id = ? AND disabled = ?", booking.Business.ID, false).
+		Order("max_people, name").
+		Find(&resources).Error
+	if err != nil || len(resources) == 0 {
+		return nil, err
+	}
+	busy, err := allocated(db, booking)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(requested) > 0 {
+		return check(resources, busy, booking.People, requested)
+	}
+	var free []Resource
+	for _, resource := range resources {
+		if !busy[resource.ID] {
+			free = append(free, resource)
+		}
+	}
+	if picked := bestFit(free, booking.People, viper.GetInt("services.resources.max_combined")); picked != nil {
+		return picked, nil
+	}
+	return nil, fmt.Errorf("%w: no free resource seats a party of %d", ErrBusinessFull, booking.People)
+}
+
+// allocated returns the resources allocated to the reservations and active
+// holds of the business of booking busy at the same time
+func allocated(db *gorm.DB, booking Booking) (map[uuid.UUID]bool, error) {
+	from, until := booking.Busy()
+	reservations := db.
+		Model(&Reservation{}).
+		Scopes(tenancy.Scope).
+		Select("id").
+		Where("business_id = ? AND status NOT IN ? AND id <> ?", booking.Business.ID, released, booking.Ignore).
+		Where("busy_from < ? AND busy_until > ?", until, from)
+	holds := db.
+		Model(&Hold{}).
+		Scopes(tenancy.Scope).
+		Select("id").
+		Where("business_id = ? AND status = ? AND expires_at > ? AND id <> ?", booking.Business.ID, HoldActive, time.Now(), booking.Ignore).
+		Where("busy_from < ? AND busy_until > ?", until, from)
+
+	var ids []uuid.UUID
+	err := db.
+		Model(&Allocation{}).
+		Scopes(tenancy.Scope).
+		Where("reservation_id IN (?) OR hold_id IN (?)", reservations, holds).
+		Pluck("resource_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	busy := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		busy[id] = true
+	}
+	return busy, nil
+}
+
+// check returns the requested resources when they are free and seat the
+// party, alone or combined within their group
+func check(resources []Resource, busy map[uuid.UUID]bool, people int, requested []uuid.UUID) ([]Resource, error) {
+	byID := make(map[uuid.UUID]Resource, len(resources))
+	for _, resource := range resources {
+		byID[resource.ID] = resource
+	}
+	picked := make([]Resource, 0, len(requested))
+	seen := map[uuid.UUID]bool{}
+	for _, id := range requested {
+		resource, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("resource %s is not an enabled resource of the business", id)
+		}
+		if busy[id] {
+			return nil, fmt.Errorf("%w: resource %s is taken", ErrBusinessFull, resource.Name)
+		}
+		if !seen[id] {
+			seen[id] = true
+			picked = append(picked, resource)
+		}
+	}
+	if len(picked) == 1 {
+		if !picked[0].fits(people) {
+			return nil, ErrResourceUnfit
+		}
+		return picked, nil
+	}
+	min, max := 0, 0
+	for _, resource := range picked {
+		if resource.CombinationGroup == "" || resource.CombinationGroup != picked[0].CombinationGroup {
+			return nil, ErrResourceUnfit
+		}
+		min += resource.MinPeople
+		max += resource.MaxPeople
+	}
+	if people < min || people > max {
+		return nil, ErrResourceUnfit
+	}
+	return picked, nil
+}
+
+// bestFit picks among free the resources wasting the fewest seats for a
+// party of people. A single resource is preferred, then the combinations of
+// the fewest resources of one group, up to combined of them. free must be
+// sorted by size, so the smallest fitting resource comes first. It returns
+// nil when nothing fits
+func bestFit(free []Resource, people int, combined int) []Resource {
+	for _, resource := range free {
+		if resource.fits(people) {
+			return []Resource{resource}
+		}
+	}
+
+	groups := map[string][]Resource{}
+	var names []string
+	for _, resource := range free {
+		if resource.CombinationGroup == "" {
+			continue
+		}
+		if _, ok := groups[resource.CombinationGroup]; !ok {
+			names = append(names, resource.CombinationGroup)
+		}
+		groups[resource.CombinationGroup] = append(groups[resource.CombinationGroup], resource)
+	}
+
+	for size := 2; size <= combined; size++ {
+		var best []Resource
+		waste := 0
+		for _, name := range names {
+			combinations(groups[name], size, func(combination []Resource) {
+				min, max := 0, 0
+				for _, resource := range combination {
+					min += resource.MinPeople
+					max += resource.MaxPeople
+				}
+				if people < min || people > max {
+					return
+				}
+				if best == nil || max-people < waste {
+					best = append([]Resource(nil), combination...)
+					waste = max - people
+				}
+			})
+		}
+		if best != nil {
+			return best
+		}
+	}
+	return nil
+}
+
+// combinations calls visit with every combination of size of resources, in
+// the order of resources
+func combinations(resources []Resource, size int, visit func([]Resource)) {
+	combination := make([]Resource, 0, size)
+	var walk func(from int)
+	walk = func(from int) {
+		if len(combination) == size {
+			visit(combination)
+			return
+		}
+		for i := from; i <= len(resources)-(size-len(combination)); i++ {
+			combination = append(combination, resources[i])
+			walk(i + 1)
+			combination = combination[:len(combination)-1]
+		}
+	}
+	walk(0)
+}
+
+// allocate writes the allocations of resources for the reservation or hold
+// set on template, replacing the ones owned by it
+func allocate(tx *gorm.DB, template Allocation, resources []Resource) ([]Allocation, error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	owner := db.Unscoped().Scopes(tenancy.Scope)
+	switch {
+	case template.ReservationID != nil:
+		owner = owner.Where("reservation_id = ?", *template.ReservationID)
+	case template.HoldID != nil:
+		owner = owner.Where("hold_id = ?", *template.HoldID)
+	default:
+		return nil, errors.New("an allocation needs a reservation or a hold")
+	}
+	if err := owner.Delete(&Allocation{}).Error; err != nil {
+		return nil, err
+	}
+	if len(resources) == 0 {
+		return nil, nil
+	}
+
+	allocations := make([]Allocation, len(resources))
+	for i, resource := range resources {
+		allocations[i] = template
+		allocations[i].ResourceID = resource.ID
+		if err := tenancy.Assign(db, &allocations[i]); err != nil {
+			return nil, err
+		}
+	}
+	return allocations, db.Omit(clause.Associations).Create(&allocations).Error
+}
@@ -0,0 +1,195 @@
+package models
+
+import (
+	"backend/database"
+	"backend/database/databasetest"
+	"backend/pkg/common"
+
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// table is a free resource named name seating min to max people, combinable
+// with the others of group
+func table(name string, min int, max int, group string) Resource {
+	resource := Resource{Name: name, Kind: "table", MinPeople: min, MaxPeople: max, CombinationGroup: group}
+	resource.ID = uuid.New()
+	return resource
+}
+
+// named lists the names of resources
+func named(resources []Resource) string {
+	names := make([]string, len(resources))
+	for i, resource := range resources {
+		names[i] = resource.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func TestBestFit(t *testing.T) {
+	// Sorted by size, as Allocate reads them
+	free := []Resource{
+		table("bar", 1, 1, ""),
+		table("t2a", 1, 2, "patio"),
+		table("t2b", 1, 2, "patio"),
+		table("t4", 3, 4, "hall"),
+		table("t4b", 2, 4, "patio"),
+		table("t6", 4, 6, "hall"),
+		table("t8", 6, 8, ""),
+	}
+
+	tests := []struct {
+		name     string
+		free     []Resource
+		people   int
+		combined int
+		want     string
+	}{
+		{"the smallest single fit", free, 2, 3, "t2a"},
+		{"a single fit before a combination", free, 5, 3, "t6"},
+		{"the largest single fit", free, 8, 3, "t8"},
+		{"min people of a single resource", free[:5], 3, 3, "t4"},
+		{"min people skip a resource", free[3:5], 2, 3, "t4b"},
+		{"a party too small for the large table", []Resource{table("t8", 6, 8, "")}, 4, 3, ""},
+		{"a combination within a group", free, 10, 3, "t4,t6"},
+		{"the combination wasting the fewest seats", free[:5], 6, 3, "t2a,t4b"},
+		{"no combination across groups", []Resource{table("a", 1, 4, "hall"), table("b", 1, 4, "patio")}, 6, 3, ""},
+		{"no combination without a group", []Resource{table("a", 1, 4, ""), table("b", 1, 4, "")}, 6, 3, ""},
+		{"combined min people bound", []Resource{table("a", 4, 6, "hall"), table("b", 4, 6, "hall")}, 7, 3, ""},
+		{"combined min people met", []Resource{table("a", 4, 6, "hall"), table("b", 4, 6, "hall")}, 8, 3, "a,b"},
+		{"the fewest resources first", free[:5], 8, 3, "t2a,t2b,t4b"},
+		{"within the cap", []Resource{table("a", 1, 2, "g"), table("b", 1, 2, "g"), table("c", 1, 2, "g")}, 6, 3, "a,b,c"},
+		{"beyond the cap", []Resource{table("a", 1, 2, "g"), table("b", 1, 2, "g"), table("c", 1, 2, "g")}, 6, 2, ""},
+		{"no combining", free[:5], 6, 1, ""},
+		{"nothing free", nil, 2, 3, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := named(bestFit(tt.free, tt.people, tt.combined)); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	t2a, t2b := table("t2a", 1, 2, "patio"), table("t2b", 1, 2, "patio")
+	t4 := table("t4", 3, 4, "hall")
+	t6 := table("t6", 4, 6, "hall")
+	resources := []Resource{t2a, t2b, t4, t6}
+	busy := map[uuid.UUID]bool{t2b.ID: true}
+
+	tests := []struct {
+		name      string
+		people    int
+		requested []uuid.UUID
+		want      error
+	}{
+		{"a single fit", 4, []uuid.UUID{t4.ID}, nil},
+		{"a single fit asked for twice", 4, []uuid.UUID{t4.ID, t4.ID}, nil},
+		{"too many for one", 5, []uuid.UUID{t4.ID}, ErrResourceUnfit},
+		{"too few for one", 2, []uuid.UUID{t4.ID}, ErrResourceUnfit},
+		{"a combination of a group", 9, []uuid.UUID{t4.ID, t6.ID}, nil},
+		{"too few for the combination", 6, []uuid.UUID{t4.ID, t6.ID}, ErrResourceUnfit},
+		{"a combination across groups", 5, []uuid.UUID{t2a.ID, t4.ID}, ErrResourceUnfit},
+		{"a busy resource", 2, []uuid.UUID{t2b.ID}, ErrBusinessFull},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := check(resources, busy, tt.people, tt.requested); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if _, err := check(resources, busy, 2, []uuid.UUID{uuid.New()}); err == nil {
+		t.Fatal("checked a resource of another business, want an error")
+	}
+}
+
+func TestCombinations(t *testing.T) {
+	resources := []Resource{table("a", 1, 1, ""), table("b", 1, 1, ""), table("c", 1, 1, ""), table("d", 1, 1, "")}
+	tests := []struct {
+		size int
+		want string
+	}{
+		{1, "a b c d"},
+		{2, "a,b a,c a,d b,c b,d c,d"},
+		{3, "a,b,c a,b,d a,c,d b,c,d"},
+		{4, "a,b,c,d"},
+		{5, ""},
+	}
+	for _, tt := range tests {
+		var got []string
+		combinations(resources, tt.size, func(combination []Resource) {
+			got = append(got, named(combination))
+		})
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("size %d: got %v, want %s", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestAllocateSkipsBusyResources(t *testing.T) {
+	databasetest.Open(t)
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("services.resources.max_combined", 3)
+	t.Cleanup(func() { viper.Set("services.resources.max_combined", nil) })
+	db := database.DB.WithContext(common.AsSystem(context.Background()))
+	b := business(t, db, "UTC", "10:00:00", "22:00:00")
+	var tables []Resource
+	for _, resource := range []Resource{table("t2", 1, 2, "hall"), table("t4", 1, 4, "hall"), table("t4b", 1, 4, "hall"), table("out", 1, 2, "")} {
+		resource.BusinessID = b.ID
+		resource.Disabled = resource.Name == "out"
+		if err := db.Omit(clause.Associations).Create(&resource).Error; err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, resource)
+	}
+	date := time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)
+
+	// A reservation at the same time holds the smallest table
+	taken := Reservation{BusinessID: b.ID, UserID: uuid.New(), NumberOfPeople: 2, Status: ReservationConfirmed}
+	taken.ID = uuid.New()
+	taken.Date = date
+	taken.EndsAt = date.Add(time.Hour)
+	taken.BusyFrom, taken.BusyUntil = taken.Date, taken.EndsAt
+	if err := db.Session(&gorm.Session{SkipHooks: true}).Omit(clause.Associations).Create(&taken).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := allocate(db, Allocation{ReservationID: &taken.ID}, tables[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	booking := Booking{Business: b, Date: date, People: 2}
+	if picked, err := Allocate(db, booking, nil); err != nil || named(picked) != "t4" {
+		t.Fatalf("got %q and %v, want the next smallest free table", named(picked), err)
+	}
+	booking.People = 8
+	if picked, err := Allocate(db, booking, nil); err != nil || named(picked) != "t4,t4b" {
+		t.Fatalf("got %q and %v, want the free tables combined", named(picked), err)
+	}
+	booking.People = 9
+	if _, err := Allocate(db, booking, nil); !errors.Is(err, ErrBusinessFull) {
+		t.Fatalf("got %v, want %v", err, ErrBusinessFull)
+	}
+	booking.People = 2
+	if _, err := Allocate(db, booking, []uuid.UUID{tables[0].ID}); !errors.Is(err, ErrBusinessFull) {
+		t.Fatalf("got %v asking for the busy table, want %v", err, ErrBusinessFull)
+	}
+
+	// An hour later the smallest table is free again
+	booking.Date = date.Add(time.Hour)
+	if picked, err := Allocate(db, booking, nil); err != nil || named(picked) != "t2" {
+		t.Fatalf("got %q and %v an hour later, want the smallest table", named(picked), err)
+	}
+}
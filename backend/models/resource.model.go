@@ -0,0 +1,178 @@
+package models
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &Resource{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &Allocation{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+}
+
+// Kinds of resource
+const (
+	ResourceTable = "table"
+	ResourceRoom  = "room"
+	ResourceCourt = "court"
+)
+
+// Resource is something of a business a party is seated at or booked into,
+// like a table, a room or a court, for parties of MinPeople to MaxPeople.
+// Resources of the same CombinationGroup can be put together for parties none
+// of them seats alone. Once a business has resources, every reservation is
+// allocated some of them
+type Resource struct {
+	common.CommonEntity `gorm:"embedded"`
+	BusinessID          uuid.UUID `gorm:"type:uuid;not null;index"`
+	Name                string    `gorm:"type:varchar(255);not null"`
+	Kind                string    `gorm:"type:varchar(255);not null"`
+	MinPeople           int       `gorm:"type:int;not null;default:1"`
+	MaxPeople           int       `gorm:"type:int;not null"`
+	CombinationGroup    string    `gorm:"type:varchar(255);not null;default:''"`
+	// Disabled resources are not allocated, like a table out for repairs
+	Disabled bool `gorm:"not null;default:false"`
+
+	// Relationships
+	Business Business `gorm:"foreignKey:BusinessID"`
+}
+
+type ResourceDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	BusinessID       uuid.UUID `json:"business_id" tstype:"string,required" validate:"required"`
+	Name             string    `json:"name" tstype:"string,required" validate:"required"`
+	Kind             string    `json:"kind" tstype:"string,required"`
+	MinPeople        int       `json:"min_people" tstype:"number,required" validate:"gte=0"`
+	MaxPeople        int       `json:"max_people" tstype:"number,required" validate:"gte=1"`
+	CombinationGroup string    `json:"combination_group" tstype:"string"`
+	Disabled         bool      `json:"disabled" tstype:"boolean"`
+}
+
+// Allocation assigns a resource to a reservation, or to a hold while the
+// guest checks out. Allocations only count while their reservation is not
+// cancelled or their hold is active
+type Allocation struct {
+	common.CommonEntity `gorm:"embedded"`
+	ResourceID          uuid.UUID  `gorm:"type:uuid;not null;index"`
+	ReservationID       *uuid.UUID `gorm:"type:uuid;index"`
+	HoldID              *uuid.UUID `gorm:"type:uuid;index"`
+
+	// Relationships
+	Resource Resource `gorm:"foreignKey:ResourceID"`
+}
+
+type AllocationDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	ResourceID       uuid.UUID  `json:"resource_id" tstype:"string,required"`
+	ReservationID    *uuid.UUID `json:"reservation_id,omitempty" tstype:"string"`
+	HoldID           *uuid.UUID `json:"hold_id,omitempty" tstype:"string"`
+}
+
+// BeforeSave defaults the kind and the smallest party, and checks the party
+// sizes
+func (r *Resource) BeforeSave(tx *gorm.DB) error {
+	if r.Kind == "" {
+		r.Kind = ResourceTable
+	}
+	if r.MinPeople <= 0 {
+		r.MinPeople = 1
+	}
+	if r.MaxPeople < r.MinPeople {
+		return errors.New("a resource must seat at least its smallest party")
+	}
+	return nil
+}
+
+// fits tells whether a party of people can be seated at the resource alone
+func (r Resource) fits(people int) bool {
+	return r.MinPeople <= people && people <= r.MaxPeople
+}
+
+// resourceIDs lists the resources of allocations
+func resourceIDs(allocations []Allocation) []uuid.UUID {
+	if len(allocations) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(allocations))
+	for i, allocation := range allocations {
+		ids[i] = allocation.ResourceID
+	}
+	return ids
+}
+
+func (r Resource) ToDTO() common.DTO {
+	dto := &ResourceDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        r.ID,
+			CreatedAt: r.CreatedAt,
+			UpdatedAt: r.UpdatedAt,
+		},
+		BusinessID:       r.BusinessID,
+		Name:             r.Name,
+		Kind:             r.Kind,
+		MinPeople:        r.MinPeople,
+		MaxPeople:        r.MaxPeople,
+		CombinationGroup: r.CombinationGroup,
+		Disabled:         r.Disabled,
+	}
+	return dto
+}
+
+func (r ResourceDTO) ToEntity() common.Entity {
+	entity := &Resource{
+		CommonEntity: common.CommonEntity{
+			ID:        r.ID,
+			CreatedAt: r.CreatedAt,
+			UpdatedAt: r.UpdatedAt,
+		},
+		BusinessID:       r.BusinessID,
+		Name:             r.Name,
+		Kind:             r.Kind,
+		MinPeople:        r.MinPeople,
+		MaxPeople:        r.MaxPeople,
+		CombinationGroup: r.CombinationGroup,
+		Disabled:         r.Disabled,
+	}
+	return entity
+}
+
+func (a Allocation) ToDTO() common.DTO {
+	dto := &AllocationDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        a.ID,
+			CreatedAt: a.CreatedAt,
+			UpdatedAt: a.UpdatedAt,
+		},
+		ResourceID:    a.ResourceID,
+		ReservationID: a.ReservationID,
+		HoldID:        a.HoldID,
+	}
+	return dto
+}
+
+func (a AllocationDTO) ToEntity() common.Entity {
+	entity := &Allocation{
+		CommonEntity: common.CommonEntity{
+			ID:        a.ID,
+			CreatedAt: a.CreatedAt,
+			UpdatedAt: a.UpdatedAt,
+		},
+		ResourceID:    a.ResourceID,
+		ReservationID: a.ReservationID,
+		HoldID:        a.HoldID,
+	}
+	return entity
+}
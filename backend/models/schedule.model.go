@@ -2,10 +2,16 @@ package models
 
 import (
 	"backend/database"
+	"backend/pkg/clock"
 	"backend/pkg/common"
-	"time"
+	"backend/pkg/tenancy"
+
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 func init() {
@@ -13,15 +19,20 @@ func init() {
 		Model:           &Schedule{},
 		DropOnFlush:     true,
 		TruncateOnFlush: true,
+		Prepare:         clockColumns("schedules", "start_time", "end_time"),
 	})
 }
 
+// Schedule opens a business every week on DayOfWeek, from StartTime to
+// EndTime on the wall clock of its time zone. The schedules of a staff member
+// are their working hours instead
 type Schedule struct {
 	common.CommonEntity `gorm:"embedded"`
-	BusinessID          uuid.UUID `gorm:"type:uuid;not null"`
-	DayOfWeek           int       `gorm:"type:int;not null"`
-	StartTime           time.Time `gorm:"type:timestamp;not null"`
-	EndTime             time.Time `gorm:"type:timestamp;not null"`
+	BusinessID          uuid.UUID   `gorm:"type:uuid;not null"`
+	StaffID             *uuid.UUID  `gorm:"type:uuid;index"`
+	DayOfWeek           int         `gorm:"type:int;not null"`
+	StartTime           clock.Clock `gorm:"type:varchar(8);not null"`
+	EndTime             clock.Clock `gorm:"type:varchar(8);not null"`
 
 	// Relationships
 	Business Business `gorm:"foreignKey:BusinessID"`
@@ -29,10 +40,66 @@ type Schedule struct {
 
 type ScheduleDTO struct {
 	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
-	BusinessID       uuid.UUID `json:"business_id" tstype:"string,required"`
-	DayOfWeek        int       `json:"day_of_week" tstype:"number,required"`
-	StartTime        time.Time `json:"start_time" tstype:"string,required"`
-	EndTime          time.Time `json:"end_time" tstype:"string,required"`
+	BusinessID       uuid.UUID   `json:"business_id" tstype:"string,required" validate:"required"`
+	StaffID          *uuid.UUID  `json:"staff_id,omitempty" tstype:"string"`
+	DayOfWeek        int         `json:"day_of_week" tstype:"number,required" validate:"gte=0,lte=6"`
+	StartTime        clock.Clock `json:"start_time" tstype:"string,required" validate:"required"`
+	EndTime          clock.Clock `json:"end_time" tstype:"string,required" validate:"required"`
+}
+
+// BeforeSave checks the schedule ends after it starts, and that its staff
+// member works at its business
+func (s *Schedule) BeforeSave(tx *gorm.DB) error {
+	if s.EndTime.Offset() <= s.StartTime.Offset() {
+		return errors.New("a schedule must end after it starts")
+	}
+	if s.StaffID == nil {
+		return nil
+	}
+	var found int64
+	err := tx.
+		Session(&gorm.Session{NewDB: true}).
+		Model(&Staff{}).
+		Scopes(tenancy.Scope).
+		Where("id = ? AND business_id = ?", *s.StaffID, s.BusinessID).
+		Count(&found).Error
+	if err != nil {
+		return err
+	}
+	if found == 0 {
+		return fmt.Errorf("%w: %s", ErrStaffNotFound, *s.StaffID)
+	}
+	return nil
+}
+
+// clockColumns converts the columns of table holding times of day as
+// timestamps to text, keeping the time of day. Only PostgreSQL needs it,
+// other databases store the text in the columns as they are
+func clockColumns(table string, columns ...string) func(db *gorm.DB) error {
+	return func(db *gorm.DB) error {
+		if db.Dialector.Name() != "postgres" || !db.Migrator().HasTable(table) {
+			return nil
+		}
+		types, err := db.Migrator().ColumnTypes(table)
+		if err != nil {
+			return err
+		}
+		for _, column := range types {
+			if !strings.HasPrefix(strings.ToLower(column.DatabaseTypeName()), "timestamp") {
+				continue
+			}
+			for _, name := range columns {
+				if column.Name() != name {
+					continue
+				}
+				err := db.Exec(fmt.Sprintf(`ALTER TABLE %q ALTER COLUMN %q TYPE varchar(8) USING to_char(%q, 'HH24:MI:SS')`, table, name, name)).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
 }
 
 func (s Schedule) ToDTO() common.DTO {
@@ -43,6 +110,7 @@ func (s Schedule) ToDTO() common.DTO {
 			UpdatedAt: s.UpdatedAt,
 		},
 		BusinessID: s.BusinessID,
+		StaffID:    s.StaffID,
 		DayOfWeek:  s.DayOfWeek,
 		StartTime:  s.StartTime,
 		EndTime:    s.EndTime,
@@ -58,6 +126,7 @@ func (s ScheduleDTO) ToEntity() common.Entity {
 			UpdatedAt: s.UpdatedAt,
 		},
 		BusinessID: s.BusinessID,
+		StaffID:    s.StaffID,
 		DayOfWeek:  s.DayOfWeek,
 		StartTime:  s.StartTime,
 		EndTime:    s.EndTime,
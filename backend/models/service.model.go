@@ -0,0 +1,179 @@
+package models
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/tenancy"
+
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrServiceNotFound = errors.New("the service is not a service of the business")
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &Service{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+}
+
+// Service is something a business books appointments for, like a haircut or
+// a consultation. Its reservations last DurationMinutes, and keep the
+// business busy for the buffers before and after them too. Capacity caps the
+// people booked for the service at the same time, zero leaving only the
+// capacity of the business. Price is in the minor units of Currency
+type Service struct {
+	common.CommonEntity `gorm:"embedded"`
+	BusinessID          uuid.UUID `gorm:"type:uuid;not null;index"`
+	Name                string    `gorm:"type:varchar(255);not null"`
+	DurationMinutes     int       `gorm:"type:int;not null"`
+	BufferBeforeMinutes int       `gorm:"type:int;not null;default:0"`
+	BufferAfterMinutes  int       `gorm:"type:int;not null;default:0"`
+	Price               int64     `gorm:"type:bigint;not null;default:0"`
+	Currency            string    `gorm:"type:varchar(3);not null;default:''"`
+	Capacity            int       `gorm:"type:int;not null;default:0"`
+
+	// Relationships
+	Business Business `gorm:"foreignKey:BusinessID"`
+}
+
+type ServiceDTO struct {
+	common.CommonDTO    `json:",inline,omitempty" tstype:",extends,required"`
+	BusinessID          uuid.UUID `json:"business_id" tstype:"string,required" validate:"required"`
+	Name                string    `json:"name" tstype:"string,required" validate:"required"`
+	DurationMinutes     int       `json:"duration_minutes" tstype:"number,required" validate:"gte=1"`
+	BufferBeforeMinutes int       `json:"buffer_before_minutes" tstype:"number" validate:"gte=0"`
+	BufferAfterMinutes  int       `json:"buffer_after_minutes" tstype:"number" validate:"gte=0"`
+	Price               int64     `json:"price" tstype:"number" validate:"gte=0"`
+	Currency            string    `json:"currency" tstype:"string" validate:"omitempty,len=3"`
+	Capacity            int       `json:"capacity" tstype:"number" validate:"gte=0"`
+}
+
+// BeforeSave checks the service lasts, and keeps the currency in upper case
+func (s *Service) BeforeSave(tx *gorm.DB) error {
+	if s.DurationMinutes <= 0 {
+		return errors.New("a service must last at least a minute")
+	}
+	if s.BufferBeforeMinutes < 0 || s.BufferAfterMinutes < 0 {
+		return errors.New("the buffers of a service cannot be negative")
+	}
+	s.Currency = strings.ToUpper(s.Currency)
+	return nil
+}
+
+// Duration is how long the appointments of the service last
+func (s Service) Duration() time.Duration {
+	return time.Duration(s.DurationMinutes) * time.Minute
+}
+
+// BufferBefore is how long the business is busy before an appointment
+func (s Service) BufferBefore() time.Duration {
+	return time.Duration(s.BufferBeforeMinutes) * time.Minute
+}
+
+// BufferAfter is how long the business is busy after an appointment
+func (s Service) BufferAfter() time.Duration {
+	return time.Duration(s.BufferAfterMinutes) * time.Minute
+}
+
+// FindService loads the service id of business, nil when id is
+func FindService(tx *gorm.DB, business Business, id *uuid.UUID) (*Service, error) {
+	if id == nil {
+		return nil, nil
+	}
+	var service Service
+	err := tx.
+		Session(&gorm.Session{NewDB: true}).
+		Scopes(tenancy.Scope).
+		First(&service, "id = ? AND business_id = ?", *id, business.ID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, *id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &service, nil
+}
+
+func (s Service) ToDTO() common.DTO {
+	dto := &ServiceDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        s.ID,
+			CreatedAt: s.CreatedAt,
+			UpdatedAt: s.UpdatedAt,
+		},
+		BusinessID:          s.BusinessID,
+		Name:                s.Name,
+		DurationMinutes:     s.DurationMinutes,
+		BufferBeforeMinutes: s.BufferBeforeMinutes,
+		BufferAfterMinutes:  s.BufferAfterMinutes,
+		Price:               s.Price,
+		Currency:            s.Currency,
+		Capacity:            s.Capacity,
+	}
+	return dto
+}
+
+func (s ServiceDTO) ToEntity() common.Entity {
+	entity := &Service{
+		CommonEntity: common.CommonEntity{
+			ID:        s.ID,
+			CreatedAt: s.CreatedAt,
+			UpdatedAt: s.UpdatedAt,
+		},
+		BusinessID:          s.BusinessID,
+		Name:                s.Name,
+		DurationMinutes:     s.DurationMinutes,
+		BufferBeforeMinutes: s.BufferBeforeMinutes,
+		BufferAfterMinutes:  s.BufferAfterMinutes,
+		Price:               s.Price,
+		Currency:            s.Currency,
+		Capacity:            s.Capacity,
+	}
+	return entity
+}
+
+// backfillBusy works out when the bookings of table written before they had
+// an end end, and the time they keep their seats. They had no service then,
+// and lasted a slot of their business
+func backfillBusy(table string) func(db *gorm.DB) error {
+	return func(db *gorm.DB) error {
+		var rows []struct {
+			ID         uuid.UUID
+			BusinessID uuid.UUID
+			Date       time.Time
+		}
+		err := db.Table(table).Select("id, business_id, date").Where("busy_until IS NULL").Scan(&rows).Error
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+		businesses := map[uuid.UUID]Business{}
+		for _, row := range rows {
+			business, ok := businesses[row.BusinessID]
+			if !ok {
+				if err := db.Unscoped().Limit(1).Find(&business, "id = ?", row.BusinessID).Error; err != nil {
+					return err
+				}
+				businesses[row.BusinessID] = business
+			}
+			booking := Booking{Business: business, Date: row.Date}
+			from, until := booking.Busy()
+			err := db.Table(table).Where("id = ?", row.ID).UpdateColumns(map[string]interface{}{
+				"ends_at":    booking.End(),
+				"busy_from":  from,
+				"busy_until": until,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,363 @@
+package models
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/tenancy"
+
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &Staff{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &StaffService{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &TimeOff{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+}
+
+var (
+	ErrStaffNotFound    = errors.New("the staff member is not a staff member of the business")
+	ErrStaffUnavailable = errors.New("the staff member is not available at that time")
+	ErrStaffUnqualified = errors.New("the staff member does not perform the service")
+)
+
+// Staff is a person working at a business, like a barber, who reservations
+// can be booked with. Schedules of the staff member are their working hours,
+// within the ones of the business, and time off takes them off work. A staff
+// member performs the services they are linked to, or every service of the
+// business when they have none
+type Staff struct {
+	common.CommonEntity `gorm:"embedded"`
+	BusinessID          uuid.UUID  `gorm:"type:uuid;not null;index"`
+	UserID              *uuid.UUID `gorm:"type:uuid;index"`
+	Name                string     `gorm:"type:varchar(255);not null"`
+	// Disabled staff members cannot be booked, like someone who left
+	Disabled bool `gorm:"not null;default:false"`
+
+	// services are the services the payload links the staff member to, nil
+	// leaving the ones they have
+	services []uuid.UUID
+
+	// Relationships
+	Business      Business       `gorm:"foreignKey:BusinessID"`
+	User          *User          `gorm:"foreignKey:UserID"`
+	Schedules     []Schedule     `gorm:"foreignKey:StaffID"`
+	StaffServices []StaffService `gorm:"foreignKey:StaffID"`
+}
+
+type StaffDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	BusinessID       uuid.UUID  `json:"business_id" tstype:"string,required" validate:"required"`
+	UserID           *uuid.UUID `json:"user_id,omitempty" tstype:"string"`
+	Name             string     `json:"name" tstype:"string,required" validate:"required"`
+	Disabled         bool       `json:"disabled" tstype:"boolean"`
+	// ServiceIDs are the services the staff member performs, all of them
+	// when empty. Reads list them when the StaffServices relation is loaded
+	ServiceIDs []uuid.UUID `json:"service_ids,omitempty" tstype:"string[]"`
+}
+
+// StaffService links a staff member to a service they perform
+type StaffService struct {
+	common.CommonEntity `gorm:"embedded"`
+	StaffID             uuid.UUID `gorm:"type:uuid;not null;index"`
+	ServiceID           uuid.UUID `gorm:"type:uuid;not null;index"`
+
+	// Relationships
+	Service Service `gorm:"foreignKey:ServiceID"`
+}
+
+type StaffServiceDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	StaffID          uuid.UUID `json:"staff_id" tstype:"string,required"`
+	ServiceID        uuid.UUID `json:"service_id" tstype:"string,required"`
+}
+
+// TimeOff takes a staff member off work from StartsAt until EndsAt, like a
+// holiday or an appointment of their own
+type TimeOff struct {
+	common.CommonEntity `gorm:"embedded"`
+	StaffID             uuid.UUID `gorm:"type:uuid;not null;index"`
+	StartsAt            time.Time `gorm:"type:timestamp;not null;index"`
+	EndsAt              time.Time `gorm:"type:timestamp;not null;index"`
+	Reason              string    `gorm:"type:varchar(255);not null;default:''"`
+
+	// Relationships
+	Staff Staff `gorm:"foreignKey:StaffID"`
+}
+
+type TimeOffDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	StaffID          uuid.UUID `json:"staff_id" tstype:"string,required" validate:"required"`
+	StartsAt         time.Time `json:"starts_at" tstype:"string,required" validate:"required"`
+	EndsAt           time.Time `json:"ends_at" tstype:"string,required" validate:"required"`
+	Reason           string    `json:"reason" tstype:"string"`
+}
+
+// BeforeSave checks the services of the payload are services of the business
+func (s *Staff) BeforeSave(tx *gorm.DB) error {
+	if len(s.services) == 0 {
+		return nil
+	}
+	unique := map[uuid.UUID]bool{}
+	for _, id := range s.services {
+		unique[id] = true
+	}
+	var found int64
+	err := tx.
+		Session(&gorm.Session{NewDB: true}).
+		Model(&Service{}).
+		Scopes(tenancy.Scope).
+		Where("id IN ? AND business_id = ?", s.services, s.BusinessID).
+		Count(&found).Error
+	if err != nil {
+		return err
+	}
+	if int(found) != len(unique) {
+		return ErrServiceNotFound
+	}
+	return nil
+}
+
+// AfterSave links the staff member to the services of the payload, when it
+// has any
+func (s *Staff) AfterSave(tx *gorm.DB) error {
+	if s.services == nil {
+		return nil
+	}
+	db := tx.Session(&gorm.Session{NewDB: true})
+	err := db.
+		Unscoped().
+		Scopes(tenancy.Scope).
+		Where("staff_id = ?", s.ID).
+		Delete(&StaffService{}).Error
+	if err != nil {
+		return err
+	}
+	s.StaffServices = nil
+	seen := map[uuid.UUID]bool{}
+	for _, id := range s.services {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		link := StaffService{StaffID: s.ID, ServiceID: id}
+		link.TenantID = s.TenantID
+		if err := tenancy.Assign(db, &link); err != nil {
+			return err
+		}
+		s.StaffServices = append(s.StaffServices, link)
+	}
+	s.services = nil
+	if len(s.StaffServices) == 0 {
+		return nil
+	}
+	return db.Omit(clause.Associations).Create(&s.StaffServices).Error
+}
+
+// BeforeSave checks the time off ends after it starts
+func (t *TimeOff) BeforeSave(tx *gorm.DB) error {
+	if !t.EndsAt.After(t.StartsAt) {
+		return errors.New("time off must end after it starts")
+	}
+	return nil
+}
+
+// FindStaff loads the staff member id of business, nil when id is. Disabled
+// staff members are not found
+func FindStaff(tx *gorm.DB, business Business, id *uuid.UUID) (*Staff, error) {
+	if id == nil {
+		return nil, nil
+	}
+	var staff Staff
+	err := tx.
+		Session(&gorm.Session{NewDB: true}).
+		Scopes(tenancy.Scope).
+		First(&staff, "id = ? AND business_id = ? AND disabled = ?", *id, business.ID, false).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrStaffNotFound, *id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &staff, nil
+}
+
+// available returns ErrStaffUnavailable unless the staff member of booking
+// works the whole booking, is not off and has no other reservation or active
+// hold at the same time, and ErrStaffUnqualified unless they perform its
+// service. local is the start of the booking in the time zone of the
+// business
+func (s Staff) available(db *gorm.DB, booking Booking, local time.Time) error {
+	if booking.Service != nil {
+		var services []uuid.UUID
+		err := db.
+			Model(&StaffService{}).
+			Scopes(tenancy.Scope).
+			Where("staff_id = ?", s.ID).
+			Pluck("service_id", &services).Error
+		if err != nil {
+			return err
+		}
+		qualified := len(services) == 0
+		for _, id := range services {
+			qualified = qualified || id == booking.Service.ID
+		}
+		if !qualified {
+			return ErrStaffUnqualified
+		}
+	}
+
+	var schedules []Schedule
+	if err := db.Scopes(tenancy.Scope).Find(&schedules, "staff_id = ?", s.ID).Error; err != nil {
+		return err
+	}
+	if len(schedules) > 0 && !scheduled(schedules, local, booking.Length()) {
+		return fmt.Errorf("%w: %s does not work then", ErrStaffUnavailable, s.Name)
+	}
+
+	from, until := booking.Busy()
+	var off int64
+	err := db.
+		Model(&TimeOff{}).
+		Scopes(tenancy.Scope).
+		Where("staff_id = ? AND starts_at < ? AND ends_at > ?", s.ID, until, from).
+		Count(&off).Error
+	if err != nil {
+		return err
+	}
+	if off > 0 {
+		return fmt.Errorf("%w: %s is off", ErrStaffUnavailable, s.Name)
+	}
+
+	var booked int64
+	err = db.
+		Model(&Reservation{}).
+		Scopes(tenancy.Scope).
+		Where("staff_id = ? AND status NOT IN ? AND id <> ?", s.ID, released, booking.Ignore).
+		Where("busy_from < ? AND busy_until > ?", until, from).
+		Count(&booked).Error
+	if err != nil {
+		return err
+	}
+	var held int64
+	err = db.
+		Model(&Hold{}).
+		Scopes(tenancy.Scope).
+		Where("staff_id = ? AND status = ? AND expires_at > ? AND id <> ?", s.ID, HoldActive, time.Now(), booking.Ignore).
+		Where("busy_from < ? AND busy_until > ?", until, from).
+		Count(&held).Error
+	if err != nil {
+		return err
+	}
+	if booked+held > 0 {
+		return fmt.Errorf("%w: %s is booked", ErrStaffUnavailable, s.Name)
+	}
+	return nil
+}
+
+func (s Staff) ToDTO() common.DTO {
+	dto := &StaffDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        s.ID,
+			CreatedAt: s.CreatedAt,
+			UpdatedAt: s.UpdatedAt,
+		},
+		BusinessID: s.BusinessID,
+		UserID:     s.UserID,
+		Name:       s.Name,
+		Disabled:   s.Disabled,
+	}
+	for _, link := range s.StaffServices {
+		dto.ServiceIDs = append(dto.ServiceIDs, link.ServiceID)
+	}
+	return dto
+}
+
+func (s StaffDTO) ToEntity() common.Entity {
+	entity := &Staff{
+		CommonEntity: common.CommonEntity{
+			ID:        s.ID,
+			CreatedAt: s.CreatedAt,
+			UpdatedAt: s.UpdatedAt,
+		},
+		BusinessID: s.BusinessID,
+		UserID:     s.UserID,
+		Name:       s.Name,
+		Disabled:   s.Disabled,
+		services:   s.ServiceIDs,
+	}
+	return entity
+}
+
+func (s StaffService) ToDTO() common.DTO {
+	dto := &StaffServiceDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        s.ID,
+			CreatedAt: s.CreatedAt,
+			UpdatedAt: s.UpdatedAt,
+		},
+		StaffID:   s.StaffID,
+		ServiceID: s.ServiceID,
+	}
+	return dto
+}
+
+func (s StaffServiceDTO) ToEntity() common.Entity {
+	entity := &StaffService{
+		CommonEntity: common.CommonEntity{
+			ID:        s.ID,
+			CreatedAt: s.CreatedAt,
+			UpdatedAt: s.UpdatedAt,
+		},
+		StaffID:   s.StaffID,
+		ServiceID: s.ServiceID,
+	}
+	return entity
+}
+
+func (t TimeOff) ToDTO() common.DTO {
+	dto := &TimeOffDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        t.ID,
+			CreatedAt: t.CreatedAt,
+			UpdatedAt: t.UpdatedAt,
+		},
+		StaffID:  t.StaffID,
+		StartsAt: t.StartsAt,
+		EndsAt:   t.EndsAt,
+		Reason:   t.Reason,
+	}
+	return dto
+}
+
+func (t TimeOffDTO) ToEntity() common.Entity {
+	entity := &TimeOff{
+		CommonEntity: common.CommonEntity{
+			ID:        t.ID,
+			CreatedAt: t.CreatedAt,
+			UpdatedAt: t.UpdatedAt,
+		},
+		StaffID:  t.StaffID,
+		StartsAt: t.StartsAt,
+		EndsAt:   t.EndsAt,
+		Reason:   t.Reason,
+	}
+	return entity
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"backend/database"
 	"backend/pkg/common"
+
+	"time"
 )
 
 func init() {
@@ -19,14 +21,22 @@ type User struct {
 	Email               string `gorm:"type:varchar(255);not null;unique"`
 	Password            string `gorm:"type:varchar(255);not null"`
 	Role                string `gorm:"type:varchar(255);not null"`
+	// NoShows counts the reservations the user did not show up to. Only
+	// the no-show sweep writes it, payloads cannot
+	NoShows int `gorm:"<-:create;type:int;not null;default:0"`
+	// NoShowsResetAt is when NoShows was last reset, the no-shows before it
+	// are forgiven
+	NoShowsResetAt *time.Time `gorm:"<-:create;type:timestamp"`
 }
 
 type UserDTO struct {
 	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
-	Name             string `json:"name" tstype:"string,required"`
-	Email            string `json:"email" tstype:"string,required"`
-	Password         string `json:"password" tstype:"string,required"`
-	Role             string `json:"role" tstype:"string,required"`
+	Name             string     `json:"name" tstype:"string,required" validate:"required"`
+	Email            string     `json:"email" tstype:"string,required" validate:"required,email"`
+	Password         string     `json:"password" tstype:"string,required" validate:"required"`
+	Role             string     `json:"role" tstype:"string,required" validate:"required"`
+	NoShows          int        `json:"no_shows" tstype:"number"`
+	NoShowsResetAt   *time.Time `json:"no_shows_reset_at,omitempty" tstype:"string"`
 }
 
 func (u User) ToDTO() common.DTO {
@@ -40,6 +50,9 @@ func (u User) ToDTO() common.DTO {
 		Email:    u.Email,
 		Password: u.Password,
 		Role:     u.Role,
+		NoShows:  u.NoShows,
+		// Set by ResetNoShows only
+		NoShowsResetAt: u.NoShowsResetAt,
 	}
 	return dto
 }
@@ -58,3 +71,9 @@ func (u UserDTO) ToEntity() common.Entity {
 	}
 	return entity
 }
+
+// Redacted leaves the password out
+func (u UserDTO) Redacted() common.DTO {
+	u.Password = ""
+	return &u
+}
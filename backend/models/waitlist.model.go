@@ -0,0 +1,396 @@
+package models
+
+import (
+	"backend/database"
+	"backend/pkg/audit"
+	"backend/pkg/common"
+	"backend/pkg/tenancy"
+
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &WaitlistEntry{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+}
+
+// Statuses of a waitlist entry
+const (
+	WaitlistWaiting   = "waiting"
+	WaitlistOffered   = "offered"
+	WaitlistConfirmed = "confirmed"
+	WaitlistExpired   = "expired"
+	WaitlistCancelled = "cancelled"
+)
+
+var (
+	ErrOfferExpired = errors.New("the offer has expired")
+	ErrNotOffered   = errors.New("no reservation has been offered to the entry")
+	ErrEntryChanged = errors.New("the waitlist entry has changed meanwhile")
+)
+
+// WaitlistEntry is a party waiting for room at a business at a time it is
+// full. When seats are freed the first entries that fit are offered a pending
+// reservation, which holds the seats until they confirm it or the offer
+// expires, services.waitlist.offer_minutes later
+type WaitlistEntry struct {
+	common.CommonEntity `gorm:"embedded"`
+	BusinessID          uuid.UUID  `gorm:"type:uuid;not null;index"`
+	UserID              uuid.UUID  `gorm:"type:uuid;not null"`
+	Date                time.Time  `gorm:"type:timestamp;not null"`
+	NumberOfPeople      int        `gorm:"type:int;not null"`
+	Status              string     `gorm:"type:varchar(255);not null"`
+	ReservationID       *uuid.UUID `gorm:"type:uuid"`
+	OfferedAt           *time.Time `gorm:"type:timestamp"`
+	OfferExpiresAt      *time.Time `gorm:"type:timestamp"`
+
+	// Position and ETA are computed for waiting entries, they are not stored
+	Position int        `gorm:"-"`
+	ETA      *time.Time `gorm:"-"`
+
+	// Relationships
+	Business    Business     `gorm:"foreignKey:BusinessID"`
+	User        User         `gorm:"foreignKey:UserID"`
+	Reservation *Reservation `gorm:"foreignKey:ReservationID"`
+}
+
+type WaitlistEntryDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	BusinessID       uuid.UUID  `json:"business_id" tstype:"string,required"`
+	UserID           uuid.UUID  `json:"user_id" tstype:"string,required" validate:"required"`
+	Date             time.Time  `json:"date" tstype:"string,required" validate:"required"`
+	NumberOfPeople   int        `json:"number_of_people" tstype:"number,required" validate:"gte=1"`
+	Status           string     `json:"status" tstype:"string,required"`
+	ReservationID    *uuid.UUID `json:"reservation_id,omitempty" tstype:"string"`
+	OfferExpiresAt   *time.Time `json:"offer_expires_at,omitempty" tstype:"string"`
+	// Position is 1 for the next party to be offered a reservation
+	Position int `json:"position,omitempty" tstype:"number"`
+	// ETA estimates when the entry will be offered a reservation, from how
+	// long the last entries of the business waited
+	ETA *time.Time `json:"eta,omitempty" tstype:"string"`
+}
+
+// overlapping restricts a query to the entries of business whose slot
+// overlaps the time from until until, which are the ones competing for the
+// same seats
+func overlapping(business Business, from time.Time, until time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("business_id = ? AND date > ? AND date < ?", business.ID, from.Add(-business.Slot()), until)
+	}
+}
+
+// PromoteWaitlist offers a reservation to the entries waiting for business
+// between from and until that fit in it now, first come first served. Entries too large
+// for the seats left are skipped, so a smaller party behind them may go first,
+// and so are the ones of guests the business blocks
+func PromoteWaitlist(tx *gorm.DB, business Business, from time.Time, until time.Time) error {
+	db := tx.Session(&gorm.Session{NewDB: true})
+
+	var entries []WaitlistEntry
+	err := db.
+		Scopes(tenancy.Scope, overlapping(business, from, until)).
+		Where("status = ? AND date > ?", WaitlistWaiting, time.Now()).
+		Order("created_at").
+		Find(&entries).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range entries {
+		err := CheckAvailability(db, Booking{Business: business, Date: entries[i].Date, People: entries[i].NumberOfPeople})
+		if errors.Is(err, ErrBusinessClosed) || errors.Is(err, ErrBusinessFull) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = entries[i].offer(db)
+		if errors.Is(err, ErrGuestBlocked) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// offer books a pending reservation for the entry, holding its seats
+func (e *WaitlistEntry) offer(db *gorm.DB) error {
+	reservation := &Reservation{
+		UserID:         e.UserID,
+		BusinessID:     e.BusinessID,
+		Date:           e.Date,
+		NumberOfPeople: e.NumberOfPeople,
+		Status:         ReservationPending,
+	}
+	reservation.TenantID = e.TenantID
+	if err := tenancy.Assign(db, reservation); err != nil {
+		return err
+	}
+	if err := db.Omit(clause.Associations).Create(reservation).Error; err != nil {
+		return err
+	}
+	if err := audit.Record(db, audit.Created, "reservations", reservation, nil, reservation.ToDTO()); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expires := now.Add(time.Duration(viper.GetInt("services.waitlist.offer_minutes")) * time.Minute)
+	return e.transition(db, func() {
+		e.Status = WaitlistOffered
+		e.ReservationID = &reservation.ID
+		e.OfferedAt = &now
+		e.OfferExpiresAt = &expires
+	})
+}
+
+// Confirm turns the reservation offered to the entry into a confirmed one.
+// The offer is claimed in the database before it expires, so the sweep
+// cannot withdraw it meanwhile
+func (e *WaitlistEntry) Confirm(tx *gorm.DB) error {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	if e.Status != WaitlistOffered {
+		return ErrNotOffered
+	}
+	if e.OfferExpiresAt.Before(time.Now()) {
+		return ErrOfferExpired
+	}
+	err := e.claim(db, WaitlistConfirmed)
+	if errors.Is(err, ErrEntryChanged) {
+		var stored WaitlistEntry
+		if err := db.Scopes(tenancy.Scope).First(&stored, "id = ?", e.ID).Error; err != nil {
+			return err
+		}
+		if stored.Status == WaitlistExpired || stored.Status == WaitlistOffered {
+			return ErrOfferExpired
+		}
+		return ErrNotOffered
+	}
+	if err != nil {
+		return err
+	}
+
+	var reservation Reservation
+	if err := db.Scopes(tenancy.Scope).First(&reservation, "id = ?", e.ReservationID).Error; err != nil {
+		return err
+	}
+	before := reservation.ToDTO()
+	reservation.Status = ReservationConfirmed
+	if err := db.Omit(clause.Associations).Save(&reservation).Error; err != nil {
+		return err
+	}
+	if err := audit.Record(db, audit.Updated, "reservations", &reservation, before, reservation.ToDTO()); err != nil {
+		return err
+	}
+
+	return e.transition(db, func() {
+		e.Status = WaitlistConfirmed
+	})
+}
+
+// Withdraw takes the entry off the waitlist with status, cancelling the
+// reservation offered to it, whose seats go to the next entries. It fails
+// with ErrEntryChanged when the entry was confirmed or withdrawn since it
+// was read
+func (e *WaitlistEntry) Withdraw(tx *gorm.DB, status string) error {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	offered := e.Status == WaitlistOffered
+	if err := e.claim(db, status); err != nil {
+		return err
+	}
+	if err := e.transition(db, func() { e.Status = status }); err != nil {
+		return err
+	}
+	if !offered {
+		return nil
+	}
+
+	var reservation Reservation
+	err := db.Scopes(tenancy.Scope).First(&reservation, "id = ?", e.ReservationID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	before := reservation.ToDTO()
+	reservation.Status = ReservationCancelled
+	if err := db.Omit(clause.Associations).Save(&reservation).Error; err != nil {
+		return err
+	}
+	return audit.Record(db, audit.Updated, "reservations", &reservation, before, reservation.ToDTO())
+}
+
+// claim moves the stored entry from the status it was read with to status,
+// the way Hold.Confirm claims a hold. An offer is only confirmed before it
+// expires
+func (e *WaitlistEntry) claim(db *gorm.DB, status string) error {
+	query := db.
+		Model(&WaitlistEntry{}).
+		Scopes(tenancy.Scope).
+		Where("id = ? AND status = ?", e.ID, e.Status)
+	if status == WaitlistConfirmed {
+		query = query.Where("offer_expires_at > ?", time.Now())
+	}
+	result := query.UpdateColumn("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrEntryChanged
+	}
+	return nil
+}
+
+func (e *WaitlistEntry) transition(db *gorm.DB, change func()) error {
+	before := e.ToDTO()
+	change()
+	if err := db.Omit(clause.Associations).Save(e).Error; err != nil {
+		return err
+	}
+	return audit.Record(db, audit.Updated, "waitlist_entries", e, before, e.ToDTO())
+}
+
+// Locate sets the position of a waiting entry among the ones competing for
+// the same seats, and its ETA when the business has promoted entries before
+func (e *WaitlistEntry) Locate(tx *gorm.DB, business Business) error {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	e.Position = 0
+	e.ETA = nil
+	if e.Status != WaitlistWaiting {
+		return nil
+	}
+
+	var ahead int64
+	err := db.
+		Model(&WaitlistEntry{}).
+		Scopes(tenancy.Scope, overlapping(business, e.Date, e.Date.Add(business.Slot()))).
+		Where("status = ? AND created_at < ?", WaitlistWaiting, e.CreatedAt).
+		Count(&ahead).Error
+	if err != nil {
+		return err
+	}
+	e.Position = int(ahead) + 1
+
+	var promoted []WaitlistEntry
+	err = db.
+		Scopes(tenancy.Scope).
+		Where("business_id = ? AND offered_at IS NOT NULL", business.ID).
+		Order("offered_at DESC").
+		Limit(viper.GetInt("services.waitlist.eta_sample")).
+		Find(&promoted).Error
+	if err != nil || len(promoted) == 0 {
+		return err
+	}
+	var waited time.Duration
+	for _, entry := range promoted {
+		waited += entry.OfferedAt.Sub(entry.CreatedAt)
+	}
+	eta := time.Now().Add(waited / time.Duration(len(promoted)) * time.Duration(e.Position))
+	if eta.After(e.Date) {
+		eta = e.Date
+	}
+	e.ETA = &eta
+	return nil
+}
+
+// SweepWaitlist expires the offers nobody confirmed in time and the entries
+// whose date has passed, then promotes the entries that fit now, which
+// catches seats freed by anything else than a cancellation. Each entry is
+// handled in a transaction bound to its tenant
+func SweepWaitlist(ctx context.Context) error {
+	now := time.Now()
+	var entries []WaitlistEntry
+	err := database.DB.
+		WithContext(common.AsSystem(ctx)).
+		Where("(status = ? AND offer_expires_at < ?) OR (status = ? AND date <= ?)", WaitlistOffered, now, WaitlistWaiting, now).
+		Find(&entries).Error
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		err := database.DB.
+			WithContext(common.WithTenant(ctx, entries[i].TenantID)).
+			Transaction(func(tx *gorm.DB) error {
+				return entries[i].Withdraw(tx, WaitlistExpired)
+			})
+		if errors.Is(err, ErrEntryChanged) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("waitlist entry %s: %w", entries[i].ID, err)
+		}
+	}
+
+	var waiting []WaitlistEntry
+	err = database.DB.
+		WithContext(common.AsSystem(ctx)).
+		Preload("Business").
+		Where("status = ?", WaitlistWaiting).
+		Order("created_at").
+		Find(&waiting).Error
+	if err != nil {
+		return err
+	}
+	for _, entry := range waiting {
+		err := database.DB.
+			WithContext(common.WithTenant(ctx, entry.TenantID)).
+			Transaction(func(tx *gorm.DB) error {
+				return PromoteWaitlist(tx, entry.Business, entry.Date, entry.Date.Add(entry.Business.Slot()))
+			})
+		if err != nil {
+			return fmt.Errorf("waitlist entry %s: %w", entry.ID, err)
+		}
+	}
+	return nil
+}
+
+func (e WaitlistEntry) ToDTO() common.DTO {
+	dto := &WaitlistEntryDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        e.ID,
+			CreatedAt: e.CreatedAt,
+			UpdatedAt: e.UpdatedAt,
+		},
+		BusinessID:     e.BusinessID,
+		UserID:         e.UserID,
+		Date:           e.Date,
+		NumberOfPeople: e.NumberOfPeople,
+		Status:         e.Status,
+		ReservationID:  e.ReservationID,
+		OfferExpiresAt: e.OfferExpiresAt,
+		Position:       e.Position,
+		ETA:            e.ETA,
+	}
+	return dto
+}
+
+func (e WaitlistEntryDTO) ToEntity() common.Entity {
+	entity := &WaitlistEntry{
+		CommonEntity: common.CommonEntity{
+			ID:        e.ID,
+			CreatedAt: e.CreatedAt,
+			UpdatedAt: e.UpdatedAt,
+		},
+		BusinessID:     e.BusinessID,
+		UserID:         e.UserID,
+		Date:           e.Date,
+		NumberOfPeople: e.NumberOfPeople,
+		Status:         e.Status,
+		ReservationID:  e.ReservationID,
+		OfferExpiresAt: e.OfferExpiresAt,
+	}
+	return entity
+}
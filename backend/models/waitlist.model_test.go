@@ -0,0 +1,190 @@
+package models
+
+import (
+	"backend/database"
+	"backend/database/databasetest"
+	"backend/pkg/common"
+
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tenantBusiness stores a business of a tenant of its own, open on Sundays
+// from 10:00 to 22:00 UTC
+func tenantBusiness(t *testing.T, db *gorm.DB) Business {
+	t.Helper()
+	b := business(t, db, "UTC", "10:00:00", "22:00:00")
+	b.TenantID = uuid.New()
+	for _, table := range []string{"businesses", "schedules"} {
+		if err := db.Table(table).Where("tenant_id IS NULL OR tenant_id = ?", uuid.Nil).UpdateColumn("tenant_id", b.TenantID).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b
+}
+
+// sunday returns the next Sunday at noon UTC, when a tenantBusiness opens
+func sunday() time.Time {
+	date := time.Now().UTC().Truncate(24 * time.Hour).Add(12 * time.Hour)
+	for date.Weekday() != time.Sunday || !date.After(time.Now()) {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// offered stores an entry of business offered a pending reservation, whose
+// offer expires at expires
+func offered(t *testing.T, db *gorm.DB, business Business, expires time.Time) (WaitlistEntry, Reservation) {
+	t.Helper()
+	date := sunday()
+	reservation := Reservation{BusinessID: business.ID, UserID: uuid.New(), NumberOfPeople: 2, Status: ReservationPending}
+	reservation.ID = uuid.New()
+	reservation.TenantID = business.TenantID
+	reservation.Date = date
+	reservation.EndsAt = date.Add(time.Hour)
+	reservation.BusyFrom, reservation.BusyUntil = reservation.Date, reservation.EndsAt
+	// Without its hooks, as the offer books it
+	if err := db.Session(&gorm.Session{SkipHooks: true}).Omit(clause.Associations).Create(&reservation).Error; err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	entry := WaitlistEntry{
+		BusinessID:     business.ID,
+		UserID:         reservation.UserID,
+		Date:           date,
+		NumberOfPeople: 2,
+		Status:         WaitlistOffered,
+		ReservationID:  &reservation.ID,
+		OfferedAt:      &now,
+		OfferExpiresAt: &expires,
+	}
+	entry.TenantID = business.TenantID
+	if err := db.Omit(clause.Associations).Create(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	return entry, reservation
+}
+
+// statuses returns the stored statuses of entry and of its reservation
+func statuses(t *testing.T, db *gorm.DB, entry WaitlistEntry) (string, string) {
+	t.Helper()
+	var stored WaitlistEntry
+	if err := db.First(&stored, "id = ?", entry.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	var reservation Reservation
+	if err := db.First(&reservation, "id = ?", entry.ReservationID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return stored.Status, reservation.Status
+}
+
+func TestConfirmAfterTheSweep(t *testing.T) {
+	databasetest.Open(t)
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	db := database.DB.WithContext(common.AsSystem(context.Background()))
+	b := tenantBusiness(t, db)
+	entry, _ := offered(t, db, b, time.Now().Add(-time.Second))
+	// The guest read the entry while the offer was still open
+	read := entry
+	open := time.Now().Add(time.Minute)
+	read.OfferExpiresAt = &open
+
+	if err := SweepWaitlist(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return read.Confirm(tx)
+	})
+	if !errors.Is(err, ErrOfferExpired) {
+		t.Fatalf("got %v, want %v", err, ErrOfferExpired)
+	}
+	if status, reserved := statuses(t, db, entry); status != WaitlistExpired || reserved != ReservationCancelled {
+		t.Fatalf("got the entry %s and its reservation %s, want them expired and cancelled", status, reserved)
+	}
+}
+
+func TestSweepAfterTheConfirm(t *testing.T) {
+	databasetest.Open(t)
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	db := database.DB.WithContext(common.AsSystem(context.Background()))
+	b := tenantBusiness(t, db)
+	entry, _ := offered(t, db, b, time.Now().Add(time.Minute))
+	// The sweep read the entry as it was, before the guest confirmed it
+	read := entry
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return entry.Confirm(tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return read.Withdraw(tx, WaitlistExpired)
+	})
+	if !errors.Is(err, ErrEntryChanged) {
+		t.Fatalf("got %v, want %v", err, ErrEntryChanged)
+	}
+	if status, reserved := statuses(t, db, entry); status != WaitlistConfirmed || reserved != ReservationConfirmed {
+		t.Fatalf("got the entry %s and its reservation %s, want both confirmed", status, reserved)
+	}
+}
+
+func TestConfirmRacingTheSweep(t *testing.T) {
+	databasetest.Open(t)
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	db := database.DB.WithContext(common.AsSystem(context.Background()))
+	b := tenantBusiness(t, db)
+	for i := 0; i < 10; i++ {
+		entry, _ := offered(t, db, b, time.Now().Add(time.Minute))
+		confirming, withdrawing := entry, entry
+
+		var wg sync.WaitGroup
+		var confirmed, withdrawn error
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			confirmed = db.Transaction(func(tx *gorm.DB) error {
+				return confirming.Confirm(tx)
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			withdrawn = db.Transaction(func(tx *gorm.DB) error {
+				return withdrawing.Withdraw(tx, WaitlistExpired)
+			})
+		}()
+		wg.Wait()
+
+		status, reserved := statuses(t, db, entry)
+		switch {
+		case confirmed == nil && errors.Is(withdrawn, ErrEntryChanged):
+			if status != WaitlistConfirmed || reserved != ReservationConfirmed {
+				t.Fatalf("got the entry %s and its reservation %s once confirmed, want both confirmed", status, reserved)
+			}
+		case withdrawn == nil && errors.Is(confirmed, ErrOfferExpired):
+			if status != WaitlistExpired || reserved != ReservationCancelled {
+				t.Fatalf("got the entry %s and its reservation %s once swept, want them expired and cancelled", status, reserved)
+			}
+		default:
+			t.Fatalf("got %v confirming and %v sweeping, want exactly one to win", confirmed, withdrawn)
+		}
+		// Frees the seats for the next entry
+		if err := db.Model(&Reservation{}).Where("id = ?", entry.ReservationID).UpdateColumn("status", ReservationCancelled).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,449 @@
+package models
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/events"
+	"backend/pkg/helpers"
+	"backend/pkg/jobs"
+	"backend/pkg/outbox"
+	"backend/pkg/tenancy"
+	"backend/pkg/webhooks"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &WebhookSubscription{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &WebhookDelivery{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+	outbox.Subscribe("*", queueDeliveries)
+}
+
+// Statuses of a webhook delivery. Pending ones are being tried, failed ones
+// ran out of attempts
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookTest is the type of the event sent by test deliveries
+const WebhookTest = "webhook.test"
+
+// WebhookSubscription posts the events of a tenant to URL, signed with
+// Secret. Events lists the types it wants, like reservation.created, with
+// reservation.* for every action on reservations and * for everything.
+// Subscriptions of a business only get the events of the business and its
+// entities. Their deliveries are queued as the outbox relays the events to
+// the bus
+type WebhookSubscription struct {
+	common.CommonEntity `gorm:"embedded"`
+	BusinessID          *uuid.UUID `gorm:"type:uuid;index"`
+	URL                 string     `gorm:"type:varchar(2048);not null"`
+	Secret              string     `gorm:"type:varchar(255);not null"`
+	Events              string     `gorm:"type:text;not null"`
+	Description         string     `gorm:"type:varchar(255);not null;default:''"`
+	Disabled            bool       `gorm:"not null;default:false"`
+
+	// Relationships
+	Business *Business `gorm:"foreignKey:BusinessID"`
+}
+
+type WebhookSubscriptionDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	BusinessID       *uuid.UUID `json:"business_id,omitempty" tstype:"string"`
+	URL              string     `json:"url" tstype:"string,required" validate:"required,url"`
+	// Secret is generated when the subscription is created without one, and
+	// kept when it is updated without one
+	Secret      string   `json:"secret" tstype:"string"`
+	Events      []string `json:"events" tstype:"string[]"`
+	Description string   `json:"description" tstype:"string"`
+	Disabled    bool     `json:"disabled" tstype:"boolean"`
+}
+
+// WebhookDelivery is an event posted, or to be, to a subscription, with the
+// last answer of its receiver. Replays are new deliveries of the event of
+// the one they replay
+type WebhookDelivery struct {
+	common.CommonEntity `gorm:"embedded"`
+	SubscriptionID      uuid.UUID    `gorm:"type:uuid;not null;index"`
+	EventID             uuid.UUID    `gorm:"type:uuid;not null;index"`
+	EventType           string       `gorm:"type:varchar(255);not null"`
+	Payload             helpers.JSON `gorm:"type:jsonb;not null"`
+	Status              string       `gorm:"type:varchar(16);not null;index"`
+	Attempts            int          `gorm:"type:int;not null;default:0"`
+	ResponseStatus      int          `gorm:"type:int;not null;default:0"`
+	ResponseBody        string       `gorm:"type:text;not null;default:''"`
+	Error               string       `gorm:"type:text;not null;default:''"`
+	DeliveredAt         *time.Time   `gorm:"type:timestamp"`
+	ReplayOf            *uuid.UUID   `gorm:"type:uuid"`
+
+	// Relationships
+	Subscription WebhookSubscription `gorm:"foreignKey:SubscriptionID"`
+}
+
+type WebhookDeliveryDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	SubscriptionID   uuid.UUID    `json:"subscription_id" tstype:"string,required"`
+	EventID          uuid.UUID    `json:"event_id" tstype:"string,required"`
+	EventType        string       `json:"event_type" tstype:"string,required"`
+	Payload          helpers.JSON `json:"payload" tstype:"Record<string, any>,required"`
+	Status           string       `json:"status" tstype:"string,required"`
+	Attempts         int          `json:"attempts" tstype:"number,required"`
+	ResponseStatus   int          `json:"response_status,omitempty" tstype:"number"`
+	ResponseBody     string       `json:"response_body,omitempty" tstype:"string"`
+	Error            string       `json:"error,omitempty" tstype:"string"`
+	DeliveredAt      *time.Time   `json:"delivered_at,omitempty" tstype:"string"`
+	ReplayOf         *uuid.UUID   `json:"replay_of,omitempty" tstype:"string"`
+}
+
+// delivery is the payload of the job posting a delivery
+type delivery struct {
+	ID uuid.UUID `json:"delivery_id"`
+}
+
+// BeforeSave checks the URL is an http or https one whose host is not
+// internal and the types are well formed, subscribes to everything when no
+// type is listed, and keeps or generates the secret
+func (s *WebhookSubscription) BeforeSave(tx *gorm.DB) error {
+	target, err := url.Parse(s.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("webhook URL %q is not an http or https URL", s.URL)
+	}
+	if err := webhooks.CheckURL(tx.Statement.Context, s.URL); err != nil {
+		return err
+	}
+	var types []string
+	for _, kind := range strings.Split(s.Events, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		if kind != "*" && !strings.Contains(kind, ".") {
+			return fmt.Errorf("event type %q is not like reservation.created, reservation.* or *", kind)
+		}
+		types = append(types, kind)
+	}
+	if len(types) == 0 {
+		types = []string{"*"}
+	}
+	s.Events = strings.Join(types, ",")
+
+	if s.Secret != "" {
+		return nil
+	}
+	var stored WebhookSubscription
+	err = tx.
+		Session(&gorm.Session{NewDB: true}).
+		Scopes(tenancy.Scope).
+		Limit(1).
+		Find(&stored, "id = ?", s.ID).Error
+	if err != nil {
+		return err
+	}
+	s.Secret = stored.Secret
+	if s.Secret == "" {
+		s.Secret = webhooks.NewSecret()
+	}
+	return nil
+}
+
+// Wants tells whether the subscription gets event
+func (s WebhookSubscription) Wants(event events.Event) bool {
+	if s.Disabled || (s.BusinessID != nil && (event.BusinessID == nil || *event.BusinessID != *s.BusinessID)) {
+		return false
+	}
+	resource, _, _ := strings.Cut(event.Type, ".")
+	for _, kind := range strings.Split(s.Events, ",") {
+		if kind == "*" || kind == event.Type || kind == resource+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// queueDeliveries queues a delivery of the event of message to each
+// subscription of its tenant wanting it. Events reach it through the outbox
+// bus once their change is committed, and one handed again is not queued
+// twice. Only the changes of services.webhooks.resources are delivered, the
+// users and the secrets of the others staying within the API
+func queueDeliveries(ctx context.Context, message outbox.Message) error {
+	var event events.Event
+	if err := json.Unmarshal(message.Payload, &event); err != nil {
+		return err
+	}
+	event.TenantID = message.TenantID
+	if !slices.Contains(viper.GetStringSlice("services.webhooks.resources"), event.Resource) || event.TenantID == uuid.Nil {
+		return nil
+	}
+	return database.DB.
+		WithContext(common.WithTenant(ctx, event.TenantID)).
+		Transaction(func(db *gorm.DB) error {
+			var subscriptions []WebhookSubscription
+			err := db.
+				Where("tenant_id = ? AND disabled = ?", event.TenantID, false).
+				Find(&subscriptions).Error
+			if err != nil {
+				return err
+			}
+			for _, subscription := range subscriptions {
+				if !subscription.Wants(event) {
+					continue
+				}
+				var queued int64
+				err := db.
+					Model(&WebhookDelivery{}).
+					Where("subscription_id = ? AND event_id = ? AND replay_of IS NULL", subscription.ID, event.ID).
+					Count(&queued).Error
+				if err != nil {
+					return err
+				}
+				if queued > 0 {
+					continue
+				}
+				entry := &WebhookDelivery{
+					SubscriptionID: subscription.ID,
+					EventID:        event.ID,
+					EventType:      event.Type,
+					Payload:        message.Payload,
+				}
+				entry.TenantID = subscription.TenantID
+				if err := entry.queue(db); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+}
+
+// queue stores the delivery as pending and queues the job posting it
+func (d *WebhookDelivery) queue(db *gorm.DB) error {
+	d.Status = DeliveryPending
+	if err := tenancy.Assign(db, d); err != nil {
+		return err
+	}
+	if err := db.Omit(clause.Associations).Create(d).Error; err != nil {
+		return err
+	}
+	_, err := jobs.Enqueue(db, JobDeliverWebhook, delivery{ID: d.ID})
+	return err
+}
+
+// DeliverWebhook posts the delivery of the payload to its subscription. A
+// failure is returned for the queue to try again after a backoff, and fails
+// the delivery once it is the last attempt of the job
+func DeliverWebhook(ctx context.Context, payload helpers.JSON) error {
+	var job delivery
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+	db := database.DB.WithContext(ctx)
+	var entry WebhookDelivery
+	if err := db.Scopes(tenancy.Scope).Preload("Subscription").First(&entry, "id = ?", job.ID).Error; err != nil {
+		return err
+	}
+	if entry.Status != DeliveryPending {
+		return nil
+	}
+	err := entry.attempt(ctx, db)
+	if err != nil && !jobs.LastAttempt(ctx) {
+		entry.Status = DeliveryPending
+	}
+	if saved := db.Omit(clause.Associations).Save(&entry).Error; saved != nil {
+		return errors.Join(err, saved)
+	}
+	return err
+}
+
+// attempt posts the delivery and records the answer. The delivery is left
+// succeeded or failed, deleted and disabled subscriptions failing it
+func (d *WebhookDelivery) attempt(ctx context.Context, db *gorm.DB) error {
+	d.Attempts++
+	d.Status = DeliveryFailed
+	d.ResponseStatus = 0
+	d.ResponseBody = ""
+	subscription := d.Subscription
+	if subscription.ID == uuid.Nil || subscription.DeletedAt.Valid || subscription.Disabled {
+		d.Error = "the subscription is deleted or disabled"
+		return nil
+	}
+
+	response, err := webhooks.Post(ctx, subscription.URL, subscription.Secret, map[string]string{
+		webhooks.EventHeader:    d.EventType,
+		webhooks.EventIDHeader:  d.EventID.String(),
+		webhooks.DeliveryHeader: d.ID.String(),
+	}, d.Payload)
+	if response != nil {
+		d.ResponseStatus = response.Status
+		d.ResponseBody = response.Body
+	}
+	if err != nil {
+		d.Error = err.Error()
+		return err
+	}
+	now := time.Now()
+	d.Status = DeliverySucceeded
+	d.DeliveredAt = &now
+	d.Error = ""
+	return nil
+}
+
+// ReplayDelivery queues the event of a delivery to its subscription again,
+// as a new delivery
+func ReplayDelivery(tx *gorm.DB, original WebhookDelivery) (*WebhookDelivery, error) {
+	entry := &WebhookDelivery{
+		SubscriptionID: original.SubscriptionID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		ReplayOf:       &original.ID,
+	}
+	entry.TenantID = original.TenantID
+	return entry, entry.queue(tx.Session(&gorm.Session{NewDB: true}))
+}
+
+// TestWebhook stores a delivery of a test event to subscription and posts it
+// right away, once. The delivery is returned with the answer of the receiver
+func TestWebhook(ctx context.Context, tx *gorm.DB, subscription WebhookSubscription) (*WebhookDelivery, error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	data, err := helpers.NewJSON(map[string]interface{}{"subscription_id": subscription.ID})
+	if err != nil {
+		return nil, err
+	}
+	event := events.Event{
+		ID:         uuid.New(),
+		Type:       WebhookTest,
+		Resource:   "webhook_subscriptions",
+		EntityID:   subscription.ID,
+		BusinessID: subscription.BusinessID,
+		Data:       data,
+		OccurredAt: time.Now().UTC(),
+	}
+	payload, err := helpers.NewJSON(event)
+	if err != nil {
+		return nil, err
+	}
+	entry := &WebhookDelivery{
+		SubscriptionID: subscription.ID,
+		EventID:        event.ID,
+		EventType:      event.Type,
+		Payload:        payload,
+		Status:         DeliveryPending,
+		Subscription:   subscription,
+	}
+	entry.TenantID = subscription.TenantID
+	if err := tenancy.Assign(db, entry); err != nil {
+		return nil, err
+	}
+	// Stored first, for the delivery header to carry its id
+	if err := db.Omit(clause.Associations).Create(entry).Error; err != nil {
+		return nil, err
+	}
+	// The answer of the receiver is the result, not an error
+	entry.attempt(ctx, db)
+	return entry, db.Omit(clause.Associations).Save(entry).Error
+}
+
+func (s WebhookSubscription) ToDTO() common.DTO {
+	dto := &WebhookSubscriptionDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        s.ID,
+			CreatedAt: s.CreatedAt,
+			UpdatedAt: s.UpdatedAt,
+		},
+		BusinessID:  s.BusinessID,
+		URL:         s.URL,
+		Secret:      s.Secret,
+		Events:      strings.Split(s.Events, ","),
+		Description: s.Description,
+		Disabled:    s.Disabled,
+	}
+	return dto
+}
+
+// Redacted leaves the secret out
+func (s WebhookSubscriptionDTO) Redacted() common.DTO {
+	s.Secret = ""
+	return &s
+}
+
+func (s WebhookSubscriptionDTO) ToEntity() common.Entity {
+	entity := &WebhookSubscription{
+		CommonEntity: common.CommonEntity{
+			ID:        s.ID,
+			CreatedAt: s.CreatedAt,
+			UpdatedAt: s.UpdatedAt,
+		},
+		BusinessID:  s.BusinessID,
+		URL:         s.URL,
+		Secret:      s.Secret,
+		Events:      strings.Join(s.Events, ","),
+		Description: s.Description,
+		Disabled:    s.Disabled,
+	}
+	return entity
+}
+
+func (d WebhookDelivery) ToDTO() common.DTO {
+	dto := &WebhookDeliveryDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        d.ID,
+			CreatedAt: d.CreatedAt,
+			UpdatedAt: d.UpdatedAt,
+		},
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Payload:        d.Payload,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		ResponseBody:   d.ResponseBody,
+		Error:          d.Error,
+		DeliveredAt:    d.DeliveredAt,
+		ReplayOf:       d.ReplayOf,
+	}
+	return dto
+}
+
+func (d WebhookDeliveryDTO) ToEntity() common.Entity {
+	entity := &WebhookDelivery{
+		CommonEntity: common.CommonEntity{
+			ID:        d.ID,
+			CreatedAt: d.CreatedAt,
+			UpdatedAt: d.UpdatedAt,
+		},
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Payload:        d.Payload,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		ResponseBody:   d.ResponseBody,
+		Error:          d.Error,
+		DeliveredAt:    d.DeliveredAt,
+		ReplayOf:       d.ReplayOf,
+	}
+	return entity
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"backend/database"
+	"backend/database/databasetest"
+	"backend/pkg/audit"
+	"backend/pkg/common"
+	"backend/pkg/outbox"
+	"backend/pkg/tenancy"
+	"backend/pkg/webhooks"
+
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// allowPrivateNetworks lets the test post to receivers on the loopback
+func allowPrivateNetworks(t *testing.T) {
+	t.Helper()
+	viper.Set("services.webhooks.allow_private_networks", true)
+	t.Cleanup(func() { viper.Set("services.webhooks.allow_private_networks", false) })
+}
+
+func TestTestWebhook(t *testing.T) {
+	databasetest.Open(t)
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	headers := make(chan http.Header, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+		w.Write([]byte("thanks"))
+	}))
+	defer receiver.Close()
+	allowPrivateNetworks(t)
+
+	ctx := common.AsSystem(context.Background())
+	db := database.DB.WithContext(ctx)
+	subscription := WebhookSubscription{URL: receiver.URL, Events: "*"}
+	if err := db.Create(&subscription).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := TestWebhook(ctx, db, subscription)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := <-headers
+	if got := received.Get(webhooks.DeliveryHeader); got != entry.ID.String() {
+		t.Errorf("got delivery header %q, want %q", got, entry.ID)
+	}
+	if got := received.Get(webhooks.EventHeader); got != WebhookTest {
+		t.Errorf("got event header %q, want %q", got, WebhookTest)
+	}
+
+	var stored WebhookDelivery
+	if err := db.First(&stored, "id = ?", entry.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != DeliverySucceeded || stored.Attempts != 1 || stored.ResponseStatus != http.StatusOK || stored.ResponseBody != "thanks" {
+		t.Errorf("got delivery %s after %d attempts answered %d %q, want it succeeded once with the answer",
+			stored.Status, stored.Attempts, stored.ResponseStatus, stored.ResponseBody)
+	}
+}
+
+func TestWebhookSubscriptionRefusesInternalURLs(t *testing.T) {
+	databasetest.Open(t)
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	db := database.DB.WithContext(common.AsSystem(context.Background()))
+	for _, url := range []string{"http://127.0.0.1:8080/hook", "http://localhost/hook", "http://169.254.169.254/latest/meta-data", "https://10.0.0.1/hook", "http://[::1]/hook"} {
+		subscription := WebhookSubscription{URL: url}
+		if err := db.Create(&subscription).Error; !errors.Is(err, webhooks.ErrForbiddenAddress) {
+			t.Errorf("%s: got %v, want %v", url, err, webhooks.ErrForbiddenAddress)
+		}
+	}
+}
+
+func TestQueueDeliveriesOfResources(t *testing.T) {
+	databasetest.Open(t)
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	allowPrivateNetworks(t)
+	viper.Set("services.webhooks.resources", []string{"reservations"})
+	t.Cleanup(func() { viper.Set("services.webhooks.resources", nil) })
+
+	tenant := uuid.New()
+	db := database.DB.WithContext(common.WithTenant(context.Background(), tenant))
+	subscription := WebhookSubscription{URL: "http://127.0.0.1/hook", Events: "*"}
+	if err := tenancy.Assign(db, &subscription); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&subscription).Error; err != nil {
+		t.Fatal(err)
+	}
+	user := User{Name: "Guest", Email: "guest@example.com", Password: "secret", Role: "user"}
+	reservation := Reservation{BusinessID: uuid.New(), UserID: uuid.New(), NumberOfPeople: 2, Status: ReservationConfirmed}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if err := audit.Record(tx, audit.Created, "users", &user, nil, user.ToDTO()); err != nil {
+			return err
+		}
+		reservation.ID = uuid.New()
+		reservation.TenantID = tenant
+		return audit.Record(tx, audit.Created, "reservations", &reservation, nil, reservation.ToDTO())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deliveries []WebhookDelivery
+	if err := db.Find(&deliveries).Error; err != nil || len(deliveries) != 0 {
+		t.Fatalf("got %d deliveries and %v before the relay, want none", len(deliveries), err)
+	}
+
+	viper.Set("services.outbox.sinks", []string{"bus"})
+	t.Cleanup(func() { viper.Set("services.outbox.sinks", nil) })
+	sinks, err := outbox.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published, err := outbox.Relay(context.Background(), sinks); err != nil || published != 2 {
+		t.Fatalf("got %d messages published and %v, want 2", published, err)
+	}
+	if err := db.Find(&deliveries).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].EventType != "reservation.created" {
+		t.Fatalf("got %d deliveries, want the one of the reservation only", len(deliveries))
+	}
+
+	// The bus handing the event again, as after a restart
+	var message outbox.Message
+	if err := db.First(&message, "topic = ?", "reservation.created").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := queueDeliveries(context.Background(), message); err != nil {
+		t.Fatal(err)
+	}
+	var queued int64
+	if err := db.Model(&WebhookDelivery{}).Count(&queued).Error; err != nil || queued != 1 {
+		t.Fatalf("got %d deliveries and %v once handed again, want 1", queued, err)
+	}
+}
@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/events"
+	"backend/pkg/helpers"
+
+	"github.com/google/uuid"
+)
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &Entry{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+}
+
+type Action string
+
+const (
+	Created     Action = "create"
+	Updated     Action = "update"
+	Deleted     Action = "delete"
+	HardDeleted Action = "hard_delete"
+)
+
+type Entry struct {
+	common.CommonEntity `gorm:"embedded"`
+	Actor               string       `gorm:"type:varchar(255);not null;index"`
+	Resource            string       `gorm:"type:varchar(255);not null;index:idx_audit_entries_resource_entity"`
+	EntityID            uuid.UUID    `gorm:"type:uuid;not null;index:idx_audit_entries_resource_entity"`
+	Action              Action       `gorm:"type:varchar(32);not null"`
+	Before              helpers.JSON `gorm:"type:jsonb"`
+	After               helpers.JSON `gorm:"type:jsonb"`
+	Changes             helpers.JSON `gorm:"type:jsonb"`
+	RequestID           string       `gorm:"type:varchar(255)"`
+}
+
+func (Entry) TableName() string {
+	return "audit_entries"
+}
+
+// past names the events of the actions
+var past = map[Action]string{
+	Created:     events.Created,
+	Updated:     events.Updated,
+	Deleted:     events.Deleted,
+	HardDeleted: events.Deleted,
+}
+
+// Event is the change the entry records, identified by the entry
+func (e Entry) Event() events.Event {
+	event := events.New(e.ID, e.Resource, past[e.Action], e.EntityID, e.TenantID, e.Before, e.After)
+	event.OccurredAt = e.CreatedAt.UTC()
+	return event
+}
+
+type EntryDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	Actor            string       `json:"actor" tstype:"string,required"`
+	Resource         string       `json:"resource" tstype:"string,required"`
+	EntityID         uuid.UUID    `json:"entity_id" tstype:"string,required"`
+	Action           Action       `json:"action" tstype:"string,required"`
+	Before           helpers.JSON `json:"before" tstype:"Record<string, any> | null"`
+	After            helpers.JSON `json:"after" tstype:"Record<string, any> | null"`
+	Changes          helpers.JSON `json:"changes" tstype:"Record<string, { from: any, to: any }> | null"`
+	RequestID        string       `json:"request_id" tstype:"string"`
+}
+
+func (e Entry) ToDTO() common.DTO {
+	dto := &EntryDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        e.ID,
+			CreatedAt: e.CreatedAt,
+			UpdatedAt: e.UpdatedAt,
+		},
+		Actor:     e.Actor,
+		Resource:  e.Resource,
+		EntityID:  e.EntityID,
+		Action:    e.Action,
+		Before:    e.Before,
+		After:     e.After,
+		Changes:   e.Changes,
+		RequestID: e.RequestID,
+	}
+	return dto
+}
+
+func (e EntryDTO) ToEntity() common.Entity {
+	entity := &Entry{
+		CommonEntity: common.CommonEntity{
+			ID:        e.ID,
+			CreatedAt: e.CreatedAt,
+			UpdatedAt: e.UpdatedAt,
+		},
+		Actor:     e.Actor,
+		Resource:  e.Resource,
+		EntityID:  e.EntityID,
+		Action:    e.Action,
+		Before:    e.Before,
+		After:     e.After,
+		Changes:   e.Changes,
+		RequestID: e.RequestID,
+	}
+	return entity
+}
@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"backend/pkg/common"
+	"backend/pkg/events"
+	"backend/pkg/helpers"
+	"encoding/json"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// Change holds the previous and the new value of a modified field
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Record stores an audit entry for a write made to entity through tx. The
+// actor and the request ID are read from the context bound to tx. Before and
+// after are the entity as seen by the API around the write, either of them
+// may be nil, and are stored without their secrets. The change is emitted
+// as an event once the entry is stored
+func Record(tx *gorm.DB, action Action, resource string, entity common.Entity, before, after common.DTO) error {
+	entry := Entry{
+		Actor:     common.ActorFromContext(tx.Statement.Context),
+		Resource:  resource,
+		EntityID:  entity.GetID(),
+		Action:    action,
+		RequestID: common.RequestIDFromContext(tx.Statement.Context),
+	}
+	if scoped, ok := entity.(common.TenantScoped); ok {
+		entry.TenantID = scoped.GetTenantID()
+	}
+
+	var err error
+	if before != nil {
+		if entry.Before, err = helpers.NewJSON(common.Redact(before)); err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		if entry.After, err = helpers.NewJSON(common.Redact(after)); err != nil {
+			return err
+		}
+	}
+
+	changes, err := Diff(entry.Before, entry.After)
+	if err != nil {
+		return err
+	}
+	if entry.Changes, err = helpers.NewJSON(changes); err != nil {
+		return err
+	}
+
+	if err := tx.Session(&gorm.Session{NewDB: true}).Create(&entry).Error; err != nil {
+		return err
+	}
+	return events.Emit(tx, entry.Event())
+}
+
+// Diff compares two JSON objects field by field and returns the ones that
+// differ. A missing document is treated as an empty object
+func Diff(before, after helpers.JSON) (map[string]Change, error) {
+	from, err := toMap(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := toMap(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]Change{}
+	for field, value := range from {
+		if !reflect.DeepEqual(value, to[field]) {
+			changes[field] = Change{From: value, To: to[field]}
+		}
+	}
+	for field, value := range to {
+		if _, ok := from[field]; !ok {
+			changes[field] = Change{From: nil, To: value}
+		}
+	}
+	return changes, nil
+}
+
+func toMap(document helpers.JSON) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if len(document) == 0 {
+		return values, nil
+	}
+	err := json.Unmarshal(document, &values)
+	return values, err
+}
@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"backend/pkg/common"
+	"backend/pkg/tenancy"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Query narrows the audit log, zero values match everything
+type Query struct {
+	Resource string
+	EntityID uuid.UUID
+	Actor    string
+}
+
+// Find returns a page of audit entries of the current tenant matching query,
+// newest first
+func Find(db *gorm.DB, query Query, pageable common.Pageable) (*common.Page[Entry], error) {
+	db = db.Scopes(tenancy.Scope).Session(&gorm.Session{})
+
+	var entries []Entry
+	limit := pageable.Size
+	offset := (pageable.Page - 1) * pageable.Size
+
+	var count int64
+	if err := db.Model(&Entry{}).Count(&count).Error; err != nil {
+		return nil, err
+	}
+
+	var filtered int64
+	if err := db.Model(&Entry{}).Scopes(query.scope).Count(&filtered).Error; err != nil {
+		return nil, err
+	}
+
+	err := db.
+		Scopes(query.scope).
+		Order("created_at desc").
+		Limit(limit).
+		Offset(offset).
+		Find(&entries).Error
+
+	return &common.Page[Entry]{
+		Items:    entries,
+		Page:     pageable.Page,
+		Size:     pageable.Size,
+		Total:    count,
+		Filtered: filtered,
+	}, err
+}
+
+func (q Query) scope(db *gorm.DB) *gorm.DB {
+	if q.Resource != "" {
+		db = db.Where("resource = ?", q.Resource)
+	}
+	if q.EntityID != uuid.Nil {
+		db = db.Where("entity_id = ?", q.EntityID)
+	}
+	if q.Actor != "" {
+		db = db.Where("actor = ?", q.Actor)
+	}
+	return db
+}
@@ -0,0 +1,71 @@
+package audit_test
+
+import (
+	"backend/database/databasetest"
+	"backend/pkg/audit"
+	"backend/pkg/common"
+
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestFind(t *testing.T) {
+	db := databasetest.Open(t, &audit.Entry{})
+	tenant, other := uuid.New(), uuid.New()
+	entity := uuid.New()
+	start := time.Now().Add(-time.Hour)
+	entries := []audit.Entry{
+		{Actor: "ana", Resource: "reservations", EntityID: entity, Action: audit.Created},
+		{Actor: "bob", Resource: "reservations", EntityID: entity, Action: audit.Updated},
+		{Actor: "ana", Resource: "reservations", EntityID: uuid.New(), Action: audit.Created},
+		{Actor: "ana", Resource: "businesses", EntityID: uuid.New(), Action: audit.Created},
+		{Actor: "ana", Resource: "reservations", EntityID: entity, Action: audit.Deleted},
+	}
+	for i := range entries {
+		entries[i].TenantID = tenant
+		entries[i].CreatedAt = start.Add(time.Duration(i) * time.Minute)
+	}
+	leaked := audit.Entry{Actor: "ana", Resource: "reservations", EntityID: entity, Action: audit.Created}
+	leaked.TenantID = other
+	entries = append(entries, leaked)
+	if err := db.Create(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		query    audit.Query
+		size     int
+		total    int64
+		filtered int64
+		actions  []audit.Action
+	}{
+		{"everything", audit.Query{}, 10, 5, 5, []audit.Action{audit.Deleted, audit.Created, audit.Created, audit.Updated, audit.Created}},
+		{"history", audit.Query{Resource: "reservations", EntityID: entity}, 10, 5, 3, []audit.Action{audit.Deleted, audit.Updated, audit.Created}},
+		{"actor", audit.Query{Actor: "bob"}, 10, 5, 1, []audit.Action{audit.Updated}},
+		{"page", audit.Query{Resource: "reservations"}, 2, 5, 4, []audit.Action{audit.Deleted, audit.Created}},
+	}
+	ctx := common.WithTenant(context.Background(), tenant)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := audit.Find(db.WithContext(ctx), tt.query, common.Pageable{Page: 1, Size: tt.size})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Total != tt.total || page.Filtered != tt.filtered {
+				t.Fatalf("got total %d and filtered %d, want %d and %d", page.Total, page.Filtered, tt.total, tt.filtered)
+			}
+			if len(page.Items) != len(tt.actions) {
+				t.Fatalf("got %d entries, want %d", len(page.Items), len(tt.actions))
+			}
+			for i, entry := range page.Items {
+				if entry.Action != tt.actions[i] || entry.TenantID != tenant {
+					t.Fatalf("entry %d is %s of tenant %s, want %s of tenant %s", i, entry.Action, entry.TenantID, tt.actions[i], tenant)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,3 @@
+// Audit package keeps a record of every write made through the generic repository, and emits each one as an event.
+
+package audit
@@ -0,0 +1,145 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const ContentType = "text/calendar; charset=utf-8"
+
+// MediaType is the type of the iCalendar documents read from requests
+const MediaType = "text/calendar"
+
+type Status string
+
+const (
+	Tentative Status = "TENTATIVE"
+	Confirmed Status = "CONFIRMED"
+	Cancelled Status = "CANCELLED"
+)
+
+// Event is one VEVENT, times are written in UTC
+type Event struct {
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	Status      Status
+	// Sequence must grow every time the event changes, clients ignore
+	// updates that do not increase it
+	Sequence     int
+	Created      time.Time
+	LastModified time.Time
+
+	// AllDay, Rule and Line are only set on the events Read returns. All-day
+	// events start at midnight UTC of their date, Rule is their raw RRULE and
+	// Line the line of the document their VEVENT begins at
+	AllDay bool
+	Rule   string
+	Line   int
+}
+
+// Calendar is a VCALENDAR published to subscribers, cancelled events stay in
+// it with the CANCELLED status so clients drop them
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Write renders c, folding lines at 75 octets and ending them with CRLF
+func Write(w io.Writer, c Calendar) error {
+	writer := &lineWriter{w: bufio.NewWriter(w)}
+	stamp := time.Now()
+
+	writer.line("BEGIN", "VCALENDAR")
+	writer.line("VERSION", "2.0")
+	writer.line("PRODID", c.ProdID)
+	writer.line("CALSCALE", "GREGORIAN")
+	writer.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		writer.line("X-WR-CALNAME", escape(c.Name))
+	}
+	for _, event := range c.Events {
+		writer.line("BEGIN", "VEVENT")
+		writer.line("UID", event.UID)
+		writer.line("DTSTAMP", formatTime(stamp))
+		writer.line("DTSTART", formatTime(event.Start))
+		writer.line("DTEND", formatTime(event.End))
+		writer.line("SUMMARY", escape(event.Summary))
+		if event.Description != "" {
+			writer.line("DESCRIPTION", escape(event.Description))
+		}
+		if event.Location != "" {
+			writer.line("LOCATION", escape(event.Location))
+		}
+		if event.Status != "" {
+			writer.line("STATUS", string(event.Status))
+		}
+		if event.Status == Cancelled {
+			writer.line("TRANSP", "TRANSPARENT")
+		}
+		writer.line("SEQUENCE", fmt.Sprint(event.Sequence))
+		if !event.Created.IsZero() {
+			writer.line("CREATED", formatTime(event.Created))
+		}
+		if !event.LastModified.IsZero() {
+			writer.line("LAST-MODIFIED", formatTime(event.LastModified))
+		}
+		writer.line("END", "VEVENT")
+	}
+	writer.line("END", "VCALENDAR")
+
+	if writer.err != nil {
+		return writer.err
+	}
+	return writer.w.Flush()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escape applies the TEXT escaping of RFC 5545 section 3.3.11
+var escape = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+).Replace
+
+// lineWriter writes content lines, keeping the first error
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes name:value folded as RFC 5545 section 3.1 requires, without
+// splitting multi-byte characters
+func (l *lineWriter) line(name string, value string) {
+	if l.err != nil {
+		return
+	}
+	content := name + ":" + value
+	limit := 75
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		if _, l.err = l.w.WriteString(content[:cut] + "\r\n "); l.err != nil {
+			return
+		}
+		content = content[cut:]
+		// Continuation lines start with a space, which counts
+		limit = 74
+	}
+	_, l.err = l.w.WriteString(content + "\r\n")
+}
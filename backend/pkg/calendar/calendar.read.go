@@ -0,0 +1,226 @@
+package calendar
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Read parses the VEVENTs of an iCalendar document. Dates without a time
+// give all-day events, times with a TZID are read in that zone and floating
+// times in UTC. A missing DTEND ends the event a day after an all-day start,
+// or at its start, unless a DURATION is given. Recurring events are returned
+// once, with their RRULE, for the caller to expand or refuse
+func Read(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []Event{}
+	var event *Event
+	var duration time.Duration
+	// Components nested in an event, like VALARM, are skipped
+	depth := 0
+	for _, line := range lines {
+		name, params, value, err := parseLine(line.content)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.number, err)
+		}
+
+		switch {
+		case name == "BEGIN" && event == nil && value == "VEVENT":
+			event = &Event{Line: line.number}
+			duration = 0
+			continue
+		case name == "BEGIN" && event != nil:
+			depth++
+			continue
+		case name == "END" && event != nil && depth > 0:
+			depth--
+			continue
+		case name == "END" && event != nil && value == "VEVENT":
+			if event.Start.IsZero() {
+				return nil, fmt.Errorf("line %d: the event has no DTSTART", event.Line)
+			}
+			if event.End.IsZero() {
+				switch {
+				case duration > 0:
+					event.End = event.Start.Add(duration)
+				case event.AllDay:
+					event.End = event.Start.AddDate(0, 0, 1)
+				default:
+					event.End = event.Start
+				}
+			}
+			events = append(events, *event)
+			event = nil
+			continue
+		case event == nil || depth > 0:
+			continue
+		}
+
+		switch name {
+		case "UID":
+			event.UID = value
+		case "SUMMARY":
+			event.Summary = unescape(value)
+		case "DESCRIPTION":
+			event.Description = unescape(value)
+		case "LOCATION":
+			event.Location = unescape(value)
+		case "STATUS":
+			event.Status = Status(strings.ToUpper(value))
+		case "RRULE":
+			event.Rule = value
+		case "DTSTART":
+			event.Start, event.AllDay, err = parseTime(params, value)
+		case "DTEND":
+			event.End, _, err = parseTime(params, value)
+		case "DURATION":
+			duration, err = parseDuration(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", line.number, name, err)
+		}
+	}
+	if event != nil {
+		return nil, fmt.Errorf("line %d: the event is not closed", event.Line)
+	}
+	return events, nil
+}
+
+type contentLine struct {
+	number  int
+	content string
+}
+
+// unfold joins the lines folded as RFC 5545 section 3.1 describes, keeping
+// the number of the line each one starts at
+func unfold(r io.Reader) ([]contentLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lines := []contentLine{}
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if number == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1].content += text[1:]
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		lines = append(lines, contentLine{number: number, content: text})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || !strings.EqualFold(lines[0].content, "BEGIN:VCALENDAR") {
+		return nil, errors.New("the document is not an iCalendar")
+	}
+	return lines, nil
+}
+
+// parseLine splits name;param=value;...:value, colons inside quoted
+// parameter values do not end the parameters
+func parseLine(line string) (string, map[string]string, string, error) {
+	quoted := false
+	colon := -1
+	for i, char := range line {
+		if char == '"' {
+			quoted = !quoted
+		}
+		if char == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", fmt.Errorf("%q is not a content line", line)
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	params := map[string]string{}
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:], nil
+}
+
+func parseTime(params map[string]string, value string) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		date, err := time.Parse("20060102", value)
+		return date, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		instant, err := time.Parse("20060102T150405Z", value)
+		return instant, false, err
+	}
+	location := time.UTC
+	if zone, ok := params["TZID"]; ok {
+		var err error
+		if location, err = time.LoadLocation(zone); err != nil {
+			return time.Time{}, false, err
+		}
+	}
+	instant, err := time.ParseInLocation("20060102T150405", value, location)
+	return instant, false, err
+}
+
+// parseDuration reads the dur-value of RFC 5545 section 3.3.6, like P1D,
+// PT1H30M or P2W
+func parseDuration(value string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(strings.TrimPrefix(value, "+"), "P")
+	if !ok || rest == "" {
+		return 0, fmt.Errorf("%q is not a duration", value)
+	}
+	units := map[byte]time.Duration{
+		'W': 7 * 24 * time.Hour,
+		'D': 24 * time.Hour,
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+	}
+	var duration time.Duration
+	number := ""
+	for i := 0; i < len(rest); i++ {
+		char := rest[i]
+		switch {
+		case char == 'T':
+		case char >= '0' && char <= '9':
+			number += string(char)
+		case units[char] > 0 && number != "":
+			n, err := strconv.Atoi(number)
+			if err != nil {
+				return 0, err
+			}
+			duration += time.Duration(n) * units[char]
+			number = ""
+		default:
+			return 0, fmt.Errorf("%q is not a duration", value)
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("%q is not a duration", value)
+	}
+	return duration, nil
+}
+
+// unescape reverts the TEXT escaping of RFC 5545 section 3.3.11
+var unescape = strings.NewReplacer(
+	`\\`, `\`,
+	`\;`, ";",
+	`\,`, ",",
+	`\n`, "\n",
+	`\N`, "\n",
+).Replace
@@ -0,0 +1,3 @@
+// Calendar package renders events as iCalendar (RFC 5545) documents and reads them back.
+
+package calendar
@@ -0,0 +1,115 @@
+package clock
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// Clock is a time of day on a wall clock, written 15:04:05. It is kept as a
+// string so every API describes it as one, and sorts in time order
+type Clock string
+
+const layout = "15:04:05"
+
+// layouts are the forms Parse accepts. Full timestamps are how times of day
+// used to be stored, their clock is kept as written
+var layouts = []string{
+	layout,
+	"15:04",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+}
+
+// Parse reads a time of day like 09:30 or 21:15:30
+func Parse(value string) (Clock, error) {
+	for _, l := range layouts {
+		if instant, err := time.Parse(l, value); err == nil {
+			return Of(instant), nil
+		}
+	}
+	return "", fmt.Errorf("%q is not a time of day, use HH:MM or HH:MM:SS", value)
+}
+
+// Of is the time of day instant shows in its location
+func Of(instant time.Time) Clock {
+	return Clock(instant.Format(layout))
+}
+
+// Offset is the time from midnight to c on a day without DST transition
+func (c Clock) Offset() time.Duration {
+	instant, err := time.Parse(layout, string(c))
+	if err != nil {
+		return 0
+	}
+	return instant.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+// On places c on the date of day, in the location of day. A time skipped by
+// a DST transition is moved forward by the length of the gap, as time.Date
+// normalizes it
+func (c Clock) On(day time.Time) time.Time {
+	offset := c.Offset()
+	year, month, date := day.Date()
+	return time.Date(year, month, date, int(offset/time.Hour), int(offset%time.Hour/time.Minute), int(offset%time.Minute/time.Second), 0, day.Location())
+}
+
+// First is the first instant the wall clock shows c on the date of day, in
+// the location of day. A time repeated as DST ends is its first occurrence,
+// and a time skipped as DST starts is the end of the gap, when the clock
+// first shows a later time
+func (c Clock) First(day time.Time) time.Time {
+	instant := c.On(day)
+	start, _ := instant.ZoneBounds()
+	if start.IsZero() {
+		return instant
+	}
+	_, offset := instant.Zone()
+	_, previous := start.Add(-time.Nanosecond).Zone()
+	switch {
+	case previous > offset:
+		earlier := instant.Add(-time.Duration(previous-offset) * time.Second)
+		if earlier.Before(start) && Of(earlier) == c {
+			return earlier
+		}
+	case previous < offset && Of(instant) != c:
+		return start
+	}
+	return instant
+}
+
+func (c *Clock) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// Scan reads the times of day stored as text, and the timestamps they used
+// to be stored as
+func (c *Clock) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = ""
+		return nil
+	case time.Time:
+		*c = Of(v)
+		return nil
+	case []byte:
+		return c.UnmarshalText(v)
+	case string:
+		return c.UnmarshalText([]byte(v))
+	}
+	return fmt.Errorf("cannot scan %T into a time of day", value)
+}
+
+func (c Clock) Value() (driver.Value, error) {
+	if c == "" {
+		return nil, nil
+	}
+	return string(c), nil
+}
@@ -0,0 +1,99 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+// The last Sundays of March and October 2026, when DST starts and ends in
+// Europe/Madrid at 02:00 and 03:00, and in Atlantic/Canary at 01:00 and 02:00
+var (
+	springForward = time.Date(2026, time.March, 29, 12, 0, 0, 0, time.UTC)
+	fallBack      = time.Date(2026, time.October, 25, 12, 0, 0, 0, time.UTC)
+)
+
+func zone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("no time zone data for %s: %s", name, err)
+	}
+	return location
+}
+
+func utc(t *testing.T, value string) time.Time {
+	t.Helper()
+	instant, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return instant
+}
+
+func TestOnAndFirst(t *testing.T) {
+	tests := []struct {
+		name  string
+		zone  string
+		day   time.Time
+		clock Clock
+		on    string
+		first string
+	}{
+		{"madrid before spring forward", "Europe/Madrid", springForward, "01:30:00", "2026-03-29T00:30:00Z", "2026-03-29T00:30:00Z"},
+		{"madrid skipped hour", "Europe/Madrid", springForward, "02:30:00", "2026-03-29T01:30:00Z", "2026-03-29T01:00:00Z"},
+		{"madrid start of the gap", "Europe/Madrid", springForward, "02:00:00", "2026-03-29T01:00:00Z", "2026-03-29T01:00:00Z"},
+		{"madrid after spring forward", "Europe/Madrid", springForward, "03:30:00", "2026-03-29T01:30:00Z", "2026-03-29T01:30:00Z"},
+		{"madrid repeated hour", "Europe/Madrid", fallBack, "02:30:00", "2026-10-25T01:30:00Z", "2026-10-25T00:30:00Z"},
+		{"madrid start of the repeated hour", "Europe/Madrid", fallBack, "02:00:00", "2026-10-25T01:00:00Z", "2026-10-25T00:00:00Z"},
+		{"madrid after fall back", "Europe/Madrid", fallBack, "03:00:00", "2026-10-25T02:00:00Z", "2026-10-25T02:00:00Z"},
+		{"madrid evening after fall back", "Europe/Madrid", fallBack, "21:00:00", "2026-10-25T20:00:00Z", "2026-10-25T20:00:00Z"},
+		{"canary before spring forward", "Atlantic/Canary", springForward, "00:30:00", "2026-03-29T00:30:00Z", "2026-03-29T00:30:00Z"},
+		{"canary skipped hour", "Atlantic/Canary", springForward, "01:30:00", "2026-03-29T01:30:00Z", "2026-03-29T01:00:00Z"},
+		{"canary after spring forward", "Atlantic/Canary", springForward, "02:30:00", "2026-03-29T01:30:00Z", "2026-03-29T01:30:00Z"},
+		{"canary repeated hour", "Atlantic/Canary", fallBack, "01:30:00", "2026-10-25T01:30:00Z", "2026-10-25T00:30:00Z"},
+		{"canary after fall back", "Atlantic/Canary", fallBack, "02:30:00", "2026-10-25T02:30:00Z", "2026-10-25T02:30:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day := tt.day.In(zone(t, tt.zone))
+			if got := tt.clock.On(day); !got.Equal(utc(t, tt.on)) {
+				t.Errorf("On got %s, want %s", got.UTC(), tt.on)
+			}
+			if got := tt.clock.First(day); !got.Equal(utc(t, tt.first)) {
+				t.Errorf("First got %s, want %s", got.UTC(), tt.first)
+			}
+		})
+	}
+}
+
+func TestOnKeepsTheDate(t *testing.T) {
+	madrid := zone(t, "Europe/Madrid")
+	for _, day := range []time.Time{springForward, fallBack} {
+		local := day.In(madrid)
+		for _, c := range []Clock{"00:00:00", "23:59:59"} {
+			if got := c.On(local); got.YearDay() != local.YearDay() {
+				t.Errorf("%s on %s fell on %s", c, local.Format(time.DateOnly), got)
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Clock
+		err   bool
+	}{
+		{"09:30", "09:30:00", false},
+		{"21:15:30", "21:15:30", false},
+		{"0000-01-01T18:00:00Z", "18:00:00", false},
+		{"25:00", "", true},
+		{"noon", "", true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.value)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("Parse(%q) got %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
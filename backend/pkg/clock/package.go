@@ -0,0 +1,3 @@
+// Clock package models wall-clock times of day, like opening hours, which only become instants once placed on a date in a time zone.
+
+package clock
@@ -0,0 +1,407 @@
+package codegen
+
+import (
+	"backend/pkg/common"
+	"backend/pkg/generics"
+	"backend/pkg/openapi"
+	_ "embed"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+const header = "// Code generated by `backend codegen ts`. DO NOT EDIT.\n\n"
+
+//go:embed templates/lib.ts
+var lib string
+
+type File struct {
+	Name    string
+	Content string
+}
+
+// methodNames names the client methods of the generic routes, any other route
+// is named after its RouteDefinition
+var methodNames = map[string]string{
+	"GET /":            "list",
+	"GET /count":       "count",
+	"GET /deleted":     "listDeleted",
+	"POST /bulk":       "bulkCreate",
+	"PATCH /bulk":      "bulkUpdate",
+	"DELETE /bulk":     "bulkDelete",
+	"GET /:id":         "get",
+	"POST /":           "create",
+	"PUT /:id":         "update",
+	"DELETE /:id":      "delete",
+	"GET /:id/history": "history",
+	"DELETE /:id/hard": "hardDelete",
+	"POST /import":     "import",
+}
+
+var pathParameter = regexp.MustCompile(`:(\w+)`)
+
+// TypeScript generates the runtime, the models and one client per controller,
+// plus an index creating all of them
+func TypeScript(controllers map[string]generics.GenericController, extraRoutes map[string][]generics.RouteDefinition) []File {
+	g := &generator{
+		registry: openapi.NewRegistry(),
+		dtos:     map[string]bool{},
+	}
+
+	keys := make([]string, 0, len(controllers))
+	for key := range controllers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// DTOs first, so payloads can be told apart from other types
+	for _, key := range keys {
+		ref := g.registry.SchemaOf(controllers[key].GetDTO())
+		g.dtos[g.typeOf(ref)] = true
+	}
+
+	files := []File{{Name: "lib.ts", Content: lib}}
+	index := &strings.Builder{}
+	index.WriteString(header)
+	index.WriteString("import type { ClientConfig } from \"./lib\";\n")
+	for _, key := range keys {
+		fmt.Fprintf(index, "import { %s } from \"./%s\";\n", factoryName(key), key)
+	}
+	index.WriteString("\nexport * from \"./lib\";\nexport * from \"./models\";\n")
+	for _, key := range keys {
+		fmt.Fprintf(index, "export * from \"./%s\";\n", key)
+	}
+	index.WriteString("\nexport const createApiClient = (config: ClientConfig) => ({\n")
+	for _, key := range keys {
+		fmt.Fprintf(index, "  %s: %s(config),\n", strcase.ToLowerCamel(key), factoryName(key))
+	}
+	index.WriteString("});\n")
+
+	for _, key := range keys {
+		controller := controllers[key]
+		routes := append(controller.Routes(), extraRoutes[key]...)
+		files = append(files, File{Name: key + ".ts", Content: g.client(key, controller, routes)})
+	}
+
+	files = append(files, File{Name: "models.ts", Content: g.models()}, File{Name: "index.ts", Content: index.String()})
+	return files
+}
+
+type generator struct {
+	registry *openapi.Registry
+	dtos     map[string]bool
+}
+
+// client renders the filter builder and the client factory of one resource
+func (g *generator) client(key string, controller generics.GenericController, routes []generics.RouteDefinition) string {
+	names := controller.GetResourceNames()
+	fieldType := strcase.ToCamel(names.Singular) + "Field"
+	imports := newImports()
+
+	body := &strings.Builder{}
+	fmt.Fprintf(body, "/** Columns of %s usable in filters and orders */\n", key)
+	fields := []string{}
+	for _, field := range g.fields(controller.GetDTO()) {
+		fields = append(fields, fmt.Sprintf("%q", field))
+	}
+	fmt.Fprintf(body, "export type %s =\n  | %s;\n\n", fieldType, strings.Join(fields, "\n  | "))
+
+	imports.value("filterBuilder")
+	fmt.Fprintf(body, "export const %sFilters = filterBuilder<%s>();\n\n", strcase.ToLowerCamel(names.Singular), fieldType)
+
+	imports.value("request")
+	imports.lib("ClientConfig")
+	fmt.Fprintf(body, "export const %s = (config: ClientConfig) => ({\n", factoryName(key))
+	for _, route := range routes {
+		g.method(body, imports, key, fieldType, route)
+	}
+	body.WriteString("});\n")
+
+	return header + imports.render() + "\n" + body.String()
+}
+
+func (g *generator) method(body *strings.Builder, imports *imports, key string, fieldType string, route generics.RouteDefinition) {
+	name, ok := methodNames[route.Verb+" "+route.Path]
+	if !ok {
+		name = strcase.ToLowerCamel(route.Name)
+		if route.Name == "" {
+			name = strcase.ToLowerCamel(route.Verb + " " + route.Path)
+		}
+	}
+
+	arguments := []string{}
+	for _, match := range pathParameter.FindAllStringSubmatch(route.Path, -1) {
+		arguments = append(arguments, match[1]+": string")
+	}
+	path := pathParameter.ReplaceAllString("/"+key+route.Path, "${encodeURIComponent($1)}")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	options := []string{}
+	if route.Request != nil {
+		arguments = append(arguments, "payload: "+g.payloadType(imports, route.Request))
+		options = append(options, "body: payload")
+	}
+	if route.Accepts != "" {
+		arguments = append(arguments, "document: Blob | string")
+		options = append(options, "body: document", fmt.Sprintf("contentType: %q", route.Accepts))
+	}
+
+	if len(route.Query) > 0 {
+		fields := []string{}
+		for _, query := range route.Query {
+			// Fields only shape exports, which get their own method
+			if query.Name == generics.FieldsQuery.Name && len(route.Exports) > 0 {
+				continue
+			}
+			fields = append(fields, fmt.Sprintf("%s?: %s", query.Name, queryType(imports, fieldType, query)))
+		}
+		arguments = append(arguments, fmt.Sprintf("query: { %s } = {}", strings.Join(fields, "; ")))
+		options = append(options, "query")
+	}
+
+	if route.ContentType != "" {
+		imports.value("url")
+		if route.Name != "" {
+			fmt.Fprintf(body, "  /** %s, as a URL */\n", route.Name)
+		}
+		fmt.Fprintf(body, "  %s: (%s) =>\n", name, strings.Join(arguments, ", "))
+		fmt.Fprintf(body, "    url(config, `%s`", path)
+		if len(route.Query) > 0 {
+			body.WriteString(", query")
+		}
+		body.WriteString("),\n")
+		return
+	}
+
+	response := "void"
+	if route.Response != nil {
+		response = g.responseType(imports, route.Response)
+	}
+
+	if route.Name != "" {
+		fmt.Fprintf(body, "  /** %s */\n", route.Name)
+	}
+	fmt.Fprintf(body, "  %s: (%s) =>\n", name, strings.Join(arguments, ", "))
+	fmt.Fprintf(body, "    request<%s>(config, %q, `%s`", response, route.Verb, path)
+	if len(options) > 0 {
+		fmt.Fprintf(body, ", { %s }", strings.Join(options, ", "))
+	}
+	body.WriteString("),\n")
+
+	if len(route.Exports) > 0 {
+		g.export(body, imports, name, fieldType, path, route)
+	}
+}
+
+// exportNames names the methods downloading the exports of the generic
+// routes, any other route gets the Export suffix
+var exportNames = map[string]string{
+	"list":        "export",
+	"listDeleted": "exportDeleted",
+}
+
+// export renders the method downloading every row a route can export, which
+// takes the filters, orders and fields of its query
+func (g *generator) export(body *strings.Builder, imports *imports, method string, fieldType string, path string, route generics.RouteDefinition) {
+	name, ok := exportNames[method]
+	if !ok {
+		name = method + "Export"
+	}
+
+	formats := make([]string, len(route.Exports))
+	for i, mediaType := range route.Exports {
+		formats[i] = fmt.Sprintf("%q", mediaType)
+	}
+	fields := []string{}
+	for _, query := range route.Query {
+		switch query.Name {
+		case generics.FiltersQuery.Name, generics.OrdersQuery.Name, generics.FieldsQuery.Name:
+			fields = append(fields, fmt.Sprintf("%s?: %s", query.Name, queryType(imports, fieldType, query)))
+		}
+	}
+
+	imports.value("download")
+	if route.Name != "" {
+		fmt.Fprintf(body, "  /** %s, as a file */\n", route.Name)
+	}
+	fmt.Fprintf(body, "  %s: (format: %s, query: { %s } = {}) =>\n", name, strings.Join(formats, " | "), strings.Join(fields, "; "))
+	fmt.Fprintf(body, "    download(config, `%s`, format, { query }),\n", path)
+}
+
+// payloadType is the type of a request body, DTOs are sent without the fields
+// managed by the server
+func (g *generator) payloadType(imports *imports, value interface{}) string {
+	schema := g.registry.SchemaOf(value)
+	if schema.Ref != "" && g.dtos[g.typeOf(schema)] {
+		imports.lib("Input")
+		return fmt.Sprintf("Input<%s>", g.tsType(imports, schema))
+	}
+	if schema.Items != nil && schema.Items.Ref != "" && g.dtos[g.typeOf(schema.Items)] {
+		imports.lib("Input")
+		return fmt.Sprintf("Input<%s>[]", g.tsType(imports, schema.Items))
+	}
+	return g.tsType(imports, schema)
+}
+
+// responseType is the type of the data of the envelope, keeping pages generic
+func (g *generator) responseType(imports *imports, value interface{}) string {
+	t := reflect.TypeOf(value)
+	if t.PkgPath() == reflect.TypeOf(common.Page[any]{}).PkgPath() && strings.HasPrefix(t.Name(), "Page[") {
+		items, _ := t.FieldByName("Items")
+		imports.lib("Page")
+		element := g.registry.SchemaOf(reflect.Zero(items.Type.Elem()).Interface())
+		return fmt.Sprintf("Page<%s>", g.tsType(imports, element))
+	}
+	return g.tsType(imports, g.registry.SchemaOf(value))
+}
+
+func queryType(imports *imports, fieldType string, query generics.QueryParameter) string {
+	switch query.Name {
+	case generics.FiltersQuery.Name:
+		imports.lib("Filter")
+		return fmt.Sprintf("Filter<%s>[]", fieldType)
+	case generics.OrdersQuery.Name:
+		imports.lib("Order")
+		return fmt.Sprintf("Order<%s>[]", fieldType)
+	case generics.RelationsQuery.Name:
+		return "string[]"
+	case generics.FieldsQuery.Name:
+		return fieldType + "[]"
+	case generics.BulkModeQuery.Name:
+		imports.lib("BulkMode")
+		return "BulkMode"
+	case generics.DryRunQuery.Name:
+		return "boolean"
+	case "page", "size":
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+// fields lists the columns behind the properties of dto, which is what the
+// filters and orders query parameters expect
+func (g *generator) fields(dto common.DTO) []string {
+	schema := g.registry.Schemas()[g.typeOf(g.registry.SchemaOf(dto))]
+	fields := []string{}
+	for _, property := range schema.PropertyNames() {
+		fields = append(fields, strcase.ToSnake(property))
+	}
+	return fields
+}
+
+// models renders every named type collected while generating the clients
+func (g *generator) models() string {
+	schemas := g.registry.Schemas()
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	models := &strings.Builder{}
+	models.WriteString(header)
+	for i, name := range names {
+		if i > 0 {
+			models.WriteString("\n")
+		}
+		fmt.Fprintf(models, "export interface %s %s\n", typeName(name), g.objectType(nil, schemas[name], ""))
+	}
+	return models.String()
+}
+
+func (g *generator) tsType(imports *imports, schema *openapi.Schema) string {
+	if schema.Ref != "" {
+		name := g.typeOf(schema)
+		if imports != nil {
+			imports.model(typeName(name))
+		}
+		return typeName(name)
+	}
+
+	switch schema.Type {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		return g.tsType(imports, schema.Items) + "[]"
+	case "object":
+		if schema.AdditionalProperties != nil {
+			return fmt.Sprintf("Record<string, %s>", g.tsType(imports, schema.AdditionalProperties))
+		}
+		return g.objectType(imports, schema, "")
+	default:
+		return "unknown"
+	}
+}
+
+func (g *generator) objectType(imports *imports, schema *openapi.Schema, indent string) string {
+	object := &strings.Builder{}
+	object.WriteString("{\n")
+	for _, property := range schema.PropertyNames() {
+		fmt.Fprintf(object, "%s  %s: %s;\n", indent, property, g.tsType(imports, schema.Properties[property]))
+	}
+	object.WriteString(indent + "}")
+	return object.String()
+}
+
+func (g *generator) typeOf(ref *openapi.Schema) string {
+	return strings.TrimPrefix(ref.Ref, "#/components/schemas/")
+}
+
+// typeName turns a component name into a TypeScript identifier, components
+// clashing with another package are prefixed with it
+func typeName(name string) string {
+	return strings.ReplaceAll(name, ".", "_")
+}
+
+func factoryName(key string) string {
+	return "create" + strcase.ToCamel(key) + "Client"
+}
+
+// imports collects what a client file uses, so no unused import is emitted
+type imports struct {
+	values map[string]bool
+	libs   map[string]bool
+	models map[string]bool
+}
+
+func newImports() *imports {
+	return &imports{values: map[string]bool{}, libs: map[string]bool{}, models: map[string]bool{}}
+}
+
+func (i *imports) value(name string) { i.values[name] = true }
+func (i *imports) lib(name string)   { i.libs[name] = true }
+func (i *imports) model(name string) { i.models[name] = true }
+
+func (i *imports) render() string {
+	rendered := &strings.Builder{}
+	if len(i.values) > 0 {
+		fmt.Fprintf(rendered, "import { %s } from \"./lib\";\n", strings.Join(sortedKeys(i.values), ", "))
+	}
+	if len(i.libs) > 0 {
+		fmt.Fprintf(rendered, "import type { %s } from \"./lib\";\n", strings.Join(sortedKeys(i.libs), ", "))
+	}
+	if len(i.models) > 0 {
+		fmt.Fprintf(rendered, "import type { %s } from \"./models\";\n", strings.Join(sortedKeys(i.models), ", "))
+	}
+	return rendered.String()
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,3 @@
+// Codegen package generates the frontend API client from the registered controllers.
+
+package codegen
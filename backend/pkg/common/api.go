@@ -2,10 +2,12 @@ package common
 
 import (
 	"backend/pkg/helpers"
+	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/iancoleman/strcase"
 )
 
@@ -31,6 +33,92 @@ type Page[T any] struct {
 	Filtered int64 `json:"filtered"`
 }
 
+// BulkMode decides what happens to the rest of a bulk request when one of
+// its items fails
+type BulkMode string
+
+const (
+	// AllOrNothing rolls back every item if any of them fails
+	AllOrNothing BulkMode = "all_or_nothing"
+	// BestEffort commits the items that succeeded and reports the rest
+	BestEffort BulkMode = "best_effort"
+)
+
+type BulkItemResult struct {
+	Index  int                         `json:"index"`
+	ID     uuid.UUID                   `json:"id,omitempty"`
+	Status ResponseStatus              `json:"status"`
+	Data   interface{}                 `json:"data,omitempty"`
+	Error  string                      `json:"error,omitempty"`
+	Errors []*helpers.ValidationErrors `json:"errors,omitempty"`
+}
+
+type BulkResult struct {
+	Mode      BulkMode         `json:"mode"`
+	Committed bool             `json:"committed"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Items     []BulkItemResult `json:"items"`
+}
+
+func NewBulkResult(mode BulkMode, size int) BulkResult {
+	return BulkResult{
+		Mode:  mode,
+		Items: make([]BulkItemResult, 0, size),
+	}
+}
+
+// Add appends an item result and updates the counters
+func (r *BulkResult) Add(item BulkItemResult) {
+	if item.Status == Error {
+		r.Failed++
+	} else {
+		r.Succeeded++
+	}
+	r.Items = append(r.Items, item)
+}
+
+// ImportAction tells what an import did with a row
+type ImportAction string
+
+const (
+	ImportCreated ImportAction = "created"
+	ImportUpdated ImportAction = "updated"
+)
+
+type ImportRowResult struct {
+	Line   int                         `json:"line"`
+	ID     uuid.UUID                   `json:"id,omitempty"`
+	Action ImportAction                `json:"action,omitempty"`
+	Status ResponseStatus              `json:"status"`
+	Error  string                      `json:"error,omitempty"`
+	Errors []*helpers.ValidationErrors `json:"errors,omitempty"`
+}
+
+// ImportResult reports what an import did, or would have done on a dry run,
+// with every row of the document
+type ImportResult struct {
+	DryRun    bool              `json:"dry_run"`
+	Committed bool              `json:"committed"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Failed    int               `json:"failed"`
+	Rows      []ImportRowResult `json:"rows"`
+}
+
+// Add appends a row result and updates the counters
+func (r *ImportResult) Add(row ImportRowResult) {
+	switch {
+	case row.Status == Error:
+		r.Failed++
+	case row.Action == ImportCreated:
+		r.Created++
+	case row.Action == ImportUpdated:
+		r.Updated++
+	}
+	r.Rows = append(r.Rows, row)
+}
+
 func NewPage[T any](items []T, page int, size int, total int64, filtered int64) Page[T] {
 	return Page[T]{
 		Items:    items,
@@ -61,6 +149,24 @@ func NewErrorResponse(err error, message string) ApiResponse[any] {
 	}
 }
 
+func NewBulkErrorResponse(result BulkResult, message string) ApiResponse[BulkResult] {
+	return ApiResponse[BulkResult]{
+		Status:  Error,
+		Message: message,
+		Error:   fmt.Sprintf("%d of %d items failed", result.Failed, len(result.Items)),
+		Data:    result,
+	}
+}
+
+func NewImportErrorResponse(result ImportResult, message string) ApiResponse[ImportResult] {
+	return ApiResponse[ImportResult]{
+		Status:  Error,
+		Message: message,
+		Error:   fmt.Sprintf("%d of %d rows failed", result.Failed, len(result.Rows)),
+		Data:    result,
+	}
+}
+
 func NewValidationErrorResponse(errors []*helpers.ValidationErrors, message string) ApiResponse[any] {
 	return ApiResponse[any]{
 		Status:  Error,
@@ -120,6 +226,16 @@ func PageableFromQuery(c *fiber.Ctx) (Pageable, error) {
 	}, nil
 }
 
+func BulkModeFromQuery(c *fiber.Ctx) (BulkMode, error) {
+	mode := BulkMode(c.Query("mode", string(AllOrNothing)))
+	switch mode {
+	case AllOrNothing, BestEffort:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown bulk mode %q, expected %q or %q", mode, AllOrNothing, BestEffort)
+	}
+}
+
 func RelationsFromQuery(c *fiber.Ctx) []string {
 	relations := c.Query("relations", "")
 	if relations == "" {
@@ -0,0 +1,80 @@
+package common
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type contextKey string
+
+const (
+	actorKey     contextKey = "actor"
+	requestIDKey contextKey = "request_id"
+	tenantKey    contextKey = "tenant"
+	systemKey    contextKey = "system"
+)
+
+// SystemActor is reported for writes made outside of an HTTP request,
+// for example from the CLI
+const SystemActor = "system"
+
+// WithActor returns a copy of ctx carrying the identity of whoever is
+// making the request
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// ActorFromContext returns the actor stored in ctx, or SystemActor if none
+func ActorFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if actor, ok := ctx.Value(actorKey).(string); ok && actor != "" {
+			return actor
+		}
+	}
+	return SystemActor
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if requestID, ok := ctx.Value(requestIDKey).(string); ok {
+			return requestID
+		}
+	}
+	return ""
+}
+
+// WithTenant returns a copy of ctx restricted to the data of one tenant
+func WithTenant(ctx context.Context, tenantID uuid.UUID) context.Context {
+	return context.WithValue(ctx, tenantKey, tenantID)
+}
+
+// TenantFromContext returns the tenant stored in ctx, if any
+func TenantFromContext(ctx context.Context) (uuid.UUID, bool) {
+	if ctx != nil {
+		if tenantID, ok := ctx.Value(tenantKey).(uuid.UUID); ok && tenantID != uuid.Nil {
+			return tenantID, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+// AsSystem returns a copy of ctx that is allowed to read and write the data
+// of every tenant. Only use it for maintenance tasks that are not triggered
+// by a request, such as CLI commands
+func AsSystem(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemKey, true)
+}
+
+func IsSystem(ctx context.Context) bool {
+	if ctx != nil {
+		if system, ok := ctx.Value(systemKey).(bool); ok {
+			return system
+		}
+	}
+	return false
+}
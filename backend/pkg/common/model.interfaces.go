@@ -26,6 +26,13 @@ type Identifiable interface {
 	SetID(id uuid.UUID)
 }
 
+// TenantScoped interface is used to read and assign the tenant owning an Entity
+// Entities that embed base.Entity have this interface implemented
+type TenantScoped interface {
+	GetTenantID() uuid.UUID
+	SetTenantID(id uuid.UUID)
+}
+
 // Dtoable interface is used to convert Entity to DTO
 // This interface must be implemented by all DTOs
 type Dtoable interface {
@@ -43,3 +50,18 @@ type Validable interface {
 type Entityable interface {
 	ToEntity() Entity
 }
+
+// Redactable interface is used to leave the secrets of a DTO out of the
+// copies kept or sent elsewhere, such as the audit log and the events
+// DTOs holding passwords, secrets or tokens must implement this interface
+type Redactable interface {
+	Redacted() DTO
+}
+
+// Redact returns dto without its secrets
+func Redact(dto DTO) DTO {
+	if redactable, ok := dto.(Redactable); ok {
+		return redactable.Redacted()
+	}
+	return dto
+}
@@ -14,6 +14,7 @@ import (
 // For example: type User struct { common.CommonEntity `gorm:"embedded"` }
 type CommonEntity struct {
 	ID        uuid.UUID `gorm:"primaryKey;type:uuid"`
+	TenantID  uuid.UUID `gorm:"type:uuid;index"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
@@ -44,6 +45,14 @@ func (e *CommonEntity) SetID(id uuid.UUID) {
 	e.ID = id
 }
 
+func (e CommonEntity) GetTenantID() uuid.UUID {
+	return e.TenantID
+}
+
+func (e *CommonEntity) SetTenantID(id uuid.UUID) {
+	e.TenantID = id
+}
+
 func (e CommonDTO) GetID() uuid.UUID {
 	return e.ID
 }
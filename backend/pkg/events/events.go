@@ -0,0 +1,90 @@
+package events
+
+import (
+	"backend/pkg/helpers"
+
+	"encoding/json"
+	"time"
+
+	"github.com/gertd/go-pluralize"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Actions of an event, the last part of its type
+const (
+	Created = "created"
+	Updated = "updated"
+	Deleted = "deleted"
+)
+
+// Event is a change of an entity. Type is the singular of the resource and
+// the action, like reservation.created. Data is the entity after the change,
+// or before it when it was deleted, and Previous the entity before an update.
+// BusinessID is the business the entity is or belongs to, when it has one
+type Event struct {
+	ID         uuid.UUID    `json:"id"`
+	Type       string       `json:"type"`
+	Resource   string       `json:"resource"`
+	EntityID   uuid.UUID    `json:"entity_id"`
+	TenantID   uuid.UUID    `json:"-"`
+	BusinessID *uuid.UUID   `json:"business_id,omitempty"`
+	Data       helpers.JSON `json:"data"`
+	Previous   helpers.JSON `json:"previous,omitempty"`
+	OccurredAt time.Time    `json:"occurred_at"`
+}
+
+// Listener is told about an event within the transaction of the change, an
+// error rolls the change back
+type Listener func(tx *gorm.DB, event Event) error
+
+var (
+	listeners []Listener
+	plurals   = pluralize.NewClient()
+)
+
+// Listen adds listener to the ones told about every event. Listeners are
+// added from init functions, before any event is emitted
+func Listen(listener Listener) {
+	listeners = append(listeners, listener)
+}
+
+// New returns the event id of action on the entity id of resource
+func New(id uuid.UUID, resource string, action string, entity uuid.UUID, tenant uuid.UUID, before helpers.JSON, after helpers.JSON) Event {
+	event := Event{
+		ID:         id,
+		Type:       plurals.Singular(resource) + "." + action,
+		Resource:   resource,
+		EntityID:   entity,
+		TenantID:   tenant,
+		Data:       after,
+		OccurredAt: time.Now().UTC(),
+	}
+	if action == Deleted {
+		event.Data = before
+	} else if action == Updated {
+		event.Previous = before
+	}
+	if resource == "businesses" {
+		event.BusinessID = &entity
+	} else {
+		var owned struct {
+			BusinessID *uuid.UUID `json:"business_id"`
+		}
+		if json.Unmarshal(event.Data, &owned) == nil && owned.BusinessID != nil && *owned.BusinessID != uuid.Nil {
+			event.BusinessID = owned.BusinessID
+		}
+	}
+	return event
+}
+
+// Emit tells the listeners about event through tx, stopping at the first one
+// failing
+func Emit(tx *gorm.DB, event Event) error {
+	for _, listener := range listeners {
+		if err := listener(tx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
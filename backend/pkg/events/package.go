@@ -0,0 +1,3 @@
+// Events package tells listeners about the changes made to entities, within the transaction that makes them.
+
+package events
@@ -0,0 +1,185 @@
+package export
+
+import (
+	"backend/pkg/helpers"
+
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+const (
+	CSV    = "text/csv"
+	NDJSON = "application/x-ndjson"
+	XLSX   = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// MediaTypes lists the formats an export can be encoded in
+var MediaTypes = []string{CSV, NDJSON, XLSX}
+
+var extensions = map[string]string{
+	CSV:    "csv",
+	NDJSON: "ndjson",
+	XLSX:   "xlsx",
+}
+
+// Extension returns the file extension of mediaType
+func Extension(mediaType string) string {
+	return extensions[mediaType]
+}
+
+// Encoder writes the rows of an export one at a time, nothing but the
+// current row is kept in memory
+type Encoder interface {
+	// Write encodes one row, holding a value per column
+	Write(row []interface{}) error
+	// Close flushes the document, it must be called once every row is written
+	Close() error
+}
+
+// NewEncoder returns an encoder of mediaType writing to w, whose first row
+// names the columns when the format has one
+func NewEncoder(mediaType string, w io.Writer, columns []string) (Encoder, error) {
+	switch mediaType {
+	case CSV:
+		return newCSVEncoder(w, columns)
+	case NDJSON:
+		return &ndjsonEncoder{w: w, columns: columns}, nil
+	case XLSX:
+		return newXLSXEncoder(w, columns)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", mediaType)
+	}
+}
+
+// Columns lists the JSON properties of value in the order of its fields
+func Columns(value interface{}) []string {
+	fields := helpers.JSONFields(value)
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = field.Name
+	}
+	return columns
+}
+
+// Select returns the columns named in fields, which may be written as the
+// JSON property or as its snake case column, or every column when fields is
+// empty
+func Select(available []string, fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		return available, nil
+	}
+
+	byName := map[string]string{}
+	for _, column := range available {
+		byName[column] = column
+		byName[strcase.ToSnake(column)] = column
+	}
+
+	selected := make([]string, 0, len(fields))
+	for _, field := range fields {
+		column, ok := byName[strings.TrimSpace(field)]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		selected = append(selected, column)
+	}
+	return selected, nil
+}
+
+// Row encodes value to JSON and picks columns from it, so exports hold the
+// same values as the JSON API
+func Row(value interface{}, columns []string) ([]interface{}, error) {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	fields := map[string]interface{}{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	row := make([]interface{}, len(columns))
+	for i, column := range columns {
+		row[i] = fields[column]
+	}
+	return row, nil
+}
+
+// text formats a value of a row for formats that only hold strings
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		payload, _ := json.Marshal(v)
+		return string(payload)
+	}
+}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func newCSVEncoder(w io.Writer, columns []string) (*csvEncoder, error) {
+	encoder := &csvEncoder{w: csv.NewWriter(w)}
+	return encoder, encoder.w.Write(columns)
+}
+
+func (e *csvEncoder) Write(row []interface{}) error {
+	record := make([]string, len(row))
+	for i, value := range row {
+		record[i] = text(value)
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// ndjsonEncoder writes one JSON object per line, keeping the column order
+type ndjsonEncoder struct {
+	w       io.Writer
+	columns []string
+}
+
+func (e *ndjsonEncoder) Write(row []interface{}) error {
+	line := &bytes.Buffer{}
+	line.WriteByte('{')
+	for i, column := range e.columns {
+		if i > 0 {
+			line.WriteByte(',')
+		}
+		name, _ := json.Marshal(column)
+		value, err := json.Marshal(row[i])
+		if err != nil {
+			return err
+		}
+		line.Write(name)
+		line.WriteByte(':')
+		line.Write(value)
+	}
+	line.WriteString("}\n")
+	_, err := e.w.Write(line.Bytes())
+	return err
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return nil
+}
@@ -0,0 +1,3 @@
+// Export package encodes the rows of a collection as CSV, NDJSON or XLSX documents.
+
+package export
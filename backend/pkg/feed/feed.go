@@ -0,0 +1,207 @@
+package feed
+
+import (
+	"backend/database"
+	"backend/pkg/audit"
+	"backend/pkg/common"
+	"backend/pkg/events"
+
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+var ErrUnknownEvent = errors.New("the last event is not in the audit log of the tenant")
+
+// Filter narrows the changes a subscriber gets to the ones of its tenant, of
+// Resources, and of the business BusinessID when it is set. Resources are
+// table names, like reservations
+type Filter struct {
+	TenantID   uuid.UUID
+	Resources  []string
+	BusinessID *uuid.UUID
+}
+
+// Match tells whether event passes the filter
+func (f Filter) Match(event events.Event) bool {
+	if event.TenantID != f.TenantID || !slices.Contains(f.Resources, event.Resource) {
+		return false
+	}
+	return f.BusinessID == nil || (event.BusinessID != nil && *event.BusinessID == *f.BusinessID)
+}
+
+// Subscription receives the changes matching its filter on Events, which is
+// closed once the subscription is cancelled or falls too far behind. Its
+// subscriber should then resume from the last event it got
+type Subscription struct {
+	Events <-chan events.Event
+	events chan events.Event
+	filter Filter
+}
+
+// Streamable tells whether the changes of resource may be streamed, the
+// ones listed in services.stream.resources
+func Streamable(resource string) bool {
+	return slices.Contains(viper.GetStringSlice("services.stream.resources"), resource)
+}
+
+// broker polls the audit log and hands the new entries to the subscriptions.
+// Entries are read again for services.stream.lag, so the ones of
+// transactions committing late are not missed, and seen keeps them from
+// being handed twice
+type broker struct {
+	mutex         sync.Mutex
+	subscriptions map[*Subscription]bool
+	cursor        time.Time
+	seen          map[uuid.UUID]time.Time
+	polling       bool
+}
+
+var shared = &broker{subscriptions: map[*Subscription]bool{}}
+
+// Subscribe starts handing the changes matching filter to the returned
+// subscription. The audit log is polled while there are subscriptions
+func Subscribe(filter Filter) *Subscription {
+	channel := make(chan events.Event, max(viper.GetInt("services.stream.buffer"), 1))
+	subscription := &Subscription{Events: channel, events: channel, filter: filter}
+	shared.mutex.Lock()
+	defer shared.mutex.Unlock()
+	if !shared.polling {
+		shared.polling = true
+		shared.cursor = time.Now().UTC()
+		shared.seen = map[uuid.UUID]time.Time{}
+		go shared.poll()
+	}
+	shared.subscriptions[subscription] = true
+	return subscription
+}
+
+// Cancel stops the subscription
+func (s *Subscription) Cancel() {
+	shared.mutex.Lock()
+	defer shared.mutex.Unlock()
+	shared.drop(s)
+}
+
+func (b *broker) drop(subscription *Subscription) {
+	if b.subscriptions[subscription] {
+		delete(b.subscriptions, subscription)
+		close(subscription.events)
+	}
+}
+
+func (b *broker) poll() {
+	interval := viper.GetDuration("services.stream.poll_interval")
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		b.mutex.Lock()
+		if len(b.subscriptions) == 0 {
+			b.polling = false
+			b.mutex.Unlock()
+			return
+		}
+		since := b.cursor.Add(-viper.GetDuration("services.stream.lag"))
+		b.mutex.Unlock()
+
+		if err := b.read(since); err != nil {
+			log.Printf("Error polling the audit log: %s", err)
+		}
+	}
+}
+
+// page is how many entries of the audit log are read at once
+const page = 1000
+
+// read publishes the entries recorded after since, a page at a time, each
+// page starting after the last entry of the previous one
+func (b *broker) read(since time.Time) error {
+	db := database.DB.WithContext(common.AsSystem(context.Background()))
+	query := db.Where("created_at > ?", since)
+	for {
+		var entries []audit.Entry
+		if err := query.Order("created_at, id").Limit(page).Find(&entries).Error; err != nil {
+			return err
+		}
+		b.publish(entries, since)
+		if len(entries) < page {
+			return nil
+		}
+		last := entries[len(entries)-1]
+		query = db.Where("created_at > ? OR (created_at = ? AND id > ?)", last.CreatedAt, last.CreatedAt, last.ID)
+	}
+}
+
+func (b *broker) publish(entries []audit.Entry, since time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, entry := range entries {
+		if _, ok := b.seen[entry.ID]; ok {
+			continue
+		}
+		b.seen[entry.ID] = entry.CreatedAt
+		if entry.CreatedAt.After(b.cursor) {
+			b.cursor = entry.CreatedAt
+		}
+		event := entry.Event()
+		for subscription := range b.subscriptions {
+			if !subscription.filter.Match(event) {
+				continue
+			}
+			select {
+			case subscription.events <- event:
+			default:
+				b.drop(subscription)
+			}
+		}
+	}
+	for id, at := range b.seen {
+		if !at.After(since) {
+			delete(b.seen, id)
+		}
+	}
+}
+
+// Since returns the changes matching filter recorded after the entry last,
+// oldest first and services.stream.replay_limit at most
+func Since(db *gorm.DB, filter Filter, last uuid.UUID) ([]events.Event, error) {
+	var from audit.Entry
+	err := db.
+		Where("tenant_id = ?", filter.TenantID).
+		Limit(1).
+		Find(&from, "id = ?", last).Error
+	if err != nil {
+		return nil, err
+	}
+	if from.ID == uuid.Nil {
+		return nil, ErrUnknownEvent
+	}
+
+	var entries []audit.Entry
+	err = db.
+		Where("tenant_id = ? AND resource IN ?", filter.TenantID, filter.Resources).
+		Where("created_at > ? OR (created_at = ? AND id > ?)", from.CreatedAt, from.CreatedAt, from.ID).
+		Order("created_at, id").
+		Limit(max(viper.GetInt("services.stream.replay_limit"), 1)).
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	missed := []events.Event{}
+	for _, entry := range entries {
+		if event := entry.Event(); filter.Match(event) {
+			missed = append(missed, event)
+		}
+	}
+	return missed, nil
+}
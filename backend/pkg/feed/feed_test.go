@@ -0,0 +1,51 @@
+package feed
+
+import (
+	"backend/database/databasetest"
+	"backend/pkg/audit"
+	"backend/pkg/events"
+
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestReadPagesThroughTheLagWindow(t *testing.T) {
+	db := databasetest.Open(t, &audit.Entry{})
+	tenant := uuid.New()
+	now := time.Now().UTC().Truncate(time.Second)
+	// More than two pages, many recorded at the same time
+	entries := make([]audit.Entry, 2*page+500)
+	for i := range entries {
+		entries[i] = audit.Entry{Actor: "system", Resource: "reservations", EntityID: uuid.New(), Action: audit.Created}
+		entries[i].TenantID = tenant
+		entries[i].CreatedAt = now.Add(time.Duration(i/700) * time.Millisecond)
+	}
+	if err := db.CreateInBatches(entries, 500).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	channel := make(chan events.Event, len(entries)+1)
+	subscription := &Subscription{Events: channel, events: channel, filter: Filter{TenantID: tenant, Resources: []string{"reservations"}}}
+	b := &broker{subscriptions: map[*Subscription]bool{subscription: true}, seen: map[uuid.UUID]time.Time{}}
+
+	since := now.Add(-time.Second)
+	if err := b.read(since); err != nil {
+		t.Fatal(err)
+	}
+	if len(channel) != len(entries) {
+		t.Fatalf("got %d events, want %d", len(channel), len(entries))
+	}
+	if want := entries[len(entries)-1].CreatedAt; !b.cursor.Equal(want) {
+		t.Errorf("got cursor %s, want %s", b.cursor, want)
+	}
+
+	// Read again within the lag, nothing is handed twice
+	if err := b.read(since); err != nil {
+		t.Fatal(err)
+	}
+	if len(channel) != len(entries) {
+		t.Fatalf("got %d events after reading again, want %d", len(channel), len(entries))
+	}
+}
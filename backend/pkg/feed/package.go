@@ -0,0 +1,3 @@
+// Feed package streams the changes recorded in the audit log to the subscribers of each server, and replays the ones they missed.
+
+package feed
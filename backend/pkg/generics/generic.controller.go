@@ -3,14 +3,19 @@ package generics
 import (
 	"backend/pkg/common"
 	"backend/pkg/helpers"
+	"errors"
 	"fmt"
+	"reflect"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/spf13/viper"
 )
 
 type GenericController interface {
 	GetResourceNames() ResourceNames
+	GetDTO() common.DTO
+	Routes() []RouteDefinition
 
 	Get() fiber.Handler
 	GetAll() fiber.Handler
@@ -21,11 +26,22 @@ type GenericController interface {
 	HardDelete() fiber.Handler
 	GetDeleted() fiber.Handler
 	GetAllDeleted() fiber.Handler
+
+	BulkCreate() fiber.Handler
+	BulkUpdate() fiber.Handler
+	BulkDelete() fiber.Handler
+
+	History() fiber.Handler
+
+	Import() fiber.Handler
+
+	Service() Service
 }
 
 type GenericControllerImpl[E common.Entity, DTO common.DTO] struct {
 	names      ResourceNames
 	repository GenericRepository[E, DTO]
+	naturalKey []string
 }
 
 type ResourceNames struct {
@@ -40,6 +56,18 @@ func NewController[E common.Entity, DTO common.DTO](names ResourceNames) Generic
 	return controller
 }
 
+// WithNaturalKey sets the columns identifying an entity besides its id, which
+// imports match rows on. Resources without one cannot be imported
+func (imp GenericControllerImpl[E, DTO]) WithNaturalKey(columns ...string) GenericControllerImpl[E, DTO] {
+	imp.naturalKey = columns
+	return imp
+}
+
+// repositoryFor binds the repository to the context of the request
+func (imp GenericControllerImpl[E, DTO]) repositoryFor(c *fiber.Ctx) Repository[E, DTO] {
+	return imp.repository.WithContext(c.UserContext())
+}
+
 func (imp GenericControllerImpl[E, DTO]) Get() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		id, err := uuid.Parse(c.Params("id"))
@@ -49,7 +77,7 @@ func (imp GenericControllerImpl[E, DTO]) Get() fiber.Handler {
 
 		relations := common.RelationsFromQuery(c)
 
-		entity, err := imp.repository.FindOne(id, relations)
+		entity, err := imp.repositoryFor(c).FindOne(id, relations)
 		if err != nil {
 			return NotFound(c, err, fmt.Sprintf("%s not found", imp.names.Singular))
 		}
@@ -60,6 +88,10 @@ func (imp GenericControllerImpl[E, DTO]) Get() fiber.Handler {
 
 func (imp GenericControllerImpl[E, DTO]) GetAll() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		if mediaType := exportFormat(c); mediaType != "" {
+			return imp.export(c, mediaType, false)
+		}
+
 		pageable, err := common.PageableFromQuery(c)
 		if err != nil {
 			return BadRequest(c, err, "Invalid pagination parameters")
@@ -69,7 +101,7 @@ func (imp GenericControllerImpl[E, DTO]) GetAll() fiber.Handler {
 		conditions := common.ConditionsFromQuery(c)
 		orders := common.OrderBysFromQuery(c)
 
-		result, err := imp.repository.FindAll(pageable, conditions, relations, orders)
+		result, err := imp.repositoryFor(c).FindAll(pageable, conditions, relations, orders)
 		if err != nil {
 			return NotFound(c, err, fmt.Sprintf("%s not found", imp.names.Plural))
 		}
@@ -105,7 +137,7 @@ func (imp GenericControllerImpl[E, DTO]) Update() fiber.Handler {
 		}
 
 		// Validate entity existence
-		exists, err := imp.repository.Exists(id)
+		exists, err := imp.repositoryFor(c).Exists(id)
 		if err != nil || !exists {
 			return BadRequest(c, err, fmt.Sprintf("Invalid %s payload", imp.names.Singular))
 		}
@@ -114,7 +146,7 @@ func (imp GenericControllerImpl[E, DTO]) Update() fiber.Handler {
 		entity := payload.ToEntity().(E)
 
 		entity.SetID(id)
-		entity, err = imp.repository.Update(entity)
+		entity, err = imp.repositoryFor(c).Update(entity)
 		if err != nil {
 			return BadRequest(c, err, fmt.Sprintf("Invalid %s payload", imp.names.Singular))
 		}
@@ -131,7 +163,7 @@ func (imp GenericControllerImpl[E, DTO]) Create() fiber.Handler {
 		}
 
 		entity := dto.ToEntity().(E)
-		entity, err := imp.repository.Create(entity)
+		entity, err := imp.repositoryFor(c).Create(entity)
 		if err != nil {
 			return BadRequest(c, err, fmt.Sprintf("Invalid %s payload", imp.names.Singular))
 		}
@@ -149,12 +181,12 @@ func (imp GenericControllerImpl[E, DTO]) Delete() fiber.Handler {
 
 		relations := common.RelationsFromQuery(c)
 
-		entity, err := imp.repository.FindOne(id, relations)
+		entity, err := imp.repositoryFor(c).FindOne(id, relations)
 		if err != nil {
 			return NotFound(c, err, fmt.Sprintf("%s not found", imp.names.Singular))
 		}
 
-		err = imp.repository.Delete(entity)
+		err = imp.repositoryFor(c).Delete(entity)
 		if err != nil {
 			return BadRequest(c, err, fmt.Sprintf("Invalid %s payload: %s", imp.names.Singular, helpers.PrettyStruct(entity)))
 		}
@@ -167,11 +199,17 @@ func (imp GenericControllerImpl[E, DTO]) GetResourceNames() ResourceNames {
 	return imp.names
 }
 
+// GetDTO returns a zero value of the DTO, for code that reflects on it
+func (imp GenericControllerImpl[E, DTO]) GetDTO() common.DTO {
+	var dto DTO
+	return dto
+}
+
 func (imp GenericControllerImpl[E, DTO]) Count() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		conditions := common.ConditionsFromQuery(c)
 
-		count, err := imp.repository.Count(conditions)
+		count, err := imp.repositoryFor(c).Count(conditions)
 		if err != nil {
 			return NotFound(c, err, fmt.Sprintf("Error counting %s", imp.names.Plural))
 		}
@@ -187,12 +225,12 @@ func (imp GenericControllerImpl[E, DTO]) HardDelete() fiber.Handler {
 			return BadRequest(c, err, fmt.Sprintf("Invalid %s id", imp.names.Singular))
 		}
 
-		entity, err := imp.repository.GetOneDeleted(id)
+		entity, err := imp.repositoryFor(c).GetOneDeleted(id)
 		if err != nil {
 			return NotFound(c, err, fmt.Sprintf("%s not found", imp.names.Singular))
 		}
 
-		err = imp.repository.HardDelete(entity)
+		err = imp.repositoryFor(c).HardDelete(entity)
 		if err != nil {
 			return BadRequest(c, err, fmt.Sprintf("Invalid %s payload", imp.names.Singular))
 		}
@@ -203,6 +241,10 @@ func (imp GenericControllerImpl[E, DTO]) HardDelete() fiber.Handler {
 
 func (imp GenericControllerImpl[E, DTO]) GetAllDeleted() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		if mediaType := exportFormat(c); mediaType != "" {
+			return imp.export(c, mediaType, true)
+		}
+
 		pageable, err := common.PageableFromQuery(c)
 		if err != nil {
 			return BadRequest(c, err, "Invalid pagination parameters")
@@ -212,7 +254,7 @@ func (imp GenericControllerImpl[E, DTO]) GetAllDeleted() fiber.Handler {
 		relations := common.RelationsFromQuery(c)
 		orderBys := common.OrderBysFromQuery(c)
 
-		result, err := imp.repository.GetDeleted(pageable, filters, relations, orderBys)
+		result, err := imp.repositoryFor(c).GetDeleted(pageable, filters, relations, orderBys)
 		if err != nil {
 			return NotFound(c, err, fmt.Sprintf("%s not found", imp.names.Plural))
 		}
@@ -241,7 +283,7 @@ func (imp GenericControllerImpl[E, DTO]) GetDeleted() fiber.Handler {
 			return BadRequest(c, err, fmt.Sprintf("Invalid %s id", imp.names.Singular))
 		}
 
-		entity, err := imp.repository.GetOneDeleted(id)
+		entity, err := imp.repositoryFor(c).GetOneDeleted(id)
 		if err != nil {
 			return NotFound(c, err, fmt.Sprintf("%s not found", imp.names.Singular))
 		}
@@ -249,3 +291,208 @@ func (imp GenericControllerImpl[E, DTO]) GetDeleted() fiber.Handler {
 		return Found(c, entity.ToDTO(), fmt.Sprintf("Found %s", imp.names.Singular))
 	}
 }
+
+// History lists the audit entries of one entity, newest first
+func (imp GenericControllerImpl[E, DTO]) History() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return BadRequest(c, err, fmt.Sprintf("Invalid %s id", imp.names.Singular))
+		}
+
+		pageable, err := common.PageableFromQuery(c)
+		if err != nil {
+			return BadRequest(c, err, "Invalid pagination parameters")
+		}
+
+		result, err := imp.repositoryFor(c).History(id, pageable)
+		if err != nil {
+			return NotFound(c, err, fmt.Sprintf("%s history not found", imp.names.Singular))
+		}
+
+		dtos := make([]common.DTO, len(result.Items))
+		for i, entry := range result.Items {
+			dtos[i] = entry.ToDTO()
+		}
+
+		return Found(c,
+			common.NewPage[common.DTO](
+				dtos,
+				result.Page,
+				result.Size,
+				result.Total,
+				result.Filtered),
+			fmt.Sprintf("Found %s history", imp.names.Singular))
+	}
+}
+
+// BulkCreate creates every DTO of a JSON array
+func (imp GenericControllerImpl[E, DTO]) BulkCreate() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		mode, err := common.BulkModeFromQuery(c)
+		if err != nil {
+			return BadRequest(c, err, "Invalid bulk parameters")
+		}
+
+		var payload []DTO
+		if err := c.BodyParser(&payload); err != nil {
+			return BadRequest(c, err, fmt.Sprintf("Invalid %s payload", imp.names.Plural))
+		}
+		if err := checkBulkSize(len(payload)); err != nil {
+			return BadRequest(c, err, fmt.Sprintf("Invalid %s payload", imp.names.Plural))
+		}
+
+		result := imp.runBulk(imp.repositoryFor(c), mode, len(payload), func(repository Repository[E, DTO], item *common.BulkItemResult) error {
+			dto := payload[item.Index]
+			if isNil(dto) {
+				return errBulkItemNull
+			}
+			if errs := helpers.ValidateStruct(dto); len(errs) > 0 {
+				item.Errors = errs
+				return errBulkItemInvalid
+			}
+
+			entity, err := repository.Create(dto.ToEntity().(E))
+			if err != nil {
+				return err
+			}
+			item.ID = entity.GetID()
+			item.Data = entity.ToDTO()
+			return nil
+		})
+
+		return BulkProcessed(c, result, fiber.StatusCreated, fmt.Sprintf("%d %s created", result.Succeeded, imp.names.Plural))
+	}
+}
+
+// BulkUpdate updates every DTO of a JSON array. Each item must carry the id of
+// an existing entity and is applied like PUT /:id
+func (imp GenericControllerImpl[E, DTO]) BulkUpdate() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		mode, err := common.BulkModeFromQuery(c)
+		if err != nil {
+			return BadRequest(c, err, "Invalid bulk parameters")
+		}
+
+		var payload []DTO
+		if err := c.BodyParser(&payload); err != nil {
+			return BadRequest(c, err, fmt.Sprintf("Invalid %s payload", imp.names.Plural))
+		}
+		if err := checkBulkSize(len(payload)); err != nil {
+			return BadRequest(c, err, fmt.Sprintf("Invalid %s payload", imp.names.Plural))
+		}
+
+		result := imp.runBulk(imp.repositoryFor(c), mode, len(payload), func(repository Repository[E, DTO], item *common.BulkItemResult) error {
+			dto := payload[item.Index]
+			if isNil(dto) {
+				return errBulkItemNull
+			}
+			item.ID = dto.GetID()
+			if item.ID == uuid.Nil {
+				return fmt.Errorf("%s id is required", imp.names.Singular)
+			}
+			if errs := helpers.ValidateStruct(dto); len(errs) > 0 {
+				item.Errors = errs
+				return errBulkItemInvalid
+			}
+
+			if exists, err := repository.Exists(item.ID); err != nil || !exists {
+				return fmt.Errorf("%s %s not found", imp.names.Singular, item.ID)
+			}
+
+			entity, err := repository.Update(dto.ToEntity().(E))
+			if err != nil {
+				return err
+			}
+			item.Data = entity.ToDTO()
+			return nil
+		})
+
+		return BulkProcessed(c, result, fiber.StatusOK, fmt.Sprintf("%d %s updated", result.Succeeded, imp.names.Plural))
+	}
+}
+
+// BulkDelete soft deletes every entity whose id is in a JSON array
+func (imp GenericControllerImpl[E, DTO]) BulkDelete() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		mode, err := common.BulkModeFromQuery(c)
+		if err != nil {
+			return BadRequest(c, err, "Invalid bulk parameters")
+		}
+
+		var ids []uuid.UUID
+		if err := c.BodyParser(&ids); err != nil {
+			return BadRequest(c, err, fmt.Sprintf("Invalid %s ids", imp.names.Singular))
+		}
+		if err := checkBulkSize(len(ids)); err != nil {
+			return BadRequest(c, err, fmt.Sprintf("Invalid %s ids", imp.names.Singular))
+		}
+
+		result := imp.runBulk(imp.repositoryFor(c), mode, len(ids), func(repository Repository[E, DTO], item *common.BulkItemResult) error {
+			item.ID = ids[item.Index]
+
+			entity, err := repository.FindOne(item.ID, common.NoFields)
+			if err != nil {
+				return fmt.Errorf("%s %s not found", imp.names.Singular, item.ID)
+			}
+			return repository.Delete(entity)
+		})
+
+		return BulkProcessed(c, result, fiber.StatusOK, fmt.Sprintf("%d %s deleted", result.Succeeded, imp.names.Plural))
+	}
+}
+
+var errBulkItemInvalid = errors.New("validation failed")
+
+var errBulkItemNull = errors.New("the item is null")
+
+// isNil tells whether dto is a nil pointer, as null items of a JSON array
+// are decoded
+func isNil(dto common.DTO) bool {
+	value := reflect.ValueOf(dto)
+	return !value.IsValid() || (value.Kind() == reflect.Pointer && value.IsNil())
+}
+
+var errBulkFailed = errors.New("bulk operation failed")
+
+// runBulk applies operation to size items inside one transaction. Every item
+// runs in its own savepoint so a failure never poisons the ones after it; in
+// AllOrNothing mode the whole transaction is then rolled back if any failed
+func (imp GenericControllerImpl[E, DTO]) runBulk(repository Repository[E, DTO], mode common.BulkMode, size int, operation func(repository Repository[E, DTO], item *common.BulkItemResult) error) common.BulkResult {
+	result := common.NewBulkResult(mode, size)
+
+	err := repository.Transaction(func(tx Repository[E, DTO]) error {
+		for i := 0; i < size; i++ {
+			item := common.BulkItemResult{Index: i, Status: common.Success}
+
+			err := tx.Transaction(func(itemTx Repository[E, DTO]) error {
+				return operation(itemTx, &item)
+			})
+			if err != nil {
+				item.Status = common.Error
+				item.Error = err.Error()
+				item.Data = nil
+			}
+
+			result.Add(item)
+		}
+
+		if mode == common.AllOrNothing && result.Failed > 0 {
+			return errBulkFailed
+		}
+		return nil
+	})
+
+	result.Committed = err == nil
+	return result
+}
+
+func checkBulkSize(size int) error {
+	if size == 0 {
+		return errors.New("at least one item is required")
+	}
+	if limit := viper.GetInt("services.bulk.max_items"); limit > 0 && size > limit {
+		return fmt.Errorf("at most %d items are allowed, got %d", limit, size)
+	}
+	return nil
+}
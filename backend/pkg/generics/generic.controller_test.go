@@ -0,0 +1,115 @@
+package generics_test
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/generics"
+
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// bulk posts body to the bulk routes of the widgets of tenant, and decodes
+// the result
+func bulk(t *testing.T, tenant uuid.UUID, method string, query string, body string) (int, common.BulkResult) {
+	t.Helper()
+	controller := generics.NewController[*Widget, *WidgetDTO](generics.ResourceNames{Singular: "widget", Plural: "widgets"})
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.SetUserContext(common.WithTenant(c.UserContext(), tenant))
+		return c.Next()
+	})
+	app.Post("/bulk", controller.BulkCreate())
+	app.Put("/bulk", controller.BulkUpdate())
+
+	request := httptest.NewRequest(method, "/bulk"+query, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	response, err := app.Test(request, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(response.Body)
+	var decoded common.ApiResponse[common.BulkResult]
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("decoding %s: %s", raw, err)
+	}
+	return response.StatusCode, decoded.Data
+}
+
+// names lists the names of the widgets of tenant
+func names(t *testing.T, tenant uuid.UUID) []string {
+	t.Helper()
+	var stored []Widget
+	if err := database.DB.Where("tenant_id = ?", tenant).Order("name").Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	found := []string{}
+	for _, w := range stored {
+		found = append(found, w.Name)
+	}
+	return found
+}
+
+func TestBulkCreateAllOrNothing(t *testing.T) {
+	a, _, _ := seed(t)
+	status, result := bulk(t, a, fiber.MethodPost, "", `[{"name":"a3"},{"name":""},{"name":"a4"}]`)
+	if status != fiber.StatusUnprocessableEntity || result.Committed || result.Succeeded != 2 || result.Failed != 1 {
+		t.Fatalf("got %d with %+v, want 422 rolled back with 1 failure", status, result)
+	}
+	if got := strings.Join(names(t, a), ","); got != "a1,a2" {
+		t.Fatalf("got widgets %s, want none added", got)
+	}
+}
+
+func TestBulkCreateBestEffort(t *testing.T) {
+	a, _, _ := seed(t)
+	status, result := bulk(t, a, fiber.MethodPost, "?mode=best_effort", `[{"name":"a3"},{"name":""},null,{"name":"a4"}]`)
+	if status != fiber.StatusMultiStatus || !result.Committed || result.Succeeded != 2 || result.Failed != 2 {
+		t.Fatalf("got %d with %+v, want 207 committed with 2 failures", status, result)
+	}
+	if item := result.Items[1]; item.Status != common.Error || len(item.Errors) != 1 {
+		t.Errorf("got item %+v, want the validation error of the empty name", item)
+	}
+	if item := result.Items[2]; item.Status != common.Error || item.Error != "the item is null" {
+		t.Errorf("got item %+v, want the null item reported", item)
+	}
+	if got := strings.Join(names(t, a), ","); got != "a1,a2,a3,a4" {
+		t.Fatalf("got widgets %s, want a3 and a4 added", got)
+	}
+}
+
+func TestBulkUpdateNullItem(t *testing.T) {
+	a, _, widgets := seed(t)
+	body := `[null,{"id":"` + widgets["a1"].ID.String() + `","name":"renamed"}]`
+	status, result := bulk(t, a, fiber.MethodPut, "?mode=best_effort", body)
+	if status != fiber.StatusMultiStatus || result.Succeeded != 1 || result.Items[0].Error != "the item is null" {
+		t.Fatalf("got %d with %+v, want the null item reported and the other updated", status, result)
+	}
+	if got := strings.Join(names(t, a), ","); got != "a2,renamed" {
+		t.Fatalf("got widgets %s, want a1 renamed", got)
+	}
+}
+
+func TestBulkMaxItems(t *testing.T) {
+	a, _, _ := seed(t)
+	viper.Set("services.bulk.max_items", 2)
+	defer viper.Set("services.bulk.max_items", nil)
+
+	status, _ := bulk(t, a, fiber.MethodPost, "", `[{"name":"a3"},{"name":"a4"},{"name":"a5"}]`)
+	if status != fiber.StatusBadRequest {
+		t.Fatalf("got %d for 3 items, want 400", status)
+	}
+	if status, _ := bulk(t, a, fiber.MethodPost, "", `[{"name":"a3"},{"name":"a4"}]`); status != fiber.StatusCreated {
+		t.Fatalf("got %d for 2 items, want 201", status)
+	}
+	if got := strings.Join(names(t, a), ","); got != "a1,a2,a3,a4" {
+		t.Fatalf("got widgets %s, want a3 and a4 added", got)
+	}
+}
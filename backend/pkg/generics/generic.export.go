@@ -0,0 +1,78 @@
+package generics
+
+import (
+	"backend/pkg/common"
+	"backend/pkg/export"
+
+	"bufio"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// exportFormat returns the export media type the request accepts best, or
+// an empty string when it should get the JSON envelope
+func exportFormat(c *fiber.Ctx) string {
+	accepted := c.Accepts(append([]string{fiber.MIMEApplicationJSON}, export.MediaTypes...)...)
+	if accepted == fiber.MIMEApplicationJSON {
+		return ""
+	}
+	return accepted
+}
+
+// export streams every entity matching the filters and orders of the query
+// as a mediaType document, ignoring pagination
+func (imp GenericControllerImpl[E, DTO]) export(c *fiber.Ctx, mediaType string, deleted bool) error {
+	var dto DTO
+	var fields []string
+	if query := c.Query(FieldsQuery.Name); query != "" {
+		fields = strings.Split(query, ",")
+	}
+	columns, err := export.Select(export.Columns(dto), fields)
+	if err != nil {
+		return BadRequest(c, err, "Invalid export fields")
+	}
+
+	conditions := common.ConditionsFromQuery(c)
+	orderBys := common.OrderBysFromQuery(c)
+	repository := imp.repositoryFor(c)
+
+	// Once the body is streamed the status cannot change, so the query is
+	// checked with a count first
+	if _, err := repository.Count(conditions); err != nil {
+		return NotFound(c, err, fmt.Sprintf("%s not found", imp.names.Plural))
+	}
+
+	stream := repository.Stream
+	name := imp.names.Plural
+	if deleted {
+		stream = repository.StreamDeleted
+		name = "deleted-" + name
+	}
+
+	c.Set(fiber.HeaderContentType, mediaType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name+"."+export.Extension(mediaType)))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		encoder, err := export.NewEncoder(mediaType, w, columns)
+		if err != nil {
+			log.Printf("Error exporting %s: %s", imp.names.Plural, err)
+			return
+		}
+		err = stream(conditions, orderBys, func(entity E) error {
+			row, err := export.Row(entity.ToDTO(), columns)
+			if err != nil {
+				return err
+			}
+			return encoder.Write(row)
+		})
+		if err != nil {
+			log.Printf("Error exporting %s: %s", imp.names.Plural, err)
+		}
+		if err := encoder.Close(); err != nil {
+			log.Printf("Error exporting %s: %s", imp.names.Plural, err)
+		}
+	})
+	return nil
+}
@@ -0,0 +1,165 @@
+package generics
+
+import (
+	"backend/pkg/common"
+	"backend/pkg/helpers"
+	"backend/pkg/importer"
+
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+var errImportFailed = errors.New("import failed")
+
+var errDryRun = errors.New("dry run")
+
+// Import creates or updates an entity per row of a CSV document, sent as the
+// body or as the file field of a multipart form
+func (imp GenericControllerImpl[E, DTO]) Import() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var document io.Reader = bytes.NewReader(c.Body())
+		if strings.HasPrefix(string(c.Request().Header.ContentType()), fiber.MIMEMultipartForm) {
+			header, err := c.FormFile("file")
+			if err != nil {
+				return BadRequest(c, err, fmt.Sprintf("Invalid %s document", imp.names.Plural))
+			}
+			file, err := header.Open()
+			if err != nil {
+				return BadRequest(c, err, fmt.Sprintf("Invalid %s document", imp.names.Plural))
+			}
+			defer file.Close()
+			document = file
+		}
+
+		result, err := imp.Service().Import(c.UserContext(), document, c.QueryBool(DryRunQuery.Name))
+		if err != nil {
+			return BadRequest(c, err, fmt.Sprintf("Invalid %s document", imp.names.Plural))
+		}
+
+		message := fmt.Sprintf("%d %s created and %d updated", result.Created, imp.names.Plural, result.Updated)
+		if result.DryRun {
+			message = fmt.Sprintf("%d %s would be created and %d updated", result.Created, imp.names.Plural, result.Updated)
+		}
+		return Imported(c, result, message)
+	}
+}
+
+// Import creates or updates an entity per row of a CSV document, matching
+// the existing ones by the natural key of the resource. Every row runs in
+// its own savepoint so all of them are reported, and the transaction is
+// only committed when none failed and it is not a dry run. The error is
+// only set when the document itself cannot be read
+func (imp GenericService[E, DTO]) Import(ctx context.Context, document io.Reader, dryRun bool) (common.ImportResult, error) {
+	result := common.ImportResult{DryRun: dryRun, Rows: []common.ImportRowResult{}}
+	if len(imp.naturalKey) == 0 {
+		return result, fmt.Errorf("%s cannot be imported", imp.names.Plural)
+	}
+
+	var dto DTO
+	reader, err := importer.NewReader(document, dto)
+	if err != nil {
+		return result, err
+	}
+
+	limit := viper.GetInt("services.import.max_rows")
+	err = imp.repository.WithContext(ctx).Transaction(func(tx Repository[E, DTO]) error {
+		for {
+			row, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if limit > 0 && len(result.Rows) == limit {
+				return fmt.Errorf("at most %d rows are allowed", limit)
+			}
+
+			item := common.ImportRowResult{Line: row.Line, Status: common.Success}
+			err = tx.Transaction(func(rowTx Repository[E, DTO]) error {
+				return imp.importRow(rowTx, row, &item)
+			})
+			if err != nil {
+				item.Status = common.Error
+				item.Error = err.Error()
+				item.ID = uuid.Nil
+				item.Action = ""
+			}
+			result.Add(item)
+		}
+
+		switch {
+		case len(result.Rows) == 0:
+			return errors.New("at least one row is required")
+		case result.Failed > 0:
+			return errImportFailed
+		case dryRun:
+			return errDryRun
+		}
+		return nil
+	})
+
+	result.Committed = err == nil
+	if errors.Is(err, errImportFailed) || errors.Is(err, errDryRun) {
+		err = nil
+	}
+	return result, err
+}
+
+// importRow updates the entity sharing the natural key of the row, or
+// creates one when there is none. Columns left out of the document, or
+// empty, keep the values of the entity being updated
+func (imp GenericService[E, DTO]) importRow(repository Repository[E, DTO], row importer.Row, item *common.ImportRowResult) error {
+	if row.Err != nil {
+		return row.Err
+	}
+	var dto DTO
+	if err := json.Unmarshal(row.Payload, &dto); err != nil {
+		return err
+	}
+
+	existing, err := repository.FindMatch(dto.ToEntity().(E), imp.naturalKey)
+	switch {
+	case err == nil:
+		dto = existing.ToDTO().(DTO)
+		if err := json.Unmarshal(row.Payload, &dto); err != nil {
+			return err
+		}
+		item.Action = common.ImportUpdated
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		item.Action = common.ImportCreated
+	default:
+		return err
+	}
+
+	if errs := helpers.ValidateStruct(dto); len(errs) > 0 {
+		item.Errors = errs
+		return errBulkItemInvalid
+	}
+
+	// Ids of exports are ignored, the natural key identifies the entity
+	entity := dto.ToEntity().(E)
+	if item.Action == common.ImportUpdated {
+		entity.SetID(existing.GetID())
+		entity, err = repository.Update(entity)
+	} else {
+		entity.SetID(uuid.Nil)
+		entity, err = repository.Create(entity)
+	}
+	if err != nil {
+		return err
+	}
+
+	item.ID = entity.GetID()
+	return nil
+}
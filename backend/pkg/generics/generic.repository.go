@@ -2,15 +2,20 @@ package generics
 
 import (
 	"backend/database"
+	"backend/pkg/audit"
 	"backend/pkg/common"
+	"backend/pkg/tenancy"
 
+	"context"
 	"fmt"
 	"log"
+	"reflect"
 	"strings"
 
 	"github.com/gertd/go-pluralize"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository[Entity common.Entity, DTO common.DTO] interface {
@@ -19,23 +24,85 @@ type Repository[Entity common.Entity, DTO common.DTO] interface {
 	Delete(payload Entity) error
 	FindOne(id uuid.UUID, relations []string) (Entity, error)
 	FindAll(pageable common.Pageable, conditions common.SQLConditions, relations []string, orderBys common.OrderBys) (*common.Page[Entity], error)
+	FindIn(column string, values []interface{}, conditions common.SQLConditions, orderBys common.OrderBys) ([]Entity, error)
+	FindMatch(payload Entity, columns []string) (Entity, error)
 	FindOneRandom() (Entity, error)
 	Exists(id uuid.UUID) (bool, error)
 	Count(conditions common.SQLConditions) (int64, error)
 	HardDelete(payload Entity) error
 	GetOneDeleted(id uuid.UUID) (Entity, error)
 	GetDeleted(pageable common.Pageable, conditions common.SQLConditions, relations []string, orderBys common.OrderBys) (*common.Page[Entity], error)
+	Stream(conditions common.SQLConditions, orderBys common.OrderBys, fn func(entity Entity) error) error
+	StreamDeleted(conditions common.SQLConditions, orderBys common.OrderBys, fn func(entity Entity) error) error
+	Transaction(fc func(tx Repository[Entity, DTO]) error) error
+	WithContext(ctx context.Context) Repository[Entity, DTO]
+	Resource() string
+	History(id uuid.UUID, pageable common.Pageable) (*common.Page[audit.Entry], error)
 }
 
-type GenericRepository[Entity common.Entity, DTO common.DTO] struct{}
+type GenericRepository[Entity common.Entity, DTO common.DTO] struct {
+	db *gorm.DB
+}
 
 func NewGenericRepositoryGORM[Entity common.Entity, DTO common.DTO]() GenericRepository[Entity, DTO] {
 	var service GenericRepository[Entity, DTO]
 	return service
 }
 
+// conn returns the connection bound to the repository, falling back to the
+// global one when the repository is not running inside a transaction
+func (imp GenericRepository[Entity, DTO]) conn() *gorm.DB {
+	if imp.db != nil {
+		return imp.db
+	}
+	return database.DB
+}
+
+// scoped returns the connection restricted to the tenant bound to its context
+func (imp GenericRepository[Entity, DTO]) scoped() *gorm.DB {
+	return imp.conn().Scopes(tenancy.Scope)
+}
+
+// Transaction runs fc inside a database transaction. Nested calls use
+// savepoints, so an inner failure only rolls back its own changes
+func (imp GenericRepository[Entity, DTO]) Transaction(fc func(tx Repository[Entity, DTO]) error) error {
+	return imp.conn().Transaction(func(tx *gorm.DB) error {
+		return fc(GenericRepository[Entity, DTO]{db: tx})
+	})
+}
+
+// WithContext binds ctx to every query made by the returned repository, which
+// is how the actor and the request ID reach the audit log
+func (imp GenericRepository[Entity, DTO]) WithContext(ctx context.Context) Repository[Entity, DTO] {
+	return GenericRepository[Entity, DTO]{db: imp.conn().WithContext(ctx)}
+}
+
+// Resource returns the table name of the entity, used to identify it in the
+// audit log
+func (imp GenericRepository[Entity, DTO]) Resource() string {
+	var entity Entity
+	statement := &gorm.Statement{DB: imp.conn()}
+	if err := statement.Parse(&entity); err != nil {
+		return ""
+	}
+	return statement.Schema.Table
+}
+
+// History returns the audit entries of one entity, newest first
+func (imp GenericRepository[Entity, DTO]) History(id uuid.UUID, pageable common.Pageable) (*common.Page[audit.Entry], error) {
+	return audit.Find(imp.conn(), audit.Query{Resource: imp.Resource(), EntityID: id}, pageable)
+}
+
 func (imp GenericRepository[Entity, DTO]) Create(payload Entity) (Entity, error) {
-	err := database.DB.Create(&payload).Error
+	err := imp.conn().Transaction(func(tx *gorm.DB) error {
+		if err := tenancy.Assign(tx, payload); err != nil {
+			return err
+		}
+		if err := tx.Create(&payload).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.Created, imp.Resource(), payload, nil, payload.ToDTO())
+	})
 	return payload, err
 }
 
@@ -43,15 +110,44 @@ func (imp GenericRepository[Entity, DTO]) Update(payload Entity) (Entity, error)
 	if payload.GetID() == uuid.Nil {
 		return payload, fmt.Errorf("ID cannot be nil")
 	}
-	err := database.DB.
-		Session(&gorm.Session{FullSaveAssociations: true}).
-		Omit("created_at").
-		Save(&payload).Error
+	err := imp.conn().Transaction(func(tx *gorm.DB) error {
+		// Looking the entity up in the current tenant first also keeps Save
+		// from falling back to an upsert over a row of another tenant
+		var before Entity
+		if err := tx.Scopes(tenancy.Scope).Unscoped().First(&before, "id = ?", payload.GetID()).Error; err != nil {
+			return err
+		}
+		if err := tenancy.Assign(tx, payload); err != nil {
+			return err
+		}
+		err := tx.
+			Scopes(tenancy.Scope).
+			Session(&gorm.Session{FullSaveAssociations: true}).
+			Omit("created_at").
+			Save(&payload).Error
+		if err != nil {
+			return err
+		}
+		var after Entity
+		if err := tx.Unscoped().First(&after, "id = ?", payload.GetID()).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.Updated, imp.Resource(), after, before.ToDTO(), after.ToDTO())
+	})
 	return payload, err
 }
 
 func (imp GenericRepository[Entity, DTO]) Delete(payload Entity) (err error) {
-	err = database.DB.Delete(&payload, payload.GetID()).Error
+	err = imp.conn().Transaction(func(tx *gorm.DB) error {
+		result := tx.Scopes(tenancy.Scope).Delete(&payload, payload.GetID())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return audit.Record(tx, audit.Deleted, imp.Resource(), payload, payload.ToDTO(), nil)
+	})
 	if err != nil {
 		log.Printf("Error deleting %s: %s", payload.GetID(), err)
 	}
@@ -60,7 +156,7 @@ func (imp GenericRepository[Entity, DTO]) Delete(payload Entity) (err error) {
 
 func (imp GenericRepository[Entity, DTO]) FindOne(id uuid.UUID, relations []string) (Entity, error) {
 	var entity Entity
-	err := database.DB.
+	err := imp.scoped().
 		Scopes(
 			Preload(relations),
 		).
@@ -70,7 +166,7 @@ func (imp GenericRepository[Entity, DTO]) FindOne(id uuid.UUID, relations []stri
 
 func (imp GenericRepository[Entity, DTO]) FindOneRandom() (Entity, error) {
 	var entity Entity
-	err := database.DB.Order("RANDOM()").First(&entity).Error
+	err := imp.scoped().Order("RANDOM()").First(&entity).Error
 	return entity, err
 }
 
@@ -80,9 +176,9 @@ func (imp GenericRepository[Entity, DTO]) FindAll(pageable common.Pageable, cond
 	offset := (pageable.Page - 1) * pageable.Size
 
 	var count int64
-	database.DB.Model(&entities).Count(&count)
+	imp.scoped().Model(&entities).Count(&count)
 
-	result := database.DB.
+	result := imp.scoped().
 		Limit(limit).
 		Offset(offset).
 		Scopes(
@@ -93,7 +189,7 @@ func (imp GenericRepository[Entity, DTO]) FindAll(pageable common.Pageable, cond
 		Find(&entities)
 
 		// Debug query
-	database.DB.Debug().
+	imp.scoped().Debug().
 		Limit(limit).
 		Offset(offset).
 		Scopes(
@@ -104,7 +200,7 @@ func (imp GenericRepository[Entity, DTO]) FindAll(pageable common.Pageable, cond
 		Find(&entities)
 
 	var filtered int64
-	database.DB.Model(&entities).
+	imp.scoped().Model(&entities).
 		Scopes(
 			Preload(relations),
 			Filters(conditions),
@@ -120,9 +216,45 @@ func (imp GenericRepository[Entity, DTO]) FindAll(pageable common.Pageable, cond
 	}, result.Error
 }
 
+// FindIn returns every entity whose column holds one of values, which loads
+// a relation of many parents with a single query
+func (imp GenericRepository[Entity, DTO]) FindIn(column string, values []interface{}, conditions common.SQLConditions, orderBys common.OrderBys) ([]Entity, error) {
+	var entities []Entity
+	err := imp.scoped().
+		Where(column+" IN ?", values).
+		Scopes(
+			Filters(conditions),
+			Order(orderBys),
+		).
+		Find(&entities).Error
+	return entities, err
+}
+
+// FindMatch returns the entity holding the same values as payload in every
+// one of columns, which identifies it when the id is not known
+func (imp GenericRepository[Entity, DTO]) FindMatch(payload Entity, columns []string) (Entity, error) {
+	var entity Entity
+	statement := &gorm.Statement{DB: imp.conn()}
+	if err := statement.Parse(payload); err != nil {
+		return entity, err
+	}
+
+	query := imp.scoped()
+	for _, column := range columns {
+		field := statement.Schema.LookUpField(column)
+		if field == nil {
+			return entity, fmt.Errorf("unknown column %q", column)
+		}
+		value, _ := field.ValueOf(query.Statement.Context, reflect.ValueOf(payload))
+		query = query.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: value})
+	}
+	err := query.First(&entity).Error
+	return entity, err
+}
+
 func (imp GenericRepository[Entity, DTO]) Exists(id uuid.UUID) (bool, error) {
 	var entity Entity
-	err := database.DB.First(&entity, "id = ?", id).Error
+	err := imp.scoped().First(&entity, "id = ?", id).Error
 	if err != nil {
 		return false, err
 	}
@@ -132,7 +264,7 @@ func (imp GenericRepository[Entity, DTO]) Exists(id uuid.UUID) (bool, error) {
 func (imp GenericRepository[Entity, DTO]) Count(conditions common.SQLConditions) (int64, error) {
 	var count int64
 	var entity Entity
-	err := database.DB.
+	err := imp.scoped().
 		Model(entity).
 		Scopes(
 			Filters(conditions),
@@ -142,12 +274,21 @@ func (imp GenericRepository[Entity, DTO]) Count(conditions common.SQLConditions)
 }
 
 func (imp GenericRepository[Entity, DTO]) HardDelete(payload Entity) error {
-	return database.DB.Unscoped().Delete(&payload).Error
+	return imp.conn().Transaction(func(tx *gorm.DB) error {
+		result := tx.Scopes(tenancy.Scope).Unscoped().Delete(&payload)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return audit.Record(tx, audit.HardDeleted, imp.Resource(), payload, payload.ToDTO(), nil)
+	})
 }
 
 func (imp GenericRepository[Entity, DTO]) GetOneDeleted(id uuid.UUID) (Entity, error) {
 	var entity Entity
-	err := database.DB.Unscoped().First(&entity, "id = ?", id).Error
+	err := imp.scoped().Unscoped().First(&entity, "id = ?", id).Error
 	return entity, err
 }
 
@@ -157,9 +298,9 @@ func (imp GenericRepository[Entity, DTO]) GetDeleted(pageable common.Pageable, c
 	offset := (pageable.Page - 1) * pageable.Size
 
 	var count int64
-	database.DB.Unscoped().Model(&entities).Count(&count)
+	imp.scoped().Unscoped().Model(&entities).Count(&count)
 
-	result := database.DB.
+	result := imp.scoped().
 		Unscoped().
 		Limit(limit).
 		Offset(offset).
@@ -171,7 +312,7 @@ func (imp GenericRepository[Entity, DTO]) GetDeleted(pageable common.Pageable, c
 		Find(&entities)
 
 	var filtered int64
-	database.DB.Model(&entities).
+	imp.scoped().Model(&entities).
 		Scopes(
 			Preload(relations),
 			Filters(conditions),
@@ -187,13 +328,56 @@ func (imp GenericRepository[Entity, DTO]) GetDeleted(pageable common.Pageable, c
 	}, result.Error
 }
 
-// Filters returns a function that applies the given conditions to a gorm.DB
+// Stream calls fn with every entity matching conditions, in order. Rows are
+// read one at a time, so the memory used does not grow with the table
+func (imp GenericRepository[Entity, DTO]) Stream(conditions common.SQLConditions, orderBys common.OrderBys, fn func(entity Entity) error) error {
+	return imp.stream(imp.scoped(), conditions, orderBys, fn)
+}
+
+// StreamDeleted is Stream over the rows GetDeleted reads
+func (imp GenericRepository[Entity, DTO]) StreamDeleted(conditions common.SQLConditions, orderBys common.OrderBys, fn func(entity Entity) error) error {
+	return imp.stream(imp.scoped().Unscoped(), conditions, orderBys, fn)
+}
+
+func (imp GenericRepository[Entity, DTO]) stream(db *gorm.DB, conditions common.SQLConditions, orderBys common.OrderBys, fn func(entity Entity) error) error {
+	var model Entity
+	rows, err := db.
+		Model(&model).
+		Scopes(
+			Filters(conditions),
+			Order(orderBys),
+		).
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entity Entity
+		if err := imp.conn().ScanRows(rows, &entity); err != nil {
+			return err
+		}
+		if err := fn(entity); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Filters returns a function that applies the given conditions to a gorm.DB.
+// They are grouped in parenthesis, so an or condition cannot escape the
+// conditions already on the query, such as the tenant
 func Filters(conditions common.SQLConditions) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
+		if len(conditions) == 0 {
+			return db
+		}
+		group := db.Session(&gorm.Session{NewDB: true})
 		for _, condition := range conditions {
-			db = filter(condition, common.And)(db)
+			group = filter(condition, common.And)(group)
 		}
-		return db
+		return db.Where(group)
 	}
 }
 
@@ -437,10 +621,12 @@ func Order(orderBys common.OrderBys) func(db *gorm.DB) *gorm.DB {
 	}
 }
 
+// Preload returns a function that eager loads the given relations, restricted
+// to the tenant of the parent query
 func Preload(relations []string) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		for _, relation := range relations {
-			db = db.Preload(relation)
+			db = db.Preload(relation, tenancy.Scope)
 		}
 		return db
 	}
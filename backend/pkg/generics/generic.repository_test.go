@@ -0,0 +1,171 @@
+package generics_test
+
+import (
+	"backend/database"
+	"backend/database/databasetest"
+	"backend/pkg/audit"
+	"backend/pkg/common"
+	"backend/pkg/generics"
+
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Widget struct {
+	common.CommonEntity `gorm:"embedded"`
+	Name                string
+}
+
+type WidgetDTO struct {
+	common.CommonDTO `json:",inline,omitempty"`
+	Name             string `json:"name" validate:"required"`
+}
+
+func (w Widget) ToDTO() common.DTO {
+	return &WidgetDTO{CommonDTO: common.CommonDTO{ID: w.ID}, Name: w.Name}
+}
+
+func (w WidgetDTO) ToEntity() common.Entity {
+	return &Widget{CommonEntity: common.CommonEntity{ID: w.ID}, Name: w.Name}
+}
+
+// seed stores a1 and a2 for tenant a and b1 for tenant b
+func seed(t *testing.T) (a uuid.UUID, b uuid.UUID, widgets map[string]*Widget) {
+	t.Helper()
+	db := databasetest.Open(t, &Widget{}, &audit.Entry{})
+	a, b = uuid.New(), uuid.New()
+	widgets = map[string]*Widget{}
+	for _, name := range []string{"a1", "a2", "b1"} {
+		w := &Widget{Name: name}
+		w.TenantID = a
+		if strings.HasPrefix(name, "b") {
+			w.TenantID = b
+		}
+		if err := db.Create(w).Error; err != nil {
+			t.Fatal(err)
+		}
+		widgets[name] = w
+	}
+	return a, b, widgets
+}
+
+func repository(tenant uuid.UUID) generics.Repository[*Widget, *WidgetDTO] {
+	repository := generics.NewGenericRepositoryGORM[*Widget, *WidgetDTO]()
+	return repository.WithContext(common.WithTenant(context.Background(), tenant))
+}
+
+func TestUpdateOfAnotherTenant(t *testing.T) {
+	_, b, widgets := seed(t)
+
+	payload := &Widget{CommonEntity: common.CommonEntity{ID: widgets["a1"].ID}, Name: "taken"}
+	_, err := repository(b).Update(payload)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("got error %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+
+	var stored []Widget
+	if err := database.DB.Order("name").Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 3 {
+		t.Fatalf("got %d widgets, want 3", len(stored))
+	}
+	for _, w := range stored {
+		if w.Name == "taken" {
+			t.Fatalf("widget %s of tenant %s was overwritten", w.ID, w.TenantID)
+		}
+	}
+	var entries int64
+	database.DB.Model(&audit.Entry{}).Count(&entries)
+	if entries != 0 {
+		t.Fatalf("got %d audit entries, want none", entries)
+	}
+}
+
+func TestUpdateOfOwnTenant(t *testing.T) {
+	a, _, widgets := seed(t)
+
+	payload := &Widget{CommonEntity: common.CommonEntity{ID: widgets["a1"].ID}, Name: "renamed"}
+	if _, err := repository(a).Update(payload); err != nil {
+		t.Fatal(err)
+	}
+	var stored Widget
+	if err := database.DB.First(&stored, "id = ?", widgets["a1"].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "renamed" || stored.TenantID != a {
+		t.Fatalf("got %q of tenant %s, want renamed of tenant %s", stored.Name, stored.TenantID, a)
+	}
+}
+
+func TestFiltersKeepTheTenant(t *testing.T) {
+	a, _, _ := seed(t)
+	equal := func(name string) common.SQLLeafCondition {
+		return common.SQLLeafCondition{Field: "name", Value: name, Comparator: common.Equal}
+	}
+	tests := []struct {
+		name       string
+		conditions common.SQLConditions
+		want       []string
+	}{
+		{"no filter", common.NoConditions, []string{"a1", "a2"}},
+		{"or", common.SQLConditions{
+			equal("a1"),
+			common.SQLCompositeCondition{Type: common.Or, Conditions: []common.SQLCondition{equal("b1")}},
+		}, []string{"a1"}},
+		{"or of another tenant only", common.SQLConditions{
+			common.SQLCompositeCondition{Type: common.Or, Conditions: []common.SQLCondition{equal("b1"), equal("a2")}},
+		}, []string{"a2"}},
+		{"or always true", common.SQLConditions{
+			equal("none"),
+			common.SQLCompositeCondition{Type: common.Or, Conditions: []common.SQLCondition{
+				common.SQLLeafCondition{Field: "name", Comparator: common.IsNotNull},
+			}},
+		}, []string{"a1", "a2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repository(a).FindAll(common.Pageable{Page: 1, Size: 10}, tt.conditions, nil, common.NoOrder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, w := range page.Items {
+				got = append(got, w.Name)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			count, err := repository(a).Count(tt.conditions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != int64(len(tt.want)) {
+				t.Fatalf("counted %d, want %d", count, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestFiltersGroupTheConditions(t *testing.T) {
+	databasetest.Open(t, &Widget{})
+	conditions := common.SQLConditions{
+		common.SQLLeafCondition{Field: "name", Value: "a1", Comparator: common.Equal},
+		common.SQLCompositeCondition{Type: common.Or, Conditions: []common.SQLCondition{
+			common.SQLLeafCondition{Field: "name", Value: "b1", Comparator: common.Equal},
+		}},
+	}
+	sql := database.DB.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("tenant_id = ?", "t").Scopes(generics.Filters(conditions)).Find(&[]Widget{})
+	})
+	if !strings.Contains(sql, "tenant_id = \"t\" AND (") {
+		t.Fatalf("the filters are not grouped apart from the tenant: %s", sql)
+	}
+}
@@ -17,11 +17,26 @@ func Unauthorized(c *fiber.Ctx, err error, message string) error {
 		JSON(common.NewErrorResponse(err, message))
 }
 
+func Forbidden(c *fiber.Ctx, err error, message string) error {
+	return c.Status(fiber.StatusForbidden).
+		JSON(common.NewErrorResponse(err, message))
+}
+
 func NotFound(c *fiber.Ctx, err error, message string) error {
 	return c.Status(fiber.StatusNotFound).
 		JSON(common.NewErrorResponse(err, message))
 }
 
+func Conflict(c *fiber.Ctx, err error, message string) error {
+	return c.Status(fiber.StatusConflict).
+		JSON(common.NewErrorResponse(err, message))
+}
+
+func UnprocessableEntity(c *fiber.Ctx, err error, message string) error {
+	return c.Status(fiber.StatusUnprocessableEntity).
+		JSON(common.NewErrorResponse(err, message))
+}
+
 func PayloadValidationFailed(c *fiber.Ctx, errors []*helpers.ValidationErrors, message string) error {
 	return c.Status(fiber.StatusBadRequest).
 		JSON(common.NewValidationErrorResponse(errors, message))
@@ -57,6 +72,33 @@ func Ok(c *fiber.Ctx, data interface{}, message string) error {
 		JSON(common.NewSuccessResponse(data, message))
 }
 
+// BulkProcessed answers a bulk request with status when every item was
+// committed, 207 when only some of them were, and 422 when nothing was
+func BulkProcessed(c *fiber.Ctx, result common.BulkResult, status int, message string) error {
+	switch {
+	case !result.Committed:
+		return c.Status(fiber.StatusUnprocessableEntity).
+			JSON(common.NewBulkErrorResponse(result, "Bulk operation rolled back, nothing was saved"))
+	case result.Failed > 0:
+		return c.Status(fiber.StatusMultiStatus).
+			JSON(common.NewSuccessResponse(result, message))
+	default:
+		return c.Status(status).
+			JSON(common.NewSuccessResponse(result, message))
+	}
+}
+
+// Imported reports the rows of an import, which is only committed when none
+// of them failed
+func Imported(c *fiber.Ctx, result common.ImportResult, message string) error {
+	if result.Failed > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).
+			JSON(common.NewImportErrorResponse(result, "Import rolled back, nothing was saved"))
+	}
+	return c.Status(fiber.StatusOK).
+		JSON(common.NewSuccessResponse(result, message))
+}
+
 func Unimplemented(c *fiber.Ctx, message string) error {
 	return c.Status(fiber.StatusNotImplemented).
 		JSON(common.NewErrorResponse(nil, message))
@@ -1,9 +1,13 @@
 package generics
 
 import (
+	"backend/pkg/audit"
+	"backend/pkg/common"
+	"backend/pkg/export"
 	"fmt"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 type RouteDefinition struct {
@@ -11,8 +15,60 @@ type RouteDefinition struct {
 	Path    string
 	Handler fiber.Handler
 	Name    string
+
+	// Optional metadata used to document the route. Request and Response hold
+	// a zero value of the body type and of the type sent in the data field of
+	// the ApiResponse envelope
+	Request  interface{}
+	Response interface{}
+	Query    []QueryParameter
+	// ContentType is set when the route answers with a document of that type
+	// instead of the ApiResponse envelope
+	ContentType string
+	// Exports lists the media types the route also answers with when the
+	// Accept header asks for them
+	Exports []string
+	// Accepts is set when the route reads a document of that type instead of
+	// a JSON body
+	Accepts string
+}
+
+type QueryParameter struct {
+	Name        string
+	Description string
 }
 
+var (
+	PaginationQuery = []QueryParameter{
+		{Name: "page", Description: "Page number, starting at 1"},
+		{Name: "size", Description: "Number of items per page"},
+	}
+	FiltersQuery = QueryParameter{
+		Name:        "filters",
+		Description: "Comma separated conditions, either field;comparator;value or and|or|not;(conditions). Comparators: eq, like, ilike, gt, lt, gte, lte, in, isnull, isnotnull",
+	}
+	OrdersQuery = QueryParameter{
+		Name:        "orders",
+		Description: "Comma separated field:asc or field:desc",
+	}
+	RelationsQuery = QueryParameter{
+		Name:        "relations",
+		Description: "Comma separated relations to preload, nested with dots",
+	}
+	FieldsQuery = QueryParameter{
+		Name:        "fields",
+		Description: "Comma separated fields of a CSV, NDJSON or XLSX export, all of them by default",
+	}
+	BulkModeQuery = QueryParameter{
+		Name:        "mode",
+		Description: fmt.Sprintf("Either %s (default) or %s", common.AllOrNothing, common.BestEffort),
+	}
+	DryRunQuery = QueryParameter{
+		Name:        "dry_run",
+		Description: "When true every row is checked and reported, but nothing is saved",
+	}
+)
+
 var NoMiddlewares = []fiber.Handler{}
 
 func NewGenericRouter(controller GenericController, middlewares []fiber.Handler, extraRoutes ...RouteDefinition) *fiber.App {
@@ -22,17 +78,8 @@ func NewGenericRouter(controller GenericController, middlewares []fiber.Handler,
 		app.Use(middleware)
 	}
 
-	app.Get("/", controller.GetAll()).Name(fmt.Sprintf("Get all %s", controller.GetResourceNames().Plural))
-	app.Get("/count", controller.Count()).Name(fmt.Sprintf("Count %s", controller.GetResourceNames().Plural))
-	app.Get("/:id", controller.Get()).Name(fmt.Sprintf("Get one %s", controller.GetResourceNames().Singular))
-	app.Post("/", controller.Create()).Name(fmt.Sprintf("Create one %s", controller.GetResourceNames().Singular))
-	app.Put("/:id", controller.Update()).Name(fmt.Sprintf("Update one %s", controller.GetResourceNames().Singular))
-	app.Delete("/:id", controller.Delete()).Name(fmt.Sprintf("Delete one %s", controller.GetResourceNames().Singular))
-
-	app.Delete("/:id/hard", controller.HardDelete()).Name(fmt.Sprintf("Hard delete one %s", controller.GetResourceNames().Singular))
-	app.Get("/deleted", controller.GetAllDeleted()).Name(fmt.Sprintf("Get deleted %s", controller.GetResourceNames().Plural))
-
-	for _, route := range extraRoutes {
+	// Extra routes go first, so /:id.ics is not taken for an /:id
+	for _, route := range append(extraRoutes, controller.Routes()...) {
 		switch route.Verb {
 		case "GET":
 			app.Get(route.Path, route.Handler).Name(route.Name)
@@ -40,6 +87,8 @@ func NewGenericRouter(controller GenericController, middlewares []fiber.Handler,
 			app.Post(route.Path, route.Handler).Name(route.Name)
 		case "PUT":
 			app.Put(route.Path, route.Handler).Name(route.Name)
+		case "PATCH":
+			app.Patch(route.Path, route.Handler).Name(route.Name)
 		case "DELETE":
 			app.Delete(route.Path, route.Handler).Name(route.Name)
 		}
@@ -47,3 +96,44 @@ func NewGenericRouter(controller GenericController, middlewares []fiber.Handler,
 
 	return app
 }
+
+// Routes returns the routes every generic resource exposes, in the order they
+// must be registered so static segments win over /:id
+func (imp GenericControllerImpl[E, DTO]) Routes() []RouteDefinition {
+	var dto DTO
+	singular, plural := imp.names.Singular, imp.names.Plural
+	listQuery := append([]QueryParameter{FiltersQuery, OrdersQuery, RelationsQuery, FieldsQuery}, PaginationQuery...)
+
+	routes := []RouteDefinition{
+		{Verb: "GET", Path: "/", Handler: imp.GetAll(), Name: fmt.Sprintf("Get all %s", plural),
+			Response: common.Page[DTO]{}, Query: listQuery, Exports: export.MediaTypes},
+		{Verb: "GET", Path: "/count", Handler: imp.Count(), Name: fmt.Sprintf("Count %s", plural),
+			Response: int64(0), Query: []QueryParameter{FiltersQuery}},
+		{Verb: "GET", Path: "/deleted", Handler: imp.GetAllDeleted(), Name: fmt.Sprintf("Get deleted %s", plural),
+			Response: common.Page[DTO]{}, Query: listQuery, Exports: export.MediaTypes},
+		{Verb: "POST", Path: "/bulk", Handler: imp.BulkCreate(), Name: fmt.Sprintf("Bulk create %s", plural),
+			Request: []DTO{}, Response: common.BulkResult{}, Query: []QueryParameter{BulkModeQuery}},
+		{Verb: "PATCH", Path: "/bulk", Handler: imp.BulkUpdate(), Name: fmt.Sprintf("Bulk update %s", plural),
+			Request: []DTO{}, Response: common.BulkResult{}, Query: []QueryParameter{BulkModeQuery}},
+		{Verb: "DELETE", Path: "/bulk", Handler: imp.BulkDelete(), Name: fmt.Sprintf("Bulk delete %s", plural),
+			Request: []uuid.UUID{}, Response: common.BulkResult{}, Query: []QueryParameter{BulkModeQuery}},
+		{Verb: "GET", Path: "/:id", Handler: imp.Get(), Name: fmt.Sprintf("Get one %s", singular),
+			Response: dto, Query: []QueryParameter{RelationsQuery}},
+		{Verb: "POST", Path: "/", Handler: imp.Create(), Name: fmt.Sprintf("Create one %s", singular),
+			Request: dto, Response: dto},
+		{Verb: "PUT", Path: "/:id", Handler: imp.Update(), Name: fmt.Sprintf("Update one %s", singular),
+			Request: dto, Response: dto},
+		{Verb: "DELETE", Path: "/:id", Handler: imp.Delete(), Name: fmt.Sprintf("Delete one %s", singular),
+			Response: ""},
+		{Verb: "GET", Path: "/:id/history", Handler: imp.History(), Name: fmt.Sprintf("Get history of one %s", singular),
+			Response: common.Page[*audit.EntryDTO]{}, Query: PaginationQuery},
+		{Verb: "DELETE", Path: "/:id/hard", Handler: imp.HardDelete(), Name: fmt.Sprintf("Hard delete one %s", singular),
+			Response: ""},
+	}
+
+	if len(imp.naturalKey) > 0 {
+		routes = append(routes, RouteDefinition{Verb: "POST", Path: "/import", Handler: imp.Import(), Name: fmt.Sprintf("Import %s", plural),
+			Accepts: export.CSV, Response: common.ImportResult{}, Query: []QueryParameter{DryRunQuery}})
+	}
+	return routes
+}
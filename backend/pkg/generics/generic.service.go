@@ -0,0 +1,155 @@
+package generics
+
+import (
+	"backend/pkg/common"
+	"backend/pkg/helpers"
+
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Service exposes the repository of a controller without its type parameters,
+// for transports other than REST that only discover the controllers at runtime.
+// Payloads are decoded and validated the same way the REST handlers do
+type Service interface {
+	Names() ResourceNames
+	DTO() common.DTO
+	Schema() (*schema.Schema, error)
+
+	FindOne(ctx context.Context, id uuid.UUID) (common.Entity, error)
+	FindAll(ctx context.Context, pageable common.Pageable, conditions common.SQLConditions, orderBys common.OrderBys) (*common.Page[common.Entity], error)
+	FindIn(ctx context.Context, column string, values []interface{}, conditions common.SQLConditions, orderBys common.OrderBys) ([]common.Entity, error)
+	Count(ctx context.Context, conditions common.SQLConditions) (int64, error)
+	Create(ctx context.Context, payload []byte) (common.Entity, error)
+	Update(ctx context.Context, id uuid.UUID, payload []byte) (common.Entity, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	Import(ctx context.Context, document io.Reader, dryRun bool) (common.ImportResult, error)
+}
+
+// InvalidPayloadError is returned by a Service when the decoded payload does
+// not pass validation
+type InvalidPayloadError struct {
+	Errors []*helpers.ValidationErrors
+}
+
+func (e InvalidPayloadError) Error() string {
+	return "Validation failed"
+}
+
+type GenericService[E common.Entity, DTO common.DTO] struct {
+	names      ResourceNames
+	repository GenericRepository[E, DTO]
+	naturalKey []string
+}
+
+func (imp GenericControllerImpl[E, DTO]) Service() Service {
+	return GenericService[E, DTO]{
+		names:      imp.names,
+		repository: imp.repository,
+		naturalKey: imp.naturalKey,
+	}
+}
+
+func (imp GenericService[E, DTO]) Names() ResourceNames {
+	return imp.names
+}
+
+func (imp GenericService[E, DTO]) DTO() common.DTO {
+	var dto DTO
+	return dto
+}
+
+// Schema returns the GORM schema of the entity, which describes its columns
+// and relationships
+func (imp GenericService[E, DTO]) Schema() (*schema.Schema, error) {
+	var entity E
+	statement := &gorm.Statement{DB: imp.repository.conn()}
+	if err := statement.Parse(&entity); err != nil {
+		return nil, err
+	}
+	return statement.Schema, nil
+}
+
+func (imp GenericService[E, DTO]) FindOne(ctx context.Context, id uuid.UUID) (common.Entity, error) {
+	return imp.repository.WithContext(ctx).FindOne(id, common.NoFields)
+}
+
+func (imp GenericService[E, DTO]) FindAll(ctx context.Context, pageable common.Pageable, conditions common.SQLConditions, orderBys common.OrderBys) (*common.Page[common.Entity], error) {
+	result, err := imp.repository.WithContext(ctx).FindAll(pageable, conditions, common.NoFields, orderBys)
+	if err != nil {
+		return nil, err
+	}
+	page := common.NewPage(entities(result.Items), result.Page, result.Size, result.Total, result.Filtered)
+	return &page, nil
+}
+
+func (imp GenericService[E, DTO]) FindIn(ctx context.Context, column string, values []interface{}, conditions common.SQLConditions, orderBys common.OrderBys) ([]common.Entity, error) {
+	result, err := imp.repository.WithContext(ctx).FindIn(column, values, conditions, orderBys)
+	if err != nil {
+		return nil, err
+	}
+	return entities(result), nil
+}
+
+func (imp GenericService[E, DTO]) Count(ctx context.Context, conditions common.SQLConditions) (int64, error) {
+	return imp.repository.WithContext(ctx).Count(conditions)
+}
+
+func (imp GenericService[E, DTO]) Create(ctx context.Context, payload []byte) (common.Entity, error) {
+	dto, err := imp.decode(payload)
+	if err != nil {
+		return nil, err
+	}
+	return imp.repository.WithContext(ctx).Create(dto.ToEntity().(E))
+}
+
+// Update replaces the entity like PUT /:id, the id of the payload is ignored
+func (imp GenericService[E, DTO]) Update(ctx context.Context, id uuid.UUID, payload []byte) (common.Entity, error) {
+	dto, err := imp.decode(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	repository := imp.repository.WithContext(ctx)
+	if exists, err := repository.Exists(id); err != nil || !exists {
+		return nil, fmt.Errorf("%s %s not found", imp.names.Singular, id)
+	}
+
+	entity := dto.ToEntity().(E)
+	entity.SetID(id)
+	return repository.Update(entity)
+}
+
+func (imp GenericService[E, DTO]) Delete(ctx context.Context, id uuid.UUID) error {
+	repository := imp.repository.WithContext(ctx)
+	entity, err := repository.FindOne(id, common.NoFields)
+	if err != nil {
+		return fmt.Errorf("%s %s not found", imp.names.Singular, id)
+	}
+	return repository.Delete(entity)
+}
+
+func (imp GenericService[E, DTO]) decode(payload []byte) (DTO, error) {
+	var dto DTO
+	if err := json.Unmarshal(payload, &dto); err != nil {
+		return dto, fmt.Errorf("Invalid %s payload: %w", imp.names.Singular, err)
+	}
+	if errs := helpers.ValidateStruct(dto); len(errs) > 0 {
+		return dto, InvalidPayloadError{Errors: errs}
+	}
+	return dto, nil
+}
+
+func entities[E common.Entity](items []E) []common.Entity {
+	result := make([]common.Entity, len(items))
+	for i, item := range items {
+		result[i] = item
+	}
+	return result
+}
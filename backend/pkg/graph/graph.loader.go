@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"backend/pkg/common"
+
+	"context"
+	"sync"
+)
+
+// batchFn loads the entities of every key at once, grouped by key
+type batchFn func(ctx context.Context, keys []string) (map[string][]common.Entity, error)
+
+// loader collects the keys requested by the resolvers of one level of the
+// query and loads them with a single call to fetch when the first of them is
+// needed. The executor resolves every field of a level before completing any
+// of them, so siblings always end up in the same batch
+type loader struct {
+	mutex   sync.Mutex
+	fetch   batchFn
+	pending []string
+	results map[string][]common.Entity
+	err     error
+}
+
+// Load queues key and returns a thunk the executor calls to complete the field
+func (l *loader) Load(ctx context.Context, key string) func() ([]common.Entity, error) {
+	l.mutex.Lock()
+	if _, ok := l.results[key]; !ok {
+		l.pending = append(l.pending, key)
+	}
+	l.mutex.Unlock()
+
+	return func() ([]common.Entity, error) {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+
+		if len(l.pending) > 0 {
+			keys := l.pending
+			l.pending = nil
+
+			results, err := l.fetch(ctx, keys)
+			if err != nil {
+				l.err = err
+			}
+			for _, key := range keys {
+				l.results[key] = results[key]
+			}
+		}
+		return l.results[key], l.err
+	}
+}
+
+// loaders keeps one loader per relationship and arguments for the lifetime
+// of a request, so nothing is cached across requests or tenants
+type loaders struct {
+	mutex   sync.Mutex
+	loaders map[string]*loader
+}
+
+type loadersKey struct{}
+
+// WithLoaders returns a context carrying empty loaders, every request must
+// get its own
+func WithLoaders(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &loaders{loaders: map[string]*loader{}})
+}
+
+// loaderFor returns the loader registered under name, creating it with fetch
+// the first time
+func loaderFor(ctx context.Context, name string, fetch batchFn) *loader {
+	registry, ok := ctx.Value(loadersKey{}).(*loaders)
+	if !ok {
+		// Without loaders in the context every field is loaded on its own
+		return &loader{fetch: fetch, results: map[string][]common.Entity{}}
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	l, ok := registry.loaders[name]
+	if !ok {
+		l = &loader{fetch: fetch, results: map[string][]common.Entity{}}
+		registry.loaders[name] = l
+	}
+	return l
+}
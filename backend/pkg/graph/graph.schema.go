@@ -0,0 +1,485 @@
+package graph
+
+import (
+	"backend/pkg/common"
+	"backend/pkg/generics"
+	"backend/pkg/openapi"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/iancoleman/strcase"
+	"gorm.io/gorm/schema"
+)
+
+// resource holds a registered service with the types derived from it
+type resource struct {
+	service generics.Service
+	name    string
+	schema  *schema.Schema
+	object  *graphql.Object
+	page    *graphql.Object
+	input   *graphql.InputObject
+}
+
+// node is the source of every object field: the entity, to follow its
+// relationships, and its DTO encoded like the REST API does
+type node struct {
+	entity common.Entity
+	fields map[string]interface{}
+}
+
+func newNode(entity common.Entity) (*node, error) {
+	payload, err := json.Marshal(entity.ToDTO())
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, err
+	}
+	return &node{entity: entity, fields: fields}, nil
+}
+
+func newNodes(entities []common.Entity) ([]*node, error) {
+	nodes := make([]*node, len(entities))
+	for i, entity := range entities {
+		n, err := newNode(entity)
+		if err != nil {
+			return nil, err
+		}
+		nodes[i] = n
+	}
+	return nodes, nil
+}
+
+// readOnlyFields are set by the server and left out of the input types
+var readOnlyFields = map[string]bool{"id": true, "createdAt": true, "updatedAt": true}
+
+var namePattern = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// NewSchema builds the GraphQL schema of services. Every service gets an
+// object type with the fields of its DTO and one field per GORM relationship
+// to another registered service, a query for one entity, a paginated query
+// and a count, and create, update and delete mutations
+func NewSchema(services []generics.Service) (graphql.Schema, error) {
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Names().Plural < services[j].Names().Plural
+	})
+
+	registry := openapi.NewRegistry()
+	resources := make([]*resource, 0, len(services))
+	byTable := map[string]*resource{}
+
+	for _, service := range services {
+		entitySchema, err := service.Schema()
+		if err != nil {
+			return graphql.Schema{}, err
+		}
+		r := &resource{
+			service: service,
+			name:    strcase.ToCamel(service.Names().Singular),
+			schema:  entitySchema,
+		}
+		resources = append(resources, r)
+		byTable[entitySchema.Table] = r
+	}
+
+	for _, r := range resources {
+		dto := dtoSchema(registry, r.service.DTO())
+		r.object = newObject(r, dto, byTable)
+		r.input = newInput(r, dto)
+		r.page = graphql.NewObject(graphql.ObjectConfig{
+			Name: r.name + "Page",
+			Fields: graphql.Fields{
+				"items":    {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(r.object)))},
+				"page":     {Type: graphql.NewNonNull(graphql.Int)},
+				"size":     {Type: graphql.NewNonNull(graphql.Int)},
+				"total":    {Type: graphql.NewNonNull(graphql.Int)},
+				"filtered": {Type: graphql.NewNonNull(graphql.Int)},
+			},
+		})
+	}
+
+	queries := graphql.Fields{}
+	mutations := graphql.Fields{}
+	for _, r := range resources {
+		addQueries(queries, r)
+		addMutations(mutations, r)
+	}
+
+	config := graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: queries}),
+	}
+	if len(mutations) > 0 {
+		config.Mutation = graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: mutations})
+	}
+	return graphql.NewSchema(config)
+}
+
+// dtoSchema describes the JSON encoding of dto, reusing the reflection of the
+// OpenAPI document so both APIs expose the same fields
+func dtoSchema(registry *openapi.Registry, dto common.DTO) *openapi.Schema {
+	ref := registry.SchemaOf(dto)
+	return registry.Schemas()[strings.TrimPrefix(ref.Ref, "#/components/schemas/")]
+}
+
+func outputType(property *openapi.Schema) graphql.Output {
+	switch property.Type {
+	case "string":
+		switch property.Format {
+		case "uuid":
+			return graphql.ID
+		case "date-time":
+			return DateTime
+		}
+		return graphql.String
+	case "integer":
+		return graphql.Int
+	case "number":
+		return graphql.Float
+	case "boolean":
+		return graphql.Boolean
+	default:
+		return JSON
+	}
+}
+
+func inputType(property *openapi.Schema) graphql.Input {
+	return outputType(property).(graphql.Input)
+}
+
+func newObject(r *resource, dto *openapi.Schema, byTable map[string]*resource) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: r.name,
+		// Relationships point at objects that may not exist yet
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+			fields := graphql.Fields{}
+			for _, name := range dto.PropertyNames() {
+				if !namePattern.MatchString(name) {
+					continue
+				}
+				var fieldType graphql.Output = outputType(dto.Properties[name])
+				if name == "id" {
+					fieldType = graphql.NewNonNull(fieldType)
+				}
+				fields[name] = &graphql.Field{
+					Type:    fieldType,
+					Resolve: scalarResolver(name),
+				}
+			}
+
+			names := make([]string, 0, len(r.schema.Relationships.Relations))
+			for name := range r.schema.Relationships.Relations {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				relationship := r.schema.Relationships.Relations[name]
+				target, ok := byTable[relationship.FieldSchema.Table]
+				if !ok || relationship.Type == schema.Many2Many || len(relationship.References) != 1 || relationship.References[0].PrimaryValue != "" {
+					continue
+				}
+				field := strcase.ToLowerCamel(name)
+				if _, taken := fields[field]; taken {
+					continue
+				}
+				fields[field] = relationField(r, relationship, target)
+			}
+			return fields
+		}),
+	})
+}
+
+func newInput(r *resource, dto *openapi.Schema) *graphql.InputObject {
+	required := map[string]bool{}
+	for _, name := range dto.Required {
+		required[name] = true
+	}
+
+	fields := graphql.InputObjectConfigFieldMap{}
+	for _, name := range dto.PropertyNames() {
+		if readOnlyFields[name] || !namePattern.MatchString(name) {
+			continue
+		}
+		fieldType := inputType(dto.Properties[name])
+		if required[name] {
+			fieldType = graphql.NewNonNull(fieldType)
+		}
+		fields[name] = &graphql.InputObjectFieldConfig{Type: fieldType}
+	}
+
+	return graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:   r.name + "Input",
+		Fields: fields,
+	})
+}
+
+func scalarResolver(name string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		source, ok := p.Source.(*node)
+		if !ok {
+			return nil, nil
+		}
+		return source.fields[name], nil
+	}
+}
+
+// relationField resolves a relationship through a loader, so the relation of
+// every parent of a level is read with one query. Has many relationships take
+// filters and orders, which apply to the related rows
+func relationField(r *resource, relationship *schema.Relationship, target *resource) *graphql.Field {
+	reference := relationship.References[0]
+
+	// ownKey is read from the parent, targetKey is the column holding it
+	ownKey, targetKey := reference.ForeignKey, reference.PrimaryKey
+	if reference.OwnPrimaryKey {
+		ownKey, targetKey = reference.PrimaryKey, reference.ForeignKey
+	}
+	many := relationship.Type == schema.HasMany
+
+	field := &graphql.Field{Type: target.object}
+	if many {
+		field.Type = graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(target.object)))
+		field.Args = relationArgs
+	}
+
+	field.Resolve = func(p graphql.ResolveParams) (interface{}, error) {
+		source, ok := p.Source.(*node)
+		if !ok {
+			return nil, nil
+		}
+		key, zero := ownKey.ValueOf(p.Context, reflect.ValueOf(source.entity))
+		if zero || isNil(key) {
+			if many {
+				return []*node{}, nil
+			}
+			return nil, nil
+		}
+
+		conditions, err := conditionsFromArgs(p.Args)
+		if err != nil {
+			return nil, err
+		}
+		orderBys, err := orderBysFromArgs(p.Args)
+		if err != nil {
+			return nil, err
+		}
+		args, err := json.Marshal(p.Args)
+		if err != nil {
+			return nil, err
+		}
+
+		name := fmt.Sprintf("%s.%s%s", r.name, relationship.Name, args)
+		thunk := loaderFor(p.Context, name, func(ctx context.Context, keys []string) (map[string][]common.Entity, error) {
+			values := make([]interface{}, len(keys))
+			for i, key := range keys {
+				values[i] = key
+			}
+			entities, err := target.service.FindIn(ctx, targetKey.DBName, values, conditions, orderBys)
+			if err != nil {
+				return nil, err
+			}
+			grouped := map[string][]common.Entity{}
+			for _, entity := range entities {
+				value, _ := targetKey.ValueOf(ctx, reflect.ValueOf(entity))
+				grouped[fmt.Sprint(value)] = append(grouped[fmt.Sprint(value)], entity)
+			}
+			return grouped, nil
+		}).Load(p.Context, fmt.Sprint(key))
+
+		return func() (interface{}, error) {
+			entities, err := thunk()
+			if err != nil {
+				return nil, err
+			}
+			nodes, err := newNodes(entities)
+			if err != nil {
+				return nil, err
+			}
+			if many {
+				return nodes, nil
+			}
+			if len(nodes) == 0 {
+				return nil, nil
+			}
+			return nodes[0], nil
+		}, nil
+	}
+	return field
+}
+
+// isNil reports whether a key read from an entity points at nothing
+func isNil(key interface{}) bool {
+	switch v := key.(type) {
+	case nil:
+		return true
+	case uuid.UUID:
+		return v == uuid.Nil
+	case string:
+		return v == ""
+	}
+	return false
+}
+
+func addQueries(fields graphql.Fields, r *resource) {
+	names := r.service.Names()
+
+	fields[strcase.ToLowerCamel(names.Singular)] = &graphql.Field{
+		Type: r.object,
+		Args: graphql.FieldConfigArgument{
+			"id": {Type: graphql.NewNonNull(graphql.ID)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, err := idFromArgs(p.Args)
+			if err != nil {
+				return nil, err
+			}
+			entity, err := r.service.FindOne(p.Context, id)
+			if err != nil {
+				return nil, fmt.Errorf("%s not found", names.Singular)
+			}
+			return newNode(entity)
+		},
+	}
+
+	fields[strcase.ToLowerCamel(names.Plural)] = &graphql.Field{
+		Type: graphql.NewNonNull(r.page),
+		Args: listArgs,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			conditions, err := conditionsFromArgs(p.Args)
+			if err != nil {
+				return nil, err
+			}
+			orderBys, err := orderBysFromArgs(p.Args)
+			if err != nil {
+				return nil, err
+			}
+			result, err := r.service.FindAll(p.Context, pageableFromArgs(p.Args), conditions, orderBys)
+			if err != nil {
+				return nil, fmt.Errorf("%s not found", names.Plural)
+			}
+			nodes, err := newNodes(result.Items)
+			if err != nil {
+				return nil, err
+			}
+			return common.NewPage(nodes, result.Page, result.Size, result.Total, result.Filtered), nil
+		},
+	}
+
+	fields[strcase.ToLowerCamel(names.Plural+" count")] = &graphql.Field{
+		Type: graphql.NewNonNull(graphql.Int),
+		Args: countArgs,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			conditions, err := conditionsFromArgs(p.Args)
+			if err != nil {
+				return nil, err
+			}
+			count, err := r.service.Count(p.Context, conditions)
+			if err != nil {
+				return nil, fmt.Errorf("Error counting %s", names.Plural)
+			}
+			return count, nil
+		},
+	}
+}
+
+func addMutations(fields graphql.Fields, r *resource) {
+	names := r.service.Names()
+
+	fields[strcase.ToLowerCamel("create "+names.Singular)] = &graphql.Field{
+		Type: graphql.NewNonNull(r.object),
+		Args: graphql.FieldConfigArgument{
+			"input": {Type: graphql.NewNonNull(r.input)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			payload, err := json.Marshal(p.Args["input"])
+			if err != nil {
+				return nil, err
+			}
+			entity, err := r.service.Create(p.Context, payload)
+			if err != nil {
+				return nil, mutationError(err)
+			}
+			return newNode(entity)
+		},
+	}
+
+	fields[strcase.ToLowerCamel("update "+names.Singular)] = &graphql.Field{
+		Type: graphql.NewNonNull(r.object),
+		Args: graphql.FieldConfigArgument{
+			"id":    {Type: graphql.NewNonNull(graphql.ID)},
+			"input": {Type: graphql.NewNonNull(r.input)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, err := idFromArgs(p.Args)
+			if err != nil {
+				return nil, err
+			}
+			payload, err := json.Marshal(p.Args["input"])
+			if err != nil {
+				return nil, err
+			}
+			entity, err := r.service.Update(p.Context, id, payload)
+			if err != nil {
+				return nil, mutationError(err)
+			}
+			return newNode(entity)
+		},
+	}
+
+	fields[strcase.ToLowerCamel("delete "+names.Singular)] = &graphql.Field{
+		Type: graphql.NewNonNull(graphql.Boolean),
+		Args: graphql.FieldConfigArgument{
+			"id": {Type: graphql.NewNonNull(graphql.ID)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, err := idFromArgs(p.Args)
+			if err != nil {
+				return nil, err
+			}
+			if err := r.service.Delete(p.Context, id); err != nil {
+				return nil, mutationError(err)
+			}
+			return true, nil
+		},
+	}
+}
+
+func idFromArgs(args map[string]interface{}) (uuid.UUID, error) {
+	id, _ := args["id"].(string)
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid id %q", id)
+	}
+	return parsed, nil
+}
+
+// validationError carries the errors of an invalid payload in the extensions
+// of the GraphQL error, with the same shape as the data of a REST response
+type validationError struct {
+	generics.InvalidPayloadError
+}
+
+func (e validationError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":   "VALIDATION_FAILED",
+		"errors": e.Errors,
+	}
+}
+
+func mutationError(err error) error {
+	var invalid generics.InvalidPayloadError
+	if errors.As(err, &invalid) {
+		return validationError{invalid}
+	}
+	return err
+}
@@ -0,0 +1,235 @@
+package graph
+
+import (
+	"backend/pkg/common"
+
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/iancoleman/strcase"
+)
+
+// DateTime is an RFC 3339 timestamp, encoded like the REST API does
+var DateTime = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "DateTime",
+	Description: "RFC 3339 timestamp",
+	Serialize: func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case time.Time:
+			return v.Format(time.RFC3339Nano)
+		case string:
+			return v
+		default:
+			return nil
+		}
+	},
+	ParseValue: func(value interface{}) interface{} {
+		if v, ok := value.(string); ok {
+			if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				return v
+			}
+		}
+		return nil
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		if v, ok := valueAST.(*ast.StringValue); ok {
+			if _, err := time.Parse(time.RFC3339Nano, v.Value); err == nil {
+				return v.Value
+			}
+		}
+		return nil
+	},
+})
+
+// JSON is any JSON value, used for fields without a better mapping
+var JSON = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "Arbitrary JSON value",
+	Serialize: func(value interface{}) interface{} {
+		return value
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return value
+	},
+	ParseLiteral: parseJSONLiteral,
+})
+
+func parseJSONLiteral(valueAST ast.Value) interface{} {
+	switch v := valueAST.(type) {
+	case *ast.ObjectValue:
+		object := map[string]interface{}{}
+		for _, field := range v.Fields {
+			object[field.Name.Value] = parseJSONLiteral(field.Value)
+		}
+		return object
+	case *ast.ListValue:
+		list := make([]interface{}, len(v.Values))
+		for i, item := range v.Values {
+			list[i] = parseJSONLiteral(item)
+		}
+		return list
+	default:
+		return v.GetValue()
+	}
+}
+
+var comparatorEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name:        "Comparator",
+	Description: "Comparators of the filters query parameter of the REST API",
+	Values: graphql.EnumValueConfigMap{
+		"EQ":          {Value: common.Equal},
+		"LIKE":        {Value: common.Like},
+		"ILIKE":       {Value: common.ILike},
+		"GT":          {Value: common.GreaterThan},
+		"LT":          {Value: common.LessThan},
+		"GTE":         {Value: common.GreaterEqualThan},
+		"LTE":         {Value: common.LessEqualThan},
+		"IN":          {Value: common.In, Description: "Value holds the candidates separated by |"},
+		"IS_NULL":     {Value: common.IsNull},
+		"IS_NOT_NULL": {Value: common.IsNotNull},
+	},
+})
+
+var compositorEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "Compositor",
+	Values: graphql.EnumValueConfigMap{
+		"AND": {Value: common.And},
+		"OR":  {Value: common.Or},
+		"NOT": {Value: common.Not},
+	},
+})
+
+var directionEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "Direction",
+	Values: graphql.EnumValueConfigMap{
+		"ASC":  {Value: common.Asc},
+		"DESC": {Value: common.Desc},
+	},
+})
+
+// filterInput mirrors SQLCondition: a leaf sets field, comparator and value,
+// a composite sets compositor and filters
+var filterInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "FilterInput",
+	Description: "Condition on a field, or on field of a relation written as relation.field",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"field":      {Type: graphql.String},
+		"comparator": {Type: comparatorEnum},
+		"value":      {Type: graphql.String},
+		"compositor": {Type: compositorEnum},
+	},
+})
+
+func init() {
+	// Composite filters nest, which the initializer cannot express
+	filterInput.AddFieldConfig("filters", &graphql.InputObjectFieldConfig{
+		Type: graphql.NewList(graphql.NewNonNull(filterInput)),
+	})
+}
+
+var orderInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "OrderInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"field":     {Type: graphql.NewNonNull(graphql.String)},
+		"direction": {Type: directionEnum, DefaultValue: common.Asc},
+	},
+})
+
+var fieldPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// column converts a field name to the column name the REST API would use
+func column(field string) (string, error) {
+	if !fieldPattern.MatchString(field) {
+		return "", fmt.Errorf("invalid field %q, expected field or relation.field", field)
+	}
+	parts := strings.Split(field, ".")
+	for i, part := range parts {
+		parts[i] = strcase.ToSnake(part)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// conditionsFromArgs converts the filters argument to the conditions taken by
+// the repository
+func conditionsFromArgs(args map[string]interface{}) (common.SQLConditions, error) {
+	filters, _ := args["filters"].([]interface{})
+	return conditions(filters)
+}
+
+func conditions(filters []interface{}) (common.SQLConditions, error) {
+	result := common.SQLConditions{}
+	for _, item := range filters {
+		filter, _ := item.(map[string]interface{})
+
+		if compositor, ok := filter["compositor"].(common.SQLCompositor); ok {
+			nested, _ := filter["filters"].([]interface{})
+			inner, err := conditions(nested)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, common.SQLCompositeCondition{Type: compositor, Conditions: inner})
+			continue
+		}
+
+		field, _ := filter["field"].(string)
+		comparator, ok := filter["comparator"].(common.SQLOperator)
+		if field == "" || !ok {
+			return nil, fmt.Errorf("filters need either a field and a comparator, or a compositor")
+		}
+		name, err := column(field)
+		if err != nil {
+			return nil, err
+		}
+		value, _ := filter["value"].(string)
+		result = append(result, common.SQLLeafCondition{Field: name, Comparator: comparator, Value: value})
+	}
+	return result, nil
+}
+
+// orderBysFromArgs converts the orders argument to the orders taken by the
+// repository
+func orderBysFromArgs(args map[string]interface{}) (common.OrderBys, error) {
+	orders, _ := args["orders"].([]interface{})
+	result := common.OrderBys{}
+	for _, item := range orders {
+		order, _ := item.(map[string]interface{})
+		field, _ := order["field"].(string)
+		name, err := column(field)
+		if err != nil {
+			return nil, err
+		}
+		direction, ok := order["direction"].(common.OrderDirection)
+		if !ok {
+			direction = common.Asc
+		}
+		result = append(result, common.OrderBy{Field: name, Direction: direction})
+	}
+	return result, nil
+}
+
+// pageableFromArgs reads page and size, pages start at 1
+func pageableFromArgs(args map[string]interface{}) common.Pageable {
+	page, _ := args["page"].(int)
+	size, _ := args["size"].(int)
+	return common.Pageable{Page: page, Size: size}
+}
+
+var listArgs = graphql.FieldConfigArgument{
+	"page":    {Type: graphql.Int, DefaultValue: 1},
+	"size":    {Type: graphql.Int, DefaultValue: 10},
+	"filters": {Type: graphql.NewList(graphql.NewNonNull(filterInput))},
+	"orders":  {Type: graphql.NewList(graphql.NewNonNull(orderInput))},
+}
+
+var relationArgs = graphql.FieldConfigArgument{
+	"filters": {Type: graphql.NewList(graphql.NewNonNull(filterInput))},
+	"orders":  {Type: graphql.NewList(graphql.NewNonNull(orderInput))},
+}
+
+var countArgs = graphql.FieldConfigArgument{
+	"filters": {Type: graphql.NewList(graphql.NewNonNull(filterInput))},
+}
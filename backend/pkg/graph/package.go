@@ -0,0 +1,3 @@
+// Graph package serves the registered resources and their relationships as a GraphQL schema.
+
+package graph
@@ -0,0 +1,53 @@
+package helpers
+
+import (
+	"reflect"
+	"strings"
+)
+
+// JSONField is a property of the JSON encoding of a struct
+type JSONField struct {
+	Name string
+	Type reflect.Type
+}
+
+// JSONFields lists the JSON properties of value in the order of its fields,
+// following the rules of encoding/json for embedded structs
+func JSONFields(value interface{}) []JSONField {
+	t := reflect.TypeOf(value)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return jsonFields(t)
+}
+
+func jsonFields(t reflect.Type) []JSONField {
+	result := []JSONField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				result = append(result, jsonFields(embedded)...)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		result = append(result, JSONField{Name: name, Type: field.Type})
+	}
+	return result
+}
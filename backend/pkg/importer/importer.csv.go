@@ -0,0 +1,165 @@
+package importer
+
+import (
+	"backend/pkg/helpers"
+
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// Row is one record of a document, encoded as the JSON object the API would
+// receive for it
+type Row struct {
+	// Line is where the record starts in the document, counting the header
+	Line    int
+	Payload []byte
+	// Err is set when a cell does not hold a value of its property, the rest
+	// of the document can still be read
+	Err error
+}
+
+// Reader reads the records of a CSV document whose header names the
+// properties of a DTO, either as the JSON property or as its snake case
+// column, in any case
+type Reader struct {
+	csv     *csv.Reader
+	columns []*helpers.JSONField
+}
+
+var textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// NewReader reads the header of the document in r, which must only name
+// properties of dto
+func NewReader(r io.Reader, dto interface{}) (*Reader, error) {
+	properties := map[string]*helpers.JSONField{}
+	for _, field := range helpers.JSONFields(dto) {
+		field := field
+		properties[strings.ToLower(field.Name)] = &field
+		properties[strcase.ToSnake(field.Name)] = &field
+	}
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	// Records with a wrong number of cells are reported like invalid ones
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("the document is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]*helpers.JSONField, len(header))
+	seen := map[string]bool{}
+	for i, name := range header {
+		// Spreadsheet apps may start the document with a byte order mark
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		field, ok := properties[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if seen[field.Name] {
+			return nil, fmt.Errorf("duplicated column %q", name)
+		}
+		seen[field.Name] = true
+		columns[i] = field
+	}
+
+	return &Reader{csv: reader, columns: columns}, nil
+}
+
+// Read returns the next record, or io.EOF once the document is over. Other
+// errors mean the document is malformed and nothing after them can be read
+func (r *Reader) Read() (Row, error) {
+	record, err := r.csv.Read()
+	if err != nil {
+		return Row{}, err
+	}
+	line, _ := r.csv.FieldPos(0)
+	row := Row{Line: line}
+	if len(record) != len(r.columns) {
+		row.Err = fmt.Errorf("expected %d cells, got %d", len(r.columns), len(record))
+		return row, nil
+	}
+
+	// Empty cells are left out, so their properties get the zero value
+	payload := &bytes.Buffer{}
+	payload.WriteByte('{')
+	for i, cell := range record {
+		if cell == "" {
+			continue
+		}
+		value, err := encode(r.columns[i].Type, cell)
+		if err != nil {
+			row.Err = fmt.Errorf("column %s: %w", r.columns[i].Name, err)
+			return row, nil
+		}
+		if payload.Len() > 1 {
+			payload.WriteByte(',')
+		}
+		name, _ := json.Marshal(r.columns[i].Name)
+		payload.Write(name)
+		payload.WriteByte(':')
+		payload.Write(value)
+	}
+	payload.WriteByte('}')
+
+	row.Payload = payload.Bytes()
+	return row, nil
+}
+
+// encode turns a cell into the JSON value of a property of type t. Cells of
+// properties that are neither scalars nor read from text must hold JSON
+func encode(t reflect.Type, cell string) (json.RawMessage, error) {
+	if t.Implements(textUnmarshaler) || reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return json.Marshal(cell)
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return json.Marshal(cell)
+	case reflect.Bool:
+		value, err := strconv.ParseBool(strings.TrimSpace(cell))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", cell)
+		}
+		return json.Marshal(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err := strconv.ParseInt(strings.TrimSpace(cell), 10, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", cell)
+		}
+		return json.Marshal(value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err := strconv.ParseUint(strings.TrimSpace(cell), 10, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a positive integer", cell)
+		}
+		return json.Marshal(value)
+	case reflect.Float32, reflect.Float64:
+		value, err := strconv.ParseFloat(strings.TrimSpace(cell), t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", cell)
+		}
+		return json.Marshal(value)
+	default:
+		if !json.Valid([]byte(cell)) {
+			return nil, fmt.Errorf("%q is not valid JSON", cell)
+		}
+		return json.RawMessage(cell), nil
+	}
+}
@@ -0,0 +1,3 @@
+// Importer package decodes the rows of CSV documents into the JSON payloads of a resource.
+
+package importer
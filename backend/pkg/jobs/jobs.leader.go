@@ -0,0 +1,137 @@
+package jobs
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// lockKey is the Postgres advisory lock the leader holds, "jobs" in ASCII
+const lockKey = 0x6a6f6273
+
+// leader holds the advisory lock electing the process queueing the periodic
+// jobs. The lock belongs to a session, so the connection that took it is kept
+// for as long as the process leads, and leadership ends with it
+type leader struct {
+	conn *sql.Conn
+}
+
+// lead tells whether this process leads, trying to take the lock when it
+// does not. Databases without advisory locks have every process lead
+func (l *leader) lead(ctx context.Context) (bool, error) {
+	if database.DB.Dialector.Name() != "postgres" {
+		return true, nil
+	}
+	if l.conn != nil {
+		if err := l.conn.PingContext(ctx); err == nil {
+			return true, nil
+		}
+		l.resign()
+	}
+
+	pool, err := database.DB.DB()
+	if err != nil {
+		return false, err
+	}
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockKey).Scan(&locked); err != nil || !locked {
+		conn.Close()
+		return false, err
+	}
+	l.conn = conn
+	return true, nil
+}
+
+// resign gives the lock up, by closing the session holding it
+func (l *leader) resign() {
+	if l.conn == nil {
+		return
+	}
+	l.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+	l.conn.Close()
+	l.conn = nil
+}
+
+// Start runs the queue until ctx is done: every services.jobs.poll_interval
+// the due jobs are worked, and the leader queues the due runs of the
+// schedules and the jobs whose worker went away. Every instance of the
+// server works the queue, only one leads
+func Start(ctx context.Context) {
+	poll := viper.GetDuration("services.jobs.poll_interval")
+	if poll <= 0 {
+		return
+	}
+	l := &leader{}
+	defer l.resign()
+	next := map[string]time.Time{}
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for {
+		leads, err := l.lead(ctx)
+		if err != nil {
+			log.Printf("Error electing the jobs leader: %s", err)
+		}
+		if leads {
+			if err := schedule(ctx, next); err != nil {
+				log.Printf("Error scheduling the jobs: %s", err)
+			}
+			if err := requeueStale(ctx); err != nil {
+				log.Printf("Error requeueing the stale jobs: %s", err)
+			}
+		} else {
+			clear(next)
+		}
+		if err := Work(ctx); err != nil {
+			log.Printf("Error working the jobs: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// schedule queues the runs of the plans due by now. next holds when each
+// plan is next due, counted from when this process started leading
+func schedule(ctx context.Context, next map[string]time.Time) error {
+	now := time.Now()
+	db := database.DB.WithContext(common.AsSystem(ctx))
+	for _, plan := range plans {
+		due, ok := next[plan.name]
+		if !ok {
+			next[plan.name] = plan.schedule.Next(now)
+			continue
+		}
+		if due.IsZero() || due.After(now) {
+			continue
+		}
+		next[plan.name] = plan.schedule.Next(now)
+
+		key := fmt.Sprintf("%s@%s", plan.name, due.UTC().Format(time.RFC3339))
+		var queued int64
+		if err := db.Model(&Job{}).Where("key = ?", key).Count(&queued).Error; err != nil {
+			return err
+		}
+		if queued > 0 {
+			continue
+		}
+		if _, err := enqueue(db, plan.name, nil, due, &key); err != nil {
+			return fmt.Errorf("queueing %s: %w", plan.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/helpers"
+
+	"time"
+)
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &Job{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+}
+
+// Statuses of a job. Failed jobs ran out of attempts, until they are retried
+const (
+	Queued    = "queued"
+	Running   = "running"
+	Succeeded = "succeeded"
+	Failed    = "failed"
+)
+
+// Job is a run of the handler registered as Name with Payload, due at RunAt.
+// Jobs of a tenant run bound to it, the others as the system. Key is set on
+// the runs queued by a schedule, so each run is queued once
+type Job struct {
+	common.CommonEntity `gorm:"embedded"`
+	Name                string       `gorm:"type:varchar(255);not null;index"`
+	Payload             helpers.JSON `gorm:"type:jsonb"`
+	Status              string       `gorm:"type:varchar(32);not null;index"`
+	Attempts            int          `gorm:"type:int;not null;default:0"`
+	MaxAttempts         int          `gorm:"type:int;not null"`
+	RunAt               time.Time    `gorm:"type:timestamp;not null;index"`
+	Key                 *string      `gorm:"type:varchar(255);uniqueIndex"`
+	StartedAt           *time.Time   `gorm:"type:timestamp"`
+	FinishedAt          *time.Time   `gorm:"type:timestamp"`
+	LastError           string       `gorm:"type:text;not null;default:''"`
+}
+
+func (Job) TableName() string {
+	return "jobs"
+}
+
+type JobDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	Name             string       `json:"name" tstype:"string,required"`
+	Payload          helpers.JSON `json:"payload" tstype:"any"`
+	Status           string       `json:"status" tstype:"string,required"`
+	Attempts         int          `json:"attempts" tstype:"number,required"`
+	MaxAttempts      int          `json:"max_attempts" tstype:"number,required"`
+	RunAt            time.Time    `json:"run_at" tstype:"string,required"`
+	Key              *string      `json:"key,omitempty" tstype:"string"`
+	StartedAt        *time.Time   `json:"started_at,omitempty" tstype:"string"`
+	FinishedAt       *time.Time   `json:"finished_at,omitempty" tstype:"string"`
+	LastError        string       `json:"last_error,omitempty" tstype:"string"`
+}
+
+func (j Job) ToDTO() common.DTO {
+	dto := &JobDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        j.ID,
+			CreatedAt: j.CreatedAt,
+			UpdatedAt: j.UpdatedAt,
+		},
+		Name:        j.Name,
+		Payload:     j.Payload,
+		Status:      j.Status,
+		Attempts:    j.Attempts,
+		MaxAttempts: j.MaxAttempts,
+		RunAt:       j.RunAt,
+		Key:         j.Key,
+		StartedAt:   j.StartedAt,
+		FinishedAt:  j.FinishedAt,
+		LastError:   j.LastError,
+	}
+	return dto
+}
+
+func (j JobDTO) ToEntity() common.Entity {
+	entity := &Job{
+		CommonEntity: common.CommonEntity{
+			ID:        j.ID,
+			CreatedAt: j.CreatedAt,
+			UpdatedAt: j.UpdatedAt,
+		},
+		Name:        j.Name,
+		Payload:     j.Payload,
+		Status:      j.Status,
+		Attempts:    j.Attempts,
+		MaxAttempts: j.MaxAttempts,
+		RunAt:       j.RunAt,
+		Key:         j.Key,
+		StartedAt:   j.StartedAt,
+		FinishedAt:  j.FinishedAt,
+		LastError:   j.LastError,
+	}
+	return entity
+}
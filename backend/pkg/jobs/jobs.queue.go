@@ -0,0 +1,262 @@
+package jobs
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/helpers"
+	"backend/pkg/tenancy"
+
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUnknownJob = errors.New("no handler is registered for the job")
+	ErrNotFailed  = errors.New("only failed jobs can be retried")
+)
+
+// Enqueue queues a run of the job name with payload, due now. It is written
+// through tx, so the job is only queued when the transaction commits, and
+// belongs to the tenant of tx
+func Enqueue(tx *gorm.DB, name string, payload interface{}) (*Job, error) {
+	return EnqueueAt(tx, name, payload, time.Now())
+}
+
+// EnqueueAt queues a run of the job name with payload, due at at
+func EnqueueAt(tx *gorm.DB, name string, payload interface{}, at time.Time) (*Job, error) {
+	return enqueue(tx, name, payload, at, nil)
+}
+
+func enqueue(tx *gorm.DB, name string, payload interface{}, at time.Time, key *string) (*Job, error) {
+	if _, ok := handlers[name]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJob, name)
+	}
+	document, err := helpers.NewJSON(payload)
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{
+		Name:        name,
+		Payload:     document,
+		Status:      Queued,
+		MaxAttempts: max(viper.GetInt("services.jobs.max_attempts"), 1),
+		RunAt:       at,
+		Key:         key,
+	}
+	db := tx.Session(&gorm.Session{NewDB: true})
+	if err := tenancy.Assign(db, job); err != nil {
+		return nil, err
+	}
+	return job, db.Omit(clause.Associations).Create(job).Error
+}
+
+// Retry queues a failed job again, due now with its attempts reset
+func Retry(tx *gorm.DB, id uuid.UUID) (*Job, error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	var job Job
+	if err := db.Scopes(tenancy.Scope).First(&job, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if job.Status != Failed {
+		return nil, fmt.Errorf("%w, job %s is %s", ErrNotFailed, job.ID, job.Status)
+	}
+	job.Status = Queued
+	job.Attempts = 0
+	job.RunAt = time.Now()
+	job.StartedAt = nil
+	job.FinishedAt = nil
+	return &job, db.Omit(clause.Associations).Save(&job).Error
+}
+
+// Run runs the job name with payload right away in the calling goroutine,
+// whatever is queued, and records it like a queued job. The error is the
+// one of the handler
+func Run(ctx context.Context, name string, payload interface{}) (*Job, error) {
+	job, err := EnqueueAt(database.DB.WithContext(ctx), name, payload, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !claim(ctx, job) {
+		return job, fmt.Errorf("job %s was claimed by a worker", job.ID)
+	}
+	return job, job.run(ctx)
+}
+
+// Work runs the jobs due now one after the other, until none is left or ctx
+// is done
+func Work(ctx context.Context) error {
+	for ctx.Err() == nil {
+		var due []Job
+		err := database.DB.
+			WithContext(common.AsSystem(ctx)).
+			Where("status = ? AND run_at <= ?", Queued, time.Now()).
+			Order("run_at").
+			Limit(max(viper.GetInt("services.jobs.batch"), 1)).
+			Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+		for i := range due {
+			if ctx.Err() != nil {
+				break
+			}
+			if !claim(ctx, &due[i]) {
+				continue
+			}
+			if err := due[i].run(ctx); err != nil {
+				log.Printf("Job %s (%s) failed: %s", due[i].Name, due[i].ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// claim marks a queued job as running, it returns false when another worker
+// got it first
+func claim(ctx context.Context, job *Job) bool {
+	now := time.Now()
+	result := database.DB.
+		WithContext(common.AsSystem(ctx)).
+		Model(&Job{}).
+		Where("id = ? AND status = ?", job.ID, Queued).
+		UpdateColumns(map[string]interface{}{
+			"status":     Running,
+			"started_at": now,
+			"attempts":   gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+	job.Status = Running
+	job.StartedAt = &now
+	job.Attempts++
+	return true
+}
+
+// run calls the handler of a claimed job and records how it went. A failed
+// attempt is queued again after a backoff doubling with each attempt, until
+// the job runs out of them
+func (j *Job) run(ctx context.Context) (err error) {
+	handler, ok := handlers[j.Name]
+	if !ok {
+		err = fmt.Errorf("%w: %s", ErrUnknownJob, j.Name)
+		j.Attempts = j.MaxAttempts
+	} else {
+		bound := common.AsSystem(ctx)
+		if j.TenantID != uuid.Nil {
+			bound = common.WithTenant(ctx, j.TenantID)
+		}
+		err = call(context.WithValue(bound, lastAttempt{}, j.Attempts >= j.MaxAttempts), handler, j.Payload)
+	}
+
+	now := time.Now()
+	j.FinishedAt = &now
+	j.LastError = ""
+	switch {
+	case err == nil:
+		j.Status = Succeeded
+	case j.Attempts >= j.MaxAttempts:
+		j.Status = Failed
+		j.LastError = err.Error()
+	default:
+		j.Status = Queued
+		j.LastError = err.Error()
+		j.RunAt = now.Add(backoff(j.Attempts))
+	}
+	saved := database.DB.
+		WithContext(common.AsSystem(context.WithoutCancel(ctx))).
+		Model(&Job{}).
+		Where("id = ? AND status = ?", j.ID, Running).
+		UpdateColumns(map[string]interface{}{
+			"status":      j.Status,
+			"run_at":      j.RunAt,
+			"finished_at": j.FinishedAt,
+			"last_error":  j.LastError,
+		}).Error
+	return errors.Join(err, saved)
+}
+
+type lastAttempt struct{}
+
+// LastAttempt tells whether the job running with ctx is on its last attempt,
+// after which a failure is final
+func LastAttempt(ctx context.Context) bool {
+	last, _ := ctx.Value(lastAttempt{}).(bool)
+	return last
+}
+
+// call runs handler, turning a panic into an error so one job cannot take
+// the worker down
+func call(ctx context.Context, handler Handler, payload helpers.JSON) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return handler(ctx, payload)
+}
+
+// backoff is how long a job waits after its failed attempt, doubling from
+// services.jobs.backoff up to services.jobs.max_backoff
+func backoff(attempt int) time.Duration {
+	base := viper.GetDuration("services.jobs.backoff")
+	ceiling := viper.GetDuration("services.jobs.max_backoff")
+	wait := time.Duration(float64(base) * math.Pow(2, float64(attempt-1)))
+	if ceiling > 0 && (wait > ceiling || wait < 0) {
+		return ceiling
+	}
+	return wait
+}
+
+// requeueStale queues again the jobs running for longer than
+// services.jobs.timeout, whose worker is gone. It counts as a failed attempt
+func requeueStale(ctx context.Context) error {
+	timeout := viper.GetDuration("services.jobs.timeout")
+	if timeout <= 0 {
+		return nil
+	}
+	db := database.DB.WithContext(common.AsSystem(ctx))
+	cutoff := time.Now().Add(-timeout)
+	reason := "the worker running the job went away"
+	err := db.
+		Model(&Job{}).
+		Where("status = ? AND started_at < ? AND attempts < max_attempts", Running, cutoff).
+		UpdateColumns(map[string]interface{}{"status": Queued, "run_at": time.Now(), "last_error": reason}).Error
+	if err != nil {
+		return err
+	}
+	return db.
+		Model(&Job{}).
+		Where("status = ? AND started_at < ? AND attempts >= max_attempts", Running, cutoff).
+		UpdateColumns(map[string]interface{}{"status": Failed, "finished_at": time.Now(), "last_error": reason}).Error
+}
+
+// List returns the jobs with status, or all of them when it is empty, most
+// recently due first
+func List(ctx context.Context, status string, limit int) ([]Job, error) {
+	query := database.DB.WithContext(ctx).Scopes(tenancy.Scope).Order("run_at DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var found []Job
+	return found, query.Find(&found).Error
+}
+
+// Prune deletes the jobs that succeeded before before
+func Prune(ctx context.Context, before time.Time) (int64, error) {
+	result := database.DB.
+		WithContext(common.AsSystem(ctx)).
+		Unscoped().
+		Where("status = ? AND finished_at < ?", Succeeded, before).
+		Delete(&Job{})
+	return result.RowsAffected, result.Error
+}
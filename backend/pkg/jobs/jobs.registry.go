@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"backend/pkg/helpers"
+
+	"context"
+	"sort"
+)
+
+// Handler runs a job with its payload. The context is bound to the tenant of
+// the job, or to the system for jobs without one
+type Handler func(ctx context.Context, payload helpers.JSON) error
+
+var handlers = map[string]Handler{}
+
+// Register makes handler run the jobs queued as name
+func Register(name string, handler Handler) {
+	handlers[name] = handler
+}
+
+// Task adapts task, which takes no payload, to a handler
+func Task(task func(ctx context.Context) error) Handler {
+	return func(ctx context.Context, payload helpers.JSON) error {
+		return task(ctx)
+	}
+}
+
+// Names lists the registered jobs
+func Names() []string {
+	names := make([]string, 0, len(handlers))
+	for name := range handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type plan struct {
+	name     string
+	schedule Schedule
+}
+
+var plans []plan
+
+// Cron queues a run of the job name whenever schedule is due, as long as
+// this process leads. A nil schedule disables it
+func Cron(name string, schedule Schedule) {
+	if schedule == nil {
+		return
+	}
+	plans = append(plans, plan{name: name, schedule: schedule})
+}
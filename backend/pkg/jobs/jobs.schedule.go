@@ -0,0 +1,166 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a periodic job is next due
+type Schedule interface {
+	// Next returns the first time the schedule is due after after
+	Next(after time.Time) time.Time
+}
+
+type interval time.Duration
+
+func (i interval) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(i))
+}
+
+// Every is due once per period, counted from when this process starts
+// leading. A zero or negative period is no schedule
+func Every(period time.Duration) Schedule {
+	if period <= 0 {
+		return nil
+	}
+	return interval(period)
+}
+
+// cron is due at the minutes whose fields are all set, in UTC. Day of month
+// and day of week are matched like cron does: when both are restricted,
+// neither starting with *, either of them is enough
+type cron struct {
+	minutes, hours, days, months, weekdays []bool
+	anyDay, anyWeekday                     bool
+}
+
+var macros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// Parse reads a schedule in the five fields of crontab(5), minute hour
+// day-of-month month day-of-week, evaluated in UTC. Fields take *, numbers,
+// ranges, lists and steps. The @hourly, @daily, @weekly and @monthly macros
+// and @every followed by a Go duration are accepted too. An empty spec is no
+// schedule
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	if period, ok := strings.CutPrefix(spec, "@every "); ok {
+		duration, err := time.ParseDuration(strings.TrimSpace(period))
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid schedule %q: @every takes a positive duration", spec)
+		}
+		return Every(duration), nil
+	}
+	if expanded, ok := macros[spec]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields", spec)
+	}
+	var c cron
+	var err error
+	if c.minutes, err = field(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid minute of schedule %q: %w", spec, err)
+	}
+	if c.hours, err = field(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid hour of schedule %q: %w", spec, err)
+	}
+	if c.days, err = field(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid day of month of schedule %q: %w", spec, err)
+	}
+	if c.months, err = field(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid month of schedule %q: %w", spec, err)
+	}
+	if c.weekdays, err = field(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid day of week of schedule %q: %w", spec, err)
+	}
+	// Sunday is both 0 and 7
+	c.weekdays[0] = c.weekdays[0] || c.weekdays[7]
+	// A field starting with *, stepped or not, does not restrict the day,
+	// like in cron
+	c.anyDay = strings.HasPrefix(fields[2], "*")
+	c.anyWeekday = strings.HasPrefix(fields[4], "*")
+	return c, nil
+}
+
+// field reads one field of a cron spec into the values from min to max it
+// matches, indexed by value
+func field(spec string, min int, max int) ([]bool, error) {
+	matches := make([]bool, max+1)
+	for _, part := range strings.Split(spec, ",") {
+		from, to, step := min, max, 1
+		rangeSpec, stepSpec, stepped := strings.Cut(part, "/")
+		if stepped {
+			var err error
+			if step, err = strconv.Atoi(stepSpec); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q", stepSpec)
+			}
+		}
+		if rangeSpec != "*" {
+			first, last, ranged := strings.Cut(rangeSpec, "-")
+			var err error
+			if from, err = strconv.Atoi(first); err != nil {
+				return nil, fmt.Errorf("invalid value %q", first)
+			}
+			to = from
+			if ranged {
+				if to, err = strconv.Atoi(last); err != nil {
+					return nil, fmt.Errorf("invalid value %q", last)
+				}
+			} else if stepped {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("%q is out of %d-%d", part, min, max)
+		}
+		for value := from; value <= to; value += step {
+			matches[value] = true
+		}
+	}
+	return matches, nil
+}
+
+func (c cron) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	// Every combination repeats within a few years, leap days included
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		if !c.months[t.Month()] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.day(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c cron) day(t time.Time) bool {
+	day, weekday := c.days[t.Day()], c.weekdays[t.Weekday()]
+	if c.anyDay || c.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
@@ -0,0 +1,110 @@
+package jobs
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec string
+		err  string
+	}{
+		{spec: "* * * * *"},
+		{spec: "*/15 0-6,22 1,15 */2 1-5"},
+		{spec: "0 0 * * 7"},
+		{spec: "@daily"},
+		{spec: "@every 90s"},
+		{spec: "", err: ""},
+		{spec: "* * * *", err: "expected 5 fields"},
+		{spec: "60 * * * *", err: "invalid minute"},
+		{spec: "* 24 * * *", err: "invalid hour"},
+		{spec: "* * 0 * *", err: "invalid day of month"},
+		{spec: "* * * 13 *", err: "invalid month"},
+		{spec: "* * * * 8", err: "invalid day of week"},
+		{spec: "*/0 * * * *", err: "invalid step"},
+		{spec: "5-1 * * * *", err: "out of 0-59"},
+		{spec: "a * * * *", err: "invalid value"},
+		{spec: "@yearly", err: "expected 5 fields"},
+		{spec: "@every -1m", err: "positive duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := Parse(tt.spec)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got %v, want an error with %q", err, tt.err)
+			}
+		})
+	}
+	if schedule, err := Parse(" "); schedule != nil || err != nil {
+		t.Fatalf("got %v and %v for an empty spec, want no schedule", schedule, err)
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		spec  string
+		after string
+		want  string
+	}{
+		{"* * * * *", "2026-01-01T10:00:30Z", "2026-01-01T10:01:00Z"},
+		{"*/15 * * * *", "2026-01-01T10:14:00Z", "2026-01-01T10:15:00Z"},
+		{"*/15 * * * *", "2026-01-01T10:45:00Z", "2026-01-01T11:00:00Z"},
+		{"30 3 * * *", "2026-01-01T03:30:00Z", "2026-01-02T03:30:00Z"},
+		{"@hourly", "2026-12-31T23:59:00Z", "2027-01-01T00:00:00Z"},
+		{"@daily", "2026-02-28T12:00:00Z", "2026-03-01T00:00:00Z"},
+		{"@weekly", "2026-01-01T00:00:00Z", "2026-01-04T00:00:00Z"},
+		{"0 0 * * 7", "2026-01-01T00:00:00Z", "2026-01-04T00:00:00Z"},
+		{"@monthly", "2026-01-15T00:00:00Z", "2026-02-01T00:00:00Z"},
+		{"0 9 * * 1-5", "2026-01-02T09:00:00Z", "2026-01-05T09:00:00Z"},
+		{"0 0 31 * *", "2026-01-31T00:00:00Z", "2026-03-31T00:00:00Z"},
+		{"0 0 29 2 *", "2026-01-01T00:00:00Z", "2028-02-29T00:00:00Z"},
+		{"0 0 1 */3 *", "2026-01-01T00:00:00Z", "2026-04-01T00:00:00Z"},
+		// Both days restricted, either is enough
+		{"0 0 13 * 5", "2026-01-01T00:00:00Z", "2026-01-02T00:00:00Z"},
+		{"0 0 13 * 5", "2026-01-10T00:00:00Z", "2026-01-13T00:00:00Z"},
+		// A stepped * does not restrict the day, the other field alone does
+		{"0 0 */1 * 1", "2026-01-01T00:00:00Z", "2026-01-05T00:00:00Z"},
+		{"0 0 1 * */2", "2026-01-02T00:00:00Z", "2026-02-01T00:00:00Z"},
+		{"0 0 */2 * *", "2026-01-01T00:00:00Z", "2026-01-03T00:00:00Z"},
+		// Never due
+		{"0 0 30 2 *", "2026-01-01T00:00:00Z", "0001-01-01T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec+" after "+tt.after, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			after, err := time.Parse(time.RFC3339, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := schedule.Next(after).Format(time.RFC3339); got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextInUTC(t *testing.T) {
+	schedule, err := Parse("0 3 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	after := time.Date(2026, 1, 1, 4, 0, 0, 0, zone)
+	if got, want := schedule.Next(after), time.Date(2026, 1, 1, 3, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if got := Every(time.Hour).Next(after); !got.Equal(after.Add(time.Hour)) {
+		t.Fatalf("got %s every hour after %s", got, after)
+	}
+}
@@ -0,0 +1,3 @@
+// Jobs package runs deferred and periodic work from a queue kept in the database, retrying what fails, with a single leader queueing the periodic runs.
+
+package jobs
@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+)
+
+// Events notified to guests, each with a text template defining the subject
+// and an HTML body rendered in the layout
+const (
+	ReservationCreated   = "reservation_created"
+	ReservationConfirmed = "reservation_confirmed"
+	ReservationCancelled = "reservation_cancelled"
+	ReservationReminder  = "reservation_reminder"
+)
+
+// DefaultColor is the brand color of the businesses with none
+const DefaultColor = "#18181b"
+
+//go:embed templates/*
+var files embed.FS
+
+var (
+	texts = map[string]*template.Template{}
+	pages = map[string]*htmltemplate.Template{}
+)
+
+func init() {
+	for _, event := range []string{ReservationCreated, ReservationConfirmed, ReservationCancelled, ReservationReminder} {
+		texts[event] = template.Must(template.ParseFS(files, "templates/"+event+".txt"))
+		pages[event] = htmltemplate.Must(htmltemplate.
+			New("layout").
+			Funcs(htmltemplate.FuncMap{"subject": func() string { return "" }}).
+			ParseFS(files, "templates/layout.html", "templates/"+event+".html"))
+	}
+}
+
+// Brand is how a business appears in its emails
+type Brand struct {
+	Name    string
+	LogoURL string
+	Color   string
+	Footer  string
+}
+
+// Render renders the templates of event with data, which must have a Brand
+// field. The message has no sender nor recipient
+func Render(event string, data interface{}) (Message, error) {
+	text, ok := texts[event]
+	if !ok {
+		return Message{}, fmt.Errorf("no template for event %q", event)
+	}
+	var subject, body bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := text.Execute(&body, data); err != nil {
+		return Message{}, err
+	}
+
+	page, err := pages[event].Clone()
+	if err != nil {
+		return Message{}, err
+	}
+	title := strings.TrimSpace(subject.String())
+	page.Funcs(htmltemplate.FuncMap{"subject": func() string { return title }})
+	var html bytes.Buffer
+	if err := page.ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, err
+	}
+	return Message{Subject: title, Text: body.String(), HTML: html.String()}, nil
+}
@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+)
+
+// reservation mirrors the data the notifications of a reservation are
+// rendered with
+type reservation struct {
+	Brand    Brand
+	Guest    string
+	Status   string
+	Date     string
+	People   int
+	Service  string
+	Staff    string
+	Location string
+	Reason   string
+	Fee      string
+}
+
+func TestRender(t *testing.T) {
+	brand := Brand{Name: "Bistro", Color: "#ff0000", Footer: "Main St 1, Madrid"}
+	data := reservation{
+		Brand:    brand,
+		Guest:    "Ana",
+		Status:   "confirmed",
+		Date:     "Sunday, 25 October 2026 at 20:30 (CET)",
+		People:   2,
+		Service:  "Tasting menu",
+		Staff:    "Luis",
+		Location: "Terrace",
+	}
+	pending := data
+	pending.Status = "pending"
+	single := data
+	single.People = 1
+	single.Service, single.Staff, single.Location = "", "", ""
+	single.Brand = Brand{Name: "Bistro", LogoURL: "https://example.com/logo.png", Color: DefaultColor}
+	cancelled := data
+	cancelled.Reason = "the kitchen is closed"
+	cancelled.Fee = "10.00 EUR"
+
+	tests := []struct {
+		name    string
+		event   string
+		data    reservation
+		subject string
+		text    []string
+		html    []string
+		absent  []string
+	}{
+		{
+			name:    "created confirmed",
+			event:   ReservationCreated,
+			data:    data,
+			subject: "We received your reservation at Bistro",
+			text:    []string{"Hello Ana,", "Bistro received your reservation.", "When: " + data.Date, "Party: 2", "Service: Tasting menu", "With: Luis", "Where: Terrace", "--\nMain St 1, Madrid"},
+			html:    []string{"<title>We received your reservation at Bistro</title>", "<p>Bistro received your reservation.</p>", "2 people &middot; Tasting menu with Luis", "background:#ff0000", "Main St 1, Madrid"},
+			absent:  []string{"once it is confirmed"},
+		},
+		{
+			name:    "created pending",
+			event:   ReservationCreated,
+			data:    pending,
+			subject: "We received your reservation at Bistro",
+			text:    []string{"Bistro received your reservation, we will let you know once it is confirmed."},
+			html:    []string{"we will let you know once it is confirmed"},
+		},
+		{
+			name:    "confirmed",
+			event:   ReservationConfirmed,
+			data:    data,
+			subject: "Your reservation at Bistro is confirmed",
+			text:    []string{"Your reservation at Bistro is confirmed, we look forward to seeing you.", "When: " + data.Date},
+			html:    []string{"<title>Your reservation at Bistro is confirmed</title>", "we look forward to seeing you"},
+		},
+		{
+			name:    "confirmed for one without extras",
+			event:   ReservationConfirmed,
+			data:    single,
+			subject: "Your reservation at Bistro is confirmed",
+			text:    []string{"Party: 1\n"},
+			html:    []string{"1 person\n", `<img src="https://example.com/logo.png" alt="Bistro"`, "background:" + DefaultColor},
+			absent:  []string{"Service:", "With:", "Where:", "&middot;", "--", "#fafafa"},
+		},
+		{
+			name:    "cancelled with a fee",
+			event:   ReservationCancelled,
+			data:    cancelled,
+			subject: "Your reservation at Bistro is cancelled",
+			text:    []string{"Your reservation at Bistro is cancelled: the kitchen is closed.", "A cancellation fee of 10.00 EUR applies."},
+			html:    []string{"<p>Your reservation at Bistro is cancelled: the kitchen is closed.</p>", "<p>A cancellation fee of 10.00 EUR applies.</p>"},
+		},
+		{
+			name:    "cancelled for free",
+			event:   ReservationCancelled,
+			data:    data,
+			subject: "Your reservation at Bistro is cancelled",
+			text:    []string{"Your reservation at Bistro is cancelled."},
+			html:    []string{"<p>Your reservation at Bistro is cancelled.</p>"},
+			absent:  []string{"fee"},
+		},
+		{
+			name:    "reminder",
+			event:   ReservationReminder,
+			data:    data,
+			subject: "Reminder: your reservation at Bistro",
+			text:    []string{"This is a reminder of your upcoming reservation at Bistro.", "When: " + data.Date},
+			html:    []string{"<title>Reminder: your reservation at Bistro</title>", "upcoming reservation at Bistro"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, err := Render(tt.event, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if message.Subject != tt.subject {
+				t.Errorf("got subject %q, want %q", message.Subject, tt.subject)
+			}
+			for _, want := range tt.text {
+				if !strings.Contains(message.Text, want) {
+					t.Errorf("text misses %q:\n%s", want, message.Text)
+				}
+			}
+			for _, want := range tt.html {
+				if !strings.Contains(message.HTML, want) {
+					t.Errorf("HTML misses %q:\n%s", want, message.HTML)
+				}
+			}
+			for _, unwanted := range tt.absent {
+				if strings.Contains(message.Text, unwanted) || strings.Contains(message.HTML, unwanted) {
+					t.Errorf("message has %q", unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderEscapesTheHTML(t *testing.T) {
+	data := reservation{Brand: Brand{Name: "Tom & Jerry's", Color: DefaultColor}, Guest: "<script>alert(1)</script>", People: 2}
+	message, err := Render(ReservationReminder, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(message.HTML, "<script>") {
+		t.Errorf("HTML has the guest unescaped:\n%s", message.HTML)
+	}
+	if !strings.Contains(message.HTML, "&lt;script&gt;") || !strings.Contains(message.HTML, "Tom &amp; Jerry&#39;s") {
+		t.Errorf("HTML misses the escaped guest and brand:\n%s", message.HTML)
+	}
+	if message.Subject != "Reminder: your reservation at Tom & Jerry's" {
+		t.Errorf("got subject %q, want it unescaped", message.Subject)
+	}
+}
+
+func TestRenderUnknownEvent(t *testing.T) {
+	if _, err := Render("reservation_lost", reservation{}); err == nil {
+		t.Fatal("rendered an unknown event, want an error")
+	}
+}
@@ -0,0 +1,205 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Message is an email with a text and an HTML body
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Transport delivers messages
+type Transport interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// Open returns the transport named in services.notifications.transport:
+// smtp, file or log
+func Open() (Transport, error) {
+	switch name := viper.GetString("services.notifications.transport"); name {
+	case "smtp":
+		return &SMTP{
+			Host:     viper.GetString("services.notifications.smtp.host"),
+			Port:     viper.GetInt("services.notifications.smtp.port"),
+			Username: viper.GetString("services.notifications.smtp.username"),
+			Password: viper.GetString("services.notifications.smtp.password"),
+		}, nil
+	case "file":
+		return &File{Directory: viper.GetString("services.notifications.directory")}, nil
+	case "log":
+		return Log{}, nil
+	default:
+		return nil, fmt.Errorf("unknown notification transport %q, expected smtp, file or log", name)
+	}
+}
+
+// SMTP sends messages through a mail server, authenticating with PLAIN when
+// a username is set
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+func (s *SMTP) Send(ctx context.Context, message Message) error {
+	from, err := mail.ParseAddress(message.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender: %w", err)
+	}
+	to, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+	body, err := message.Encode()
+	if err != nil {
+		return err
+	}
+
+	address := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	dialer := net.Dialer{Timeout: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(nil); err != nil {
+			return err
+		}
+	}
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(body); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// File writes each message to an .eml file of Directory, which mail clients
+// open
+type File struct {
+	Directory string
+}
+
+func (f *File) Send(ctx context.Context, message Message) error {
+	body, err := message.Encode()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(f.Directory, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), boundary()[:8])
+	return os.WriteFile(filepath.Join(f.Directory, name), body, 0o644)
+}
+
+// Log prints the recipient, subject and text of each message to the log
+type Log struct{}
+
+func (Log) Send(ctx context.Context, message Message) error {
+	log.Printf("Email to %s: %s\n%s", message.To, message.Subject, message.Text)
+	return nil
+}
+
+// Encode renders message as a MIME document, with the text and the HTML body
+// as alternatives
+func (m Message) Encode() ([]byte, error) {
+	var buffer bytes.Buffer
+	parts := multipart.NewWriter(&buffer)
+	if err := parts.SetBoundary(boundary()); err != nil {
+		return nil, err
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("From", m.From)
+	header.Set("To", m.To)
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("MIME-Version", "1.0")
+	header.Set("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	var document bytes.Buffer
+	for _, key := range []string{"From", "To", "Subject", "Date", "MIME-Version", "Content-Type"} {
+		fmt.Fprintf(&document, "%s: %s\r\n", key, header.Get(key))
+	}
+	document.WriteString("\r\n")
+
+	for _, alternative := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {alternative.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := quotedprintable.NewWriter(part)
+		if _, err := encoder.Write([]byte(alternative.body)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	document.Write(buffer.Bytes())
+	return document.Bytes(), nil
+}
+
+func boundary() string {
+	random := make([]byte, 16)
+	rand.Read(random)
+	return hex.EncodeToString(random)
+}
@@ -0,0 +1,185 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envelope is what the fake server received for one message
+type envelope struct {
+	Auth string
+	From string
+	To   []string
+	Data []byte
+}
+
+// fakeSMTP listens on a local port and accepts every message, sending what it
+// received to the returned channel. It offers AUTH PLAIN and no STARTTLS
+func fakeSMTP(t *testing.T) (string, int, <-chan envelope) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	received := make(chan envelope, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, received)
+		}
+	}()
+	address := listener.Addr().(*net.TCPAddr)
+	return address.IP.String(), address.Port, received
+}
+
+func serveSMTP(conn net.Conn, received chan<- envelope) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	var message envelope
+	text.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, argument, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			text.PrintfLine("250-fake\r\n250 AUTH PLAIN")
+		case "AUTH":
+			credentials, _ := strings.CutPrefix(argument, "PLAIN ")
+			decoded, _ := base64.StdEncoding.DecodeString(credentials)
+			message.Auth = string(decoded)
+			text.PrintfLine("235 authenticated")
+		case "MAIL":
+			message.From = argument
+			text.PrintfLine("250 ok")
+		case "RCPT":
+			message.To = append(message.To, argument)
+			text.PrintfLine("250 ok")
+		case "DATA":
+			text.PrintfLine("354 go ahead")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			message.Data = data
+			text.PrintfLine("250 queued")
+			received <- message
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// parts decodes the bodies of the multipart document read from r by content
+// type
+func parts(t *testing.T, header mail.Header, r io.Reader) map[string]string {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("got content type %q, want multipart/alternative", header.Get("Content-Type"))
+	}
+	bodies := map[string]string{}
+	reader := multipart.NewReader(r, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			return bodies
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoding := part.Header.Get("Content-Transfer-Encoding"); encoding != "quoted-printable" {
+			t.Fatalf("got transfer encoding %q, want quoted-printable", encoding)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bodies[part.Header.Get("Content-Type")] = string(body)
+	}
+}
+
+func TestSMTPSend(t *testing.T) {
+	host, port, received := fakeSMTP(t)
+	transport := &SMTP{Host: host, Port: port, Username: "mailer", Password: "secret"}
+	message := Message{
+		From:    "Bistro <bistro@example.com>",
+		To:      "guest@example.com",
+		Subject: "Your reservation at Café is confirmed",
+		Text:    "Hello Ana,\n\nSee you soon = tonight.",
+		HTML:    "<p>Hello Ana,</p>",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := transport.Send(ctx, message); err != nil {
+		t.Fatal(err)
+	}
+	var got envelope
+	select {
+	case got = <-received:
+	case <-ctx.Done():
+		t.Fatal("the server received no message")
+	}
+
+	if got.Auth != "\x00mailer\x00secret" {
+		t.Errorf("got credentials %q, want the username and password", got.Auth)
+	}
+	if got.From != "FROM:<bistro@example.com>" {
+		t.Errorf("got sender %q", got.From)
+	}
+	if len(got.To) != 1 || got.To[0] != "TO:<guest@example.com>" {
+		t.Errorf("got recipients %q", got.To)
+	}
+	document, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(string(got.Data))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(document.Header.Get("Subject"))
+	if err != nil || subject != message.Subject {
+		t.Errorf("got subject %q, want %q", subject, message.Subject)
+	}
+	if from := document.Header.Get("From"); from != message.From {
+		t.Errorf("got From %q, want %q", from, message.From)
+	}
+	bodies := parts(t, document.Header, document.Body)
+	if text := bodies["text/plain; charset=utf-8"]; text != message.Text {
+		t.Errorf("got text %q, want %q", text, message.Text)
+	}
+	if html := bodies["text/html; charset=utf-8"]; html != message.HTML {
+		t.Errorf("got HTML %q, want %q", html, message.HTML)
+	}
+}
+
+func TestSMTPSendRejectsInvalidAddresses(t *testing.T) {
+	host, port, _ := fakeSMTP(t)
+	transport := &SMTP{Host: host, Port: port}
+	for _, message := range []Message{
+		{From: "not an address", To: "guest@example.com"},
+		{From: "bistro@example.com", To: "not an address"},
+	} {
+		if err := transport.Send(context.Background(), message); err == nil {
+			t.Errorf("sent from %q to %q, want an error", message.From, message.To)
+		}
+	}
+}
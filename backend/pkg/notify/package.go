@@ -0,0 +1,3 @@
+// Notify package renders the emails sent to guests from templates and delivers them over SMTP, or to files and the log while developing.
+
+package notify
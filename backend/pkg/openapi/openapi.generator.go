@@ -0,0 +1,179 @@
+package openapi
+
+import (
+	"backend/pkg/common"
+	"backend/pkg/generics"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+const version = "3.1.0"
+
+type Options struct {
+	Info Info
+	// BasePath is where the resources are mounted, for example /api/v1
+	BasePath string
+	// Headers are sent with every operation
+	Headers []Parameter
+	// PostHeaders are sent with the POST operations too
+	PostHeaders []Parameter
+}
+
+var pathParameter = regexp.MustCompile(`:(\w+)`)
+
+// Generate describes every route of the controllers, mounted under their key,
+// including the extra routes registered with them
+func Generate(options Options, controllers map[string]generics.GenericController, extraRoutes map[string][]generics.RouteDefinition) *Document {
+	registry := NewRegistry()
+	registry.schemas["ApiResponse"] = registry.structSchema(reflect.TypeOf(common.ApiResponse[any]{}))
+
+	document := &Document{
+		OpenAPI: version,
+		Info:    options.Info,
+		Servers: []Server{{URL: options.BasePath}},
+		Paths:   map[string]PathItem{},
+	}
+
+	keys := make([]string, 0, len(controllers))
+	for key := range controllers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		document.Tags = append(document.Tags, Tag{Name: key})
+		routes := append(controllers[key].Routes(), extraRoutes[key]...)
+		for _, route := range routes {
+			path, parameters := convertPath("/" + key + route.Path)
+			item, ok := document.Paths[path]
+			if !ok {
+				item = PathItem{}
+				document.Paths[path] = item
+			}
+			parameters = append(parameters, options.Headers...)
+			if route.Verb == "POST" {
+				parameters = append(parameters, options.PostHeaders...)
+			}
+			item[strings.ToLower(route.Verb)] = operation(registry, key, route, parameters)
+		}
+	}
+
+	document.Components.Schemas = registry.schemas
+	return document
+}
+
+func operation(registry *Registry, tag string, route generics.RouteDefinition, parameters []Parameter) *Operation {
+	summary := route.Name
+	if summary == "" {
+		summary = route.Verb + " " + route.Path
+	}
+
+	operation := &Operation{
+		OperationID: strcase.ToLowerCamel(tag + " " + summary),
+		Summary:     summary,
+		Tags:        []string{tag},
+		Parameters:  parameters,
+		Responses: map[string]Response{
+			"2XX": {
+				Description: "Success",
+				Content:     jsonContent(envelope(registry.SchemaOf(route.Response))),
+			},
+			"4XX": {
+				Description: "Error",
+				Content:     jsonContent(refTo("ApiResponse")),
+			},
+		},
+	}
+
+	if route.ContentType != "" {
+		operation.Responses["2XX"] = Response{
+			Description: "Success",
+			Content: map[string]MediaType{
+				route.ContentType: {Schema: &Schema{Type: "string"}},
+			},
+		}
+	}
+
+	for _, mediaType := range route.Exports {
+		operation.Responses["2XX"].Content[mediaType] = MediaType{Schema: &Schema{Type: "string"}}
+	}
+
+	for _, query := range route.Query {
+		operation.Parameters = append(operation.Parameters, Parameter{
+			Name:        query.Name,
+			In:          "query",
+			Description: query.Description,
+			Schema:      &Schema{Type: "string"},
+		})
+	}
+
+	if request := registry.SchemaOf(route.Request); request != nil {
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content:  jsonContent(request),
+		}
+	}
+
+	// Documents are sent as the body or as the file field of a form
+	if route.Accepts != "" {
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				route.Accepts: {Schema: &Schema{Type: "string"}},
+				"multipart/form-data": {Schema: &Schema{
+					Type:       "object",
+					Properties: map[string]*Schema{"file": {Type: "string", Format: "binary"}},
+					Required:   []string{"file"},
+				}},
+			},
+		}
+	}
+
+	return operation
+}
+
+// convertPath turns the fiber parameters of path into OpenAPI ones
+func convertPath(path string) (string, []Parameter) {
+	parameters := []Parameter{}
+	for _, match := range pathParameter.FindAllStringSubmatch(path, -1) {
+		schema := &Schema{Type: "string"}
+		if match[1] == "id" {
+			schema.Format = "uuid"
+		}
+		parameters = append(parameters, Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   schema,
+		})
+	}
+
+	path = pathParameter.ReplaceAllString(path, "{$1}")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path, parameters
+}
+
+// envelope wraps data in the ApiResponse every handler answers with
+func envelope(data *Schema) *Schema {
+	if data == nil {
+		return refTo("ApiResponse")
+	}
+	return &Schema{
+		AllOf: []*Schema{
+			refTo("ApiResponse"),
+			{Type: "object", Properties: map[string]*Schema{"data": data}},
+		},
+	}
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{
+		"application/json": {Schema: schema},
+	}
+}
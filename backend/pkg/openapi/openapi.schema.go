@@ -0,0 +1,163 @@
+package openapi
+
+import (
+	"backend/pkg/helpers"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	uuidType = reflect.TypeOf(uuid.UUID{})
+	timeType = reflect.TypeOf(time.Time{})
+	jsonType = reflect.TypeOf(helpers.JSON{})
+)
+
+// Registry reflects Go types into schemas, keeping every named struct as a
+// component so it is only described once
+type Registry struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		schemas: map[string]*Schema{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+// Schemas returns the components collected so far, by name
+func (r *Registry) Schemas() map[string]*Schema {
+	return r.schemas
+}
+
+// SchemaOf describes the JSON encoding of value, nil meaning no body
+func (r *Registry) SchemaOf(value interface{}) *Schema {
+	if value == nil {
+		return nil
+	}
+	return r.schemaFor(reflect.TypeOf(value))
+}
+
+func (r *Registry) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case jsonType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schemaFor(t.Elem())}
+	case reflect.Struct:
+		// Instances of generic types such as Page[T] are described inline,
+		// their element type still ends up in the components
+		if t.Name() == "" || strings.Contains(t.Name(), "[") {
+			return r.structSchema(t)
+		}
+		name := r.nameFor(t)
+		if _, ok := r.schemas[name]; !ok {
+			schema := &Schema{}
+			r.schemas[name] = schema
+			*schema = *r.structSchema(t)
+		}
+		return refTo(name)
+	default:
+		return &Schema{}
+	}
+}
+
+// nameFor returns the component name of t, prefixed with its package when
+// another type already took the bare name
+func (r *Registry) nameFor(t reflect.Type) string {
+	if name, ok := r.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	for other, taken := range r.names {
+		if taken == name && other != t {
+			path := strings.Split(t.PkgPath(), "/")
+			name = path[len(path)-1] + "." + name
+			break
+		}
+	}
+	r.names[t] = name
+	return name
+}
+
+func (r *Registry) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	r.addFields(schema, t)
+	return schema
+}
+
+// addFields follows the rules of encoding/json: embedded structs without a
+// name are flattened, "-" is skipped and unexported fields are ignored
+func (r *Registry) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				r.addFields(schema, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = r.schemaFor(field.Type)
+		schema.propertyNames = append(schema.propertyNames, name)
+		if isRequired(field) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// isRequired reports whether the validator rejects a payload without field
+func isRequired(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
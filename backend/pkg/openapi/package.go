@@ -0,0 +1,3 @@
+// Openapi package describes the registered resources as an OpenAPI 3.1 document.
+
+package openapi
@@ -0,0 +1,104 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+var ErrNoClient = errors.New("no client is linked in for the broker")
+
+// IdempotencyHeader carries the key of a message to the consumers of the
+// brokers, who handle a key they already saw as a duplicate. NATS JetStream
+// does so on its own with the Nats-Msg-Id header, set to the key too
+const IdempotencyHeader = "Idempotency-Key"
+
+func init() {
+	Register("nats", func() (Sink, error) {
+		conn, err := DialNATS(viper.GetString("services.outbox.nats.url"))
+		if err != nil {
+			return nil, err
+		}
+		return &NATS{Conn: conn, Prefix: viper.GetString("services.outbox.nats.subject_prefix")}, nil
+	})
+	Register("kafka", func() (Sink, error) {
+		producer, err := DialKafka(viper.GetStringSlice("services.outbox.kafka.brokers"))
+		if err != nil {
+			return nil, err
+		}
+		return &Kafka{Producer: producer, Topic: viper.GetString("services.outbox.kafka.topic")}, nil
+	})
+}
+
+// NATSConn is the connection the NATS sink publishes with. A NATS client
+// plugs in by wrapping its connection and replacing DialNATS
+type NATSConn interface {
+	Publish(ctx context.Context, subject string, headers map[string]string, data []byte) error
+}
+
+// KafkaProducer is the producer the Kafka sink writes with. A Kafka client
+// plugs in by wrapping its producer and replacing DialKafka
+type KafkaProducer interface {
+	Produce(ctx context.Context, topic string, key []byte, headers map[string]string, value []byte) error
+}
+
+// DialNATS connects to the NATS server at url. No client is linked in, so
+// it fails until one replaces it
+var DialNATS = func(url string) (NATSConn, error) {
+	return nil, fmt.Errorf("%w: NATS at %s", ErrNoClient, url)
+}
+
+// DialKafka connects to the Kafka brokers. No client is linked in, so it
+// fails until one replaces it
+var DialKafka = func(brokers []string) (KafkaProducer, error) {
+	return nil, fmt.Errorf("%w: Kafka at %s", ErrNoClient, strings.Join(brokers, ","))
+}
+
+// NATS is the sink publishing each message on the subject of its topic,
+// under Prefix
+type NATS struct {
+	Conn   NATSConn
+	Prefix string
+}
+
+func (n *NATS) Name() string {
+	return "nats"
+}
+
+func (n *NATS) Publish(ctx context.Context, message Message) error {
+	subject := message.Topic
+	if n.Prefix != "" {
+		subject = n.Prefix + "." + subject
+	}
+	return n.Conn.Publish(ctx, subject, headers(message, "Nats-Msg-Id"), message.Payload)
+}
+
+// Kafka is the sink writing every message to Topic, keyed by its key
+type Kafka struct {
+	Producer KafkaProducer
+	Topic    string
+}
+
+func (k *Kafka) Name() string {
+	return "kafka"
+}
+
+func (k *Kafka) Publish(ctx context.Context, message Message) error {
+	return k.Producer.Produce(ctx, k.Topic, []byte(message.Key), headers(message), message.Payload)
+}
+
+// headers are the headers a message is published with, the key is also set
+// under each of aliases
+func headers(message Message, aliases ...string) map[string]string {
+	h := map[string]string{
+		IdempotencyHeader: message.Key,
+		"Event-Type":      message.Topic,
+	}
+	for _, alias := range aliases {
+		h[alias] = message.Key
+	}
+	return h
+}
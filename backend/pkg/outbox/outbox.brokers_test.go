@@ -0,0 +1,144 @@
+package outbox
+
+import (
+	"backend/database"
+	"backend/database/databasetest"
+	"backend/pkg/common"
+	"backend/pkg/events"
+
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// fakeNATS is an in-memory NATS connection keeping what is published. Like
+// JetStream, it drops a message whose Nats-Msg-Id it already has. err, when
+// set, fails every publish
+type fakeNATS struct {
+	mu       sync.Mutex
+	err      error
+	messages []fakeNATSMessage
+}
+
+type fakeNATSMessage struct {
+	Subject string
+	Headers map[string]string
+	Data    []byte
+}
+
+func (f *fakeNATS) Publish(ctx context.Context, subject string, headers map[string]string, data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	for _, m := range f.messages {
+		if id := headers["Nats-Msg-Id"]; id != "" && m.Headers["Nats-Msg-Id"] == id {
+			return nil
+		}
+	}
+	f.messages = append(f.messages, fakeNATSMessage{Subject: subject, Headers: headers, Data: data})
+	return nil
+}
+
+// fakeKafka is an in-memory Kafka producer keeping the records written,
+// duplicates included as Kafka would. err, when set, fails every write
+type fakeKafka struct {
+	mu      sync.Mutex
+	err     error
+	records []fakeKafkaRecord
+}
+
+type fakeKafkaRecord struct {
+	Topic   string
+	Key     []byte
+	Headers map[string]string
+	Value   []byte
+}
+
+func (f *fakeKafka) Produce(ctx context.Context, topic string, key []byte, headers map[string]string, value []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.records = append(f.records, fakeKafkaRecord{Topic: topic, Key: key, Headers: headers, Value: value})
+	return nil
+}
+
+func TestOpenWithoutClients(t *testing.T) {
+	for _, name := range []string{"nats", "kafka"} {
+		viper.Set("services.outbox.sinks", []string{name})
+		if _, err := Open(); !errors.Is(err, ErrNoClient) {
+			t.Errorf("%s: got %v, want %v", name, err, ErrNoClient)
+		}
+	}
+	viper.Set("services.outbox.sinks", nil)
+}
+
+func TestRelayToBrokers(t *testing.T) {
+	databasetest.Open(t, &Message{})
+	nats, kafka := &fakeNATS{}, &fakeKafka{err: errors.New("broker down")}
+	dialNATS, dialKafka := DialNATS, DialKafka
+	DialNATS = func(string) (NATSConn, error) { return nats, nil }
+	DialKafka = func([]string) (KafkaProducer, error) { return kafka, nil }
+	viper.Set("services.outbox.sinks", []string{"nats", "kafka"})
+	viper.Set("services.outbox.nats.subject_prefix", "reservations")
+	viper.Set("services.outbox.kafka.topic", "reservations.events")
+	viper.Set("services.outbox.batch", 10)
+	viper.Set("services.outbox.backoff", time.Minute)
+	t.Cleanup(func() {
+		DialNATS, DialKafka = dialNATS, dialKafka
+		for _, key := range []string{"sinks", "nats.subject_prefix", "kafka.topic", "batch", "backoff"} {
+			viper.Set("services.outbox."+key, nil)
+		}
+	})
+	sinks, err := Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := common.AsSystem(context.Background())
+	event := events.New(uuid.New(), "reservations", events.Created, uuid.New(), uuid.New(), nil, []byte(`{}`))
+	if err := write(database.DB.WithContext(ctx), event); err != nil {
+		t.Fatal(err)
+	}
+
+	// Kafka failing, the message waits to be published again to both
+	if published, err := Relay(ctx, sinks); err != nil || published != 0 {
+		t.Fatalf("got %d published and %v while Kafka fails, want none", published, err)
+	}
+	var message Message
+	if err := database.DB.WithContext(ctx).First(&message).Error; err != nil {
+		t.Fatal(err)
+	}
+	if message.Status != Pending || message.Attempts != 1 || message.LastError == "" {
+		t.Fatalf("got message %s after %d attempts with error %q, want it pending with the error", message.Status, message.Attempts, message.LastError)
+	}
+
+	kafka.err = nil
+	if err := database.DB.WithContext(ctx).Model(&message).UpdateColumn("available_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if published, err := Relay(ctx, sinks); err != nil || published != 1 {
+		t.Fatalf("got %d published and %v, want 1", published, err)
+	}
+
+	if len(nats.messages) != 1 {
+		t.Fatalf("got %d NATS messages, want the one published twice dropped", len(nats.messages))
+	}
+	if got := nats.messages[0]; got.Subject != "reservations.reservation.created" || got.Headers["Nats-Msg-Id"] != event.ID.String() {
+		t.Errorf("got NATS message on %s with id %s", got.Subject, got.Headers["Nats-Msg-Id"])
+	}
+	if len(kafka.records) != 1 {
+		t.Fatalf("got %d Kafka records, want 1", len(kafka.records))
+	}
+	if got := kafka.records[0]; got.Topic != "reservations.events" || string(got.Key) != event.ID.String() || got.Headers[IdempotencyHeader] != event.ID.String() {
+		t.Errorf("got Kafka record on %s keyed %s", got.Topic, got.Key)
+	}
+}
@@ -0,0 +1,96 @@
+package outbox
+
+import (
+	"context"
+	"path"
+	"sync"
+)
+
+// Handler consumes the messages of the bus
+type Handler func(ctx context.Context, message Message) error
+
+// Bus is the sink delivering the messages to handlers in the process. Each
+// subscription remembers the keys of the last messages it handled, so that
+// one published again because another subscription or sink failed is not
+// handled twice
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions []*subscription
+}
+
+type subscription struct {
+	pattern string
+	handler Handler
+
+	mu      sync.Mutex
+	handled map[string]struct{}
+	order   []string
+}
+
+// handledKeys is how many keys a subscription remembers
+const handledKeys = 4096
+
+// bus is the bus of the process, opened as the "bus" sink
+var bus = &Bus{}
+
+func init() {
+	Register("bus", func() (Sink, error) {
+		return bus, nil
+	})
+}
+
+// Subscribe has handler called with the messages of the process bus whose
+// topic matches pattern, as in path.Match, e.g. "reservation.*"
+func Subscribe(pattern string, handler Handler) {
+	bus.Subscribe(pattern, handler)
+}
+
+// Subscribe has handler called with the messages whose topic matches pattern
+func (b *Bus) Subscribe(pattern string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions = append(b.subscriptions, &subscription{
+		pattern: pattern,
+		handler: handler,
+		handled: map[string]struct{}{},
+	})
+}
+
+func (b *Bus) Name() string {
+	return "bus"
+}
+
+// Publish calls the handlers subscribed to the topic of message that did not
+// handle it yet, and fails when one of them does
+func (b *Bus) Publish(ctx context.Context, message Message) error {
+	b.mu.RLock()
+	subscriptions := b.subscriptions
+	b.mu.RUnlock()
+	for _, s := range subscriptions {
+		if matched, _ := path.Match(s.pattern, message.Topic); !matched {
+			continue
+		}
+		if err := s.handle(ctx, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *subscription) handle(ctx context.Context, message Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.handled[message.Key]; ok {
+		return nil
+	}
+	if err := s.handler(ctx, message); err != nil {
+		return err
+	}
+	s.handled[message.Key] = struct{}{}
+	s.order = append(s.order, message.Key)
+	if len(s.order) > handledKeys {
+		delete(s.handled, s.order[0])
+		s.order = s.order[1:]
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package outbox
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+	"backend/pkg/events"
+	"backend/pkg/helpers"
+
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	database.RegisterModel(&database.MigrationTask{
+		Model:           &Message{},
+		DropOnFlush:     true,
+		TruncateOnFlush: true,
+	})
+	events.Listen(write)
+}
+
+// Statuses of a message
+const (
+	Pending   = "pending"
+	Published = "published"
+)
+
+// Message is an event waiting in the outbox to be published, or published.
+// Topic is the type of the event and Key its id, which consumers receiving
+// the message twice use to handle it once. Relays lease the messages they
+// publish until LockedUntil, failed ones are available again at AvailableAt
+type Message struct {
+	common.CommonEntity `gorm:"embedded"`
+	Topic               string       `gorm:"type:varchar(255);not null;index"`
+	Key                 string       `gorm:"type:varchar(255);not null;uniqueIndex"`
+	Payload             helpers.JSON `gorm:"type:jsonb;not null"`
+	Status              string       `gorm:"type:varchar(16);not null;index"`
+	Attempts            int          `gorm:"type:int;not null;default:0"`
+	AvailableAt         time.Time    `gorm:"type:timestamp;not null;index"`
+	LockedUntil         *time.Time   `gorm:"type:timestamp"`
+	PublishedAt         *time.Time   `gorm:"type:timestamp"`
+	LastError           string       `gorm:"type:text;not null;default:''"`
+}
+
+func (Message) TableName() string {
+	return "outbox_messages"
+}
+
+type MessageDTO struct {
+	common.CommonDTO `json:",inline,omitempty" tstype:",extends,required"`
+	Topic            string       `json:"topic" tstype:"string,required"`
+	Key              string       `json:"key" tstype:"string,required"`
+	Payload          helpers.JSON `json:"payload" tstype:"Record<string, any>,required"`
+	Status           string       `json:"status" tstype:"string,required"`
+	Attempts         int          `json:"attempts" tstype:"number,required"`
+	AvailableAt      time.Time    `json:"available_at" tstype:"string,required"`
+	PublishedAt      *time.Time   `json:"published_at,omitempty" tstype:"string"`
+	LastError        string       `json:"last_error,omitempty" tstype:"string"`
+}
+
+// write puts event in the outbox, in the transaction of the change
+func write(tx *gorm.DB, event events.Event) error {
+	payload, err := helpers.NewJSON(event)
+	if err != nil {
+		return err
+	}
+	message := Message{
+		Topic:       event.Type,
+		Key:         event.ID.String(),
+		Payload:     payload,
+		Status:      Pending,
+		AvailableAt: time.Now(),
+	}
+	message.TenantID = event.TenantID
+	return tx.Session(&gorm.Session{NewDB: true}).Create(&message).Error
+}
+
+func (m Message) ToDTO() common.DTO {
+	dto := &MessageDTO{
+		CommonDTO: common.CommonDTO{
+			ID:        m.ID,
+			CreatedAt: m.CreatedAt,
+			UpdatedAt: m.UpdatedAt,
+		},
+		Topic:       m.Topic,
+		Key:         m.Key,
+		Payload:     m.Payload,
+		Status:      m.Status,
+		Attempts:    m.Attempts,
+		AvailableAt: m.AvailableAt,
+		PublishedAt: m.PublishedAt,
+		LastError:   m.LastError,
+	}
+	return dto
+}
+
+func (m MessageDTO) ToEntity() common.Entity {
+	entity := &Message{
+		CommonEntity: common.CommonEntity{
+			ID:        m.ID,
+			CreatedAt: m.CreatedAt,
+			UpdatedAt: m.UpdatedAt,
+		},
+		Topic:       m.Topic,
+		Key:         m.Key,
+		Payload:     m.Payload,
+		Status:      m.Status,
+		Attempts:    m.Attempts,
+		AvailableAt: m.AvailableAt,
+		PublishedAt: m.PublishedAt,
+		LastError:   m.LastError,
+	}
+	return entity
+}
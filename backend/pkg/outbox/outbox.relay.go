@@ -0,0 +1,177 @@
+package outbox
+
+import (
+	"backend/database"
+	"backend/pkg/common"
+
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// Relay publishes the due messages of the outbox to sinks, oldest first,
+// until none is left or ctx is done. It returns how many were published.
+// A message is leased before being published so that relays running side
+// by side do not publish it twice, and a lease outlived by its relay lapses
+// after services.outbox.lease for another relay to take over
+func Relay(ctx context.Context, sinks []Sink) (int, error) {
+	published := 0
+	for ctx.Err() == nil {
+		var due []Message
+		now := time.Now()
+		err := database.DB.
+			WithContext(common.AsSystem(ctx)).
+			Where("status = ? AND available_at <= ?", Pending, now).
+			Where("locked_until IS NULL OR locked_until < ?", now).
+			Order("created_at").
+			Limit(max(viper.GetInt("services.outbox.batch"), 1)).
+			Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return published, err
+		}
+		for i := range due {
+			if ctx.Err() != nil {
+				break
+			}
+			if !lease(ctx, &due[i]) {
+				continue
+			}
+			err := due[i].publish(ctx, sinks)
+			if err == nil {
+				published++
+				continue
+			}
+			log.Printf("Outbox message %s (%s) failed: %s", due[i].Key, due[i].Topic, err)
+		}
+	}
+	return published, nil
+}
+
+// lease locks a pending message for the relay, it returns false when another
+// relay holds it
+func lease(ctx context.Context, message *Message) bool {
+	now := time.Now()
+	until := now.Add(viper.GetDuration("services.outbox.lease"))
+	result := database.DB.
+		WithContext(common.AsSystem(ctx)).
+		Model(&Message{}).
+		Where("id = ? AND status = ?", message.ID, Pending).
+		Where("locked_until IS NULL OR locked_until < ?", now).
+		UpdateColumns(map[string]interface{}{
+			"locked_until": until,
+			"attempts":     gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+	message.LockedUntil = &until
+	message.Attempts++
+	return true
+}
+
+// publish hands a leased message to every sink and records how it went. A
+// message any sink failed is published again to all of them after a backoff
+// doubling with each attempt, messages are never given up on
+func (m *Message) publish(ctx context.Context, sinks []Sink) error {
+	var err error
+	for _, sink := range sinks {
+		if failed := call(ctx, sink, *m); failed != nil {
+			err = errors.Join(err, fmt.Errorf("%s: %w", sink.Name(), failed))
+		}
+	}
+
+	now := time.Now()
+	columns := map[string]interface{}{"locked_until": nil}
+	if err == nil {
+		columns["status"] = Published
+		columns["published_at"] = now
+		columns["last_error"] = ""
+	} else {
+		columns["available_at"] = now.Add(backoff(m.Attempts))
+		columns["last_error"] = err.Error()
+	}
+	saved := database.DB.
+		WithContext(common.AsSystem(context.WithoutCancel(ctx))).
+		Model(&Message{}).
+		Where("id = ? AND status = ?", m.ID, Pending).
+		UpdateColumns(columns).Error
+	return errors.Join(err, saved)
+}
+
+// call publishes message to sink, turning a panic into an error so one sink
+// cannot take the relay down
+func call(ctx context.Context, sink Sink, message Message) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("sink panicked: %v", recovered)
+		}
+	}()
+	return sink.Publish(ctx, message)
+}
+
+// backoff is how long a message waits after its failed attempt, doubling
+// from services.outbox.backoff up to services.outbox.max_backoff
+func backoff(attempt int) time.Duration {
+	base := viper.GetDuration("services.outbox.backoff")
+	ceiling := viper.GetDuration("services.outbox.max_backoff")
+	wait := time.Duration(float64(base) * math.Pow(2, float64(attempt-1)))
+	if ceiling > 0 && (wait > ceiling || wait < 0) {
+		return ceiling
+	}
+	return wait
+}
+
+// Start relays the outbox to the configured sinks every
+// services.outbox.poll_interval until ctx is done
+func Start(ctx context.Context) {
+	poll := viper.GetDuration("services.outbox.poll_interval")
+	if poll <= 0 {
+		return
+	}
+	sinks, err := Open()
+	if err != nil {
+		log.Printf("Error opening the outbox sinks: %s", err)
+		return
+	}
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for {
+		if _, err := Relay(ctx, sinks); err != nil {
+			log.Printf("Error relaying the outbox: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// List returns the messages with status, or all of them when it is empty,
+// most recent first
+func List(ctx context.Context, status string, limit int) ([]Message, error) {
+	query := database.DB.WithContext(common.AsSystem(ctx)).Order("created_at DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var found []Message
+	return found, query.Find(&found).Error
+}
+
+// Prune deletes the messages published before before
+func Prune(ctx context.Context, before time.Time) (int64, error) {
+	result := database.DB.
+		WithContext(common.AsSystem(ctx)).
+		Unscoped().
+		Where("status = ? AND published_at < ?", Published, before).
+		Delete(&Message{})
+	return result.RowsAffected, result.Error
+}
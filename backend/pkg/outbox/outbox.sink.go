@@ -0,0 +1,52 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Sink publishes the messages of the outbox. Publishing returns once the
+// sink has the message, a failure has it published again later, along with
+// the ones of other sinks. Sinks may therefore get a message more than once
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, message Message) error
+}
+
+var sinks = map[string]func() (Sink, error){}
+
+// Register makes the sink opened by open available as name
+func Register(name string, open func() (Sink, error)) {
+	sinks[name] = open
+}
+
+// Names lists the sinks that can be opened
+func Names() []string {
+	names := make([]string, 0, len(sinks))
+	for name := range sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open opens the sinks listed in services.outbox.sinks
+func Open() ([]Sink, error) {
+	var opened []Sink
+	for _, name := range viper.GetStringSlice("services.outbox.sinks") {
+		open, ok := sinks[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown outbox sink %q, expected one of %s", name, strings.Join(Names(), ", "))
+		}
+		sink, err := open()
+		if err != nil {
+			return nil, fmt.Errorf("opening outbox sink %s: %w", name, err)
+		}
+		opened = append(opened, sink)
+	}
+	return opened, nil
+}
@@ -0,0 +1,3 @@
+// Outbox package stores the events of each change in the transaction making it, and relays them to message sinks at least once.
+
+package outbox
@@ -0,0 +1,3 @@
+// Payments package talks to the payment providers deposits are collected with, behind one interface so they can be swapped.
+
+package payments
@@ -0,0 +1,108 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// FakeSignatureHeader carries the signature of the webhook calls of the fake
+// provider
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// DefaultFakeSecret is the webhook secret of the fake provider in the
+// configuration of the repository, only accepted in development
+const DefaultFakeSecret = "change-me"
+
+var (
+	// ErrFakeDisabled is returned when the fake provider is opened while
+	// another one is configured, so its webhook cannot be called
+	ErrFakeDisabled = errors.New("the fake payment provider is not the configured one")
+	// ErrFakeSecret is returned when the fake provider would check its
+	// webhook calls with no secret, or the default one outside development
+	ErrFakeSecret = errors.New("set services.payments.webhook_secret to a secret of your own")
+)
+
+func init() {
+	Register("fake", func() (Provider, error) {
+		if viper.GetString("services.payments.provider") != "fake" {
+			return nil, ErrFakeDisabled
+		}
+		secret := viper.GetString("services.payments.webhook_secret")
+		if secret == "" || (secret == DefaultFakeSecret && viper.GetString("general.app.enviroment") != "development") {
+			return nil, ErrFakeSecret
+		}
+		return &Fake{Secret: secret}, nil
+	})
+}
+
+// Fake is an in-process provider for development and tests, which can only
+// be opened while it is the configured provider. Its charges stay
+// pending until a webhook call signed with Secret reports them, and refunds
+// always go through. Webhook calls are JSON documents holding the reference
+// and status of a payment
+type Fake struct {
+	Secret string
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) Create(ctx context.Context, charge Charge) (Intent, error) {
+	if charge.Amount <= 0 {
+		return Intent{}, errors.New("the fake provider only charges positive amounts")
+	}
+	reference := "fake_" + uuid.NewSHA1(uuid.NameSpaceOID, []byte(charge.Key)).String()
+	return Intent{
+		Reference:    reference,
+		ClientSecret: reference + "_secret",
+		Status:       StatusPending,
+	}, nil
+}
+
+func (f *Fake) Refund(ctx context.Context, refund Refund) error {
+	if refund.Amount <= 0 {
+		return errors.New("the fake provider only refunds positive amounts")
+	}
+	return nil
+}
+
+func (f *Fake) Parse(header http.Header, body []byte) (Event, error) {
+	signature, err := hex.DecodeString(header.Get(FakeSignatureHeader))
+	if err != nil || !hmac.Equal(signature, f.sign(body)) {
+		return Event{}, ErrInvalidSignature
+	}
+	var event Event
+	if err := json.Unmarshal(body, &struct {
+		Reference *string `json:"reference"`
+		Status    *string `json:"status"`
+	}{&event.Reference, &event.Status}); err != nil {
+		return Event{}, err
+	}
+	switch event.Status {
+	case StatusPending, StatusSucceeded, StatusFailed, StatusRefunded:
+		return event, nil
+	default:
+		return Event{}, fmt.Errorf("unknown payment status %q", event.Status)
+	}
+}
+
+// Sign returns the signature header value of a webhook call carrying body
+func (f *Fake) Sign(body []byte) string {
+	return hex.EncodeToString(f.sign(body))
+}
+
+func (f *Fake) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(f.Secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
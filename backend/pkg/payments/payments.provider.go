@@ -0,0 +1,97 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// Statuses of a payment, as reported by its provider
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusRefunded  = "refunded"
+)
+
+// ErrInvalidSignature is returned for webhook calls the provider did not sign
+var ErrInvalidSignature = errors.New("the webhook call is not signed by the provider")
+
+// Charge asks a provider to collect Amount, in the minor units of Currency.
+// Key is sent along so the provider does not charge twice for one payment
+type Charge struct {
+	Key         string
+	Amount      int64
+	Currency    string
+	Description string
+}
+
+// Refund asks a provider to give Amount of the payment Reference back. Key
+// is sent along so the provider does not refund twice for one payment
+type Refund struct {
+	Key       string
+	Reference string
+	Amount    int64
+}
+
+// Intent is a charge as created by its provider. Reference identifies it in
+// the webhook calls of the provider, and ClientSecret lets the guest pay it
+type Intent struct {
+	Reference    string
+	ClientSecret string
+	Status       string
+}
+
+// Event is a change of status of a payment, read from a webhook call
+type Event struct {
+	Reference string
+	Status    string
+}
+
+// Provider collects and refunds payments
+type Provider interface {
+	// Name is the name the provider is registered and configured with
+	Name() string
+	// Create starts collecting charge
+	Create(ctx context.Context, charge Charge) (Intent, error)
+	// Refund gives part or all of a payment back
+	Refund(ctx context.Context, refund Refund) error
+	// Parse verifies a webhook call of the provider and reads its event
+	Parse(header http.Header, body []byte) (Event, error)
+}
+
+var providers = map[string]func() (Provider, error){}
+
+// Register makes a provider available under name, open is called whenever
+// it is configured
+func Register(name string, open func() (Provider, error)) {
+	providers[name] = open
+}
+
+// Open returns the provider named in services.payments.provider
+func Open() (Provider, error) {
+	return OpenNamed(viper.GetString("services.payments.provider"))
+}
+
+// OpenNamed returns the provider registered as name
+func OpenNamed(name string) (Provider, error) {
+	open, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown payment provider %q, expected one of %v", name, Names())
+	}
+	return open()
+}
+
+// Names lists the registered providers
+func Names() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,3 @@
+// Recurrence package parses and expands the subset of RFC 5545 recurrence rules reservations can repeat with.
+
+package recurrence
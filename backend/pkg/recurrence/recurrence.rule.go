@@ -0,0 +1,264 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var weekdayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+const untilLayout = "20060102T150405Z"
+
+// Rule is an RRULE made of FREQ (DAILY, WEEKLY or MONTHLY), INTERVAL, COUNT
+// or UNTIL, BYDAY on weekly rules and BYMONTHDAY on monthly ones. Any other
+// part is rejected instead of being ignored
+type Rule struct {
+	Frequency Frequency
+	Interval  int
+	// Count is the number of occurrences, or zero when unbounded
+	Count int
+	// Until is the last instant an occurrence may start at, or zero
+	Until     time.Time
+	Weekdays  []time.Weekday
+	MonthDays []int
+}
+
+// Parse reads a rule such as FREQ=WEEKLY;BYDAY=TU;COUNT=10, with or without
+// the RRULE: prefix
+func Parse(value string) (Rule, error) {
+	rule := Rule{Interval: 1}
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return rule, errors.New("the rule is empty")
+	}
+
+	seen := map[string]bool{}
+	for _, part := range strings.Split(value, ";") {
+		name, argument, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		argument = strings.ToUpper(strings.TrimSpace(argument))
+		if !ok || argument == "" {
+			return rule, fmt.Errorf("invalid rule part %q", part)
+		}
+		if seen[name] {
+			return rule, fmt.Errorf("%s is set twice", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			rule.Frequency = Frequency(argument)
+			if rule.Frequency != Daily && rule.Frequency != Weekly && rule.Frequency != Monthly {
+				err = fmt.Errorf("unsupported frequency %s", argument)
+			}
+		case "INTERVAL":
+			rule.Interval, err = positive(name, argument)
+		case "COUNT":
+			rule.Count, err = positive(name, argument)
+		case "UNTIL":
+			rule.Until, err = until(argument)
+		case "BYDAY":
+			for _, day := range strings.Split(argument, ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return rule, fmt.Errorf("unsupported BYDAY value %s", day)
+				}
+				if slices.Contains(rule.Weekdays, weekday) {
+					return rule, fmt.Errorf("BYDAY value %s is set twice", day)
+				}
+				rule.Weekdays = append(rule.Weekdays, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(argument, ",") {
+				number, err := strconv.Atoi(day)
+				if err != nil || number < 1 || number > 31 {
+					return rule, fmt.Errorf("unsupported BYMONTHDAY value %s", day)
+				}
+				if slices.Contains(rule.MonthDays, number) {
+					return rule, fmt.Errorf("BYMONTHDAY value %s is set twice", day)
+				}
+				rule.MonthDays = append(rule.MonthDays, number)
+			}
+		default:
+			err = fmt.Errorf("unsupported rule part %s", name)
+		}
+		if err != nil {
+			return rule, err
+		}
+	}
+
+	switch {
+	case rule.Frequency == "":
+		return rule, errors.New("FREQ is required")
+	case rule.Count > 0 && !rule.Until.IsZero():
+		return rule, errors.New("COUNT and UNTIL cannot be combined")
+	case len(rule.Weekdays) > 0 && rule.Frequency != Weekly:
+		return rule, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	case len(rule.MonthDays) > 0 && rule.Frequency != Monthly:
+		return rule, errors.New("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+
+	sort.Slice(rule.Weekdays, func(i, j int) bool {
+		return weekdayOffset(rule.Weekdays[i]) < weekdayOffset(rule.Weekdays[j])
+	})
+	sort.Ints(rule.MonthDays)
+	return rule, nil
+}
+
+func positive(name string, argument string) (int, error) {
+	number, err := strconv.Atoi(argument)
+	if err != nil || number < 1 {
+		return 0, fmt.Errorf("%s must be a positive number", name)
+	}
+	return number, nil
+}
+
+// until reads a date, which includes the whole day, or a UTC date-time
+func until(argument string) (time.Time, error) {
+	if date, err := time.Parse("20060102", argument); err == nil {
+		return date.Add(24*time.Hour - time.Second), nil
+	}
+	if instant, err := time.Parse(untilLayout, argument); err == nil {
+		return instant, nil
+	}
+	return time.Time{}, fmt.Errorf("UNTIL must be a date or a UTC date-time, got %s", argument)
+}
+
+// String returns the rule in its canonical form
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Frequency)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+	if len(r.Weekdays) > 0 {
+		days := make([]string, len(r.Weekdays))
+		for i, weekday := range r.Weekdays {
+			days[i] = weekdayNames[weekday]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.MonthDays) > 0 {
+		days := make([]string, len(r.MonthDays))
+		for i, day := range r.MonthDays {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Between returns the occurrences of a series starting at start that fall in
+// [from, to). COUNT is counted from start, whatever from is
+func (r Rule) Between(start time.Time, from time.Time, to time.Time) []time.Time {
+	occurrences := []time.Time{}
+	generated := 0
+	for period := 0; ; period++ {
+		candidates, periodStart := r.period(start, period)
+		if !periodStart.Before(to) {
+			return occurrences
+		}
+		for _, candidate := range candidates {
+			if candidate.Before(start) {
+				continue
+			}
+			if !r.Until.IsZero() && candidate.After(r.Until) {
+				return occurrences
+			}
+			if r.Count > 0 && generated == r.Count {
+				return occurrences
+			}
+			generated++
+			if !candidate.Before(to) {
+				return occurrences
+			}
+			if !candidate.Before(from) {
+				occurrences = append(occurrences, candidate)
+			}
+		}
+	}
+}
+
+// Before counts the occurrences of a series starting at start that happen
+// before instant
+func (r Rule) Before(start time.Time, instant time.Time) int {
+	return len(r.Between(start, start, instant))
+}
+
+// period returns the candidates of the nth period of the series, in order,
+// and the instant the period begins at
+func (r Rule) period(start time.Time, n int) ([]time.Time, time.Time) {
+	hour, minute, second := start.Clock()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, minute, second, start.Nanosecond(), start.Location())
+	}
+
+	switch r.Frequency {
+	case Weekly:
+		// Weeks start on Monday, as RFC 5545 defaults WKST to MO
+		monday := start.AddDate(0, 0, -weekdayOffset(start.Weekday())+7*r.Interval*n)
+		days := r.Weekdays
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		candidates := make([]time.Time, len(days))
+		for i, weekday := range days {
+			candidates[i] = monday.AddDate(0, 0, weekdayOffset(weekday))
+		}
+		year, month, day := monday.Date()
+		return candidates, time.Date(year, month, day, 0, 0, 0, 0, start.Location())
+	case Monthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(r.Interval*n), 1, 0, 0, 0, 0, start.Location())
+		days := r.MonthDays
+		if len(days) == 0 {
+			days = []int{start.Day()}
+		}
+		candidates := []time.Time{}
+		for _, day := range days {
+			// Days the month does not have are skipped, as RFC 5545 says
+			candidate := at(first.Year(), first.Month(), day)
+			if candidate.Month() == first.Month() {
+				candidates = append(candidates, candidate)
+			}
+		}
+		return candidates, first
+	default:
+		candidate := at(start.Year(), start.Month(), start.Day()+r.Interval*n)
+		year, month, day := candidate.Date()
+		return []time.Time{candidate}, time.Date(year, month, day, 0, 0, 0, 0, start.Location())
+	}
+}
+
+// weekdayOffset is the number of days from Monday to weekday
+func weekdayOffset(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
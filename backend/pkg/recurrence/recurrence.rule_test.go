@@ -0,0 +1,191 @@
+package recurrence
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const layout = "2006-01-02 15:04 MST"
+
+// local reads value, like 2026-01-05 10:00, in zone
+func local(t *testing.T, zone string, value string) time.Time {
+	t.Helper()
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		t.Skipf("time zone %s is not available: %s", zone, err)
+	}
+	instant, err := time.ParseInLocation("2006-01-02 15:04", value, location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return instant
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		err   string
+	}{
+		{value: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{value: "RRULE:freq=weekly;byday=fr,mo;interval=2", want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR"},
+		{value: "FREQ=WEEKLY;BYDAY=SU,MO", want: "FREQ=WEEKLY;BYDAY=MO,SU"},
+		{value: "FREQ=MONTHLY;BYMONTHDAY=31,1;COUNT=4", want: "FREQ=MONTHLY;COUNT=4;BYMONTHDAY=1,31"},
+		{value: "FREQ=DAILY;INTERVAL=1;UNTIL=20261231", want: "FREQ=DAILY;UNTIL=20261231T235959Z"},
+		{value: "FREQ=DAILY;UNTIL=20261231T100000Z", want: "FREQ=DAILY;UNTIL=20261231T100000Z"},
+		{value: "", err: "empty"},
+		{value: "INTERVAL=2", err: "FREQ is required"},
+		{value: "FREQ=YEARLY", err: "unsupported frequency"},
+		{value: "FREQ=DAILY;FREQ=WEEKLY", err: "set twice"},
+		{value: "FREQ=DAILY;INTERVAL=0", err: "positive"},
+		{value: "FREQ=DAILY;COUNT=-1", err: "positive"},
+		{value: "FREQ=DAILY;COUNT=2;UNTIL=20261231", err: "cannot be combined"},
+		{value: "FREQ=DAILY;UNTIL=2026-12-31", err: "UNTIL must be"},
+		{value: "FREQ=DAILY;BYDAY=MO", err: "only supported with FREQ=WEEKLY"},
+		{value: "FREQ=WEEKLY;BYDAY=1MO", err: "unsupported BYDAY"},
+		{value: "FREQ=WEEKLY;BYDAY=MO,TU,MO", err: "BYDAY value MO is set twice"},
+		{value: "FREQ=WEEKLY;BYMONTHDAY=1", err: "only supported with FREQ=MONTHLY"},
+		{value: "FREQ=MONTHLY;BYMONTHDAY=32", err: "unsupported BYMONTHDAY"},
+		{value: "FREQ=MONTHLY;BYMONTHDAY=0", err: "unsupported BYMONTHDAY"},
+		{value: "FREQ=MONTHLY;BYMONTHDAY=15,15", err: "BYMONTHDAY value 15 is set twice"},
+		{value: "FREQ=DAILY;BYHOUR=10", err: "unsupported rule part"},
+		{value: "FREQ=DAILY;COUNT", err: "invalid rule part"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			rule, err := Parse(tt.value)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got %v, want an error with %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		zone  string
+		start string
+		from  string
+		to    string
+		want  []string
+	}{
+		{
+			name: "daily", rule: "FREQ=DAILY", zone: "UTC",
+			start: "2026-01-01 09:00", from: "2026-01-01 00:00", to: "2026-01-04 00:00",
+			want: []string{"2026-01-01 09:00 UTC", "2026-01-02 09:00 UTC", "2026-01-03 09:00 UTC"},
+		},
+		{
+			name: "every third day", rule: "FREQ=DAILY;INTERVAL=3", zone: "UTC",
+			start: "2026-01-30 09:00", from: "2026-01-01 00:00", to: "2026-02-08 00:00",
+			want: []string{"2026-01-30 09:00 UTC", "2026-02-02 09:00 UTC", "2026-02-05 09:00 UTC"},
+		},
+		{
+			name: "window after the start", rule: "FREQ=DAILY", zone: "UTC",
+			start: "2026-01-01 09:00", from: "2026-01-10 09:00", to: "2026-01-12 09:00",
+			want: []string{"2026-01-10 09:00 UTC", "2026-01-11 09:00 UTC"},
+		},
+		{
+			name: "count from the start whatever the window", rule: "FREQ=DAILY;COUNT=5", zone: "UTC",
+			start: "2026-01-01 09:00", from: "2026-01-04 00:00", to: "2026-02-01 00:00",
+			want: []string{"2026-01-04 09:00 UTC", "2026-01-05 09:00 UTC"},
+		},
+		{
+			name: "until a date includes the day", rule: "FREQ=DAILY;UNTIL=20260103", zone: "UTC",
+			start: "2026-01-01 21:00", from: "2026-01-01 00:00", to: "2026-02-01 00:00",
+			want: []string{"2026-01-01 21:00 UTC", "2026-01-02 21:00 UTC", "2026-01-03 21:00 UTC"},
+		},
+		{
+			name: "until an instant", rule: "FREQ=DAILY;UNTIL=20260103T090000Z", zone: "UTC",
+			start: "2026-01-01 09:30", from: "2026-01-01 00:00", to: "2026-02-01 00:00",
+			want: []string{"2026-01-01 09:30 UTC", "2026-01-02 09:30 UTC"},
+		},
+		{
+			name: "weekly on the day of the start", rule: "FREQ=WEEKLY;COUNT=3", zone: "UTC",
+			start: "2026-01-07 18:00", from: "2026-01-01 00:00", to: "2026-03-01 00:00",
+			want: []string{"2026-01-07 18:00 UTC", "2026-01-14 18:00 UTC", "2026-01-21 18:00 UTC"},
+		},
+		{
+			name: "weekly by day skips the days before the start", rule: "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=5", zone: "UTC",
+			start: "2026-01-07 18:00", from: "2026-01-01 00:00", to: "2026-03-01 00:00",
+			want: []string{"2026-01-07 18:00 UTC", "2026-01-09 18:00 UTC", "2026-01-12 18:00 UTC", "2026-01-14 18:00 UTC", "2026-01-16 18:00 UTC"},
+		},
+		{
+			name: "every other week with Sunday closing the week", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=SU,MO;COUNT=4", zone: "UTC",
+			start: "2026-01-05 08:00", from: "2026-01-01 00:00", to: "2026-03-01 00:00",
+			want: []string{"2026-01-05 08:00 UTC", "2026-01-11 08:00 UTC", "2026-01-19 08:00 UTC", "2026-01-25 08:00 UTC"},
+		},
+		{
+			name: "monthly on the 31st skips the shorter months", rule: "FREQ=MONTHLY;COUNT=4", zone: "UTC",
+			start: "2026-01-31 12:00", from: "2026-01-01 00:00", to: "2027-01-01 00:00",
+			want: []string{"2026-01-31 12:00 UTC", "2026-03-31 12:00 UTC", "2026-05-31 12:00 UTC", "2026-07-31 12:00 UTC"},
+		},
+		{
+			name: "monthly on the 29th and 30th in February", rule: "FREQ=MONTHLY;BYMONTHDAY=1,29,30", zone: "UTC",
+			start: "2028-01-30 12:00", from: "2028-01-01 00:00", to: "2028-03-02 00:00",
+			want: []string{"2028-01-30 12:00 UTC", "2028-02-01 12:00 UTC", "2028-02-29 12:00 UTC", "2028-03-01 12:00 UTC"},
+		},
+		{
+			name: "every other month", rule: "FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=15", zone: "UTC",
+			start: "2026-11-15 12:00", from: "2026-01-01 00:00", to: "2027-06-01 00:00",
+			want: []string{"2026-11-15 12:00 UTC", "2027-01-15 12:00 UTC", "2027-03-15 12:00 UTC", "2027-05-15 12:00 UTC"},
+		},
+		{
+			name: "weekly keeps the wall clock across spring forward", rule: "FREQ=WEEKLY;COUNT=3", zone: "Europe/Madrid",
+			start: "2026-03-22 10:00", from: "2026-03-01 00:00", to: "2026-05-01 00:00",
+			want: []string{"2026-03-22 10:00 CET", "2026-03-29 10:00 CEST", "2026-04-05 10:00 CEST"},
+		},
+		{
+			name: "daily keeps the wall clock across fall back", rule: "FREQ=DAILY;COUNT=3", zone: "Atlantic/Canary",
+			start: "2026-10-24 20:00", from: "2026-10-01 00:00", to: "2026-11-01 00:00",
+			want: []string{"2026-10-24 20:00 WEST", "2026-10-25 20:00 WET", "2026-10-26 20:00 WET"},
+		},
+		{
+			name: "a time the spring forward skips moves past the gap", rule: "FREQ=DAILY;COUNT=3", zone: "Europe/Madrid",
+			start: "2026-03-28 02:30", from: "2026-03-01 00:00", to: "2026-04-01 00:00",
+			want: []string{"2026-03-28 02:30 CET", "2026-03-29 03:30 CEST", "2026-03-30 02:30 CEST"},
+		},
+		{
+			name: "monthly across both changes", rule: "FREQ=MONTHLY;BYMONTHDAY=28;COUNT=3", zone: "Europe/Madrid",
+			start: "2026-02-28 19:00", from: "2026-01-01 00:00", to: "2027-01-01 00:00",
+			want: []string{"2026-02-28 19:00 CET", "2026-03-28 19:00 CET", "2026-04-28 19:00 CEST"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, occurrence := range rule.Between(local(t, tt.zone, tt.start), local(t, tt.zone, tt.from), local(t, tt.zone, tt.to)) {
+				got = append(got, occurrence.Format(layout))
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBefore(t *testing.T) {
+	rule, err := Parse("FREQ=WEEKLY;BYDAY=TU,TH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := local(t, "UTC", "2026-01-06 10:00")
+	if got := rule.Before(start, local(t, "UTC", "2026-01-20 10:00")); got != 4 {
+		t.Fatalf("got %d occurrences before the third Tuesday, want 4", got)
+	}
+}
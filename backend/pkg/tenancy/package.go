@@ -0,0 +1,3 @@
+// Tenancy package isolates the data of every organization sharing the deployment.
+
+package tenancy
@@ -0,0 +1,61 @@
+package tenancy
+
+import (
+	"backend/pkg/common"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNoTenant is returned when a query is made without a tenant, which would
+// otherwise expose the data of every organization
+var ErrNoTenant = errors.New("no tenant bound to the context")
+
+// Current returns the tenant the context bound to db is restricted to. System
+// contexts are not restricted and get uuid.Nil
+func Current(db *gorm.DB) (uuid.UUID, error) {
+	ctx := db.Statement.Context
+	if tenantID, ok := common.TenantFromContext(ctx); ok {
+		return tenantID, nil
+	}
+	if common.IsSystem(ctx) {
+		return uuid.Nil, nil
+	}
+	return uuid.Nil, ErrNoTenant
+}
+
+// Scope restricts a query, count, update or delete to the rows of the current
+// tenant. Without a tenant the statement fails instead of running unscoped
+func Scope(db *gorm.DB) *gorm.DB {
+	tenantID, err := Current(db)
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+	if tenantID == uuid.Nil {
+		return db
+	}
+	return db.Where(clause.Eq{
+		Column: clause.Column{Table: clause.CurrentTable, Name: "tenant_id"},
+		Value:  tenantID,
+	})
+}
+
+// Assign stamps entity with the current tenant before it is written, whatever
+// tenant the payload claimed. System contexts keep the tenant of the entity
+func Assign(db *gorm.DB, entity interface{}) error {
+	scoped, ok := entity.(common.TenantScoped)
+	if !ok {
+		return nil
+	}
+	tenantID, err := Current(db)
+	if err != nil {
+		return err
+	}
+	if tenantID != uuid.Nil {
+		scoped.SetTenantID(tenantID)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package tenancy_test
+
+import (
+	"backend/database"
+	"backend/database/databasetest"
+	"backend/pkg/common"
+	"backend/pkg/tenancy"
+
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type widget struct {
+	common.CommonEntity `gorm:"embedded"`
+	Name                string
+}
+
+func seed(t *testing.T) (a uuid.UUID, b uuid.UUID) {
+	t.Helper()
+	db := databasetest.Open(t, &widget{})
+	a, b = uuid.New(), uuid.New()
+	for _, w := range []*widget{{Name: "a1"}, {Name: "a2"}, {Name: "b1"}} {
+		w.TenantID = a
+		if w.Name == "b1" {
+			w.TenantID = b
+		}
+		if err := db.Create(w).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return a, b
+}
+
+func TestScope(t *testing.T) {
+	a, b := seed(t)
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want int
+		err  error
+	}{
+		{"tenant a", common.WithTenant(context.Background(), a), 2, nil},
+		{"tenant b", common.WithTenant(context.Background(), b), 1, nil},
+		{"other tenant", common.WithTenant(context.Background(), uuid.New()), 0, nil},
+		{"system", common.AsSystem(context.Background()), 3, nil},
+		{"no tenant", context.Background(), 0, tenancy.ErrNoTenant},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var found []widget
+			err := database.DB.WithContext(tt.ctx).Scopes(tenancy.Scope).Find(&found).Error
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if len(found) != tt.want {
+				t.Fatalf("got %d widgets, want %d", len(found), tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeRestrictsWrites(t *testing.T) {
+	a, b := seed(t)
+	ctx := common.WithTenant(context.Background(), b)
+	db := database.DB.WithContext(ctx)
+
+	updated := db.Model(&widget{}).Scopes(tenancy.Scope).Where("name = ?", "a1").Update("name", "taken")
+	if updated.Error != nil || updated.RowsAffected != 0 {
+		t.Fatalf("updated %d rows of another tenant, error %v", updated.RowsAffected, updated.Error)
+	}
+	deleted := db.Scopes(tenancy.Scope).Where("tenant_id = ?", a).Delete(&widget{})
+	if deleted.Error != nil || deleted.RowsAffected != 0 {
+		t.Fatalf("deleted %d rows of another tenant, error %v", deleted.RowsAffected, deleted.Error)
+	}
+}
+
+func TestAssign(t *testing.T) {
+	databasetest.Open(t, &widget{})
+	tenant, own := uuid.New(), uuid.New()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want uuid.UUID
+		err  error
+	}{
+		{"tenant overrides the payload", common.WithTenant(context.Background(), tenant), tenant, nil},
+		{"system keeps the payload", common.AsSystem(context.Background()), own, nil},
+		{"no tenant", context.Background(), own, tenancy.ErrNoTenant},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &widget{Name: "w"}
+			w.TenantID = own
+			err := tenancy.Assign(database.DB.WithContext(tt.ctx), w)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if w.TenantID != tt.want {
+				t.Fatalf("got tenant %s, want %s", w.TenantID, tt.want)
+			}
+		})
+	}
+}
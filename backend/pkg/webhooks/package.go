@@ -0,0 +1,3 @@
+// Webhooks package signs event payloads and posts them to the URLs partners subscribe with.
+
+package webhooks
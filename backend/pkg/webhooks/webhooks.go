@@ -0,0 +1,170 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Headers of a delivery. The signature is t=<unix time>,v1=<hex HMAC-SHA256
+// of "<unix time>.<body>"> with the secret of the subscription, and receivers
+// should refuse old timestamps to stop replays
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	EventIDHeader   = "X-Webhook-Event-ID"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+var ErrForbiddenAddress = errors.New("webhooks cannot be posted to loopback, private, link-local or metadata addresses")
+
+// Response is what the receiver answered, Body cut to its first kilobyte
+type Response struct {
+	Status int
+	Body   string
+}
+
+// NewSecret returns a random secret to sign payloads with
+func NewSecret() string {
+	random := make([]byte, 32)
+	rand.Read(random)
+	return "whsec_" + hex.EncodeToString(random)
+}
+
+// Sign returns the signature header of body sent at at
+func Sign(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify tells whether signature is the one of body, made with secret less
+// than tolerance ago
+func Verify(secret string, signature string, body []byte, tolerance time.Duration) bool {
+	timestamp, _, _ := strings.Cut(strings.TrimPrefix(signature, "t="), ",")
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(unix, 0)).Abs() > tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, time.Unix(unix, 0), body)), []byte(signature))
+}
+
+// Post sends body to url signed with secret, within
+// services.webhooks.timeout. Receivers answering other than 2xx fail the
+// delivery, and their response is returned along with the error. Redirects
+// are not followed, and forbidden addresses are not dialed
+func Post(ctx context.Context, url string, secret string, headers map[string]string, body []byte) (*Response, error) {
+	if timeout := viper.GetDuration("services.webhooks.timeout"); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "reservations-webhooks/1")
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	request.Header.Set(SignatureHeader, Sign(secret, time.Now(), body))
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	answer, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+	result := &Response{Status: response.StatusCode, Body: string(answer)}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return result, fmt.Errorf("the receiver answered %d", response.StatusCode)
+	}
+	return result, nil
+}
+
+// client posts the deliveries without a proxy, so that each address dialed
+// is checked, and hands redirects back as the answer instead of following
+// them
+var client = &http.Client{
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 30 * time.Second, Control: control}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// control refuses to connect to the forbidden addresses, once the host is
+// resolved, so that a name cannot point somewhere else after CheckURL
+func control(network string, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if forbidden(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+	return nil
+}
+
+// CheckURL returns ErrForbiddenAddress when the host of raw resolves to an
+// address deliveries cannot be posted to
+func CheckURL(ctx context.Context, raw string) error {
+	target, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	addresses, err := net.DefaultResolver.LookupNetIP(ctx, "ip", target.Hostname())
+	if err != nil {
+		return fmt.Errorf("cannot resolve the webhook host %q: %w", target.Hostname(), err)
+	}
+	for _, ip := range addresses {
+		if forbidden(ip) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenAddress, target.Hostname(), ip.Unmap())
+		}
+	}
+	return nil
+}
+
+// metadata is the shared address space, which some clouds serve their
+// metadata from. The others use link-local or private addresses
+var metadata = netip.MustParsePrefix("100.64.0.0/10")
+
+// forbidden tells whether ip is a loopback, private, link-local, multicast,
+// unspecified or metadata address. Every address is allowed when
+// services.webhooks.allow_private_networks is set, for receivers run next to
+// the API while developing
+func forbidden(ip netip.Addr) bool {
+	if viper.GetBool("services.webhooks.allow_private_networks") {
+		return false
+	}
+	ip = ip.Unmap()
+	return !ip.IsValid() || metadata.Contains(ip) || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
@@ -0,0 +1,107 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{"https://93.184.215.14/hook", nil},
+		{"https://[2606:2800:21f:cb07:6820:80da:af6b:8b2c]/hook", nil},
+		{"http://127.0.0.1:8080/hook", ErrForbiddenAddress},
+		{"http://localhost/hook", ErrForbiddenAddress},
+		{"http://[::1]/hook", ErrForbiddenAddress},
+		{"http://[::ffff:127.0.0.1]/hook", ErrForbiddenAddress},
+		{"http://0.0.0.0/hook", ErrForbiddenAddress},
+		{"http://10.1.2.3/hook", ErrForbiddenAddress},
+		{"http://172.16.0.1/hook", ErrForbiddenAddress},
+		{"http://192.168.1.1/hook", ErrForbiddenAddress},
+		{"http://[fd00::1]/hook", ErrForbiddenAddress},
+		{"http://169.254.169.254/latest/meta-data", ErrForbiddenAddress},
+		{"http://[fe80::1]/hook", ErrForbiddenAddress},
+		{"http://100.100.100.200/latest/meta-data", ErrForbiddenAddress},
+		{"http://224.0.0.1/hook", ErrForbiddenAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if err := CheckURL(context.Background(), tt.url); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckURLAllowingPrivateNetworks(t *testing.T) {
+	viper.Set("services.webhooks.allow_private_networks", true)
+	defer viper.Set("services.webhooks.allow_private_networks", false)
+	if err := CheckURL(context.Background(), "http://127.0.0.1:8080/hook"); err != nil {
+		t.Fatalf("got %v, want nil", err)
+	}
+}
+
+func TestPostRefusesToDialForbiddenAddresses(t *testing.T) {
+	called := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	_, err := Post(context.Background(), receiver.URL, "secret", nil, []byte(`{}`))
+	if !errors.Is(err, ErrForbiddenAddress) || called {
+		t.Fatalf("got %v, want %v without reaching the receiver", err, ErrForbiddenAddress)
+	}
+}
+
+func TestPostDoesNotFollowRedirects(t *testing.T) {
+	viper.Set("services.webhooks.allow_private_networks", true)
+	defer viper.Set("services.webhooks.allow_private_networks", false)
+	redirected := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer receiver.Close()
+
+	response, err := Post(context.Background(), receiver.URL, "secret", nil, []byte(`{}`))
+	if err == nil || response == nil || response.Status != http.StatusTemporaryRedirect {
+		t.Fatalf("got %v and %+v, want the redirect as a failed answer", err, response)
+	}
+	if redirected {
+		t.Fatal("the redirect was followed")
+	}
+}
+
+func TestPostSigns(t *testing.T) {
+	viper.Set("services.webhooks.allow_private_networks", true)
+	defer viper.Set("services.webhooks.allow_private_networks", false)
+	body := []byte(`{"type":"reservation.created"}`)
+	signatures := make(chan string, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signatures <- r.Header.Get(SignatureHeader)
+	}))
+	defer receiver.Close()
+
+	if _, err := Post(context.Background(), receiver.URL, "secret", nil, body); err != nil {
+		t.Fatal(err)
+	}
+	signature := <-signatures
+	if !Verify("secret", signature, body, time.Minute) {
+		t.Errorf("signature %q does not verify", signature)
+	}
+	if Verify("other", signature, body, time.Minute) || Verify("secret", signature, []byte(`{}`), time.Minute) {
+		t.Errorf("signature %q verifies another secret or body", signature)
+	}
+}
@@ -0,0 +1,107 @@
+// Code generated by `backend codegen ts`. DO NOT EDIT.
+
+import { download, filterBuilder, request, url } from "./lib";
+import type { BulkMode, ClientConfig, Filter, Input, Order, Page } from "./lib";
+import type { BulkResult, BusinessDTO, EntryDTO, HoldConfirmationDTO, HoldDTO, ImportResult, LedgerEntryDTO, ReservationDTO, WaitlistEntryDTO } from "./models";
+
+/** Columns of businesses usable in filters and orders */
+export type BusinessField =
+  | "id"
+  | "created_at"
+  | "updated_at"
+  | "name"
+  | "type"
+  | "location"
+  | "owner_id"
+  | "capacity"
+  | "slot_minutes"
+  | "time_zone"
+  | "logo_url"
+  | "brand_color"
+  | "email_footer";
+
+export const businessFilters = filterBuilder<BusinessField>();
+
+export const createBusinessesClient = (config: ClientConfig) => ({
+  /** Get all businesses */
+  list: (query: { filters?: Filter<BusinessField>[]; orders?: Order<BusinessField>[]; relations?: string[]; page?: number; size?: number } = {}) =>
+    request<Page<BusinessDTO>>(config, "GET", `/businesses`, { query }),
+  /** Get all businesses, as a file */
+  export: (format: "text/csv" | "application/x-ndjson" | "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", query: { filters?: Filter<BusinessField>[]; orders?: Order<BusinessField>[]; fields?: BusinessField[] } = {}) =>
+    download(config, `/businesses`, format, { query }),
+  /** Count businesses */
+  count: (query: { filters?: Filter<BusinessField>[] } = {}) =>
+    request<number>(config, "GET", `/businesses/count`, { query }),
+  /** Get deleted businesses */
+  listDeleted: (query: { filters?: Filter<BusinessField>[]; orders?: Order<BusinessField>[]; relations?: string[]; page?: number; size?: number } = {}) =>
+    request<Page<BusinessDTO>>(config, "GET", `/businesses/deleted`, { query }),
+  /** Get deleted businesses, as a file */
+  exportDeleted: (format: "text/csv" | "application/x-ndjson" | "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", query: { filters?: Filter<BusinessField>[]; orders?: Order<BusinessField>[]; fields?: BusinessField[] } = {}) =>
+    download(config, `/businesses/deleted`, format, { query }),
+  /** Bulk create businesses */
+  bulkCreate: (payload: Input<BusinessDTO>[], query: { mode?: BulkMode } = {}) =>
+    request<BulkResult>(config, "POST", `/businesses/bulk`, { body: payload, query }),
+  /** Bulk update businesses */
+  bulkUpdate: (payload: Input<BusinessDTO>[], query: { mode?: BulkMode } = {}) =>
+    request<BulkResult>(config, "PATCH", `/businesses/bulk`, { body: payload, query }),
+  /** Bulk delete businesses */
+  bulkDelete: (payload: string[], query: { mode?: BulkMode } = {}) =>
+    request<BulkResult>(config, "DELETE", `/businesses/bulk`, { body: payload, query }),
+  /** Get one business */
+  get: (id: string, query: { relations?: string[] } = {}) =>
+    request<BusinessDTO>(config, "GET", `/businesses/${encodeURIComponent(id)}`, { query }),
+  /** Create one business */
+  create: (payload: Input<BusinessDTO>) =>
+    request<BusinessDTO>(config, "POST", `/businesses`, { body: payload }),
+  /** Update one business */
+  update: (id: string, payload: Input<BusinessDTO>) =>
+    request<BusinessDTO>(config, "PUT", `/businesses/${encodeURIComponent(id)}`, { body: payload }),
+  /** Delete one business */
+  delete: (id: string) =>
+    request<string>(config, "DELETE", `/businesses/${encodeURIComponent(id)}`),
+  /** Get history of one business */
+  history: (id: string, query: { page?: number; size?: number } = {}) =>
+    request<Page<EntryDTO>>(config, "GET", `/businesses/${encodeURIComponent(id)}/history`, { query }),
+  /** Hard delete one business */
+  hardDelete: (id: string) =>
+    request<string>(config, "DELETE", `/businesses/${encodeURIComponent(id)}/hard`),
+  /** Import businesses */
+  import: (document: Blob | string, query: { dry_run?: boolean } = {}) =>
+    request<ImportResult>(config, "POST", `/businesses/import`, { body: document, contentType: "text/csv", query }),
+  /** Get calendar of one business, as a URL */
+  getCalendarOfOneBusiness: (id: string) =>
+    url(config, `/businesses/${encodeURIComponent(id)}/calendar.ics`),
+  /** Join waitlist of one business */
+  joinWaitlistOfOneBusiness: (id: string, payload: WaitlistEntryDTO) =>
+    request<WaitlistEntryDTO>(config, "POST", `/businesses/${encodeURIComponent(id)}/waitlist`, { body: payload }),
+  /** Get waitlist of one business */
+  getWaitlistOfOneBusiness: (id: string) =>
+    request<WaitlistEntryDTO[]>(config, "GET", `/businesses/${encodeURIComponent(id)}/waitlist`),
+  /** Get waitlist entry of one business */
+  getWaitlistEntryOfOneBusiness: (id: string, entry: string) =>
+    request<WaitlistEntryDTO>(config, "GET", `/businesses/${encodeURIComponent(id)}/waitlist/${encodeURIComponent(entry)}`),
+  /** Confirm waitlist entry of one business */
+  confirmWaitlistEntryOfOneBusiness: (id: string, entry: string) =>
+    request<WaitlistEntryDTO>(config, "POST", `/businesses/${encodeURIComponent(id)}/waitlist/${encodeURIComponent(entry)}/confirm`),
+  /** Leave waitlist of one business */
+  leaveWaitlistOfOneBusiness: (id: string, entry: string) =>
+    request<WaitlistEntryDTO>(config, "DELETE", `/businesses/${encodeURIComponent(id)}/waitlist/${encodeURIComponent(entry)}`),
+  /** Place hold on one business */
+  placeHoldOnOneBusiness: (id: string, payload: HoldDTO) =>
+    request<HoldDTO>(config, "POST", `/businesses/${encodeURIComponent(id)}/holds`, { body: payload }),
+  /** Get hold of one business */
+  getHoldOfOneBusiness: (id: string, hold: string) =>
+    request<HoldDTO>(config, "GET", `/businesses/${encodeURIComponent(id)}/holds/${encodeURIComponent(hold)}`),
+  /** Confirm hold of one business */
+  confirmHoldOfOneBusiness: (id: string, hold: string, payload: HoldConfirmationDTO) =>
+    request<ReservationDTO>(config, "POST", `/businesses/${encodeURIComponent(id)}/holds/${encodeURIComponent(hold)}/confirm`, { body: payload }),
+  /** Release hold of one business */
+  releaseHoldOfOneBusiness: (id: string, hold: string) =>
+    request<HoldDTO>(config, "DELETE", `/businesses/${encodeURIComponent(id)}/holds/${encodeURIComponent(hold)}`),
+  /** Get ledger of one business */
+  getLedgerOfOneBusiness: (id: string) =>
+    request<LedgerEntryDTO[]>(config, "GET", `/businesses/${encodeURIComponent(id)}/ledger`),
+  /** Import holidays of one business */
+  importHolidaysOfOneBusiness: (id: string, document: Blob | string, query: { dry_run?: boolean } = {}) =>
+    request<ImportResult>(config, "POST", `/businesses/${encodeURIComponent(id)}/holidays`, { body: document, contentType: "text/calendar", query }),
+});
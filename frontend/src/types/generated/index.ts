@@ -0,0 +1,48 @@
+// Code generated by `backend codegen ts`. DO NOT EDIT.
+
+import type { ClientConfig } from "./lib";
+import { createBusinessesClient } from "./businesses";
+import { createCancellationPoliciesClient } from "./cancellation-policies";
+import { createDepositRulesClient } from "./deposit-rules";
+import { createRecurringReservationsClient } from "./recurring-reservations";
+import { createReservationsClient } from "./reservations";
+import { createResourcesClient } from "./resources";
+import { createScheduleExceptionsClient } from "./schedule-exceptions";
+import { createSchedulesClient } from "./schedules";
+import { createServicesClient } from "./services";
+import { createStaffClient } from "./staff";
+import { createTimeOffClient } from "./time-off";
+import { createUsersClient } from "./users";
+import { createWebhookSubscriptionsClient } from "./webhook-subscriptions";
+
+export * from "./lib";
+export * from "./models";
+export * from "./businesses";
+export * from "./cancellation-policies";
+export * from "./deposit-rules";
+export * from "./recurring-reservations";
+export * from "./reservations";
+export * from "./resources";
+export * from "./schedule-exceptions";
+export * from "./schedules";
+export * from "./services";
+export * from "./staff";
+export * from "./time-off";
+export * from "./users";
+export * from "./webhook-subscriptions";
+
+export const createApiClient = (config: ClientConfig) => ({
+  businesses: createBusinessesClient(config),
+  cancellationPolicies: createCancellationPoliciesClient(config),
+  depositRules: createDepositRulesClient(config),
+  recurringReservations: createRecurringReservationsClient(config),
+  reservations: createReservationsClient(config),
+  resources: createResourcesClient(config),
+  scheduleExceptions: createScheduleExceptionsClient(config),
+  schedules: createSchedulesClient(config),
+  services: createServicesClient(config),
+  staff: createStaffClient(config),
+  timeOff: createTimeOffClient(config),
+  users: createUsersClient(config),
+  webhookSubscriptions: createWebhookSubscriptionsClient(config),
+});
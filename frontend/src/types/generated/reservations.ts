@@ -0,0 +1,87 @@
+// Code generated by `backend codegen ts`. DO NOT EDIT.
+
+import { download, filterBuilder, request, url } from "./lib";
+import type { BulkMode, ClientConfig, Filter, Input, Order, Page } from "./lib";
+import type { BulkResult, CancellationDTO, EntryDTO, NotificationDTO, PaymentDTO, ReservationDTO } from "./models";
+
+/** Columns of reservations usable in filters and orders */
+export type ReservationField =
+  | "id"
+  | "created_at"
+  | "updated_at"
+  | "user_id"
+  | "business_id"
+  | "date"
+  | "number_of_people"
+  | "status"
+  | "service_id"
+  | "staff_id"
+  | "ends_at"
+  | "series_id"
+  | "occurrence_at"
+  | "cancelled_at"
+  | "cancellation_reason"
+  | "cancellation_fee"
+  | "resource_ids";
+
+export const reservationFilters = filterBuilder<ReservationField>();
+
+export const createReservationsClient = (config: ClientConfig) => ({
+  /** Get all reservations */
+  list: (query: { filters?: Filter<ReservationField>[]; orders?: Order<ReservationField>[]; relations?: string[]; page?: number; size?: number } = {}) =>
+    request<Page<ReservationDTO>>(config, "GET", `/reservations`, { query }),
+  /** Get all reservations, as a file */
+  export: (format: "text/csv" | "application/x-ndjson" | "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", query: { filters?: Filter<ReservationField>[]; orders?: Order<ReservationField>[]; fields?: ReservationField[] } = {}) =>
+    download(config, `/reservations`, format, { query }),
+  /** Count reservations */
+  count: (query: { filters?: Filter<ReservationField>[] } = {}) =>
+    request<number>(config, "GET", `/reservations/count`, { query }),
+  /** Get deleted reservations */
+  listDeleted: (query: { filters?: Filter<ReservationField>[]; orders?: Order<ReservationField>[]; relations?: string[]; page?: number; size?: number } = {}) =>
+    request<Page<ReservationDTO>>(config, "GET", `/reservations/deleted`, { query }),
+  /** Get deleted reservations, as a file */
+  exportDeleted: (format: "text/csv" | "application/x-ndjson" | "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", query: { filters?: Filter<ReservationField>[]; orders?: Order<ReservationField>[]; fields?: ReservationField[] } = {}) =>
+    download(config, `/reservations/deleted`, format, { query }),
+  /** Bulk create reservations */
+  bulkCreate: (payload: Input<ReservationDTO>[], query: { mode?: BulkMode } = {}) =>
+    request<BulkResult>(config, "POST", `/reservations/bulk`, { body: payload, query }),
+  /** Bulk update reservations */
+  bulkUpdate: (payload: Input<ReservationDTO>[], query: { mode?: BulkMode } = {}) =>
+    request<BulkResult>(config, "PATCH", `/reservations/bulk`, { body: payload, query }),
+  /** Bulk delete reservations */
+  bulkDelete: (payload: string[], query: { mode?: BulkMode } = {}) =>
+    request<BulkResult>(config, "DELETE", `/reservations/bulk`, { body: payload, query }),
+  /** Get one reservation */
+  get: (id: string, query: { relations?: string[] } = {}) =>
+    request<ReservationDTO>(config, "GET", `/reservations/${encodeURIComponent(id)}`, { query }),
+  /** Create one reservation */
+  create: (payload: Input<ReservationDTO>) =>
+    request<ReservationDTO>(config, "POST", `/reservations`, { body: payload }),
+  /** Update one reservation */
+  update: (id: string, payload: Input<ReservationDTO>) =>
+    request<ReservationDTO>(config, "PUT", `/reservations/${encodeURIComponent(id)}`, { body: payload }),
+  /** Delete one reservation */
+  delete: (id: string) =>
+    request<string>(config, "DELETE", `/reservations/${encodeURIComponent(id)}`),
+  /** Get history of one reservation */
+  history: (id: string, query: { page?: number; size?: number } = {}) =>
+    request<Page<EntryDTO>>(config, "GET", `/reservations/${encodeURIComponent(id)}/history`, { query }),
+  /** Hard delete one reservation */
+  hardDelete: (id: string) =>
+    request<string>(config, "DELETE", `/reservations/${encodeURIComponent(id)}/hard`),
+  /** Get calendar of one reservation, as a URL */
+  getCalendarOfOneReservation: (id: string) =>
+    url(config, `/reservations/${encodeURIComponent(id)}.ics`),
+  /** Cancel one reservation */
+  cancelOneReservation: (id: string, payload: CancellationDTO) =>
+    request<ReservationDTO>(config, "POST", `/reservations/${encodeURIComponent(id)}/cancel`, { body: payload }),
+  /** Request deposit of one reservation */
+  requestDepositOfOneReservation: (id: string) =>
+    request<PaymentDTO>(config, "POST", `/reservations/${encodeURIComponent(id)}/deposit`),
+  /** Get payments of one reservation */
+  getPaymentsOfOneReservation: (id: string) =>
+    request<PaymentDTO[]>(config, "GET", `/reservations/${encodeURIComponent(id)}/payments`),
+  /** Get notifications of one reservation */
+  getNotificationsOfOneReservation: (id: string) =>
+    request<NotificationDTO[]>(config, "GET", `/reservations/${encodeURIComponent(id)}/notifications`),
+});
@@ -0,0 +1,66 @@
+// Code generated by `backend codegen ts`. DO NOT EDIT.
+
+import { download, filterBuilder, request } from "./lib";
+import type { BulkMode, ClientConfig, Filter, Input, Order, Page } from "./lib";
+import type { BulkResult, EntryDTO, ImportResult, ScheduleDTO } from "./models";
+
+/** Columns of schedules usable in filters and orders */
+export type ScheduleField =
+  | "id"
+  | "created_at"
+  | "updated_at"
+  | "business_id"
+  | "staff_id"
+  | "day_of_week"
+  | "start_time"
+  | "end_time";
+
+export const scheduleFilters = filterBuilder<ScheduleField>();
+
+export const createSchedulesClient = (config: ClientConfig) => ({
+  /** Get all schedules */
+  list: (query: { filters?: Filter<ScheduleField>[]; orders?: Order<ScheduleField>[]; relations?: string[]; page?: number; size?: number } = {}) =>
+    request<Page<ScheduleDTO>>(config, "GET", `/schedules`, { query }),
+  /** Get all schedules, as a file */
+  export: (format: "text/csv" | "application/x-ndjson" | "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", query: { filters?: Filter<ScheduleField>[]; orders?: Order<ScheduleField>[]; fields?: ScheduleField[] } = {}) =>
+    download(config, `/schedules`, format, { query }),
+  /** Count schedules */
+  count: (query: { filters?: Filter<ScheduleField>[] } = {}) =>
+    request<number>(config, "GET", `/schedules/count`, { query }),
+  /** Get deleted schedules */
+  listDeleted: (query: { filters?: Filter<ScheduleField>[]; orders?: Order<ScheduleField>[]; relations?: string[]; page?: number; size?: number } = {}) =>
+    request<Page<ScheduleDTO>>(config, "GET", `/schedules/deleted`, { query }),
+  /** Get deleted schedules, as a file */
+  exportDeleted: (format: "text/csv" | "application/x-ndjson" | "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", query: { filters?: Filter<ScheduleField>[]; orders?: Order<ScheduleField>[]; fields?: ScheduleField[] } = {}) =>
+    download(config, `/schedules/deleted`, format, { query }),
+  /** Bulk create schedules */
+  bulkCreate: (payload: Input<ScheduleDTO>[], query: { mode?: BulkMode } = {}) =>
+    request<BulkResult>(config, "POST", `/schedules/bulk`, { body: payload, query }),
+  /** Bulk update schedules */
+  bulkUpdate: (payload: Input<ScheduleDTO>[], query: { mode?: BulkMode } = {}) =>
+    request<BulkResult>(config, "PATCH", `/schedules/bulk`, { body: payload, query }),
+  /** Bulk delete schedules */
+  bulkDelete: (payload: string[], query: { mode?: BulkMode } = {}) =>
+    request<BulkResult>(config, "DELETE", `/schedules/bulk`, { body: payload, query }),
+  /** Get one schedule */
+  get: (id: string, query: { relations?: string[] } = {}) =>
+    request<ScheduleDTO>(config, "GET", `/schedules/${encodeURIComponent(id)}`, { query }),
+  /** Create one schedule */
+  create: (payload: Input<ScheduleDTO>) =>
+    request<ScheduleDTO>(config, "POST", `/schedules`, { body: payload }),
+  /** Update one schedule */
+  update: (id: string, payload: Input<ScheduleDTO>) =>
+    request<ScheduleDTO>(config, "PUT", `/schedules/${encodeURIComponent(id)}`, { body: payload }),
+  /** Delete one schedule */
+  delete: (id: string) =>
+    request<string>(config, "DELETE", `/schedules/${encodeURIComponent(id)}`),
+  /** Get history of one schedule */
+  history: (id: string, query: { page?: number; size?: number } = {}) =>
+    request<Page<EntryDTO>>(config, "GET", `/schedules/${encodeURIComponent(id)}/history`, { query }),
+  /** Hard delete one schedule */
+  hardDelete: (id: string) =>
+    request<string>(config, "DELETE", `/schedules/${encodeURIComponent(id)}/hard`),
+  /** Import schedules */
+  import: (document: Blob | string, query: { dry_run?: boolean } = {}) =>
+    request<ImportResult>(config, "POST", `/schedules/import`, { body: document, contentType: "text/csv", query }),
+});
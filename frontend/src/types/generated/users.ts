@@ -0,0 +1,76 @@
+// Code generated by `backend codegen ts`. DO NOT EDIT.
+
+import { download, filterBuilder, request } from "./lib";
+import type { BulkMode, ClientConfig, Filter, Input, Order, Page } from "./lib";
+import type { BulkResult, CalendarTokenDTO, EntryDTO, ImportResult, UserDTO } from "./models";
+
+/** Columns of users usable in filters and orders */
+export type UserField =
+  | "id"
+  | "created_at"
+  | "updated_at"
+  | "name"
+  | "email"
+  | "password"
+  | "role"
+  | "no_shows"
+  | "no_shows_reset_at";
+
+export const userFilters = filterBuilder<UserField>();
+
+export const createUsersClient = (config: ClientConfig) => ({
+  /** Get all users */
+  list: (query: { filters?: Filter<UserField>[]; orders?: Order<UserField>[]; relations?: string[]; page?: number; size?: number } = {}) =>
+    request<Page<UserDTO>>(config, "GET", `/users`, { query }),
+  /** Get all users, as a file */
+  export: (format: "text/csv" | "application/x-ndjson" | "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", query: { filters?: Filter<UserField>[]; orders?: Order<UserField>[]; fields?: UserField[] } = {}) =>
+    download(config, `/users`, format, { query }),
+  /** Count users */
+  count: (query: { filters?: Filter<UserField>[] } = {}) =>
+    request<number>(config, "GET", `/users/count`, { query }),
+  /** Get deleted users */
+  listDeleted: (query: { filters?: Filter<UserField>[]; orders?: Order<UserField>[]; relations?: string[]; page?: number; size?: number } = {}) =>
+    request<Page<UserDTO>>(config, "GET", `/users/deleted`, { query }),
+  /** Get deleted users, as a file */
+  exportDeleted: (format: "text/csv" | "application/x-ndjson" | "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", query: { filters?: Filter<UserField>[]; orders?: Order<UserField>[]; fields?: UserField[] } = {}) =>
+    download(config, `/users/deleted`, format, { query }),
+  /** Bulk create users */
+  bulkCreate: (payload: Input<UserDTO>[], query: { mode?: BulkMode } = {}) =>
+    request<BulkResult>(config, "POST", `/users/bulk`, { body: payload, query }),
+  /** Bulk update users */
+  bulkUpdate: (payload: Input<UserDTO>[], query: { mode?: BulkMode } = {}) =>
+    request<BulkResult>(config, "PATCH", `/users/bulk`, { body: payload, query }),
+  /** Bulk delete users */
+  bulkDelete: (payload: string[], query: { mode?: BulkMode } = {}) =>
+    request<BulkResult>(config, "DELETE", `/users/bulk`, { body: payload, query }),
+  /** Get one user */
+  get: (id: string, query: { relations?: string[] } = {}) =>
+    request<UserDTO>(config, "GET", `/users/${encodeURIComponent(id)}`, { query }),
+  /** Create one user */
+  create: (payload: Input<UserDTO>) =>
+    request<UserDTO>(config, "POST", `/users`, { body: payload }),
+  /** Update one user */
+  update: (id: string, payload: Input<UserDTO>) =>
+    request<UserDTO>(config, "PUT", `/users/${encodeURIComponent(id)}`, { body: payload }),
+  /** Delete one user */
+  delete: (id: string) =>
+    request<string>(config, "DELETE", `/users/${encodeURIComponent(id)}`),
+  /** Get history of one user */
+  history: (id: string, query: { page?: number; size?: number } = {}) =>
+    request<Page<EntryDTO>>(config, "GET", `/users/${encodeURIComponent(id)}/history`, { query }),
+  /** Hard delete one user */
+  hardDelete: (id: string) =>
+    request<string>(config, "DELETE", `/users/${encodeURIComponent(id)}/hard`),
+  /** Import users */
+  import: (document: Blob | string, query: { dry_run?: boolean } = {}) =>
+    request<ImportResult>(config, "POST", `/users/import`, { body: document, contentType: "text/csv", query }),
+  /** Issue calendar token of one user */
+  issueCalendarTokenOfOneUser: (id: string) =>
+    request<CalendarTokenDTO>(config, "POST", `/users/${encodeURIComponent(id)}/calendar-token`),
+  /** Revoke calendar token of one user */
+  revokeCalendarTokenOfOneUser: (id: string) =>
+    request<string>(config, "DELETE", `/users/${encodeURIComponent(id)}/calendar-token`),
+  /** Reset no-shows of one user */
+  resetNoShowsOfOneUser: (id: string) =>
+    request<UserDTO>(config, "POST", `/users/${encodeURIComponent(id)}/no-shows/reset`),
+});